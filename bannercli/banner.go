@@ -2,62 +2,63 @@
 // to the terminal. These styles include binary representation and simple
 // animation effects to enhance the visual presentation of CLI applications.
 //
+// Every message goes through an Output, which types it at a configurable speed in a terminal and
+// prints it at once otherwise, hides it below its Level (such as LevelQuiet, showing errors only),
+// and colours it with a Theme. The package-level functions use the Output returned by Default.
+//
+// It also renders the progress of long exports with ProgressBar, which redraws a
+// bar with an ETA in a terminal and writes periodic log lines otherwise.
+//
 // # Example Usage
 //
 //	func main() {
 //		// ... existing code ...
 //
-//		// Display a binary banner
+//		// Print errors only, at once
+//		bannercli.Default().Level = bannercli.LevelQuiet
+//		bannercli.Default().Speed = bannercli.SpeedInstant
+//
+//		// Display a typing banner
 //		bannercli.PrintTypingBanner("ChatGPT Session Exporter", 100*time.Millisecond)
+//		bannercli.Success("Sessions exported.")
 //		// Optionally, display an typing animated banner
 //		bannercli.PrintAnimatedBanner("ChatGPT Session Exporter", 3, 200*time.Millisecond)
 //
+//		// Show the progress of an export on standard error
+//		bar := bannercli.NewProgressBar(os.Stderr, "Exporting")
+//		files, err := e.Export(exporter.WithProgress(ctx, bar), rfs, sessions, "output.csv", nil)
+//		bar.Finish()
+//
 //		// ... rest of your main function ...
 //	}
 //
 // Copyright (c) 2023 H0llyW00dzZ
 package bannercli
 
-import (
-	"fmt"
-	"strings"
-	"time"
-)
+import "time"
 
-// PrintBinaryBanner prints a binary representation of a banner.
+// PrintBinaryBanner prints a binary representation of a banner with the default Output.
 // Each character of the message is converted into its binary form.
 // Spaces between words are widened to enhance readability.
 func PrintBinaryBanner(message string) {
-	banner := strings.ReplaceAll(message, " ", "   ")
-	for _, char := range banner {
-		fmt.Printf(" %08b", char)
-	}
-	fmt.Println()
+	std.Binary(message)
 }
 
-// PrintAnimatedBanner prints a simple animated banner by scrolling the message
-// horizontally across the terminal. The animation repeats the number of times
+// PrintAnimatedBanner prints a simple animated banner with the default Output by scrolling the
+// message horizontally across the terminal. The animation repeats the number of times
 // specified by the `repeat` parameter with a delay between each frame as
-// specified by the `delay` parameter.
+// specified by the `delay` parameter, scaled by the speed of the Output.
 func PrintAnimatedBanner(message string, repeat int, delay time.Duration) {
-	for r := 0; r < repeat; r++ {
-		for i := 0; i < len(message); i++ {
-			fmt.Print("\r" + strings.Repeat(" ", i) + message)
-			time.Sleep(delay)
-		}
-	}
-	fmt.Println()
+	std.Animate(message, repeat, delay)
 }
 
-// PrintTypingBanner prints the message with a typing animation effect.
+// PrintTypingBanner prints the message as a banner with a typing animation effect, using the
+// default Output.
 //
-// Each character appears sequentially with a delay, simulating a typing effect.
+// Each character appears sequentially with a delay, scaled by the speed of the Output, simulating
+// a typing effect. Outside of a terminal, the message is printed at once.
 //
 // Note: This simulation typing just like a human would type.
 func PrintTypingBanner(message string, delay time.Duration) {
-	for _, char := range message {
-		fmt.Printf("%c", char)
-		time.Sleep(delay)
-	}
-	fmt.Println()
+	std.typeMessage(LevelNormal, std.stdout(), std.Theme.Banner, message, delay)
 }
@@ -0,0 +1,373 @@
+// Below, the package bannercli (@output.go) provides Output, the layer every message of the CLI
+// goes through. An Output animates messages at a configurable speed in a terminal and prints
+// them at once otherwise, filters them by Level, and colours them with a Theme.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package bannercli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTypingDelay is the delay between the characters of a message typed at SpeedSlow.
+	DefaultTypingDelay = 100 * time.Millisecond
+
+	// DefaultMaxTyping caps the time taken to type a single message, so that long messages such
+	// as errors are not typed for tens of seconds.
+	DefaultMaxTyping = 1500 * time.Millisecond
+
+	// minTypingDelay is the shortest pause while typing; faster messages are typed several
+	// characters at a time, since shorter sleeps last longer than requested.
+	minTypingDelay = 10 * time.Millisecond
+)
+
+// Level selects the messages shown by an Output.
+type Level int
+
+const (
+	// LevelQuiet shows errors only.
+	LevelQuiet Level = iota - 1
+	// LevelNormal shows banners, information, successes, warnings and errors.
+	LevelNormal
+	// LevelVerbose also shows debugging details.
+	LevelVerbose
+)
+
+// Speed scales the delays of animations: 1 plays them as requested by the caller, 0 disables them.
+// It implements flag.Value, accepting the name of a speed or a non-negative factor.
+type Speed float64
+
+const (
+	// SpeedInstant prints messages at once.
+	SpeedInstant Speed = 0
+	// SpeedFast types messages ten times faster than requested.
+	SpeedFast Speed = 0.1
+	// SpeedNormal types messages about three times faster than requested.
+	SpeedNormal Speed = 0.3
+	// SpeedSlow types messages as slowly as requested, one character every DefaultTypingDelay.
+	SpeedSlow Speed = 1
+)
+
+// speedNames maps the names accepted by ParseSpeed to their speed.
+var speedNames = map[string]Speed{
+	"instant": SpeedInstant,
+	"fast":    SpeedFast,
+	"normal":  SpeedNormal,
+	"slow":    SpeedSlow,
+}
+
+// ParseSpeed returns the speed with the given name, or the speed factor given as a number.
+func ParseSpeed(s string) (Speed, error) {
+	if speed, ok := speedNames[strings.ToLower(s)]; ok {
+		return speed, nil
+	}
+	factor, err := strconv.ParseFloat(s, 64)
+	if err != nil || factor < 0 {
+		return SpeedNormal, fmt.Errorf("invalid speed %q: use instant, fast, normal, slow or a non-negative factor", s)
+	}
+	return Speed(factor), nil
+}
+
+// String returns the name of the speed, or its factor if it has no name.
+func (s Speed) String() string {
+	for name, speed := range speedNames {
+		if speed == s {
+			return name
+		}
+	}
+	return strconv.FormatFloat(float64(s), 'g', -1, 64)
+}
+
+// Set sets the speed from its name or factor, for use as a flag.Value.
+func (s *Speed) Set(value string) error {
+	speed, err := ParseSpeed(value)
+	if err != nil {
+		return err
+	}
+	*s = speed
+	return nil
+}
+
+// Theme holds the colours of the kinds of messages, as SGR parameters such as "1;36" for bold cyan.
+// An empty parameter leaves the messages of its kind uncoloured.
+type Theme struct {
+	Name    string // Name of the theme, as accepted by LookupTheme.
+	Banner  string // Colour of banners.
+	Info    string // Colour of information messages.
+	Success string // Colour of success messages.
+	Warning string // Colour of warnings.
+	Error   string // Colour of errors.
+	Debug   string // Colour of debugging details.
+}
+
+// themes holds the built-in themes by name.
+var themes = map[string]Theme{
+	"none":    {Name: "none"},
+	"default": {Name: "default", Banner: "1;36", Success: "32", Warning: "33", Error: "31", Debug: "2"},
+	"high-contrast": {Name: "high-contrast", Banner: "1;97;44", Info: "97", Success: "1;92", Warning: "1;93",
+		Error: "1;91", Debug: "37"},
+}
+
+// LookupTheme returns the built-in theme with the given name.
+func LookupTheme(name string) (Theme, bool) {
+	theme, ok := themes[strings.ToLower(name)]
+	return theme, ok
+}
+
+// ThemeNames returns the names of the built-in themes, sorted.
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Output writes the messages of the CLI. Banners, information and successes go to Out; warnings,
+// errors and debugging details go to Err, so that they stay visible when Out is redirected.
+type Output struct {
+	mu          sync.Mutex
+	Out         io.Writer     // Banners, information and successes are written to Out; nil writes to os.Stdout.
+	Err         io.Writer     // Warnings, errors and debugging details are written to Err; nil writes to os.Stderr.
+	Interactive bool          // Whether messages are animated; set if Out is a terminal.
+	Color       bool          // Whether messages are coloured; set if Out is a terminal and NO_COLOR is not set.
+	Level       Level         // Messages below Level are not shown.
+	Speed       Speed         // Scale of the animation delays.
+	Theme       Theme         // Colours of the messages.
+	MaxTyping   time.Duration // Longest time taken to type a message; 0 means no limit.
+	Logger      *slog.Logger  // If set, warnings and errors are also logged to Logger, whatever Level is.
+}
+
+// NewOutput returns an Output writing to out and errOut at SpeedNormal with the default theme.
+// Messages are only animated and coloured if out is a terminal. A nil writer stands for the
+// standard output or error as it is when a message is written, so that it can be redirected.
+func NewOutput(out, errOut io.Writer) *Output {
+	interactive := false
+	if out == nil {
+		interactive = IsTerminal(os.Stdout)
+	} else if f, ok := out.(*os.File); ok {
+		interactive = IsTerminal(f)
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	return &Output{
+		Out:         out,
+		Err:         errOut,
+		Interactive: interactive,
+		Color:       interactive && !noColor,
+		Speed:       SpeedNormal,
+		Theme:       themes["default"],
+		MaxTyping:   DefaultMaxTyping,
+	}
+}
+
+// std is the Output used by the package-level functions.
+var std = NewOutput(nil, nil)
+
+// Default returns the Output used by the package-level functions, such as PrintTypingBanner.
+func Default() *Output {
+	return std
+}
+
+// SetDefault makes o the Output used by the package-level functions.
+func SetDefault(o *Output) {
+	std = o
+}
+
+// Banner types message as a banner. It is not shown at LevelQuiet.
+func (o *Output) Banner(message string) {
+	o.typeMessage(LevelNormal, o.stdout(), o.Theme.Banner, message, DefaultTypingDelay)
+}
+
+// Info types an information message. It is not shown at LevelQuiet.
+func (o *Output) Info(message string) {
+	o.typeMessage(LevelNormal, o.stdout(), o.Theme.Info, message, DefaultTypingDelay)
+}
+
+// Print prints an information message at once, for log-like lines such as those of the
+// non-interactive commands. It is not shown at LevelQuiet.
+func (o *Output) Print(message string) {
+	o.typeMessage(LevelNormal, o.stdout(), o.Theme.Info, message, 0)
+}
+
+// Success types a message reporting a completed operation. It is not shown at LevelQuiet.
+func (o *Output) Success(message string) {
+	o.typeMessage(LevelNormal, o.stdout(), o.Theme.Success, message, DefaultTypingDelay)
+}
+
+// Warn types a warning on Err. It is not shown at LevelQuiet.
+func (o *Output) Warn(message string) {
+	o.log(slog.LevelWarn, message)
+	o.typeMessage(LevelNormal, o.stderr(), o.Theme.Warning, message, DefaultTypingDelay)
+}
+
+// Error types an error on Err. Errors are shown at every level.
+func (o *Output) Error(message string) {
+	o.log(slog.LevelError, message)
+	o.typeMessage(LevelQuiet, o.stderr(), o.Theme.Error, message, DefaultTypingDelay)
+}
+
+// Debug prints debugging details on Err at once. They are only shown at LevelVerbose.
+func (o *Output) Debug(message string) {
+	o.typeMessage(LevelVerbose, o.stderr(), o.Theme.Debug, message, 0)
+}
+
+// Enabled reports whether messages of the given level are shown.
+func (o *Output) Enabled(level Level) bool {
+	return o.Level >= level
+}
+
+// Animate scrolls message horizontally repeat times, with delay between the frames, as a banner.
+// Outside of a terminal, or at SpeedInstant, message is printed once.
+func (o *Output) Animate(message string, repeat int, delay time.Duration) {
+	if !o.Enabled(LevelNormal) {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delay = o.scale(delay)
+	if !o.Interactive || delay == 0 {
+		fmt.Fprintln(o.stdout(), o.colorize(o.Theme.Banner, message))
+		return
+	}
+	for r := 0; r < repeat; r++ {
+		for i := 0; i < len(message); i++ {
+			fmt.Fprint(o.stdout(), "\r"+strings.Repeat(" ", i)+o.colorize(o.Theme.Banner, message))
+			time.Sleep(delay)
+		}
+	}
+	fmt.Fprintln(o.stdout())
+}
+
+// Binary prints every character of message in binary as a banner, with the spaces between words
+// widened to enhance readability.
+func (o *Output) Binary(message string) {
+	if !o.Enabled(LevelNormal) {
+		return
+	}
+	var b strings.Builder
+	for _, char := range strings.ReplaceAll(message, " ", "   ") {
+		fmt.Fprintf(&b, " %08b", char)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fmt.Fprintln(o.stdout(), o.colorize(o.Theme.Banner, b.String()))
+}
+
+// typeMessage writes message and a newline to w if level is shown, with delay between the
+// characters scaled by Speed and capped by MaxTyping. Messages are typed in a terminal only.
+func (o *Output) typeMessage(level Level, w io.Writer, color, message string, delay time.Duration) {
+	if !o.Enabled(level) {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !o.isTerminal(w) {
+		fmt.Fprintln(w, message)
+		return
+	}
+	delay = o.scale(delay)
+	if n := time.Duration(len([]rune(message))); o.MaxTyping > 0 && n > 0 && delay*n > o.MaxTyping {
+		delay = o.MaxTyping / n
+	}
+	if delay == 0 {
+		fmt.Fprintln(w, o.colorize(color, message))
+		return
+	}
+
+	if o.Color && color != "" {
+		fmt.Fprint(w, "\x1b["+color+"m")
+		defer fmt.Fprint(w, "\x1b[0m\n")
+	} else {
+		defer fmt.Fprintln(w)
+	}
+	runes, step := []rune(message), 1
+	if delay < minTypingDelay {
+		step = int((minTypingDelay + delay - 1) / delay)
+		delay *= time.Duration(step)
+	}
+	for i := 0; i < len(runes); i += step {
+		fmt.Fprint(w, string(runes[i:min(i+step, len(runes))]))
+		time.Sleep(delay)
+	}
+}
+
+// isTerminal reports whether messages written to w may be animated and coloured: w must be Out,
+// if Out is interactive, or a terminal, so that a redirected standard error stays plain.
+func (o *Output) isTerminal(w io.Writer) bool {
+	if !o.Interactive {
+		return false
+	}
+	if f, ok := w.(*os.File); ok && w != o.stdout() {
+		return IsTerminal(f)
+	}
+	return true
+}
+
+// log logs message at level to Logger, if set, without its surrounding blank lines and tag.
+func (o *Output) log(level slog.Level, message string) {
+	if o.Logger == nil {
+		return
+	}
+	message = strings.TrimPrefix(strings.TrimSpace(message), "[GopherHelper] ")
+	o.Logger.Log(context.Background(), level, message)
+}
+
+// stdout returns the writer of banners, information and successes.
+func (o *Output) stdout() io.Writer {
+	if o.Out == nil {
+		return os.Stdout
+	}
+	return o.Out
+}
+
+// stderr returns the writer of warnings, errors and debugging details.
+func (o *Output) stderr() io.Writer {
+	if o.Err == nil {
+		return os.Stderr
+	}
+	return o.Err
+}
+
+// scale returns delay scaled by Speed.
+func (o *Output) scale(delay time.Duration) time.Duration {
+	return time.Duration(float64(delay) * float64(o.Speed))
+}
+
+// colorize wraps text in the SGR sequence of color, if colours are enabled.
+func (o *Output) colorize(color, text string) string {
+	if !o.Color || color == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + "\x1b[0m"
+}
+
+// Info types an information message with the default Output.
+func Info(message string) { std.Info(message) }
+
+// Print prints an information message at once with the default Output.
+func Print(message string) { std.Print(message) }
+
+// Success types a message reporting a completed operation with the default Output.
+func Success(message string) { std.Success(message) }
+
+// Warn types a warning with the default Output.
+func Warn(message string) { std.Warn(message) }
+
+// Error types an error with the default Output.
+func Error(message string) { std.Error(message) }
+
+// Debug prints debugging details with the default Output.
+func Debug(message string) { std.Debug(message) }
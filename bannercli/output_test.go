@@ -0,0 +1,97 @@
+// Below, the package bannercli (@output_test.go) tests the levels, colours and typing speed of
+// Output.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package bannercli_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+)
+
+// TestOutput checks the output layer of bannercli: messages are filtered by level, printed at
+// once outside of a terminal, coloured by the theme, and typed within the configured time limit.
+func TestOutput(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	o := bannercli.NewOutput(out, errOut)
+	if o.Interactive || o.Color {
+		t.Fatal("NewOutput() of a buffer is interactive, want plain output")
+	}
+	emit := func() {
+		out.Reset()
+		errOut.Reset()
+		o.Banner("banner")
+		o.Info("info")
+		o.Print("print")
+		o.Success("success")
+		o.Warn("warning")
+		o.Error("error")
+		o.Debug("debug")
+	}
+
+	levels := []struct {
+		level   bannercli.Level
+		wantOut string
+		wantErr string
+	}{
+		{bannercli.LevelQuiet, "", "error\n"},
+		{bannercli.LevelNormal, "banner\ninfo\nprint\nsuccess\n", "warning\nerror\n"},
+		{bannercli.LevelVerbose, "banner\ninfo\nprint\nsuccess\n", "warning\nerror\ndebug\n"},
+	}
+	for _, tc := range levels {
+		o.Level = tc.level
+		start := time.Now()
+		emit()
+		if out.String() != tc.wantOut || errOut.String() != tc.wantErr {
+			t.Errorf("Level %d wrote %q and %q, want %q and %q", tc.level, out.String(), errOut.String(), tc.wantOut, tc.wantErr)
+		}
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Errorf("Level %d took %s without a terminal, want instant output", tc.level, elapsed)
+		}
+	}
+
+	// Pretend to be a terminal to check the colours and the cap on the typing time.
+	o.Level, o.Interactive, o.Color = bannercli.LevelNormal, true, true
+	o.Theme, _ = bannercli.LookupTheme("default")
+	o.Speed = bannercli.SpeedSlow
+	o.MaxTyping = 30 * time.Millisecond
+	emit()
+	if !strings.HasPrefix(out.String(), "\x1b[1;36mbanner\x1b[0m\n") || !strings.Contains(errOut.String(), "\x1b[31merror\x1b[0m\n") {
+		t.Errorf("Coloured output = %q and %q", out.String(), errOut.String())
+	}
+	start := time.Now()
+	o.Error(strings.Repeat("a long error message ", 50))
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Typing a long error took %s, want at most about %s", elapsed, o.MaxTyping)
+	}
+	o.Theme, _ = bannercli.LookupTheme("none")
+	out.Reset()
+	o.Binary("Go")
+	if out.String() != " 01000111 01101111\n" {
+		t.Errorf("Binary() = %q", out.String())
+	}
+
+	o.Interactive = false
+	out.Reset()
+	o.Animate("scrolling", 3, time.Second)
+	if out.String() != "scrolling\n" {
+		t.Errorf("Animate() without a terminal = %q, want the message once", out.String())
+	}
+
+	var speed bannercli.Speed
+	for input, want := range map[string]bannercli.Speed{"instant": bannercli.SpeedInstant, "Fast": bannercli.SpeedFast, "0.5": 0.5} {
+		if err := speed.Set(input); err != nil || speed != want {
+			t.Errorf("Speed.Set(%q) = %v, %v, want %v", input, speed, err, want)
+		}
+	}
+	if err := speed.Set("-1"); err == nil {
+		t.Error("Speed.Set(-1) succeeded, want an error")
+	}
+	if _, ok := bannercli.LookupTheme("high-contrast"); !ok {
+		t.Error("LookupTheme(high-contrast) failed")
+	}
+}
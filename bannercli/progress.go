@@ -0,0 +1,192 @@
+// Below, the package bannercli (@progress.go) renders the progress of long exports: as a live
+// progress bar with an ETA in a terminal, and as periodic log lines when the output is redirected
+// to a file or a pipe, where a redrawn bar would only clutter the log.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package bannercli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+const (
+	// DefaultBarWidth is the number of characters of the bar itself.
+	DefaultBarWidth = 30
+
+	// DefaultRefreshInterval is the minimum time between two redraws of the bar in a terminal.
+	DefaultRefreshInterval = 100 * time.Millisecond
+
+	// DefaultLogInterval is the minimum time between two log lines when not in a terminal.
+	DefaultLogInterval = 5 * time.Second
+)
+
+// Ensure the progress bar can be passed to exporter.WithProgress.
+var _ exporter.ProgressReporter = (*ProgressBar)(nil)
+
+// ProgressBar renders the exporter.Progress of an export. Passed to exporter.WithProgress, it
+// shows the progress of every export run with the returned context.
+type ProgressBar struct {
+	Label       string        // Shown in front of the progress, e.g. "Exporting".
+	Interactive bool          // Whether to redraw a bar in place instead of writing log lines.
+	Width       int           // Number of characters of the bar.
+	Interval    time.Duration // Minimum time between two updates, see DefaultRefreshInterval and DefaultLogInterval.
+
+	mu       sync.Mutex
+	w        io.Writer
+	started  bool
+	start    time.Time         // When the current export started.
+	last     time.Time         // When the progress was last shown.
+	progress exporter.Progress // The latest progress received.
+	lineLen  int               // Length of the bar last drawn, to clear what is left of it.
+}
+
+// NewProgressBar returns a progress bar writing to w. The bar is interactive if w is a terminal.
+func NewProgressBar(w io.Writer, label string) *ProgressBar {
+	interactive := false
+	if f, ok := w.(*os.File); ok {
+		interactive = IsTerminal(f)
+	}
+	interval := DefaultLogInterval
+	if interactive {
+		interval = DefaultRefreshInterval
+	}
+	return &ProgressBar{Label: label, Interactive: interactive, Width: DefaultBarWidth, Interval: interval, w: w}
+}
+
+// IsTerminal reports whether f is a terminal rather than a file or a pipe.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Report records p and shows it if the interval has passed since the progress was last shown.
+// A progress without any written session or byte marks the start of a new export, which
+// completes the progress of the previous export as Finish does.
+func (b *ProgressBar) Report(p exporter.Progress) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if p.Sessions == 0 && p.Bytes == 0 && b.started {
+		// A new export, such as the next output of a pipeline, completes the previous one.
+		b.finish(now)
+	}
+	if !b.started {
+		b.started = true
+		b.start = now
+		b.last = time.Time{}
+		b.lineLen = 0
+	}
+	b.progress = p
+
+	switch {
+	case b.last.IsZero():
+		// The bar is drawn right away, while log lines only start after the first interval,
+		// so that short exports log a single line from Finish.
+		if b.Interactive {
+			b.draw(now)
+		}
+	case now.Sub(b.last) < b.Interval:
+		return
+	case b.Interactive:
+		b.draw(now)
+	default:
+		fmt.Fprintf(b.w, "[GopherHelper] %s: %s\n", b.Label, b.status(now))
+	}
+	b.last = now
+}
+
+// Finish shows the final progress: it completes the bar in a terminal, or writes a last log line
+// with the time the export took. It does nothing if no export reported its progress.
+func (b *ProgressBar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.started {
+		b.finish(time.Now())
+	}
+}
+
+// finish shows the final progress of the current export.
+func (b *ProgressBar) finish(now time.Time) {
+	if b.Interactive {
+		b.draw(now)
+		fmt.Fprintln(b.w)
+	} else {
+		p := b.progress
+		fmt.Fprintf(b.w, "[GopherHelper] %s: %d/%d sessions, %d/%d messages, %s in %s\n", b.Label,
+			p.Sessions, p.TotalSessions, p.Messages, p.TotalMessages, FormatBytes(p.Bytes), formatDuration(now.Sub(b.start)))
+	}
+	b.started = false
+}
+
+// draw redraws the bar in place.
+func (b *ProgressBar) draw(now time.Time) {
+	width := b.Width
+	if width <= 0 {
+		width = DefaultBarWidth
+	}
+	filled := int(b.progress.Fraction() * float64(width))
+	if filled > width {
+		filled = width
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < width {
+		bar += ">" + strings.Repeat(" ", width-filled-1)
+	}
+
+	line := fmt.Sprintf("%s [%s] %s", b.Label, bar, b.status(now))
+	padding := ""
+	if len(line) < b.lineLen {
+		padding = strings.Repeat(" ", b.lineLen-len(line))
+	}
+	b.lineLen = len(line)
+	fmt.Fprint(b.w, "\r"+line+padding)
+}
+
+// status describes the latest progress along with the estimated time left.
+func (b *ProgressBar) status(now time.Time) string {
+	p := b.progress
+	fraction := p.Fraction()
+	return fmt.Sprintf("%3.0f%% %d/%d sessions, %s, ETA %s",
+		100*fraction, p.Sessions, p.TotalSessions, FormatBytes(p.Bytes), eta(now.Sub(b.start), fraction))
+}
+
+// eta estimates the time left from the time elapsed so far and the completed fraction.
+func eta(elapsed time.Duration, fraction float64) string {
+	if fraction >= 1 {
+		return formatDuration(0)
+	}
+	if fraction <= 0 || elapsed <= 0 {
+		return "--"
+	}
+	left := time.Duration(float64(elapsed) * (1 - fraction) / fraction)
+	return formatDuration(left)
+}
+
+// formatDuration formats d rounded to the second, e.g. "1m5s".
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// FormatBytes formats n bytes in the largest binary unit that keeps the value at least 1,
+// e.g. "512 B" or "1.5 MiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,67 @@
+// Below, the package bannercli (@progress_test.go) tests the rendering of ProgressBar.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package bannercli_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// TestProgressBar verifies that the progress bar renders the progress of an export as log lines
+// without a terminal, and as a bar redrawn in place in a terminal.
+func TestProgressBar(t *testing.T) {
+	sessions := make([]exporter.Session, 20)
+	for i := range sessions {
+		sessions[i] = exporter.Session{ID: fmt.Sprintf("s%03d", i), Messages: make([]exporter.Message, 3)}
+	}
+
+	// Without a terminal, the bar logs every interval and once more when the export is finished.
+	var logged bytes.Buffer
+	bar := bannercli.NewProgressBar(&logged, "Exporting")
+	if bar.Interactive {
+		t.Fatal("NewProgressBar() is interactive on a buffer")
+	}
+	bar.Interval = 0
+	if err := exporter.WriteJSONL(exporter.WithProgress(context.Background(), bar), io.Discard, sessions); err != nil {
+		t.Fatalf("WriteJSONL() returned error: %v", err)
+	}
+	bar.Finish()
+	if !strings.Contains(logged.String(), "[GopherHelper] Exporting:  50% 10/20 sessions") {
+		t.Errorf("The log does not show the progress halfway:\n%s", logged.String())
+	}
+	finished := fmt.Sprintf("[GopherHelper] Exporting: 20/20 sessions, 60/60 messages, %s in 0s\n", bannercli.FormatBytes(jsonlSize(t, sessions)))
+	if !strings.HasSuffix(logged.String(), finished) {
+		t.Errorf("The log does not end with the finished export:\n%s", logged.String())
+	}
+
+	// In a terminal, the bar is redrawn in place and completed on its own line.
+	var term bytes.Buffer
+	bar = bannercli.NewProgressBar(&term, "Exporting")
+	bar.Interactive, bar.Width = true, 10
+	bar.Report(exporter.Progress{TotalSessions: 2, TotalMessages: 4})
+	bar.Report(exporter.Progress{Sessions: 2, TotalSessions: 2, Messages: 4, TotalMessages: 4, Bytes: 2048})
+	bar.Finish()
+	if want := "\rExporting [>         ]   0% 0/2 sessions, 0 B, ETA --"; !strings.HasPrefix(term.String(), want) {
+		t.Errorf("The bar starts with %q, want %q", term.String(), want)
+	}
+	if want := "\rExporting [==========] 100% 2/2 sessions, 2.0 KiB, ETA 0s\n"; !strings.HasSuffix(term.String(), want) {
+		t.Errorf("The bar ends with %q, want %q", term.String(), want)
+	}
+}
+
+// jsonlSize returns the size of sessions in the JSONL format.
+func jsonlSize(t *testing.T, sessions []exporter.Session) int64 {
+	var buf bytes.Buffer
+	if err := exporter.WriteJSONL(context.Background(), &buf, sessions); err != nil {
+		t.Fatalf("WriteJSONL() returned error: %v", err)
+	}
+	return int64(buf.Len())
+}
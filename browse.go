@@ -0,0 +1,122 @@
+// @browse.go:
+// Package main (browse.go) implements the browse command, which opens a backup in a full-screen
+// session browser and exports only the sessions picked there, so that specific conversations can
+// be exported without writing a filter.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pipeline"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tui"
+)
+
+// runBrowseCommand shows the sessions of the backup given as argument in the session browser and
+// exports the selection, either with the format given by -format or through the interactive menus.
+func runBrowseCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	format := flags.String("format", "", "export the selection with this format instead of asking: "+strings.Join(exporter.Names(), ", "))
+	outDir := flags.String("out", ".", "directory of the files written with -format")
+	keyFile := flags.String("key-file", "", "file whose first line is the passphrase of an encrypted backup")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ChatGPT-Next-Web-Session-Exporter browse [flags] BACKUP")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, i18n.T("error.oneBackup", "browse"))
+		flags.Usage()
+		return ExitUsage
+	}
+	var e exporter.Exporter
+	if *format != "" {
+		var ok bool
+		if e, ok = exporter.Lookup(*format); !ok {
+			fmt.Fprintln(os.Stderr, i18n.T("error.unknownFormat", "browse", *format, strings.Join(exporter.Names(), ", ")))
+			return ExitUsage
+		}
+	}
+	if !tui.IsTerminal(os.Stdin, os.Stdout) {
+		fmt.Fprintln(os.Stderr, i18n.T("error.notTerminal", "browse"))
+		return ExitUsage
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := &encryption.KeySource{KeyFile: *keyFile, Prompt: func() (string, error) {
+		return interactivity.PromptPassphrase(ctx, reader, i18n.T(PromptEnterPassphrase))
+	}}
+	rfs := &filesystem.RealFileSystem{}
+	store, err := exporter.ReadJSONFromFileWithKeys(rfs, flags.Arg(0), keys)
+	if err != nil {
+		return printError(i18n.T("error.parseJSON"), err)
+	}
+	recordInput(flags.Arg(0), len(store.ChatNextWebStore.Sessions))
+
+	selection, ok := browseSessions(ctx, reader, store.ChatNextWebStore.Sessions)
+	if !ok {
+		return ExitOK
+	}
+	if e == nil {
+		outputOption, err := promptForInput(ctx, reader, outputFormatMenu())
+		if err != nil {
+			handleError("Error reading input", err)
+			return ExitFailure
+		}
+		processOutputOption(rfs, ctx, reader, outputOption, selection)
+		return ExitOK
+	}
+
+	files, err := exportSelection(rfs, ctx, e, selection, *outDir)
+	if err != nil {
+		return printError(i18n.T("error.exportSessions"), err)
+	}
+	for _, file := range files {
+		bannercli.Print("[GopherHelper] " + i18n.T("success.outputSaved", e.Description(), file))
+	}
+	return ExitOK
+}
+
+// browseSessions shows sessions in the session browser and returns the sessions picked there.
+// It reports false if the user left the browser without a selection or the browser failed.
+func browseSessions(ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) ([]exporter.Session, bool) {
+	selection, err := tui.Run(ctx, os.Stdin, os.Stdout, reader, sessions)
+	switch {
+	case errors.Is(err, tui.ErrQuit):
+		bannercli.Print("[GopherHelper] " + i18n.T("info.cancelled"))
+		return nil, false
+	case err != nil:
+		printError(i18n.T("error.browser"), err)
+		return nil, false
+	}
+	bannercli.Print("[GopherHelper] " + i18n.T("info.sessionsSelected", len(selection), len(sessions)))
+	return selection, true
+}
+
+// exportSelection writes sessions with e into dir, naming the files as the watch command does,
+// records the export into the summary of the run and returns the files written.
+func exportSelection(rfs filesystem.FileSystem, ctx context.Context, e exporter.Exporter, sessions []exporter.Session, dir string) ([]string, error) {
+	var files []string
+	output := watchOutput(e, dir)
+	err := withProgressBar(ctx, func(ctx context.Context) (err error) {
+		files, err = pipeline.Export(ctx, rfs, sessions, output, nil)
+		return err
+	})
+	if err == nil {
+		recordExport(e, output.Path, exporter.FullIncrement(sessions), output.Options, files)
+	}
+	return files, err
+}
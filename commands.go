@@ -0,0 +1,66 @@
+// @commands.go:
+// Package main (commands.go) dispatches the non-interactive commands of the CLI tool.
+// When no command is given, the interactive prompt flow in main.go is used instead.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+)
+
+// command describes a non-interactive command of the CLI tool.
+type command struct {
+	summary i18n.Key                                     // One-line description shown in the usage text.
+	run     func(ctx context.Context, args []string) int // Runs the command and returns the exit code.
+}
+
+// commands maps command names to their implementations.
+var commands = map[string]command{
+	"browse":   {summary: "command.browse", run: runBrowseCommand},
+	"decrypt":  {summary: "command.decrypt", run: runDecryptCommand},
+	"diff":     {summary: "command.diff", run: runDiffCommand},
+	"encrypt":  {summary: "command.encrypt", run: runEncryptCommand},
+	"run":      {summary: "command.run", run: runPipelineCommand},
+	"sanitize": {summary: "command.sanitize", run: runSanitizeCommand},
+	"watch":    {summary: "command.watch", run: runWatchCommand},
+}
+
+// runCommand executes the named command with the remaining command-line arguments.
+// It returns the exit code for the process.
+func runCommand(ctx context.Context, name string, args []string) int {
+	if name == "help" || name == "-h" || name == "--help" {
+		printCommandUsage()
+		return ExitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s\n\n", i18n.T("error.unknownCommand", name))
+		printCommandUsage()
+		return ExitUsage
+	}
+	return cmd.run(ctx, args)
+}
+
+// printCommandUsage prints the list of available commands.
+func printCommandUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, i18n.T("usage.synopsis"))
+	fmt.Fprintf(os.Stderr, "\n%s\n\n%s\n", i18n.T("usage.interactive"), i18n.T("usage.commands"))
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, i18n.T(commands[name].summary))
+	}
+	fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T("usage.globalFlags"))
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\n%s\n", i18n.T("usage.commandFlags"))
+}
@@ -0,0 +1,42 @@
+// Below, the package compression (@bundle.go) bundles several files into a single zip archive,
+// as used for multi-file exports such as separate sessions and messages CSV files.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package compression
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"time"
+)
+
+// BundleEntry is a file stored in a zip bundle.
+type BundleEntry struct {
+	Name string // Name is the path of the file inside the archive.
+	Data []byte // Data is the content of the file.
+}
+
+// WriteBundle writes entries as a zip archive to w, in order, using Deflate compression.
+// Entry names must be unique.
+func WriteBundle(w io.Writer, entries []BundleEntry) error {
+	archive := zip.NewWriter(w)
+	seen := make(map[string]bool, len(entries))
+	modified := time.Now()
+	for _, entry := range entries {
+		if seen[entry.Name] {
+			return fmt.Errorf("duplicate zip entry %q", entry.Name)
+		}
+		seen[entry.Name] = true
+
+		header := &zip.FileHeader{Name: entry.Name, Method: zip.Deflate, Modified: modified}
+		file, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Write(entry.Data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
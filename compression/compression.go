@@ -0,0 +1,250 @@
+// Package compression reads and writes the compressed containers used for NextChat backups
+// and exports: gzip, zstd and zip.
+//
+// Input is detected by file extension (.gz, .zst, .zip) or, failing that, by the magic bytes at
+// the start of the data, so a backup renamed to .json is still read correctly. Plain data is
+// passed through unchanged, which lets callers read every input through this package.
+//
+// # Example Usage
+//
+//	data, err := compression.ReadFile(rfs, "backups/2023-12-01.json.gz")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	store, err := exporter.ReadJSON(bytes.NewReader(data))
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package compression
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Format identifies a compression format.
+type Format string
+
+const (
+	// None is uncompressed data.
+	None Format = ""
+
+	// Gzip is the gzip format (.gz).
+	Gzip Format = "gzip"
+
+	// Zstd is the Zstandard format (.zst).
+	Zstd Format = "zstd"
+
+	// Zip is a zip archive (.zip). When reading, the first .json entry (or the only entry) is used.
+	Zip Format = "zip"
+)
+
+// Magic bytes at the start of each compressed format.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagic  = []byte{'P', 'K', 0x03, 0x04}
+)
+
+// Formats lists the supported compression formats, for flag help and validation.
+var Formats = []Format{Gzip, Zstd, Zip}
+
+// ParseFormat returns the format named by s ("gzip", "zstd", "zip", or "" / "none" for no compression).
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case None, "none":
+		return None, nil
+	case Gzip, Zstd, Zip:
+		return f, nil
+	default:
+		return None, fmt.Errorf("unknown compression %q (valid: gzip, zstd, zip)", s)
+	}
+}
+
+// Extension returns the file extension of the format, including the leading dot.
+func (f Format) Extension() string {
+	switch f {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	case Zip:
+		return ".zip"
+	default:
+		return ""
+	}
+}
+
+// FormatFromExtension returns the format indicated by the extension of name, or None.
+func FormatFromExtension(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz", ".gzip":
+		return Gzip
+	case ".zst", ".zstd":
+		return Zstd
+	case ".zip":
+		return Zip
+	default:
+		return None
+	}
+}
+
+// FormatFromMagic returns the format indicated by the first bytes of the data, or None.
+func FormatFromMagic(header []byte) Format {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return Zstd
+	case bytes.HasPrefix(header, zipMagic):
+		return Zip
+	default:
+		return None
+	}
+}
+
+// TrimExtension removes a compression extension from name, e.g. "backup.json.gz" becomes "backup.json".
+func TrimExtension(name string) string {
+	if FormatFromExtension(name) == None {
+		return name
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// NewReader returns a reader that decompresses r. The format is taken from the extension of
+// name and, if that does not indicate one, from the magic bytes of the data. Uncompressed data
+// is returned as is. The caller must close the returned reader; this does not close r.
+func NewReader(r io.Reader, name string) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	format := FormatFromExtension(name)
+	if header, _ := br.Peek(len(zstdMagic)); FormatFromMagic(header) != None {
+		// The data itself is authoritative, e.g. for a gzip backup saved as .json.
+		format = FormatFromMagic(header)
+	}
+
+	switch format {
+	case Gzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		return zr, nil
+	case Zstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zstd data: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	case Zip:
+		return openZipEntry(br)
+	default:
+		return io.NopCloser(br), nil
+	}
+}
+
+// openZipEntry reads the zip archive from r and opens its backup entry: the first entry with a
+// .json extension (after removing any compression extension), or the only entry of the archive.
+func openZipEntry(r io.Reader) (io.ReadCloser, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+
+	var files []*zip.File
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		if strings.EqualFold(filepath.Ext(TrimExtension(file.Name)), ".json") {
+			return file.Open()
+		}
+	}
+	if len(files) == 1 {
+		return files[0].Open()
+	}
+	return nil, fmt.Errorf("zip archive contains no JSON backup")
+}
+
+// Decompress returns the decompressed contents of data, detecting the format as NewReader does.
+func Decompress(data []byte, name string) ([]byte, error) {
+	if FormatFromMagic(data) == None && FormatFromExtension(name) == None {
+		return data, nil
+	}
+	r, err := NewReader(bytes.NewReader(data), name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// ReadFile reads the named file through rfs and returns its decompressed contents.
+func ReadFile(rfs filesystem.FileSystem, name string) ([]byte, error) {
+	data, err := rfs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := Decompress(data, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return decompressed, nil
+}
+
+// NewWriter returns a writer that compresses everything written to it into w using format.
+// For Zip, the data is stored as a single entry called name. Closing the returned writer
+// flushes the compressed data but does not close w.
+func NewWriter(w io.Writer, format Format, name string) (io.WriteCloser, error) {
+	switch format {
+	case Gzip:
+		zw := gzip.NewWriter(w)
+		zw.Name = filepath.Base(name)
+		return zw, nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	case Zip:
+		zw := zip.NewWriter(w)
+		entry, err := zw.Create(filepath.Base(name))
+		if err != nil {
+			return nil, err
+		}
+		return &zipEntryWriter{Writer: entry, archive: zw}, nil
+	case None:
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("unknown compression %q", format)
+	}
+}
+
+// zipEntryWriter writes a single zip entry and finishes the archive on Close.
+type zipEntryWriter struct {
+	io.Writer             // Writer is the entry being written.
+	archive   *zip.Writer // archive is finished when the entry is closed.
+}
+
+// Close writes the zip central directory.
+func (z *zipEntryWriter) Close() error {
+	return z.archive.Close()
+}
+
+// nopWriteCloser adds a no-op Close method to an io.Writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,51 @@
+// Below, the package compression (@compression_test.go) tests that compressed data is read back
+// whatever its container, detected by extension or by magic bytes.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package compression_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestCompressedInput verifies that compressed backups are read transparently, by extension or by
+// magic bytes, and that plain data is passed through unchanged.
+func TestCompressedInput(t *testing.T) {
+	data, err := os.ReadFile("../testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	inputs := map[string]compression.Format{
+		"backup.json.gz": compression.Gzip,
+		"backup.json":    compression.Zstd, // Detected from the magic bytes despite the extension.
+		"backup.zip":     compression.Zip,
+	}
+	for name, format := range inputs {
+		var buf bytes.Buffer
+		w, err := compression.NewWriter(&buf, format, "backup.json")
+		if err != nil {
+			t.Fatalf("NewWriter(%s) returned error: %v", format, err)
+		}
+		w.Write(data)
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close(%s) returned error: %v", format, err)
+		}
+		mockFS.Files[name] = buf.Bytes()
+
+		if got, err := compression.ReadFile(mockFS, name); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("ReadFile(%s) = %d bytes, %v, want the original %d bytes", name, len(got), err, len(data))
+		}
+	}
+
+	mockFS.Files["plain.json"] = data
+	if got, err := compression.ReadFile(mockFS, "plain.json"); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("ReadFile(plain.json) = %d bytes, %v, want the data unchanged", len(got), err)
+	}
+}
@@ -0,0 +1,169 @@
+// @crypt.go:
+// Package main (crypt.go) implements the encrypt and decrypt commands, which protect backups
+// and exports at rest with a passphrase using the encryption package.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// PromptEnterPassphrase is the key of the prompt shown when an encrypted backup is read and no passphrase has been configured.
+const PromptEnterPassphrase i18n.Key = "prompt.backupPassphrase"
+
+// cryptFlags holds the flags shared by the encrypt and decrypt commands.
+type cryptFlags struct {
+	in      *string // File to read.
+	out     *string // File to write.
+	keyFile *string // File holding the passphrase.
+	keyEnv  *string // Environment variable holding the passphrase.
+	force   *bool   // Whether an existing output file is replaced.
+}
+
+// newCryptFlags defines the flags shared by the encrypt and decrypt commands.
+func newCryptFlags(flags *flag.FlagSet, outUsage string) cryptFlags {
+	return cryptFlags{
+		in:      flags.String("in", "", "file to read (required)"),
+		out:     flags.String("out", "", outUsage),
+		keyFile: flags.String("key-file", "", "file whose first line is the passphrase"),
+		keyEnv:  flags.String("key-env", encryption.PassphraseEnv, "environment variable holding the passphrase"),
+		force:   flags.Bool("force", false, "replace the output file if it already exists"),
+	}
+}
+
+// runEncryptCommand encrypts a backup or export with a passphrase.
+func runEncryptCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	cf := newCryptFlags(flags, "encrypted file to write (default: the input file with "+encryption.Extension+" appended)")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := &encryption.KeySource{Env: *cf.keyEnv, KeyFile: *cf.keyFile, Prompt: func() (string, error) {
+		return interactivity.PromptNewPassphrase(ctx, reader)
+	}}
+	return runCrypt(ctx, flags, cf, keys, *cf.in+encryption.Extension, encryptFile)
+}
+
+// runDecryptCommand decrypts a file written by the encrypt command or an encrypted export.
+func runDecryptCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("decrypt", flag.ContinueOnError)
+	cf := newCryptFlags(flags, "decrypted file to write (default: the input file without "+encryption.Extension+")")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := &encryption.KeySource{Env: *cf.keyEnv, KeyFile: *cf.keyFile, Prompt: func() (string, error) {
+		return interactivity.PromptPassphrase(ctx, reader, i18n.T(PromptEnterPassphrase))
+	}}
+	defaultOut := encryption.TrimExtension(*cf.in)
+	if defaultOut == *cf.in {
+		defaultOut += ".decrypted"
+	}
+	return runCrypt(ctx, flags, cf, keys, defaultOut, decryptFile)
+}
+
+// runCrypt validates the shared flags and runs transform from the input to the output file.
+func runCrypt(ctx context.Context, flags *flag.FlagSet, cf cryptFlags, keys *encryption.KeySource, defaultOut string,
+	transform func(rfs filesystem.FileSystem, ctx context.Context, in, out string, keys *encryption.KeySource) error) int {
+	if *cf.in == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("error.flagRequired", flags.Name(), "-in"))
+		flags.Usage()
+		return ExitUsage
+	}
+	out := *cf.out
+	if out == "" {
+		out = defaultOut
+	}
+
+	rfs := &filesystem.RealFileSystem{}
+	if exists, err := rfs.FileExists(out); err == nil && exists && !*cf.force {
+		fmt.Fprintln(os.Stderr, i18n.T("error.outputExists", flags.Name(), out))
+		return ExitFailure
+	}
+
+	if err := transform(rfs, ctx, *cf.in, out, keys); err != nil {
+		return printError(i18n.T("error.generic"), err)
+	}
+	recordInput(*cf.in, 0)
+	recordFile(flags.Name(), out, 0)
+	bannercli.Print("[GopherHelper] " + i18n.T("success.written", *cf.in, out))
+	return ExitOK
+}
+
+// encryptFile encrypts the file in into out, which is written atomically.
+func encryptFile(rfs filesystem.FileSystem, ctx context.Context, in, out string, keys *encryption.KeySource) error {
+	return transformFile(rfs, ctx, in, out, func(src io.Reader, dst io.Writer) error {
+		br := bufio.NewReader(src)
+		if header, _ := br.Peek(len(encryption.Magic)); encryption.IsEncrypted(header) {
+			return errors.New(i18n.T("error.alreadyEncrypted", in))
+		}
+		passphrase, err := keys.Passphrase(rfs)
+		if err != nil {
+			return err
+		}
+		ew, err := encryption.NewWriter(dst, passphrase)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(ew, br); err != nil {
+			return err
+		}
+		return ew.Close()
+	})
+}
+
+// decryptFile decrypts the file in into out, which is written atomically.
+func decryptFile(rfs filesystem.FileSystem, ctx context.Context, in, out string, keys *encryption.KeySource) error {
+	return transformFile(rfs, ctx, in, out, func(src io.Reader, dst io.Writer) error {
+		passphrase, err := keys.Passphrase(rfs)
+		if err != nil {
+			return err
+		}
+		er, err := encryption.NewReader(src, passphrase)
+		if err != nil {
+			return fmt.Errorf("%s: %w", in, err)
+		}
+		if _, err := io.Copy(dst, er); err != nil {
+			return fmt.Errorf("%s: %w", in, err)
+		}
+		return nil
+	})
+}
+
+// transformFile streams the file in through transform into out. The output only replaces out
+// once transform has succeeded and the context has not been cancelled.
+func transformFile(rfs filesystem.FileSystem, ctx context.Context, in, out string, transform func(io.Reader, io.Writer) error) error {
+	src, err := rfs.Open(in)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := filesystem.CreateAtomic(rfs, out, 0600)
+	if err != nil {
+		return err
+	}
+	defer dst.Discard()
+
+	if err := transform(src, dst); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return dst.Commit()
+}
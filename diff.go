@@ -0,0 +1,116 @@
+// @diff.go:
+// Package main (diff.go) implements the diff command, which compares two backups at the
+// session and message level, for example to check what a repair or a merge changed.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/diff"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// runDiffCommand compares the backups given as arguments. Like diff(1), it exits with 0 if
+// the backups hold the same sessions, 1 if they differ and 2 or another exit code of the tool,
+// such as ExitParse, on errors.
+func runDiffCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	color := flags.String("color", "auto", "colour the text output: auto, always or never")
+	keyFile := flags.String("key-file", "", "file whose first line is the passphrase of encrypted backups")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ChatGPT-Next-Web-Session-Exporter diff [flags] OLD NEW")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, i18n.T("error.twoBackups", "diff"))
+		flags.Usage()
+		return ExitUsage
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintln(os.Stderr, i18n.T("error.unknownFormat", "diff", *format, "text, json"))
+		return ExitUsage
+	}
+	useColor, err := colorEnabled(*color, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitUsage
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := &encryption.KeySource{KeyFile: *keyFile, Prompt: func() (string, error) {
+		return interactivity.PromptPassphrase(ctx, reader, i18n.T(PromptEnterPassphrase))
+	}}
+	result, err := diffBackups(&filesystem.RealFileSystem{}, flags.Arg(0), flags.Arg(1), keys)
+	if err != nil {
+		return diffError(err)
+	}
+
+	if *format == "json" {
+		err = diff.WriteJSON(os.Stdout, result)
+	} else {
+		err = diff.WriteText(os.Stdout, result, useColor)
+	}
+	if err != nil {
+		return diffError(err)
+	}
+	if !result.Empty() {
+		return ExitFailure // The backups differ.
+	}
+	return ExitOK
+}
+
+// diffError reports err and returns its exit code, or ExitUsage instead of ExitFailure, which means
+// that the backups differ.
+func diffError(err error) int {
+	if code := printError(i18n.T("error.generic"), err); code != ExitFailure {
+		return code
+	}
+	return ExitUsage
+}
+
+// diffBackups reads the two backups, which may be compressed or encrypted, and compares their sessions.
+func diffBackups(rfs filesystem.FileSystem, oldPath, newPath string, keys *encryption.KeySource) (*diff.Result, error) {
+	before, err := exporter.ReadJSONFromFileWithKeys(rfs, oldPath, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", oldPath, err)
+	}
+	after, err := exporter.ReadJSONFromFileWithKeys(rfs, newPath, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", newPath, err)
+	}
+	recordInput(oldPath, len(before.ChatNextWebStore.Sessions))
+	recordInput(newPath, len(after.ChatNextWebStore.Sessions))
+	return diff.Compare(before.ChatNextWebStore.Sessions, after.ChatNextWebStore.Sessions), nil
+}
+
+// colorEnabled resolves the -color flag: "auto" enables colour when out is a terminal and the
+// NO_COLOR environment variable is not set.
+func colorEnabled(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := out.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, errors.New(i18n.T("error.unknownColorMode", "diff", mode, "auto, always, never"))
+	}
+}
@@ -0,0 +1,253 @@
+// Package diff compares two NextChat backups at the session and message level.
+//
+// Sessions and messages are matched by their IDs. Compare reports the sessions that were added,
+// removed or renamed, and for every session present in both backups the messages that were
+// added, removed or edited along with the fields of its mask and model configuration that
+// changed. This makes it easy to check what a repair, a recovery or a merge actually did.
+//
+// # Example Usage
+//
+//	result := diff.Compare(before.ChatNextWebStore.Sessions, after.ChatNextWebStore.Sessions)
+//	if err := diff.WriteText(os.Stdout, result, true); err != nil {
+//		return err
+//	}
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// Result holds the differences between two backups.
+type Result struct {
+	SessionsAdded   []SessionRef  `json:"sessionsAdded"`   // Sessions only present in the new backup.
+	SessionsRemoved []SessionRef  `json:"sessionsRemoved"` // Sessions only present in the old backup.
+	SessionsChanged []SessionDiff `json:"sessionsChanged"` // Sessions present in both backups that differ.
+}
+
+// SessionRef identifies a session that was added or removed.
+type SessionRef struct {
+	ID       string `json:"id"`       // ID of the session.
+	Topic    string `json:"topic"`    // Topic of the session.
+	Messages int    `json:"messages"` // Number of messages in the session.
+}
+
+// SessionDiff describes how a session present in both backups changed.
+type SessionDiff struct {
+	ID              string             `json:"id"`                        // ID of the session.
+	Topic           string             `json:"topic"`                     // Topic of the session in the new backup.
+	Renamed         bool               `json:"renamed"`                   // Whether the topic of the session changed.
+	OldTopic        string             `json:"oldTopic,omitempty"`        // Topic in the old backup if the session was renamed.
+	MessagesAdded   []exporter.Message `json:"messagesAdded,omitempty"`   // Messages only present in the new backup.
+	MessagesRemoved []exporter.Message `json:"messagesRemoved,omitempty"` // Messages only present in the old backup.
+	MessagesEdited  []MessageEdit      `json:"messagesEdited,omitempty"`  // Messages whose role or content changed.
+	MaskChanges     []FieldChange      `json:"maskChanges,omitempty"`     // Changed fields of the mask and its model configuration.
+}
+
+// MessageEdit describes a message whose role or content changed.
+type MessageEdit struct {
+	ID         string `json:"id"`         // ID of the message.
+	OldRole    string `json:"oldRole"`    // Role in the old backup.
+	NewRole    string `json:"newRole"`    // Role in the new backup.
+	OldContent string `json:"oldContent"` // Content in the old backup.
+	NewContent string `json:"newContent"` // Content in the new backup.
+}
+
+// FieldChange describes a changed field of a mask, such as "modelConfig.temperature".
+type FieldChange struct {
+	Field string `json:"field"` // Path of the field within the mask.
+	Old   string `json:"old"`   // Value in the old backup; empty if the field was added.
+	New   string `json:"new"`   // Value in the new backup; empty if the field was removed.
+}
+
+// Empty reports whether the backups hold the same sessions.
+func (r *Result) Empty() bool {
+	return len(r.SessionsAdded) == 0 && len(r.SessionsRemoved) == 0 && len(r.SessionsChanged) == 0
+}
+
+// Renamed returns the changed sessions whose topic changed.
+func (r *Result) Renamed() []SessionDiff {
+	var renamed []SessionDiff
+	for _, session := range r.SessionsChanged {
+		if session.Renamed {
+			renamed = append(renamed, session)
+		}
+	}
+	return renamed
+}
+
+// Compare returns the differences between the sessions of an old and a new backup.
+// Added sessions are listed in the order of the new backup, removed sessions in the order
+// of the old one. Sessions and messages without an ID are matched by their position.
+func Compare(oldSessions, newSessions []exporter.Session) *Result {
+	oldByID := indexSessions(oldSessions)
+	newByID := indexSessions(newSessions)
+
+	result := &Result{}
+	for i, session := range oldSessions {
+		if _, ok := newByID[sessionKey(session, i)]; !ok {
+			result.SessionsRemoved = append(result.SessionsRemoved, SessionRef{ID: session.ID, Topic: session.Topic, Messages: len(session.Messages)})
+		}
+	}
+	for i, session := range newSessions {
+		old, ok := oldByID[sessionKey(session, i)]
+		if !ok {
+			result.SessionsAdded = append(result.SessionsAdded, SessionRef{ID: session.ID, Topic: session.Topic, Messages: len(session.Messages)})
+			continue
+		}
+		if d := compareSession(old, session); d != nil {
+			result.SessionsChanged = append(result.SessionsChanged, *d)
+		}
+	}
+	return result
+}
+
+// compareSession returns the differences between two versions of a session, or nil if there are none.
+func compareSession(old, current exporter.Session) *SessionDiff {
+	d := &SessionDiff{ID: current.ID, Topic: current.Topic}
+	if old.Topic != current.Topic {
+		d.Renamed, d.OldTopic = true, old.Topic
+	}
+
+	oldMessages := indexMessages(old.Messages)
+	newMessages := indexMessages(current.Messages)
+	for i, message := range old.Messages {
+		if _, ok := newMessages[messageKey(message, i)]; !ok {
+			d.MessagesRemoved = append(d.MessagesRemoved, message)
+		}
+	}
+	for i, message := range current.Messages {
+		before, ok := oldMessages[messageKey(message, i)]
+		switch {
+		case !ok:
+			d.MessagesAdded = append(d.MessagesAdded, message)
+		case before.Role != message.Role || before.Content != message.Content:
+			d.MessagesEdited = append(d.MessagesEdited, MessageEdit{
+				ID:         message.ID,
+				OldRole:    before.Role,
+				NewRole:    message.Role,
+				OldContent: before.Content,
+				NewContent: message.Content,
+			})
+		}
+	}
+
+	d.MaskChanges = compareFields(flatten(old.Mask), flatten(current.Mask))
+
+	if !d.Renamed && len(d.MessagesAdded) == 0 && len(d.MessagesRemoved) == 0 &&
+		len(d.MessagesEdited) == 0 && len(d.MaskChanges) == 0 {
+		return nil
+	}
+	return d
+}
+
+// compareFields returns the fields whose values differ, sorted by path.
+func compareFields(before, after map[string]string) []FieldChange {
+	paths := make(map[string]bool, len(before)+len(after))
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var changes []FieldChange
+	for _, path := range sorted {
+		oldValue, inOld := before[path]
+		newValue, inNew := after[path]
+		if inOld != inNew || oldValue != newValue {
+			changes = append(changes, FieldChange{Field: path, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// flatten returns the fields of mask keyed by their JSON path, e.g. "modelConfig.temperature"
+// or "context[0].content", with strings kept as they are and other values rendered as JSON.
+func flatten(mask exporter.Mask) map[string]string {
+	fields := make(map[string]string)
+	data, err := json.Marshal(mask)
+	if err != nil {
+		return fields
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fields
+	}
+	flattenValue("", value, fields)
+	return fields
+}
+
+// flattenValue adds value and its nested fields to fields under path.
+func flattenValue(path string, value interface{}, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if path == "" {
+				flattenValue(key, nested, fields)
+			} else {
+				flattenValue(path+"."+key, nested, fields)
+			}
+		}
+	case []interface{}:
+		for i, nested := range v {
+			flattenValue(path+"["+strconv.Itoa(i)+"]", nested, fields)
+		}
+	case string:
+		fields[path] = v
+	default:
+		encoded, _ := json.Marshal(v)
+		fields[path] = string(encoded)
+	}
+}
+
+// indexSessions maps the key of every session to the session; the first session wins on duplicate IDs.
+func indexSessions(sessions []exporter.Session) map[string]exporter.Session {
+	index := make(map[string]exporter.Session, len(sessions))
+	for i, session := range sessions {
+		key := sessionKey(session, i)
+		if _, ok := index[key]; !ok {
+			index[key] = session
+		}
+	}
+	return index
+}
+
+// indexMessages maps the key of every message to the message; the first message wins on duplicate IDs.
+func indexMessages(messages []exporter.Message) map[string]exporter.Message {
+	index := make(map[string]exporter.Message, len(messages))
+	for i, message := range messages {
+		key := messageKey(message, i)
+		if _, ok := index[key]; !ok {
+			index[key] = message
+		}
+	}
+	return index
+}
+
+// sessionKey returns the ID of the session, or its position if it has no ID.
+func sessionKey(session exporter.Session, index int) string {
+	if session.ID != "" {
+		return session.ID
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// messageKey returns the ID of the message, or its position if it has no ID.
+func messageKey(message exporter.Message, index int) string {
+	if message.ID != "" {
+		return message.ID
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -0,0 +1,93 @@
+// Below, the package diff (@diff_test.go) tests the comparison of two backups and its output.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package diff_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/diff"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// TestDiff verifies that added, removed and renamed sessions, added, removed and edited messages,
+// and mask changes are reported, as text and as JSON.
+func TestDiff(t *testing.T) {
+	before := []exporter.Session{
+		{ID: "s1", Topic: "Go", Messages: []exporter.Message{
+			{ID: "m1", Role: "user", Content: "Hello"},
+			{ID: "m2", Role: "assistant", Content: "Hi"},
+		}, Mask: exporter.Mask{Name: "Default", ModelConfig: exporter.ModelConfig{Model: "gpt-4", Temperature: 0.5}}},
+		{ID: "s2", Topic: "Removed"},
+		{ID: "s3", Topic: "Old topic"},
+		{ID: "s4", Topic: "Unchanged", Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Same"}}},
+	}
+	after := []exporter.Session{
+		{ID: "s1", Topic: "Go", Messages: []exporter.Message{
+			{ID: "m1", Role: "user", Content: "Hello, Gopher"},
+			{ID: "m3", Role: "user", Content: "New"},
+		}, Mask: exporter.Mask{Name: "Default", ModelConfig: exporter.ModelConfig{Model: "gpt-4", Temperature: 0.7}}},
+		{ID: "s3", Topic: "New topic"},
+		{ID: "s4", Topic: "Unchanged", Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Same"}}},
+		{ID: "s5", Topic: "Added", Messages: []exporter.Message{{ID: "m1"}}},
+	}
+
+	result := diff.Compare(before, after)
+	if len(result.SessionsAdded) != 1 || result.SessionsAdded[0].ID != "s5" || result.SessionsAdded[0].Messages != 1 {
+		t.Errorf("SessionsAdded = %+v, want s5", result.SessionsAdded)
+	}
+	if len(result.SessionsRemoved) != 1 || result.SessionsRemoved[0].ID != "s2" {
+		t.Errorf("SessionsRemoved = %+v, want s2", result.SessionsRemoved)
+	}
+	if len(result.SessionsChanged) != 2 {
+		t.Fatalf("SessionsChanged = %+v, want s1 and s3", result.SessionsChanged)
+	}
+	changed := result.SessionsChanged[0]
+	if len(changed.MessagesAdded) != 1 || changed.MessagesAdded[0].ID != "m3" ||
+		len(changed.MessagesRemoved) != 1 || changed.MessagesRemoved[0].ID != "m2" ||
+		len(changed.MessagesEdited) != 1 || changed.MessagesEdited[0].NewContent != "Hello, Gopher" {
+		t.Errorf("SessionsChanged[0] messages = %+v", changed)
+	}
+	if len(changed.MaskChanges) != 1 || changed.MaskChanges[0] != (diff.FieldChange{Field: "modelConfig.temperature", Old: "0.5", New: "0.7"}) {
+		t.Errorf("MaskChanges = %+v, want modelConfig.temperature", changed.MaskChanges)
+	}
+	if renamed := result.Renamed(); len(renamed) != 1 || renamed[0].OldTopic != "Old topic" || renamed[0].Topic != "New topic" {
+		t.Errorf("Renamed() = %+v, want s3", renamed)
+	}
+
+	var text bytes.Buffer
+	if err := diff.WriteText(&text, result, false); err != nil {
+		t.Fatalf("WriteText() returned error: %v", err)
+	}
+	for _, want := range []string{
+		"Sessions: 1 added, 1 removed, 1 renamed, 2 changed",
+		`+ session s5 "Added" (1 messages)`,
+		`- session s2 "Removed"`,
+		`~ session s3 renamed "Old topic" -> "New topic"`,
+		`    ~ message m1 [user] "Hello" -> "Hello, Gopher"`,
+		`    ~ mask.modelConfig.temperature: "0.5" -> "0.7"`,
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("WriteText() output is missing %q:\n%s", want, text.String())
+		}
+	}
+	if strings.Contains(text.String(), "\033[") {
+		t.Error("WriteText() coloured the output without color")
+	}
+	text.Reset()
+	if err := diff.WriteText(&text, result, true); err != nil || !strings.Contains(text.String(), "\033[32m+ session s5") {
+		t.Errorf("WriteText() with color = %q, %v", text.String(), err)
+	}
+
+	var decoded diff.Result
+	var encoded bytes.Buffer
+	if err := diff.WriteJSON(&encoded, result); err != nil {
+		t.Fatalf("WriteJSON() returned error: %v", err)
+	}
+	if err := json.Unmarshal(encoded.Bytes(), &decoded); err != nil || len(decoded.SessionsChanged) != 2 {
+		t.Errorf("WriteJSON() = %s, %v", encoded.String(), err)
+	}
+}
@@ -0,0 +1,110 @@
+// Below, the package diff (@output.go) renders a Result as human-readable text, optionally
+// coloured with ANSI escape codes, or as JSON.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package diff
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ANSI escape codes used by WriteText.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorBold   = "\033[1m"
+)
+
+// previewLength is the number of characters of message content shown by WriteText.
+const previewLength = 60
+
+// WriteJSON writes result to w as indented JSON.
+func WriteJSON(w io.Writer, result *Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// WriteText writes result to w in a diff-like format: "+" marks additions, "-" removals and
+// "~" changes. If color is true, the markers are coloured with ANSI escape codes.
+func WriteText(w io.Writer, result *Result, color bool) error {
+	bw := bufio.NewWriter(w)
+	p := printer{w: bw, color: color}
+
+	p.line("", colorBold, "Sessions: %d added, %d removed, %d renamed, %d changed",
+		len(result.SessionsAdded), len(result.SessionsRemoved), len(result.Renamed()), len(result.SessionsChanged))
+	for _, session := range result.SessionsAdded {
+		p.line("+ ", colorGreen, "session %s %q (%d messages)", session.ID, session.Topic, session.Messages)
+	}
+	for _, session := range result.SessionsRemoved {
+		p.line("- ", colorRed, "session %s %q (%d messages)", session.ID, session.Topic, session.Messages)
+	}
+	for _, session := range result.SessionsChanged {
+		if session.Renamed {
+			p.line("~ ", colorYellow, "session %s renamed %q -> %q", session.ID, session.OldTopic, session.Topic)
+		} else {
+			p.line("~ ", colorYellow, "session %s %q", session.ID, session.Topic)
+		}
+		for _, message := range session.MessagesAdded {
+			p.line("    + ", colorGreen, "message %s [%s] %s", message.ID, message.Role, preview(message.Content))
+		}
+		for _, message := range session.MessagesRemoved {
+			p.line("    - ", colorRed, "message %s [%s] %s", message.ID, message.Role, preview(message.Content))
+		}
+		for _, edit := range session.MessagesEdited {
+			role := edit.NewRole
+			if edit.OldRole != edit.NewRole {
+				role = edit.OldRole + " -> " + edit.NewRole
+			}
+			p.line("    ~ ", colorYellow, "message %s [%s] %s -> %s", edit.ID, role, preview(edit.OldContent), preview(edit.NewContent))
+		}
+		for _, change := range session.MaskChanges {
+			p.line("    ~ ", colorYellow, "mask.%s: %q -> %q", change.Field, change.Old, change.New)
+		}
+	}
+	if result.Empty() {
+		p.line("", "", "No differences.")
+	}
+
+	if p.err != nil {
+		return p.err
+	}
+	return bw.Flush()
+}
+
+// printer writes lines with an optionally coloured prefix and keeps the first error.
+type printer struct {
+	w     io.Writer // w receives the output.
+	color bool      // color enables ANSI escape codes.
+	err   error     // err is the first write error.
+}
+
+// line writes prefix and the formatted text as one line, coloured with code if enabled.
+func (p *printer) line(prefix, code, format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+	text := prefix + fmt.Sprintf(format, args...)
+	if p.color && code != "" {
+		text = code + text + colorReset
+	}
+	_, p.err = fmt.Fprintln(p.w, text)
+}
+
+// preview returns the first line of content, shortened to previewLength characters and quoted.
+func preview(content string) string {
+	content = strings.TrimSpace(content)
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[:i] + " ..."
+	}
+	if runes := []rune(content); len(runes) > previewLength {
+		content = string(runes[:previewLength]) + "..."
+	}
+	return fmt.Sprintf("%q", content)
+}
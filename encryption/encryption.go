@@ -0,0 +1,318 @@
+// Package encryption protects NextChat backups and exports at rest with a passphrase.
+//
+// Data is stored in a small envelope: a header holding the scrypt parameters and a random salt,
+// followed by the plaintext split into 64 KiB chunks, each sealed with AES-256-GCM. The key is
+// derived from the passphrase with scrypt, so every file has its own key, and the chunk nonces
+// are a counter with a final-chunk flag, so truncated, reordered or modified data is rejected
+// instead of being returned partially.
+//
+// Since chunks are sealed one by one, NewWriter and NewReader stream data of any size without
+// holding it in memory.
+//
+// # Envelope Format
+//
+//	magic   "NCSEENC1" (8 bytes)
+//	logN    scrypt cost parameter N = 2^logN (1 byte)
+//	r, p    scrypt block size and parallelism (1 byte each)
+//	salt    random scrypt salt (16 bytes)
+//	chunks  AES-256-GCM(chunk) with nonce = 11-byte big-endian counter || final flag,
+//	        and the header as additional data; every chunk but the last holds 64 KiB
+//
+// # Example Usage
+//
+//	w, err := encryption.NewWriter(file, passphrase)
+//	if err != nil {
+//		return err
+//	}
+//	if _, err := w.Write(backup); err != nil {
+//		return err
+//	}
+//	return w.Close() // Writes the final chunk.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Extension is the file extension of encrypted files.
+	Extension = ".enc"
+
+	// chunkSize is the amount of plaintext sealed in each chunk.
+	chunkSize = 64 * 1024
+
+	// saltSize is the length of the random scrypt salt.
+	saltSize = 16
+
+	// headerSize is the length of the envelope header.
+	headerSize = len(Magic) + 3 + saltSize
+
+	// keySize selects AES-256.
+	keySize = 32
+
+	// defaultLogN, defaultR and defaultP are the scrypt parameters of new files
+	// (N = 2^15, as recommended for interactive logins).
+	defaultLogN = 15
+	defaultR    = 8
+	defaultP    = 1
+
+	// maxLogN bounds the work a crafted header can demand when decrypting.
+	maxLogN = 22
+)
+
+// Magic is the signature at the start of every encrypted file.
+const Magic = "NCSEENC1"
+
+var (
+	// ErrWrongPassphrase is returned when data cannot be decrypted with the given passphrase,
+	// either because the passphrase is wrong or because the data has been modified.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+
+	// ErrTruncated is returned when encrypted data ends before its final chunk.
+	ErrTruncated = errors.New("encrypted data is truncated")
+
+	// ErrNotEncrypted is returned when data does not start with the envelope header.
+	ErrNotEncrypted = errors.New("data is not encrypted")
+)
+
+// IsEncrypted reports whether data starts with the envelope header.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Writer encrypts everything written to it. Close must be called to write the final chunk.
+type Writer struct {
+	w       io.Writer   // w receives the envelope.
+	aead    cipher.AEAD // aead seals the chunks.
+	header  []byte      // header is the additional data of every chunk.
+	buf     []byte      // buf holds the plaintext of the current chunk.
+	counter uint64      // counter is the index of the current chunk.
+	err     error       // err is the first error encountered; it is returned by every later call.
+	closed  bool        // closed reports whether Close has been called.
+}
+
+// NewWriter writes the envelope header to w and returns a Writer that encrypts data with
+// a key derived from passphrase. Closing the Writer does not close w.
+func NewWriter(w io.Writer, passphrase string) (*Writer, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase must not be empty")
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, Magic)
+	header[len(Magic)] = defaultLogN
+	header[len(Magic)+1] = defaultR
+	header[len(Magic)+2] = defaultP
+	if _, err := rand.Read(header[len(Magic)+3:]); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, header: header, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+// Write encrypts p. Data is written to the underlying writer one chunk at a time.
+func (ew *Writer) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, errors.New("encryption: write to closed Writer")
+	}
+	if ew.err != nil {
+		return 0, ew.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, since the last chunk must carry the final flag.
+		if len(ew.buf) == chunkSize {
+			if ew.err = ew.seal(false); ew.err != nil {
+				return written, ew.err
+			}
+		}
+		n := copy(ew.buf[len(ew.buf):chunkSize], p)
+		ew.buf = ew.buf[:len(ew.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the final chunk. It does not close the underlying writer.
+func (ew *Writer) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	if ew.err != nil {
+		return ew.err
+	}
+	return ew.seal(true)
+}
+
+// seal encrypts the buffered plaintext as the next chunk and writes it.
+func (ew *Writer) seal(final bool) error {
+	sealed := ew.aead.Seal(nil, chunkNonce(ew.counter, final), ew.buf, ew.header)
+	ew.counter++
+	ew.buf = ew.buf[:0]
+	_, err := ew.w.Write(sealed)
+	return err
+}
+
+// Reader decrypts an envelope written by Writer.
+type Reader struct {
+	r       *bufio.Reader // r supplies the sealed chunks.
+	aead    cipher.AEAD   // aead opens the chunks.
+	header  []byte        // header is the additional data of every chunk.
+	buf     []byte        // buf holds decrypted plaintext not yet returned.
+	counter uint64        // counter is the index of the next chunk.
+	done    bool          // done reports whether the final chunk has been read.
+}
+
+// NewReader reads the envelope header from r and returns a Reader that decrypts the data with
+// a key derived from passphrase. A wrong passphrase is reported by the first Read.
+func NewReader(r io.Reader, passphrase string) (*Reader, error) {
+	br := bufio.NewReaderSize(r, chunkSize+64)
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(br, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotEncrypted
+		}
+		return nil, err
+	}
+	if !IsEncrypted(header) {
+		return nil, ErrNotEncrypted
+	}
+
+	aead, err := newAEAD(passphrase, header)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{r: br, aead: aead, header: header}, nil
+}
+
+// Read returns decrypted data. It returns ErrWrongPassphrase if a chunk fails authentication
+// and ErrTruncated if the data ends before the final chunk.
+func (er *Reader) Read(p []byte) (int, error) {
+	for len(er.buf) == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+		if err := er.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, er.buf)
+	er.buf = er.buf[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk.
+func (er *Reader) open() error {
+	sealed := make([]byte, chunkSize+er.aead.Overhead())
+	n, err := io.ReadFull(er.r, sealed)
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrTruncated
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// A short chunk can only be the final one.
+	case err != nil:
+		return err
+	}
+	sealed = sealed[:n]
+
+	final := n < len(sealed) || err != nil
+	if !final {
+		// A full chunk is final if nothing follows it.
+		if _, peekErr := er.r.Peek(1); errors.Is(peekErr, io.EOF) {
+			final = true
+		}
+	}
+
+	plaintext, openErr := er.aead.Open(nil, chunkNonce(er.counter, final), sealed, er.header)
+	if openErr != nil {
+		if final && er.counter > 0 {
+			// A full non-final chunk followed by nothing means the data was cut at a chunk boundary.
+			if _, retryErr := er.aead.Open(nil, chunkNonce(er.counter, false), sealed, er.header); retryErr == nil {
+				return ErrTruncated
+			}
+		}
+		return ErrWrongPassphrase
+	}
+	er.counter++
+	er.done = final
+	er.buf = plaintext
+	return nil
+}
+
+// Encrypt returns data encrypted with passphrase.
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt returns the plaintext of data encrypted with passphrase.
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// newAEAD derives the key from passphrase with the scrypt parameters and salt of header.
+func newAEAD(passphrase string, header []byte) (cipher.AEAD, error) {
+	logN := int(header[len(Magic)])
+	r := int(header[len(Magic)+1])
+	p := int(header[len(Magic)+2])
+	if logN < 10 || logN > maxLogN || r == 0 || p == 0 {
+		return nil, fmt.Errorf("unsupported scrypt parameters (logN=%d, r=%d, p=%d)", logN, r, p)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), header[len(Magic)+3:], 1<<logN, r, p, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of chunk number counter: an 11-byte big-endian counter
+// followed by 1 for the final chunk and 0 otherwise.
+func chunkNonce(counter uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,94 @@
+// Below, the package encryption (@encryption_test.go) tests the encryption envelope and the
+// sources of the passphrase.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package encryption_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// TestEncryption verifies that data of any size is restored with its passphrase only, and that
+// truncated data is rejected.
+func TestEncryption(t *testing.T) {
+	large := bytes.Repeat([]byte("Gopher"), 2*64*1024/6+1) // Spans several chunks.
+	for _, plaintext := range [][]byte{nil, []byte("hello"), large, large[:2*64*1024]} {
+		sealed, err := encryption.Encrypt(plaintext, "secret")
+		if err != nil {
+			t.Fatalf("Encrypt() returned error: %v", err)
+		}
+		if opened, err := encryption.Decrypt(sealed, "secret"); err != nil || !bytes.Equal(opened, plaintext) {
+			t.Errorf("Decrypt() of %d bytes = %d bytes, %v", len(plaintext), len(opened), err)
+		}
+		if _, err := encryption.Decrypt(sealed, "wrong"); !errors.Is(err, encryption.ErrWrongPassphrase) {
+			t.Errorf("Decrypt() with a wrong passphrase returned %v, want ErrWrongPassphrase", err)
+		}
+		if len(plaintext) == len(large) {
+			if _, err := encryption.Decrypt(sealed[:len(sealed)-100], "secret"); err == nil {
+				t.Error("Decrypt() accepted truncated data")
+			}
+		}
+	}
+}
+
+// TestKeySource verifies that encrypted files are read with the passphrase of a key file or a
+// prompt, asked for once, and that a passphrase is the same whichever source gives it.
+func TestKeySource(t *testing.T) {
+	sealed, err := encryption.Encrypt([]byte("hello"), "secret")
+	if err != nil {
+		t.Fatalf("Encrypt() returned error: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["backup.json.enc"] = sealed
+	mockFS.Files["passphrase.txt"] = []byte("secret\n")
+
+	keys := &encryption.KeySource{Env: "CHAT_EXPORTER_TEST_UNSET", KeyFile: "passphrase.txt"}
+	if data, err := encryption.ReadFile(mockFS, "backup.json.enc", keys); err != nil || string(data) != "hello" {
+		t.Errorf("ReadFile() with a key file = %q, %v, want hello", data, err)
+	}
+
+	prompted := 0
+	promptKeys := &encryption.KeySource{Env: "CHAT_EXPORTER_TEST_UNSET", Prompt: func() (string, error) {
+		prompted++
+		return "secret", nil
+	}}
+	for i := 0; i < 2; i++ {
+		if _, err := encryption.ReadFile(mockFS, "backup.json.enc", promptKeys); err != nil || prompted != 1 {
+			t.Errorf("ReadFile() returned %v after %d prompts, want the cached passphrase", err, prompted)
+		}
+	}
+	if _, err := encryption.ReadFile(mockFS, "backup.json.enc", &encryption.KeySource{Env: "CHAT_EXPORTER_TEST_UNSET"}); !errors.Is(err, encryption.ErrPassphraseRequired) {
+		t.Errorf("ReadFile() without a passphrase returned %v, want ErrPassphraseRequired", err)
+	}
+
+	// A passphrase with surrounding spaces is the same from the environment, a key file or the prompt.
+	t.Setenv("CHAT_EXPORTER_TEST_SPACES", " secret ")
+	passphrase, err := (&encryption.KeySource{Env: "CHAT_EXPORTER_TEST_SPACES"}).Passphrase(mockFS)
+	if err != nil {
+		t.Fatalf("Passphrase() returned error: %v", err)
+	}
+	if mockFS.Files["spaces.json.enc"], err = encryption.Encrypt([]byte("hello"), passphrase); err != nil {
+		t.Fatalf("Encrypt() returned error: %v", err)
+	}
+	mockFS.Files["spaces.txt"] = []byte(" secret \r\n")
+	spaceKeys := []*encryption.KeySource{
+		{Env: "CHAT_EXPORTER_TEST_UNSET", KeyFile: "spaces.txt"},
+		{Env: "CHAT_EXPORTER_TEST_UNSET", Prompt: func() (string, error) {
+			return interactivity.PromptPassphrase(context.Background(), bufio.NewReader(strings.NewReader(" secret \n")), "")
+		}},
+	}
+	for i, keys := range spaceKeys {
+		if _, err := encryption.ReadFile(mockFS, "spaces.json.enc", keys); err != nil {
+			t.Errorf("ReadFile() with key source %d returned %v, want the passphrase with its spaces", i, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Below, the package encryption (@keys.go) resolves the passphrase of encrypted files and reads
+// backups that may be encrypted, compressed, both or neither.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package encryption
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// PassphraseEnv is the environment variable that holds the passphrase by default.
+const PassphraseEnv = "CHAT_EXPORTER_PASSPHRASE"
+
+// ErrPassphraseRequired is returned when encrypted data is read but no passphrase is available.
+var ErrPassphraseRequired = errors.New("the data is encrypted; set " + PassphraseEnv + " or provide a key file")
+
+// KeySource describes where the passphrase comes from. The sources are tried in order:
+// the environment variable, the key file, and finally the interactive prompt.
+// The passphrase of every source is used as given, surrounding spaces included; only the line
+// ending of the key file is removed.
+type KeySource struct {
+	Env     string                 // Environment variable holding the passphrase; PassphraseEnv if empty.
+	KeyFile string                 // File whose first line is the passphrase; ignored if empty.
+	Prompt  func() (string, error) // Asks the user for the passphrase; ignored if nil.
+
+	passphrase string // passphrase caches the resolved passphrase.
+}
+
+// Passphrase returns the passphrase from the first source that provides one, reading the key
+// file through rfs. The result is cached, so the user is prompted at most once.
+// A nil KeySource only consults PassphraseEnv.
+func (k *KeySource) Passphrase(rfs filesystem.FileSystem) (string, error) {
+	if k == nil {
+		k = &KeySource{}
+	}
+	if k.passphrase != "" {
+		return k.passphrase, nil
+	}
+
+	env := k.Env
+	if env == "" {
+		env = PassphraseEnv
+	}
+	passphrase := os.Getenv(env)
+	if passphrase == "" && k.KeyFile != "" {
+		data, err := rfs.ReadFile(k.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		passphrase, _, _ = strings.Cut(string(data), "\n")
+		passphrase = strings.TrimSuffix(passphrase, "\r")
+		if passphrase == "" {
+			return "", fmt.Errorf("key file %s is empty", k.KeyFile)
+		}
+	}
+	if passphrase == "" && k.Prompt != nil {
+		var err error
+		if passphrase, err = k.Prompt(); err != nil {
+			return "", err
+		}
+	}
+	if passphrase == "" {
+		return "", ErrPassphraseRequired
+	}
+
+	k.passphrase = passphrase
+	return passphrase, nil
+}
+
+// OpenReader returns a reader of the plaintext of r: if r starts with the envelope header, the
+// passphrase is resolved through keys and the data is decrypted; otherwise r is returned as is.
+func OpenReader(rfs filesystem.FileSystem, r io.Reader, keys *KeySource) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	header, _ := br.Peek(len(Magic))
+	if !IsEncrypted(header) {
+		return br, nil
+	}
+
+	passphrase, err := keys.Passphrase(rfs)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(br, passphrase)
+}
+
+// ReadFile reads the named backup through rfs, decrypting it with the passphrase from keys if it
+// is encrypted and then decompressing it if it is compressed.
+func ReadFile(rfs filesystem.FileSystem, name string, keys *KeySource) ([]byte, error) {
+	data, err := rfs.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if IsEncrypted(data) {
+		passphrase, err := keys.Passphrase(rfs)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = Decrypt(data, passphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	decompressed, err := compression.Decompress(data, TrimExtension(name))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return decompressed, nil
+}
+
+// TrimExtension removes the encryption extension from name, e.g. "backup.json.gz.enc" becomes "backup.json.gz".
+func TrimExtension(name string) string {
+	return strings.TrimSuffix(name, Extension)
+}
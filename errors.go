@@ -0,0 +1,73 @@
+// @errors.go:
+// Package main (errors.go) maps the errors of the exporter, repairdata and filesystem packages to
+// the documented exit codes of the CLI tool, and reports them through a single handler.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+// Exit codes of the CLI tool, as documented in the README. The diff command also exits with 1
+// when the backups differ.
+const (
+	ExitOK        = 0   // The run succeeded.
+	ExitFailure   = 1   // The run failed for another reason, such as a wrong passphrase.
+	ExitUsage     = 2   // Invalid flags, configuration, export format or option.
+	ExitParse     = 3   // The backup is not valid JSON, or is truncated beyond recovery.
+	ExitSchema    = 4   // The backup is valid JSON, but not a NextChat backup.
+	ExitIO        = 5   // A file could not be read or written.
+	ExitCancelled = 130 // The run was interrupted, or its input ended, before it finished.
+)
+
+// exitCode returns the exit code reporting err, or ExitOK if err is nil.
+func exitCode(err error) int {
+	var exportParseErr *exporter.ParseError
+	var repairParseErr *repairdata.ParseError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled) || errors.Is(err, io.EOF):
+		return ExitCancelled
+	case errors.Is(err, exporter.ErrInvalidFormat):
+		return ExitUsage
+	case errors.As(err, &exportParseErr) || errors.As(err, &repairParseErr) || errors.Is(err, repairdata.ErrUnrecoverable):
+		return ExitParse
+	case errors.Is(err, exporter.ErrSchemaMismatch) || errors.Is(err, repairdata.ErrSchemaMismatch):
+		return ExitSchema
+	case filesystem.IsIO(err):
+		return ExitIO
+	default:
+		return ExitFailure
+	}
+}
+
+// handleError ends the run of the interactive mode with the exit code of err, after reporting
+// err after message. A cancellation or the end of the input is reported as such instead.
+func handleError(message string, err error) {
+	code := exitCode(err)
+	if code == ExitCancelled {
+		bannercli.Info("\n[GopherHelper] " + i18n.T("banner.cancelled"))
+	} else {
+		bannercli.Error(fmt.Sprintf("\n[GopherHelper] %s: %s\n", message, err))
+	}
+	exit(code)
+}
+
+// printError prints the failure of a command with its cause on the standard error, logs it as an
+// error, so that it is reported in the summary of the run, and returns the exit code of err.
+func printError(message string, err error) int {
+	slog.Error(message, "error", err)
+	fmt.Fprintf(os.Stderr, "[GopherHelper] %s: %s\n", message, err)
+	return exitCode(err)
+}
@@ -0,0 +1,268 @@
+// Below, the package exporter (@append.go) lets formats that hold one record per message add
+// the sessions and messages of a later backup to a previous export instead of rewriting it.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// Increment holds the records to add to a previous export.
+type Increment struct {
+	Sessions []Session       // Sessions with records to add; each holds only the messages to append.
+	New      map[string]bool // IDs of the sessions in Sessions that were not exported at all before.
+}
+
+// IsNew reports whether session was not exported before, so that session-level records such as
+// the rows of the sessions CSV file must be written for it as well.
+func (inc Increment) IsNew(session Session) bool {
+	return inc.New[session.ID]
+}
+
+// FullIncrement returns an Increment adding every record of sessions, as when a whole export is
+// appended to the files of another one.
+func FullIncrement(sessions []Session) Increment {
+	inc := Increment{Sessions: sessions, New: make(map[string]bool, len(sessions))}
+	for _, session := range sessions {
+		inc.New[session.ID] = true
+	}
+	return inc
+}
+
+// AppendExporter is implemented by exporters whose files hold one record per message, so that
+// the messages added since a previous export can be appended to its files.
+type AppendExporter interface {
+	Exporter
+
+	// Append adds the records of inc to the files of a previous export at outputPath and returns
+	// the paths of the files that were written. Missing files are created with their headers.
+	Append(ctx context.Context, rfs filesystem.FileSystem, inc Increment, outputPath string, options Options) ([]string, error)
+
+	// SessionFields returns a function giving the values written with options for a session that
+	// are not fields of one of its messages, such as its topic or the session columns of a CSV
+	// file. Appending cannot update them, so the files must be rewritten when they change.
+	SessionFields(options Options) (func(session Session) []string, error)
+}
+
+// Ensure the formats with one record per message can append.
+var (
+	_ AppendExporter = (*perLineExporter)(nil)
+	_ AppendExporter = separateCSVExporter{}
+	_ AppendExporter = jsonlExporter{}
+)
+
+// Append appends a row for every message of inc to the CSV file at outputPath.
+func (e *perLineExporter) Append(ctx context.Context, rfs filesystem.FileSystem, inc Increment, outputPath string, options Options) ([]string, error) {
+	layout, err := e.layout(options)
+	if err != nil {
+		return nil, err
+	}
+	progress := trackProgress(ctx, inc.Sessions)
+	err = appendCSV(rfs, outputPath, layout, progress, func(csvWriter rowWriter) error {
+		for _, session := range inc.Sessions {
+			if err := checkContextCancellation(ctx); err != nil {
+				return err
+			}
+			if err := layout.writeRows(csvWriter, session); err != nil {
+				return err
+			}
+			if progress != nil {
+				if err := flushCSVWriter(csvWriter); err != nil {
+					return err
+				}
+				progress.done(session)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// SessionFields returns the values of the session columns repeated on every row.
+func (e *perLineExporter) SessionFields(options Options) (func(session Session) []string, error) {
+	layout, err := e.layout(options)
+	if err != nil {
+		return nil, err
+	}
+	return func(session Session) []string {
+		return layout.sessionFields(&session)
+	}, nil
+}
+
+// Append appends the new sessions to the sessions file at outputPath and every message of inc
+// to the messages file.
+func (e separateCSVExporter) Append(ctx context.Context, rfs filesystem.FileSystem, inc Increment, outputPath string, options Options) ([]string, error) {
+	sessionsLayout, messagesLayout, err := e.layouts(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContextCancellation(ctx); err != nil {
+		return nil, err
+	}
+
+	var newSessions []Session
+	for _, session := range inc.Sessions {
+		if inc.IsNew(session) {
+			newSessions = append(newSessions, session)
+		}
+	}
+	// The messages are appended first: a message row whose session row is missing is easier
+	// to spot and repair than a session row without its messages.
+	progress := trackProgress(ctx, inc.Sessions)
+	messagesPath := options.Get(e.Options(), OptionMessages)
+	if err := appendCSV(rfs, messagesPath, messagesLayout, progress, func(csvWriter rowWriter) error {
+		return writeRows(csvWriter, messagesLayout, inc.Sessions, progress)
+	}); err != nil {
+		return nil, err
+	}
+	if err := appendCSV(rfs, outputPath, sessionsLayout, progress, func(csvWriter rowWriter) error {
+		return writeRows(csvWriter, sessionsLayout, newSessions, nil)
+	}); err != nil {
+		return nil, err
+	}
+	return []string{outputPath, messagesPath}, nil
+}
+
+// SessionFields returns the values of the columns of the sessions file, and of the session
+// columns of the messages file.
+func (e separateCSVExporter) SessionFields(options Options) (func(session Session) []string, error) {
+	sessionsLayout, messagesLayout, err := e.layouts(options)
+	if err != nil {
+		return nil, err
+	}
+	return func(session Session) []string {
+		return append(sessionsLayout.sessionFields(&session), messagesLayout.sessionFields(&session)...)
+	}, nil
+}
+
+// Append appends a line for every message of inc to the JSONL file at outputPath.
+func (e jsonlExporter) Append(ctx context.Context, rfs filesystem.FileSystem, inc Increment, outputPath string, options Options) ([]string, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	err := appendFile(rfs, outputPath, func(w io.Writer, empty bool) error {
+		return WriteJSONL(ctx, w, inc.Sessions)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// SessionFields returns the topic, which is written on every line.
+func (e jsonlExporter) SessionFields(options Options) (func(session Session) []string, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	return func(session Session) []string {
+		return []string{session.Topic}
+	}, nil
+}
+
+// appendCSV appends the rows written by write to the CSV file at path in the dialect of layout,
+// writing its byte order mark and headers first if the file is new or empty. The bytes of the
+// rows are recorded by progress. A file written with other columns, headers, delimiter or byte
+// order mark is left untouched and reported with a FormatError.
+func appendCSV(rfs filesystem.FileSystem, path string, layout *csvLayout, progress *progressTracker, write func(rowWriter) error) error {
+	if err := checkCSVLayout(rfs, path, layout); err != nil {
+		return err
+	}
+	return appendFile(rfs, path, func(w io.Writer, empty bool) error {
+		w = progress.writer(w)
+		csvWriter := layout.dialect.newWriter(w)
+		if empty {
+			var err error
+			if csvWriter, err = initializeCSVWriter(w, layout); err != nil {
+				return err
+			}
+		}
+		if err := write(csvWriter); err != nil {
+			return err
+		}
+		return flushCSVWriter(csvWriter)
+	})
+}
+
+// checkCSVLayout checks that the CSV file at path, if it exists and is not empty, starts with the
+// byte order mark and header row that layout writes, so that the rows appended match its columns.
+func checkCSVLayout(rfs filesystem.FileSystem, path string, layout *csvLayout) error {
+	file, err := rfs.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	start, err := reader.Peek(len(utf8BOM))
+	if len(start) == 0 && errors.Is(err, io.EOF) {
+		return nil
+	}
+	hasBOM := string(start) == utf8BOM
+	if hasBOM != layout.dialect.bom {
+		return &FormatError{Kind: "file", Name: path, Reason: "was written with another bom option"}
+	}
+	if hasBOM {
+		reader.Discard(len(utf8BOM))
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = layout.dialect.comma
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	headers, err := csvReader.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read the headers of %s: %w", path, err)
+	}
+	if !slices.Equal(headers, layout.headers) {
+		return &FormatError{Kind: "file", Name: path, Reason: "has headers that do not match the columns, headers and delimiter options"}
+	}
+	return nil
+}
+
+// appendFile appends the data written by write to the file at path, creating it if needed.
+// The data is collected in memory and appended with a single write followed by a sync, so a
+// failed export never leaves half a record at the end of the file.
+func appendFile(rfs filesystem.FileSystem, path string, write func(w io.Writer, empty bool) error) error {
+	file, err := rfs.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, info.Size() == 0); err != nil {
+		return err
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return file.Close()
+}
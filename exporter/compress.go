@@ -0,0 +1,226 @@
+// Below, the package exporter (@compress.go) writes the output of any Exporter compressed with
+// gzip or zstd, bundled into a single zip archive, and optionally encrypted.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// OutputOptions controls how the files written by an Exporter are stored.
+type OutputOptions struct {
+	Compression compression.Format // Compression or bundling of the files, see ExportCompressed.
+	Passphrase  string             // When set, every file is encrypted and gets the ".enc" extension.
+}
+
+// ExportCompressed runs e and writes its output compressed with format, returning the paths of
+// the files that were written.
+//
+// With compression.Gzip and compression.Zstd, every file of the export is compressed on its own
+// and gets the compression extension appended, e.g. "messages.csv" becomes "messages.csv.gz";
+// paths that already carry the extension are kept as they are.
+//
+// With compression.Zip, all files of the export are bundled into the single archive at outputPath
+// (".zip" is appended if missing). Inside the archive, the primary file is named after outputPath
+// without ".zip" and additional files after the base name of their path option.
+//
+// With compression.None, ExportCompressed is the same as e.Export. All files are written atomically.
+func ExportCompressed(ctx context.Context, rfs filesystem.FileSystem, e Exporter, sessions []Session, outputPath string, options Options, format compression.Format) ([]string, error) {
+	return ExportWithOutput(ctx, rfs, e, sessions, outputPath, options, OutputOptions{Compression: format})
+}
+
+// ExportWithOutput runs e and stores its files as described by output: compressed or bundled as
+// ExportCompressed does, then encrypted with encryption.NewWriter if output.Passphrase is set.
+// Encrypted files get the ".enc" extension appended, e.g. "messages.csv.gz.enc".
+func ExportWithOutput(ctx context.Context, rfs filesystem.FileSystem, e Exporter, sessions []Session, outputPath string, options Options, output OutputOptions) ([]string, error) {
+	outputPath = encryption.TrimExtension(outputPath)
+	switch output.Compression {
+	case compression.None:
+		if output.Passphrase == "" {
+			return e.Export(ctx, rfs, sessions, outputPath, options)
+		}
+		return exportCompressedFiles(ctx, rfs, e, sessions, outputPath, options, output)
+	case compression.Gzip, compression.Zstd:
+		return exportCompressedFiles(ctx, rfs, e, sessions, outputPath, options, output)
+	case compression.Zip:
+		return exportZipBundle(ctx, rfs, e, sessions, outputPath, options, output)
+	default:
+		return nil, &FormatError{Kind: "compression", Name: string(output.Compression)}
+	}
+}
+
+// exportCompressedFiles compresses (and encrypts) every file of the export on its own.
+func exportCompressedFiles(ctx context.Context, rfs filesystem.FileSystem, e Exporter, sessions []Session, outputPath string, options Options, output OutputOptions) ([]string, error) {
+	format := output.Compression
+	// Single-stream formats are compressed while they are written, without buffering the export.
+	if we, ok := e.(WriterExporter); ok {
+		if err := options.Validate(e.Options()); err != nil {
+			return nil, err
+		}
+		dest := withExtension(outputPath, format.Extension())
+		err := writeCompressed(rfs, dest, output, func(w io.Writer) error {
+			zw, err := compression.NewWriter(w, format, compression.TrimExtension(dest))
+			if err != nil {
+				return err
+			}
+			if err := we.ExportTo(ctx, zw, sessions, options); err != nil {
+				zw.Close() // ignore error; we're already handling an error
+				return err
+			}
+			return zw.Close()
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{outputFileName(dest, output)}, nil
+	}
+
+	memFS, files, err := exportInMemory(ctx, rfs, e, sessions, compression.TrimExtension(outputPath), trimPathOptions(e, options))
+	if err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, file := range files {
+		dest := withExtension(file, format.Extension())
+		err := writeCompressed(rfs, dest, output, func(w io.Writer) error {
+			zw, err := compression.NewWriter(w, format, file)
+			if err != nil {
+				return err
+			}
+			if _, err := zw.Write(memFS.Files[file]); err != nil {
+				zw.Close() // ignore error; we're already handling an error
+				return err
+			}
+			return zw.Close()
+		})
+		if err != nil {
+			return written, err
+		}
+		written = append(written, outputFileName(dest, output))
+	}
+	return written, nil
+}
+
+// exportZipBundle writes all files of the export into a single zip archive.
+func exportZipBundle(ctx context.Context, rfs filesystem.FileSystem, e Exporter, sessions []Session, outputPath string, options Options, output OutputOptions) ([]string, error) {
+	dest := withExtension(outputPath, compression.Zip.Extension())
+	primary := compression.TrimExtension(dest)
+	if filepath.Ext(primary) == "" {
+		primary += e.Extension()
+	}
+
+	memFS, files, err := exportInMemory(ctx, rfs, e, sessions, filepath.Base(primary), basePathOptions(e, options))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]compression.BundleEntry, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, compression.BundleEntry{Name: filepath.ToSlash(file), Data: memFS.Files[file]})
+	}
+
+	err = writeCompressed(rfs, dest, output, func(w io.Writer) error {
+		return compression.WriteBundle(w, entries)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputFileName(dest, output)}, nil
+}
+
+// exportInMemory runs e against an in-memory file system and returns it along with the files
+// written. The files the exporter reads, such as fonts, are read through rfs.
+func exportInMemory(ctx context.Context, rfs filesystem.FileSystem, e Exporter, sessions []Session, outputPath string, options Options) (*filesystem.MockFileSystem, []string, error) {
+	// The mock file system doubles as an in-memory file system here.
+	memFS := filesystem.NewMockFileSystem()
+	memFS.Base = rfs
+	files, err := e.Export(ctx, memFS, sessions, outputPath, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	return memFS, files, nil
+}
+
+// writeCompressed writes dest atomically through rfs using write, encrypting the data if
+// output.Passphrase is set, so that a failed or cancelled export never leaves a truncated
+// archive behind.
+func writeCompressed(rfs filesystem.FileSystem, dest string, output OutputOptions, write func(io.Writer) error) error {
+	name := outputFileName(dest, output)
+	f, err := filesystem.CreateAtomic(rfs, name, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", name, err)
+	}
+	defer f.Discard()
+
+	if output.Passphrase == "" {
+		if err := write(f); err != nil {
+			return err
+		}
+		return f.Commit()
+	}
+
+	ew, err := encryption.NewWriter(f, output.Passphrase)
+	if err != nil {
+		return err
+	}
+	if err := write(ew); err != nil {
+		return err
+	}
+	if err := ew.Close(); err != nil {
+		return err
+	}
+	return f.Commit()
+}
+
+// outputFileName returns the name dest is stored under: with the encryption extension
+// appended when the output is encrypted.
+func outputFileName(dest string, output OutputOptions) string {
+	if output.Passphrase == "" {
+		return dest
+	}
+	return dest + encryption.Extension
+}
+
+// withExtension appends ext to name unless name already ends with it.
+func withExtension(name, ext string) string {
+	if ext == "" || filepath.Ext(name) == ext {
+		return name
+	}
+	return name + ext
+}
+
+// trimPathOptions returns a copy of options with compression extensions removed from path options.
+func trimPathOptions(e Exporter, options Options) Options {
+	return mapPathOptions(e, options, compression.TrimExtension)
+}
+
+// basePathOptions returns a copy of options with path options reduced to their base names,
+// as used for the names of zip entries.
+func basePathOptions(e Exporter, options Options) Options {
+	return mapPathOptions(e, options, func(path string) string {
+		return filepath.Base(compression.TrimExtension(path))
+	})
+}
+
+// mapPathOptions returns a copy of options with fn applied to every path option.
+func mapPathOptions(e Exporter, options Options, fn func(string) string) Options {
+	mapped := make(Options, len(options))
+	for name, value := range options {
+		mapped[name] = value
+	}
+	for _, option := range e.Options() {
+		if option.Path {
+			if value := options.Get(e.Options(), option.Name); value != "" {
+				mapped[option.Name] = fn(value)
+			}
+		}
+	}
+	return mapped
+}
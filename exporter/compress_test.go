@@ -0,0 +1,130 @@
+// Below, the package exporter (@compress_test.go) tests the reading of compressed and encrypted
+// backups, and the compressed, bundled and encrypted outputs.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestCompressedInputAndOutput verifies that compressed backups are read transparently, and that
+// exports can be compressed or bundled into a zip archive.
+func TestCompressedInputAndOutput(t *testing.T) {
+	data, err := os.ReadFile("../testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	var buf bytes.Buffer
+	w, err := compression.NewWriter(&buf, compression.Zstd, "backup.json")
+	if err != nil {
+		t.Fatalf("NewWriter() returned error: %v", err)
+	}
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	mockFS.Files["backup.json"] = buf.Bytes()
+	store, err := exporter.ReadJSONFromFileFS(mockFS, "backup.json")
+	if err != nil {
+		t.Errorf("ReadJSONFromFileFS() returned error: %v", err)
+	} else if len(store.ChatNextWebStore.Sessions) != 1 {
+		t.Errorf("ReadJSONFromFileFS() read %d sessions, want 1", len(store.ChatNextWebStore.Sessions))
+	}
+
+	sessions := []exporter.Session{{ID: "1", Topic: "Test Session", Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Hello"}}}}
+	ctx := context.Background()
+	perLine, _ := exporter.Lookup(exporter.FormatNamePerLine)
+	files, err := exporter.ExportCompressed(ctx, mockFS, perLine, sessions, "messages.csv", nil, compression.Gzip)
+	if err != nil || len(files) != 1 || files[0] != "messages.csv.gz" {
+		t.Fatalf("ExportCompressed(gzip) = %v, %v, want [messages.csv.gz]", files, err)
+	}
+	var plain bytes.Buffer
+	exporter.WriteSessionsCSV(ctx, &plain, sessions, exporter.FormatOptionPerLine)
+	if got, err := compression.Decompress(mockFS.Files["messages.csv.gz"], "messages.csv.gz"); err != nil || string(got) != plain.String() {
+		t.Errorf("decompressed export = %q, %v, want %q", got, err, plain.String())
+	}
+
+	separate, _ := exporter.Lookup(exporter.FormatNameSeparate)
+	options := exporter.Options{exporter.OptionMessages: filepath.Join("out", "messages.csv")}
+	files, err = exporter.ExportCompressed(ctx, mockFS, separate, sessions, "bundle.zip", options, compression.Zip)
+	if err != nil || len(files) != 1 || files[0] != "bundle.zip" {
+		t.Fatalf("ExportCompressed(zip) = %v, %v, want [bundle.zip]", files, err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(mockFS.Files["bundle.zip"]), int64(len(mockFS.Files["bundle.zip"])))
+	if err != nil {
+		t.Fatalf("bundle.zip is not a valid zip archive: %v", err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	if got, want := strings.Join(names, ","), "bundle.csv,messages.csv"; got != want {
+		t.Errorf("bundle.zip contains %s, want %s", got, want)
+	}
+	if _, ok := mockFS.Files[filepath.Join("out", "messages.csv")]; ok {
+		t.Error("ExportCompressed(zip) wrote a bundled file outside of the archive")
+	}
+
+	// Bundled files are written in memory, while the files an exporter reads come from rfs.
+	font, err := os.ReadFile(filepath.Join("..", "pdf", "fonts", "DejaVuSansMono.ttf"))
+	if err != nil {
+		t.Fatalf("Failed to read the font: %v", err)
+	}
+	mockFS.Files["fallback.ttf"] = font
+	transcript, _ := exporter.Lookup(exporter.FormatNamePDF)
+	files, err = exporter.ExportCompressed(ctx, mockFS, transcript, sessions, "transcript.zip", exporter.Options{exporter.OptionFonts: "fallback.ttf"}, compression.Zip)
+	if err != nil || len(files) != 1 || files[0] != "transcript.zip" {
+		t.Errorf("ExportCompressed(pdf, zip) = %v, %v, want [transcript.zip]", files, err)
+	}
+}
+
+// TestEncryptedInputAndOutput verifies that encrypted backups are read with the passphrase of a
+// key source, and that exports can be compressed and encrypted.
+func TestEncryptedInputAndOutput(t *testing.T) {
+	data, err := os.ReadFile("../testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+	sealed, err := encryption.Encrypt(data, "secret")
+	if err != nil {
+		t.Fatalf("Encrypt() returned error: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["backup.json.enc"] = sealed
+
+	keys := &encryption.KeySource{Env: "CHAT_EXPORTER_TEST_UNSET", Prompt: func() (string, error) {
+		return "secret", nil
+	}}
+	store, err := exporter.ReadJSONFromFileWithKeys(mockFS, "backup.json.enc", keys)
+	if err != nil || len(store.ChatNextWebStore.Sessions) != 1 {
+		t.Fatalf("ReadJSONFromFileWithKeys() = %d sessions, %v, want 1 session", len(store.ChatNextWebStore.Sessions), err)
+	}
+	if _, err := exporter.ReadJSONFromFileFS(mockFS, "backup.json.enc"); err == nil {
+		t.Error("ReadJSONFromFileFS() read an encrypted backup without a passphrase")
+	}
+
+	perLine, _ := exporter.Lookup(exporter.FormatNamePerLine)
+	output := exporter.OutputOptions{Compression: compression.Gzip, Passphrase: "secret"}
+	files, err := exporter.ExportWithOutput(context.Background(), mockFS, perLine, store.ChatNextWebStore.Sessions, "messages.csv", nil, output)
+	if err != nil || len(files) != 1 || files[0] != "messages.csv.gz.enc" {
+		t.Fatalf("ExportWithOutput() = %v, %v, want [messages.csv.gz.enc]", files, err)
+	}
+	content, err := encryption.ReadFile(mockFS, "messages.csv.gz.enc", keys)
+	if err != nil || !strings.HasPrefix(string(content), "session_id,message_id") {
+		t.Errorf("ReadFile() of the encrypted export = %q, %v, want the CSV", content, err)
+	}
+}
@@ -0,0 +1,513 @@
+// Below, the package exporter (@dialect.go) lets the CSV formats be written in another dialect,
+// such as tab-separated values for spreadsheets or semicolons for European locales, and with
+// other columns than their default ones.
+//
+// The columns are the fields of a session, of its mask and model configuration, and of its
+// messages, named after their JSON keys: "topic", "stat.tokenCount", "mask.name",
+// "mask.modelConfig.model" or "message.content". Slices, such as "messages" or "mask.context",
+// are written as JSON, and "messages.inline" writes the messages as the inline format does.
+//
+// # Example Usage
+//
+//	e, _ := exporter.Lookup(exporter.FormatNamePerLine)
+//	options := exporter.Options{
+//		exporter.OptionDelimiter: "tab",
+//		exporter.OptionColumns:   "id,topic,mask.modelConfig.model,message.role,message.content",
+//		exporter.OptionHeaders:   "id=Session,message.content=Text",
+//		exporter.OptionBOM:       "true",
+//	}
+//	files, err := e.Export(ctx, rfs, sessions, "messages.tsv", options)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// OptionDelimiter is the option of the CSV formats that sets the field delimiter: a single
+	// character, or "tab".
+	OptionDelimiter = "delimiter"
+
+	// OptionQuote is the option of the CSV formats that sets which fields are quoted: "minimal"
+	// quotes the fields that need it, "all" quotes every field.
+	OptionQuote = "quote"
+
+	// OptionLineEnding is the option of the CSV formats that sets the line ending: "lf" or "crlf".
+	OptionLineEnding = "lineEnding"
+
+	// OptionBOM is the option of the CSV formats that starts new files with a UTF-8 byte order
+	// mark when "true", so that Excel reads them as UTF-8.
+	OptionBOM = "bom"
+
+	// OptionColumns is the option of the CSV formats that selects the columns of the file, or of
+	// the sessions file of the separate format, as a comma-separated list of column names.
+	OptionColumns = "columns"
+
+	// OptionMessageColumns is the option of the separate CSV format that selects the columns of
+	// the messages file.
+	OptionMessageColumns = "messageColumns"
+
+	// OptionHeaders is the option of the CSV formats that renames headers, as comma-separated
+	// pairs of a column name or default header and its new header, such as "id=Session ID".
+	OptionHeaders = "headers"
+)
+
+// utf8BOM is the UTF-8 byte order mark.
+const utf8BOM = "\ufeff"
+
+// column is a field that can be written as a CSV column.
+type column struct {
+	name    string                              // Name of the column, such as "mask.name".
+	message bool                                // Whether the column is a field of a message.
+	value   func(s *Session, m *Message) string // Returns the field of s or of m, its message.
+}
+
+// columns holds the available columns by name, and columnNames their names in struct order.
+var columns, columnNames = listColumns()
+
+// listColumns returns the columns of the fields of Session and Message.
+func listColumns() (map[string]column, []string) {
+	byName := make(map[string]column)
+	var names []string
+	add := func(c column) {
+		byName[c.name] = c
+		names = append(names, c.name)
+	}
+	addFields(reflect.TypeOf(Session{}), "", nil, func(name string, index []int) {
+		add(column{name: name, value: func(s *Session, m *Message) string {
+			return formatField(reflect.ValueOf(s).Elem().FieldByIndex(index))
+		}})
+	})
+	add(column{name: "messages.inline", value: func(s *Session, m *Message) string {
+		return inlineMessages(s.Messages)
+	}})
+	addFields(reflect.TypeOf(Message{}), "message.", nil, func(name string, index []int) {
+		add(column{name: name, message: true, value: func(s *Session, m *Message) string {
+			return formatField(reflect.ValueOf(m).Elem().FieldByIndex(index))
+		}})
+	})
+	return byName, names
+}
+
+// addFields calls add with the name and the index of every field of t, named after its JSON key
+// and prefix. The fields of nested structs are added instead of the structs themselves.
+func addFields(t reflect.Type, prefix string, parent []int, add func(name string, index []int)) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		index := append(append([]int(nil), parent...), i)
+		if field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, prefix+key+".", index, add)
+			continue
+		}
+		add(prefix+key, index)
+	}
+}
+
+// formatField returns v as it is written in a CSV column: numbers and booleans as in JSON, and
+// slices as JSON.
+func formatField(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
+
+// inlineMessages returns messages concatenated as in the inline format.
+func inlineMessages(messages []Message) string {
+	var contents []string
+	for _, message := range messages {
+		contents = append(contents, fmt.Sprintf("[%s, %s] \"%s\"", message.Role, message.Date, message.Content))
+	}
+	return strings.Join(contents, "; ")
+}
+
+// CSVColumns returns the names of the columns that the CSV formats can write. The columns of
+// messages, named "message.*", are only available to the formats writing a row per message.
+func CSVColumns() []string {
+	return append([]string(nil), columnNames...)
+}
+
+// csvDialect tells how the rows of a CSV file are written.
+type csvDialect struct {
+	comma    rune // Field delimiter.
+	quoteAll bool // Whether every field is quoted, rather than only those that need it.
+	useCRLF  bool // Whether lines end with \r\n rather than \n.
+	bom      bool // Whether new files start with a UTF-8 byte order mark.
+}
+
+// defaultDialect is the dialect of encoding/csv, used when no dialect option is set.
+var defaultDialect = csvDialect{comma: ','}
+
+// parseDialect returns the dialect set by options.
+func parseDialect(options Options) (csvDialect, error) {
+	dialect := defaultDialect
+	var err error
+	if value := options[OptionDelimiter]; value != "" {
+		if dialect.comma, err = parseDelimiter(value); err != nil {
+			return dialect, &FormatError{Kind: "option", Name: OptionDelimiter, Reason: err.Error()}
+		}
+	}
+	if value := options[OptionQuote]; value != "" {
+		if dialect.quoteAll, err = parseChoice(value, "minimal", "all"); err != nil {
+			return dialect, &FormatError{Kind: "option", Name: OptionQuote, Reason: err.Error()}
+		}
+	}
+	if value := options[OptionLineEnding]; value != "" {
+		if dialect.useCRLF, err = parseChoice(value, "lf", "crlf"); err != nil {
+			return dialect, &FormatError{Kind: "option", Name: OptionLineEnding, Reason: err.Error()}
+		}
+	}
+	if value := options[OptionBOM]; value != "" {
+		if dialect.bom, err = parseBool(value); err != nil {
+			return dialect, &FormatError{Kind: "option", Name: OptionBOM, Reason: err.Error()}
+		}
+	}
+	return dialect, nil
+}
+
+// parseDelimiter returns the delimiter named by value: a single character, "tab" or "\t".
+func parseDelimiter(value string) (rune, error) {
+	switch strings.ToLower(value) {
+	case "tab", `\t`:
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, errors.New("must be a single character other than a quote or a newline, or tab")
+	}
+	return r, nil
+}
+
+// parseChoice reports whether value is on rather than off, in any case.
+func parseChoice(value, off, on string) (bool, error) {
+	switch strings.ToLower(value) {
+	case off:
+		return false, nil
+	case on:
+		return true, nil
+	default:
+		return false, fmt.Errorf("must be %s or %s", off, on)
+	}
+}
+
+// parseBool returns the boolean value, such as true, false, 1 or 0.
+func parseBool(value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, errors.New("must be true or false")
+	}
+	return b, nil
+}
+
+// newWriter returns a writer of the rows of the dialect on top of w.
+func (d csvDialect) newWriter(w io.Writer) rowWriter {
+	if d.quoteAll {
+		return &quotingWriter{w: bufio.NewWriter(w), comma: d.comma, useCRLF: d.useCRLF}
+	}
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = d.comma
+	csvWriter.UseCRLF = d.useCRLF
+	return csvWriter
+}
+
+// writeBOM starts a new file written to w with the byte order mark, if the dialect has one.
+func (d csvDialect) writeBOM(w io.Writer) error {
+	if !d.bom {
+		return nil
+	}
+	_, err := io.WriteString(w, utf8BOM)
+	return err
+}
+
+// rowWriter writes CSV rows, like a csv.Writer.
+type rowWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// quotingWriter is a rowWriter quoting every field.
+type quotingWriter struct {
+	w       *bufio.Writer
+	comma   rune
+	useCRLF bool
+	err     error
+}
+
+// Write writes record with every field quoted.
+func (q *quotingWriter) Write(record []string) error {
+	if q.err != nil {
+		return q.err
+	}
+	for i, field := range record {
+		if i > 0 {
+			q.w.WriteRune(q.comma)
+		}
+		field = strings.ReplaceAll(field, `"`, `""`)
+		if q.useCRLF {
+			field = strings.ReplaceAll(strings.ReplaceAll(field, "\r\n", "\n"), "\n", "\r\n")
+		}
+		q.w.WriteByte('"')
+		q.w.WriteString(field)
+		q.w.WriteByte('"')
+	}
+	if q.useCRLF {
+		q.w.WriteString("\r\n")
+	} else {
+		q.w.WriteByte('\n')
+	}
+	return nil
+}
+
+// Flush writes the buffered rows.
+func (q *quotingWriter) Flush() {
+	if err := q.w.Flush(); err != nil && q.err == nil {
+		q.err = err
+	}
+}
+
+// Error returns the first error of a Write or a Flush.
+func (q *quotingWriter) Error() error {
+	return q.err
+}
+
+// csvLayout tells which columns a CSV file holds and how its rows are written.
+type csvLayout struct {
+	columns  []column   // Columns of the rows.
+	headers  []string   // Header of every column.
+	messages bool       // Whether a row is written per message rather than per session.
+	dialect  csvDialect // How the rows are written.
+}
+
+// csvColumns are the default columns of a CSV file and their headers.
+type csvColumns struct {
+	names    []string // Names of the columns.
+	headers  []string // Headers of the columns.
+	messages bool     // Whether a row is written per message rather than per session.
+}
+
+// Default columns of the built-in CSV formats.
+var (
+	inlineColumns = csvColumns{
+		names:   []string{"id", "topic", "memoryPrompt", "messages.inline"},
+		headers: []string{"id", "topic", "memoryPrompt", "messages"},
+	}
+	jsonColumns = csvColumns{
+		names:   []string{"id", "topic", "memoryPrompt", "messages"},
+		headers: []string{"id", "topic", "memoryPrompt", "messages"},
+	}
+	sessionsColumns = csvColumns{
+		names:   []string{"id", "topic", "memoryPrompt"},
+		headers: sessionsHeaders,
+	}
+	messagesColumns = csvColumns{
+		names:    []string{"id", "message.id", "message.date", "message.role", "message.content", "memoryPrompt"},
+		headers:  messagesHeaders,
+		messages: true,
+	}
+)
+
+// layout returns the default layout of the columns.
+func (c csvColumns) layout() *csvLayout {
+	layout := &csvLayout{headers: c.headers, messages: c.messages, dialect: defaultDialect}
+	for _, name := range c.names {
+		layout.columns = append(layout.columns, columns[name])
+	}
+	return layout
+}
+
+// layoutFor returns the layout set by options, whose columnsOption selects other columns than
+// the default ones.
+func (c csvColumns) layoutFor(options Options, columnsOption string) (*csvLayout, error) {
+	layout := c.layout()
+	var err error
+	if layout.dialect, err = parseDialect(options); err != nil {
+		return nil, err
+	}
+	if value := options[columnsOption]; value != "" {
+		if layout.columns, err = c.parseColumns(value); err != nil {
+			return nil, &FormatError{Kind: "option", Name: columnsOption, Reason: err.Error()}
+		}
+		layout.headers = make([]string, len(layout.columns))
+		for i, column := range layout.columns {
+			layout.headers[i] = column.name
+		}
+	}
+	if value := options[OptionHeaders]; value != "" {
+		renames, err := parseHeaders(value)
+		if err != nil {
+			return nil, &FormatError{Kind: "option", Name: OptionHeaders, Reason: err.Error()}
+		}
+		headers := make([]string, len(layout.headers))
+		for i, header := range layout.headers {
+			headers[i] = header
+			if renamed, ok := renames[layout.columns[i].name]; ok {
+				headers[i] = renamed
+			} else if renamed, ok := renames[header]; ok {
+				headers[i] = renamed
+			}
+		}
+		layout.headers = headers
+	}
+	return layout, nil
+}
+
+// parseColumns returns the columns named in the comma-separated value.
+func (c csvColumns) parseColumns(value string) ([]column, error) {
+	var selected []column
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		column, ok := columns[name]
+		switch {
+		case name == "":
+			continue
+		case !ok:
+			return nil, fmt.Errorf("has unknown column %q", name)
+		case column.message && !c.messages:
+			return nil, fmt.Errorf("has column %q, but the file has a row per session", name)
+		}
+		selected = append(selected, column)
+	}
+	if len(selected) == 0 {
+		return nil, errors.New("selects no column")
+	}
+	return selected, nil
+}
+
+// parseHeaders returns the new headers of the comma-separated "column=header" pairs of value.
+func parseHeaders(value string) (map[string]string, error) {
+	renames := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, header, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("has %q, which is not a column=header pair", pair)
+		}
+		renames[strings.TrimSpace(name)] = strings.TrimSpace(header)
+	}
+	return renames, nil
+}
+
+// writeHeaders writes the headers of the layout to csvWriter.
+func (l *csvLayout) writeHeaders(csvWriter rowWriter) error {
+	if err := csvWriter.Write(l.headers); err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
+	}
+	return nil
+}
+
+// writeRows writes the row of session, or a row per message of session, to csvWriter.
+func (l *csvLayout) writeRows(csvWriter rowWriter, session Session) error {
+	if !l.messages {
+		return csvWriter.Write(l.row(&session, nil))
+	}
+	for i := range session.Messages {
+		if err := csvWriter.Write(l.row(&session, &session.Messages[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// row returns the fields of the layout's columns for session and message, which is nil in the
+// rows of sessions.
+func (l *csvLayout) row(session *Session, message *Message) []string {
+	row := make([]string, len(l.columns))
+	for i, column := range l.columns {
+		row[i] = column.value(session, message)
+	}
+	return row
+}
+
+// sessionFields returns the values of the columns of l that are fields of session rather than of
+// one of its messages.
+func (l *csvLayout) sessionFields(session *Session) []string {
+	var fields []string
+	for _, column := range l.columns {
+		if !column.message {
+			fields = append(fields, column.value(session, nil))
+		}
+	}
+	return fields
+}
+
+// option returns the schema of the option named name selecting other columns than c.
+func (c csvColumns) option(name string) Option {
+	return Option{
+		Name:        name,
+		Description: "comma-separated columns, such as " + strings.Join(c.names, ","),
+		Check: func(value string) error {
+			_, err := c.parseColumns(value)
+			return err
+		},
+	}
+}
+
+// csvOptions returns the schema of the options of a CSV format: the dialect options, the
+// columnOptions selecting the columns of its files, and the renaming of headers.
+func csvOptions(columnOptions ...Option) []Option {
+	options := []Option{
+		{Name: OptionDelimiter, Description: "field delimiter, a single character or tab", Default: ",", Check: func(value string) error {
+			_, err := parseDelimiter(value)
+			return err
+		}},
+		{Name: OptionQuote, Description: "fields to quote, minimal or all", Default: "minimal", Check: func(value string) error {
+			_, err := parseChoice(value, "minimal", "all")
+			return err
+		}},
+		{Name: OptionLineEnding, Description: "line ending, lf or crlf", Default: "lf", Check: func(value string) error {
+			_, err := parseChoice(value, "lf", "crlf")
+			return err
+		}},
+		{Name: OptionBOM, Description: "whether new files start with a UTF-8 byte order mark for Excel", Default: "false", Check: func(value string) error {
+			_, err := parseBool(value)
+			return err
+		}},
+	}
+	options = append(options, columnOptions...)
+	return append(options, Option{
+		Name:        OptionHeaders,
+		Description: "comma-separated column=header pairs renaming headers",
+		Check: func(value string) error {
+			_, err := parseHeaders(value)
+			return err
+		},
+	})
+}
+
+// kind returns what the rows of the layout hold, "message" or "session".
+func (l *csvLayout) kind() string {
+	if l.messages {
+		return "message"
+	}
+	return "session"
+}
@@ -0,0 +1,135 @@
+// Below, the package exporter (@dialect_test.go) tests the columns, headers and dialect options
+// of the CSV formats.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestCSVDialect verifies that the CSV formats write the selected columns of sessions, masks and
+// messages in the configured dialect, and that invalid options are rejected.
+func TestCSVDialect(t *testing.T) {
+	ctx := context.Background()
+	sessions := []exporter.Session{{
+		ID:    "1",
+		Topic: "Test Session",
+		Stat:  exporter.Stat{TokenCount: 42},
+		Mask:  exporter.Mask{Name: "Gopher", ModelConfig: exporter.ModelConfig{Model: "gpt-4", Temperature: 0.5}},
+		Messages: []exporter.Message{
+			{ID: "m1", Role: "user", Content: "Hello,\n\"Gopher\""},
+			{ID: "m2", Role: "assistant", Content: "Hi!"},
+		},
+	}}
+	for _, name := range []string{"stat.tokenCount", "lastUpdate", "mask.lang", "mask.modelConfig.temperature", "message.role"} {
+		if !strings.Contains(","+strings.Join(exporter.CSVColumns(), ",")+",", ","+name+",") {
+			t.Errorf("CSVColumns() = %v, want %s", exporter.CSVColumns(), name)
+		}
+	}
+
+	perLine, _ := exporter.Lookup(exporter.FormatNamePerLine)
+	options := exporter.Options{
+		exporter.OptionDelimiter:  "tab",
+		exporter.OptionQuote:      "all",
+		exporter.OptionLineEnding: "crlf",
+		exporter.OptionBOM:        "true",
+		exporter.OptionColumns:    "id, stat.tokenCount, mask.name, mask.modelConfig.model, mask.modelConfig.temperature, message.role, message.content",
+		exporter.OptionHeaders:    "id=Session,message.content=Text",
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	if _, err := perLine.Export(ctx, mockFS, sessions, "messages.tsv", options); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	want := "\ufeff" + `"Session"	"stat.tokenCount"	"mask.name"	"mask.modelConfig.model"	"mask.modelConfig.temperature"	"message.role"	"Text"` + "\r\n" +
+		`"1"	"42"	"Gopher"	"gpt-4"	"0.5"	"user"	"Hello,` + "\r\n" + `""Gopher"""` + "\r\n" +
+		`"1"	"42"	"Gopher"	"gpt-4"	"0.5"	"assistant"	"Hi!"` + "\r\n"
+	if got := string(mockFS.Files["messages.tsv"]); got != want {
+		t.Errorf("messages.tsv = %q, want %q", got, want)
+	}
+
+	// Appending writes neither a second byte order mark nor the headers again.
+	inc := exporter.FullIncrement([]exporter.Session{{ID: "2", Messages: []exporter.Message{{ID: "m3", Role: "user", Content: "Bye"}}}})
+	if _, err := perLine.(exporter.AppendExporter).Append(ctx, mockFS, inc, "messages.tsv", options); err != nil {
+		t.Fatalf("Append() returned error: %v", err)
+	}
+	want += `"2"	"0"	""	""	"0"	"user"	"Bye"` + "\r\n"
+	if got := string(mockFS.Files["messages.tsv"]); got != want {
+		t.Errorf("appended messages.tsv = %q, want %q", got, want)
+	}
+
+	// Rows are not appended to a file written with other columns, headers, delimiter or BOM.
+	for name, value := range map[string]string{
+		exporter.OptionColumns:   "id,message.role,message.content",
+		exporter.OptionHeaders:   "id=ID,message.content=Text",
+		exporter.OptionDelimiter: ";",
+		exporter.OptionBOM:       "false",
+	} {
+		other := maps.Clone(options)
+		other[name] = value
+		if _, err := perLine.(exporter.AppendExporter).Append(ctx, mockFS, inc, "messages.tsv", other); !errors.Is(err, exporter.ErrInvalidFormat) {
+			t.Errorf("Append() with another %s = %v, want an invalid format", name, err)
+		}
+	}
+	if got := string(mockFS.Files["messages.tsv"]); got != want {
+		t.Errorf("messages.tsv after mismatched appends = %q, want %q", got, want)
+	}
+
+	// The default headers of the separate format can be renamed, and both files use the dialect.
+	separate, _ := exporter.Lookup(exporter.FormatNameSeparate)
+	options = exporter.Options{
+		exporter.OptionMessages:       "messages.csv",
+		exporter.OptionDelimiter:      ";",
+		exporter.OptionColumns:        "id,topic,mask.name,messages",
+		exporter.OptionMessageColumns: "id,message.content",
+		exporter.OptionHeaders:        "session_id=Session,message.content=Content",
+	}
+	if _, err := separate.Export(ctx, mockFS, sessions, "sessions.csv", options); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	if got, want := string(mockFS.Files["sessions.csv"]), "id;topic;mask.name;messages\n1;Test Session;Gopher;\"[{\"\"id\"\":\"\"m1\"\",\"\"date\"\":\"\"\"\",\"\"role\"\":\"\"user\"\",\"\"content\"\":\"\"Hello,\\n\\\"\"Gopher\\\"\"\"\"},{\"\"id\"\":\"\"m2\"\",\"\"date\"\":\"\"\"\",\"\"role\"\":\"\"assistant\"\",\"\"content\"\":\"\"Hi!\"\"}]\"\n"; got != want {
+		t.Errorf("sessions.csv = %q, want %q", got, want)
+	}
+	if got, want := string(mockFS.Files["messages.csv"]), "id;Content\n1;\"Hello,\n\"\"Gopher\"\"\"\n1;Hi!\n"; got != want {
+		t.Errorf("messages.csv = %q, want %q", got, want)
+	}
+
+	// Without options, the formats write their default columns.
+	var plain, configured bytes.Buffer
+	inline, _ := exporter.Lookup(exporter.FormatNameInline)
+	exporter.WriteSessionsCSV(ctx, &plain, sessions, exporter.FormatOptionInline)
+	if err := inline.(exporter.WriterExporter).ExportTo(ctx, &configured, sessions, exporter.Options{exporter.OptionHeaders: "messages=Conversation"}); err != nil {
+		t.Fatalf("ExportTo() returned error: %v", err)
+	}
+	if got, want := configured.String(), strings.Replace(plain.String(), "messages", "Conversation", 1); got != want {
+		t.Errorf("inline CSV = %q, want %q", got, want)
+	}
+
+	for _, invalid := range []exporter.Options{
+		{exporter.OptionDelimiter: "::"},
+		{exporter.OptionDelimiter: `"`},
+		{exporter.OptionQuote: "some"},
+		{exporter.OptionLineEnding: "cr"},
+		{exporter.OptionBOM: "maybe"},
+		{exporter.OptionColumns: "id,nope"},
+		{exporter.OptionColumns: "id,message.content"},
+		{exporter.OptionColumns: " , "},
+		{exporter.OptionHeaders: "id"},
+	} {
+		_, err := inline.Export(ctx, mockFS, sessions, "invalid.csv", invalid)
+		if !errors.Is(err, exporter.ErrInvalidFormat) {
+			t.Errorf("Export() with %v returned %v, want an invalid option", invalid, err)
+		}
+	}
+	if _, ok := mockFS.Files["invalid.csv"]; ok {
+		t.Error("an export with invalid options wrote its file")
+	}
+}
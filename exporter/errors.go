@@ -0,0 +1,140 @@
+// Below, the package exporter (@errors.go) defines the errors of the package, so that callers can
+// tell an invalid format or option, malformed JSON and a backup of another application apart with
+// errors.Is and errors.As. Failures to read or write files are filesystem.IOError values, and
+// cancelled exports return the error of their context.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrInvalidFormat is matched by every FormatError with errors.Is.
+	ErrInvalidFormat = errors.New("invalid format")
+	// ErrSchemaMismatch is matched by every SchemaError with errors.Is.
+	ErrSchemaMismatch = errors.New("JSON does not match the expected format chat-next-web-store")
+)
+
+// FormatError reports an unknown export format, compression or exporter option, an option
+// that is missing, or a file to append to that was written with other options.
+type FormatError struct {
+	Kind   string // What is invalid: "format", "format option", "compression", "option" or "file".
+	Name   string // The invalid value, the name of the invalid option or the path of the file.
+	Reason string // Why the option is invalid, such as "is required"; empty if it is unknown.
+}
+
+// Error returns a message such as `unknown compression "rar"` or `option "messages" is required`.
+func (e *FormatError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("unknown %s %q", e.Kind, e.Name)
+	}
+	return fmt.Sprintf("%s %q %s", e.Kind, e.Name, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidFormat.
+func (e *FormatError) Is(target error) bool {
+	return target == ErrInvalidFormat
+}
+
+// ParseError reports JSON that is malformed or truncated, and where. It unwraps to the error of
+// the decoder, a *json.SyntaxError or io.ErrUnexpectedEOF.
+type ParseError struct {
+	Path   string // File the JSON was read from; empty if unknown.
+	Offset int64  // Offset in bytes at which the JSON became invalid.
+	Err    error  // Error of the decoder.
+}
+
+// Error returns the file, the offset and the error of the decoder.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%sinvalid JSON at offset %d: %v", pathPrefix(e.Path), e.Offset, e.Err)
+}
+
+// Unwrap returns the error of the decoder.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError reports well-formed JSON that is not a NextChat backup, such as a backup without
+// its chat-next-web-store key or with a value of the wrong type.
+type SchemaError struct {
+	Path   string // File the JSON was read from; empty if unknown.
+	Offset int64  // Offset in bytes of the mismatching value; 0 if unknown.
+	Err    error  // Cause of the mismatch, such as a *json.UnmarshalTypeError; nil if the store is missing.
+}
+
+// Error returns the file and the cause of the mismatch.
+func (e *SchemaError) Error() string {
+	if e.Err == nil {
+		return pathPrefix(e.Path) + ErrSchemaMismatch.Error()
+	}
+	return fmt.Sprintf("%s%s: %v", pathPrefix(e.Path), ErrSchemaMismatch, e.Err)
+}
+
+// Unwrap returns the cause of the mismatch.
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrSchemaMismatch.
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrSchemaMismatch
+}
+
+// decodeError returns err, returned by a decoder that read read bytes, as a ParseError or a
+// SchemaError if it is one.
+func decodeError(read int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &ParseError{Offset: syntaxErr.Offset, Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &ParseError{Offset: read, Err: err}
+	case errors.Is(err, io.EOF):
+		// An empty input is as truncated as any other.
+		return &ParseError{Err: io.ErrUnexpectedEOF}
+	case errors.As(err, &typeErr):
+		return &SchemaError{Offset: typeErr.Offset, Err: err}
+	default:
+		return err
+	}
+}
+
+// countingReader counts the bytes read from r, so that truncated JSON is reported where it ends.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read reads from r and counts the bytes read.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// withPath returns err with path set if it is a ParseError or a SchemaError.
+func withPath(err error, path string) error {
+	var parseErr *ParseError
+	var schemaErr *SchemaError
+	switch {
+	case errors.As(err, &parseErr):
+		parseErr.Path = path
+	case errors.As(err, &schemaErr):
+		schemaErr.Path = path
+	}
+	return err
+}
+
+// pathPrefix returns "path: ", or nothing if path is empty.
+func pathPrefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
@@ -0,0 +1,57 @@
+// Below, the package exporter (@errors_test.go) tests that the errors of the package can be told
+// apart with errors.Is and errors.As.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestErrorTaxonomy verifies that malformed JSON, backups of another shape, invalid options and
+// failures to read a file are reported with their own errors.
+func TestErrorTaxonomy(t *testing.T) {
+	_, err := exporter.ReadJSON(strings.NewReader(`{"chat-next-web-store": {"sessions": [}`))
+	var parseErr *exporter.ParseError
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &parseErr) || parseErr.Offset != 39 || !errors.As(err, &syntaxErr) {
+		t.Errorf("ReadJSON() of malformed JSON = %v, want a ParseError at offset 39 wrapping a SyntaxError", err)
+	}
+	if _, err := exporter.ReadJSON(strings.NewReader("")); !errors.As(err, &parseErr) || errors.Is(err, io.EOF) {
+		t.Errorf("ReadJSON() of an empty input = %v, want a ParseError that is not io.EOF", err)
+	}
+	if _, err := exporter.ReadJSON(strings.NewReader(`{"other": {}}`)); !errors.Is(err, exporter.ErrSchemaMismatch) {
+		t.Errorf("ReadJSON() of another store = %v, want ErrSchemaMismatch", err)
+	}
+	var schemaErr *exporter.SchemaError
+	if _, err := exporter.ReadJSON(strings.NewReader(`{"chat-next-web-store": {"sessions": "none"}}`)); !errors.As(err, &schemaErr) || schemaErr.Offset == 0 {
+		t.Errorf("ReadJSON() of a mistyped store = %v, want a SchemaError with its offset", err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["broken.json"] = []byte(`{"chat-next-web-store": `)
+	_, err = exporter.ReadJSONFromFileFS(mockFS, "broken.json")
+	if !errors.As(err, &parseErr) || parseErr.Path != "broken.json" || parseErr.Offset != 24 || !strings.HasPrefix(err.Error(), "broken.json: invalid JSON") {
+		t.Errorf("ReadJSONFromFileFS() of a truncated file = %v, want a ParseError naming the file and its end", err)
+	}
+	_, err = exporter.ReadJSONFromFileFS(mockFS, "missing.json")
+	var ioErr *filesystem.IOError
+	if !errors.Is(err, filesystem.ErrIO) || !errors.Is(err, os.ErrNotExist) || !errors.As(err, &ioErr) || ioErr.Path != "missing.json" {
+		t.Errorf("ReadJSONFromFileFS() of a missing file = %v, want an IOError matching os.ErrNotExist", err)
+	}
+
+	separate, _ := exporter.Lookup(exporter.FormatNameSeparate)
+	err = exporter.Options{"colour": "red"}.Validate(separate.Options())
+	var formatErr *exporter.FormatError
+	if !errors.Is(err, exporter.ErrInvalidFormat) || !errors.As(err, &formatErr) || formatErr.Name == "" {
+		t.Errorf("Options.Validate() of an unknown option = %v, want a FormatError", err)
+	}
+}
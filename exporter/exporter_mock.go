@@ -0,0 +1,57 @@
+// Below, the package exporter (@exporter_mock.go) furnishes a mock implementation of the Exporter
+// interface intended for testing. It records the sessions it was asked to export and can be set
+// to return errors, without writing anything.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// Ensure MockExporter adheres to the Exporter interface.
+var _ Exporter = (*MockExporter)(nil)
+
+// MockExporter is a mock implementation of the Exporter interface for testing purposes.
+// It allows for the simulation of session conversion to CSV format and can be set to return errors for testing error handling.
+//
+// Note: this types is proof of concept after touring golang.
+type MockExporter struct {
+	ErrToReturn      error     // ErrToReturn is the error that Export and ConvertSessionsToCSV will return when called.
+	ExportCalled     bool      // Track if Export has been called.
+	ExportedSessions []Session // Track the sessions provided to Export.
+	ExportedPath     string    // Track the output path provided to Export.
+}
+
+// Name returns "mock".
+func (m *MockExporter) Name() string { return "mock" }
+
+// Description returns the menu description of the mock format.
+func (m *MockExporter) Description() string { return "Mock Exporter" }
+
+// Extension returns ".mock".
+func (m *MockExporter) Extension() string { return ".mock" }
+
+// Options returns nil; the mock format has no options.
+func (m *MockExporter) Options() []Option { return nil }
+
+// Export records its arguments and returns ErrToReturn.
+func (m *MockExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	m.ExportCalled = true
+	m.ExportedSessions = sessions
+	m.ExportedPath = outputPath
+	if m.ErrToReturn != nil {
+		return nil, m.ErrToReturn
+	}
+	return []string{outputPath}, nil
+}
+
+// ConvertSessionsToCSV simulates the conversion of sessions to CSV format.
+// It returns an error specified by ErrToReturn, allowing for error handling tests.
+//
+// Note: this function is proof of concept after touring golang.
+func (m *MockExporter) ConvertSessionsToCSV(ctx context.Context, sessions []Session, formatOption int, csvFileName string) error {
+	return m.ErrToReturn
+}
@@ -0,0 +1,301 @@
+// Below, the package exporter (@formats.go) registers the built-in export formats:
+// the CSV variants, the Hugging Face dataset, JSON Lines, the Excel workbook and the PDF transcript.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+const (
+	// FormatNameInline is the registry name of the inline CSV format (FormatOptionInline).
+	FormatNameInline = "inline"
+
+	// FormatNamePerLine is the registry name of the one-message-per-line CSV format (FormatOptionPerLine).
+	FormatNamePerLine = "perline"
+
+	// FormatNameJSON is the registry name of the JSON-in-CSV format (FormatOptionJSON).
+	FormatNameJSON = "json"
+
+	// FormatNameSeparate is the registry name of the separate sessions and messages CSV files (OutputFormatSeparateCSVFiles).
+	FormatNameSeparate = "separate"
+
+	// FormatNameDataset is the registry name of the Hugging Face dataset format.
+	FormatNameDataset = "dataset"
+
+	// FormatNameJSONL is the registry name of the JSON Lines format, one message per line.
+	FormatNameJSONL = "jsonl"
+
+	// FormatNameXLSX is the registry name of the Excel workbook, with a sheet for the sessions, the messages and the summary.
+	FormatNameXLSX = "xlsx"
+
+	// FormatNamePDF is the registry name of the printable PDF transcript.
+	FormatNamePDF = "pdf"
+
+	// OptionMessages is the option of the separate CSV format that holds the messages file path.
+	OptionMessages = "messages"
+)
+
+// Ensure the single-stream formats can write to any io.Writer.
+var (
+	_ WriterExporter = (*csvExporter)(nil)
+	_ WriterExporter = (*perLineExporter)(nil)
+	_ WriterExporter = datasetExporter{}
+	_ WriterExporter = jsonlExporter{}
+)
+
+// Register the built-in formats in the order they appear in the CLI menus.
+func init() {
+	Register(&csvExporter{
+		name:        FormatNameInline,
+		description: "Inline Formatting",
+		option:      FormatOptionInline,
+		columns:     inlineColumns,
+	})
+	Register(&perLineExporter{csvExporter{
+		name:        FormatNamePerLine,
+		description: "One Message Per Line",
+		option:      FormatOptionPerLine,
+		columns:     messagesColumns,
+	}})
+	Register(&csvExporter{
+		name:        FormatNameJSON,
+		description: "JSON String in CSV",
+		option:      FormatOptionJSON,
+		columns:     jsonColumns,
+	})
+	Register(separateCSVExporter{})
+	Register(datasetExporter{})
+	Register(jsonlExporter{})
+	Register(xlsxExporter{})
+	Register(pdfExporter{})
+}
+
+// csvExporter writes all sessions to a single CSV file, one row per session or per message.
+type csvExporter struct {
+	name        string
+	description string
+	option      int        // The legacy FormatOption constant of the format.
+	columns     csvColumns // The default columns of the format.
+}
+
+// Name returns the registry name of the format.
+func (e *csvExporter) Name() string { return e.name }
+
+// Description returns the menu description of the format.
+func (e *csvExporter) Description() string { return e.description }
+
+// Extension returns ".csv".
+func (e *csvExporter) Extension() string { return ".csv" }
+
+// Options returns the schema of the dialect and the columns of the file, see OptionDelimiter.
+func (e *csvExporter) Options() []Option { return csvOptions(e.columns.option(OptionColumns)) }
+
+// layout returns the layout of the file set by options.
+func (e *csvExporter) layout(options Options) (*csvLayout, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	return e.columns.layoutFor(options, OptionColumns)
+}
+
+// Export writes sessions to the CSV file at outputPath.
+func (e *csvExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	layout, err := e.layout(options)
+	if err != nil {
+		return nil, err
+	}
+	err = writeCSVFile(rfs, outputPath, func(w io.Writer) error {
+		return writeSessionsCSV(ctx, w, sessions, layout, DefaultWorkers())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// ExportTo writes sessions as CSV to w.
+func (e *csvExporter) ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error {
+	layout, err := e.layout(options)
+	if err != nil {
+		return err
+	}
+	return writeSessionsCSV(ctx, w, sessions, layout, DefaultWorkers())
+}
+
+// csvFormat returns e; it lets csvExporterForOption find the CSV formats embedding a csvExporter.
+func (e *csvExporter) csvFormat() *csvExporter { return e }
+
+// csvExporterForOption returns the registered single-file CSV exporter for a legacy FormatOption constant.
+func csvExporterForOption(formatOption int) (*csvExporter, error) {
+	for _, e := range Exporters() {
+		if cf, ok := e.(interface{ csvFormat() *csvExporter }); ok && cf.csvFormat().option == formatOption {
+			return cf.csvFormat(), nil
+		}
+	}
+	return nil, &FormatError{Kind: "format option", Name: strconv.Itoa(formatOption)}
+}
+
+// perLineExporter is the one-message-per-line CSV format. Unlike the other single-file CSV
+// formats, it can append the messages of a later backup, see AppendExporter.
+type perLineExporter struct {
+	csvExporter
+}
+
+// separateCSVExporter writes separate CSV files for sessions and messages.
+type separateCSVExporter struct{}
+
+// Name returns the registry name of the format.
+func (separateCSVExporter) Name() string { return FormatNameSeparate }
+
+// Description returns the menu description of the format.
+func (separateCSVExporter) Description() string { return "Separate Files for Sessions and Messages" }
+
+// Extension returns ".csv".
+func (separateCSVExporter) Extension() string { return ".csv" }
+
+// PrimaryOutputName returns "sessions", since the output path receives the sessions file.
+func (separateCSVExporter) PrimaryOutputName() string { return "sessions" }
+
+// Options returns the schema with the required messages file path, followed by the dialect and
+// the columns of both files, see OptionDelimiter.
+func (separateCSVExporter) Options() []Option {
+	return append([]Option{
+		{Name: OptionMessages, Description: "path of the messages CSV file", Required: true, Path: true},
+	}, csvOptions(sessionsColumns.option(OptionColumns), messagesColumns.option(OptionMessageColumns))...)
+}
+
+// layouts returns the layouts of the sessions and messages files set by options.
+func (e separateCSVExporter) layouts(options Options) (sessionsLayout, messagesLayout *csvLayout, err error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, nil, err
+	}
+	if sessionsLayout, err = sessionsColumns.layoutFor(options, OptionColumns); err != nil {
+		return nil, nil, err
+	}
+	if messagesLayout, err = messagesColumns.layoutFor(options, OptionMessageColumns); err != nil {
+		return nil, nil, err
+	}
+	return sessionsLayout, messagesLayout, nil
+}
+
+// Export writes the sessions file to outputPath and the messages file to the messages option.
+func (e separateCSVExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	sessionsLayout, messagesLayout, err := e.layouts(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContextCancellation(ctx); err != nil {
+		return nil, err
+	}
+	messagesPath := options.Get(e.Options(), OptionMessages)
+	if err := createSeparateCSVFiles(ctx, rfs, sessions, outputPath, messagesPath, sessionsLayout, messagesLayout); err != nil {
+		return nil, err
+	}
+	return []string{outputPath, messagesPath}, nil
+}
+
+// datasetExporter writes sessions as a Hugging Face dataset JSON file.
+type datasetExporter struct{}
+
+// Name returns the registry name of the format.
+func (datasetExporter) Name() string { return FormatNameDataset }
+
+// Description returns the menu description of the format.
+func (datasetExporter) Description() string { return "Hugging Face Dataset" }
+
+// Extension returns ".json".
+func (datasetExporter) Extension() string { return ".json" }
+
+// Options returns nil; the dataset format has no options.
+func (datasetExporter) Options() []Option { return nil }
+
+// Export writes the dataset produced by ExtractToDataset to outputPath.
+func (e datasetExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	if err := checkContextCancellation(ctx); err != nil {
+		return nil, err
+	}
+	progress := trackProgress(ctx, sessions)
+	datasetOutput, err := ExtractToDataset(sessions)
+	if err != nil {
+		return nil, err
+	}
+	if err := rfs.WriteFile(outputPath, []byte(datasetOutput), 0644); err != nil {
+		return nil, err
+	}
+	progress.wrote(len(datasetOutput))
+	progress.done(sessions...)
+	return []string{outputPath}, nil
+}
+
+// ExportTo writes the dataset produced by ExtractToDataset to w.
+func (e datasetExporter) ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error {
+	if err := options.Validate(e.Options()); err != nil {
+		return err
+	}
+	if err := checkContextCancellation(ctx); err != nil {
+		return err
+	}
+	progress := trackProgress(ctx, sessions)
+	datasetOutput, err := ExtractToDataset(sessions)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(progress.writer(w), datasetOutput); err != nil {
+		return err
+	}
+	progress.done(sessions...)
+	return nil
+}
+
+// jsonlExporter writes every message as a line of JSON, see JSONLRecord.
+type jsonlExporter struct{}
+
+// Name returns the registry name of the format.
+func (jsonlExporter) Name() string { return FormatNameJSONL }
+
+// Description returns the menu description of the format.
+func (jsonlExporter) Description() string { return "JSON Lines (One Message Per Line)" }
+
+// Extension returns ".jsonl".
+func (jsonlExporter) Extension() string { return ".jsonl" }
+
+// Options returns nil; the JSONL format has no options.
+func (jsonlExporter) Options() []Option { return nil }
+
+// Export writes sessions to the JSONL file at outputPath.
+func (e jsonlExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	outputFile, err := filesystem.CreateAtomic(rfs, outputPath, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output JSONL file: %w", err)
+	}
+	defer outputFile.Discard() // Leave no partial file behind on error or cancellation.
+
+	if err := WriteJSONL(ctx, outputFile, sessions); err != nil {
+		return nil, err
+	}
+	if err := outputFile.Commit(); err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// ExportTo writes sessions as JSONL to w.
+func (e jsonlExporter) ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error {
+	if err := options.Validate(e.Options()); err != nil {
+		return err
+	}
+	return WriteJSONL(ctx, w, sessions)
+}
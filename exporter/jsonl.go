@@ -0,0 +1,62 @@
+// Below, the package exporter (@jsonl.go) writes sessions in the JSON Lines format, one message
+// per line, which suits streaming consumers and can be extended by appending lines.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+)
+
+// JSONLRecord is a line of the JSONL format: a single message along with its session.
+type JSONLRecord struct {
+	SessionID string `json:"session_id"`
+	Topic     string `json:"topic"`
+	MessageID string `json:"message_id"`
+	Date      string `json:"date"`
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+}
+
+// WriteJSONL writes a JSONLRecord for every message of sessions to w, one per line.
+// The lines of the sessions are encoded concurrently and written in the order of sessions.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WriteJSONL(ctx context.Context, w io.Writer, sessions []Session) error {
+	progress := trackProgress(ctx, sessions)
+	bw := bufio.NewWriter(progress.writer(w))
+	encode := func(ctx context.Context, session Session) ([]byte, error) {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		for _, message := range session.Messages {
+			record := JSONLRecord{
+				SessionID: session.ID,
+				Topic:     session.Topic,
+				MessageID: message.ID,
+				Date:      message.Date,
+				Role:      message.Role,
+				Content:   message.Content,
+			}
+			if err := encoder.Encode(record); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+	err := ProcessOrdered(ctx, sessions, DefaultWorkers(), encode, func(i int, lines []byte) error {
+		if _, err := bw.Write(lines); err != nil {
+			return err
+		}
+		progress.done(sessions[i])
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
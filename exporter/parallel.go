@@ -0,0 +1,166 @@
+// Below, the package exporter (@parallel.go) runs the per-session work of an export concurrently:
+// a reader goroutine feeds the sessions to a pool of workers, and an ordered writer passes the
+// results on in the original session order, so the output is identical to a sequential run.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// windowPerWorker bounds the number of sessions that may be in flight per worker, so that a
+// slow session cannot make the writer buffer the results of the entire backup.
+const windowPerWorker = 4
+
+// DefaultWorkers returns the number of workers used when none is given: one per CPU.
+func DefaultWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// ProcessOrdered runs work on every session using up to workers goroutines (DefaultWorkers if
+// workers is not positive) and calls emit with the results in the order of sessions. Work may run
+// concurrently, while emit is always called from the calling goroutine, one result at a time.
+//
+// Processing stops at the first error returned by work or emit, or when the context is cancelled,
+// and that error is returned once all goroutines have exited.
+func ProcessOrdered[T any](ctx context.Context, sessions []Session, workers int, work func(context.Context, Session) (T, error), emit func(index int, result T) error) error {
+	if workers <= 0 {
+		workers = DefaultWorkers()
+	}
+	if workers > len(sessions) {
+		workers = len(sessions)
+	}
+	if workers <= 1 {
+		return processSequential(ctx, sessions, work, emit)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		index   int
+		session Session
+	}
+	type result struct {
+		index int
+		value T
+		err   error
+	}
+	jobs := make(chan job)
+	results := make(chan result, workers)
+	window := make(chan struct{}, workers*windowPerWorker)
+
+	// The reader hands out sessions in order, waiting for room in the window first.
+	go func() {
+		defer close(jobs)
+		for i, session := range sessions {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- job{index: i, session: session}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if err := checkContextCancellation(ctx); err != nil {
+					return
+				}
+				value, err := work(ctx, j.session)
+				select {
+				case results <- result{index: j.index, value: value, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// The writer reorders the results and keeps draining after an error so no goroutine leaks.
+	var firstErr error
+	pending := make(map[int]T)
+	next := 0
+	for r := range results {
+		if firstErr != nil {
+			continue
+		}
+		if r.err != nil {
+			firstErr = r.err
+			cancel()
+			continue
+		}
+		pending[r.index] = r.value
+		for {
+			value, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if err := emit(next, value); err != nil {
+				firstErr = err
+				cancel()
+				break
+			}
+			next++
+			<-window
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if next < len(sessions) {
+		// The pipeline only stops early when the caller's context is cancelled.
+		return ctx.Err()
+	}
+	return nil
+}
+
+// processSequential is ProcessOrdered with a single worker, without any goroutines.
+func processSequential[T any](ctx context.Context, sessions []Session, work func(context.Context, Session) (T, error), emit func(int, T) error) error {
+	for i, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		value, err := work(ctx, session)
+		if err != nil {
+			return err
+		}
+		if err := emit(i, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransformSessions returns the result of transform applied to every session, in order, using up
+// to workers goroutines (DefaultWorkers if workers is not positive). It is meant for expensive
+// per-session steps such as redaction.
+func TransformSessions(ctx context.Context, sessions []Session, workers int, transform func(context.Context, Session) (Session, error)) ([]Session, error) {
+	transformed := make([]Session, len(sessions))
+	err := ProcessOrdered(ctx, sessions, workers, transform, func(i int, session Session) error {
+		transformed[i] = session
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transformed, nil
+}
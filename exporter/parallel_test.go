@@ -0,0 +1,119 @@
+// Below, the package exporter (@parallel_test.go) tests and benchmarks the parallel pipeline that
+// formats sessions with several workers.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+// TestProcessOrdered verifies that the parallel pipeline keeps the session order regardless of
+// the number of workers, and stops on errors and cancellation.
+func TestProcessOrdered(t *testing.T) {
+	sessions := benchmarkSessions(50, 3)
+	ctx := context.Background()
+
+	var sequential bytes.Buffer
+	if err := exporter.WriteSessionsCSVWithWorkers(ctx, &sequential, sessions, exporter.FormatOptionPerLine, 1); err != nil {
+		t.Fatalf("WriteSessionsCSVWithWorkers() with 1 worker returned error: %v", err)
+	}
+	for _, workers := range []int{2, 8, 100} {
+		var parallel bytes.Buffer
+		if err := exporter.WriteSessionsCSVWithWorkers(ctx, &parallel, sessions, exporter.FormatOptionPerLine, workers); err != nil {
+			t.Fatalf("WriteSessionsCSVWithWorkers() with %d workers returned error: %v", workers, err)
+		}
+		if parallel.String() != sequential.String() {
+			t.Errorf("The output with %d workers differs from the sequential output", workers)
+		}
+	}
+
+	// Later sessions finish first, yet they are emitted in order.
+	var order []int
+	err := exporter.ProcessOrdered(ctx, sessions, 8, func(ctx context.Context, session exporter.Session) (exporter.Session, error) {
+		if session.ID < "s010" {
+			time.Sleep(time.Millisecond)
+		}
+		return session, nil
+	}, func(i int, session exporter.Session) error {
+		if session.ID != sessions[i].ID {
+			t.Errorf("emit(%d) got session %s, want %s", i, session.ID, sessions[i].ID)
+		}
+		order = append(order, i)
+		return nil
+	})
+	if err != nil || len(order) != len(sessions) {
+		t.Fatalf("ProcessOrdered() emitted %d sessions, %v", len(order), err)
+	}
+	for i, index := range order {
+		if i != index {
+			t.Fatalf("ProcessOrdered() emitted %v, want ascending order", order)
+		}
+	}
+
+	errBoom := errors.New("boom")
+	transformed, err := exporter.TransformSessions(ctx, sessions, 4, func(ctx context.Context, session exporter.Session) (exporter.Session, error) {
+		if session.ID == "s025" {
+			return session, errBoom
+		}
+		return session, nil
+	})
+	if !errors.Is(err, errBoom) || transformed != nil {
+		t.Errorf("TransformSessions() = %d sessions, %v, want errBoom", len(transformed), err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := exporter.WriteSessionsCSVWithWorkers(cancelled, io.Discard, sessions, exporter.FormatOptionPerLine, 4); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteSessionsCSVWithWorkers() with a cancelled context returned %v, want context.Canceled", err)
+	}
+}
+
+// benchmarkSessions returns n sessions with the given number of messages each, whose contents
+// are long enough for the formatting and redaction work to dominate.
+func benchmarkSessions(n, messages int) []exporter.Session {
+	content := strings.Repeat("Contact gopher@example.com with key sk-abcdefghijklmnopqrstuvwxyz. ", 40)
+	sessions := make([]exporter.Session, n)
+	for i := range sessions {
+		session := exporter.Session{ID: fmt.Sprintf("s%03d", i), Topic: "Benchmark", MemoryPrompt: "Memory"}
+		for j := 0; j < messages; j++ {
+			session.Messages = append(session.Messages, exporter.Message{
+				ID: fmt.Sprintf("m%d", j), Date: "2023-11-28", Role: "user", Content: content,
+			})
+		}
+		sessions[i] = session
+	}
+	return sessions
+}
+
+// benchmarkWorkers returns the worker counts compared by the benchmarks: one, a few, and one per CPU.
+func benchmarkWorkers() []int {
+	workers := []int{1, 2, 4}
+	if n := exporter.DefaultWorkers(); n > 4 {
+		workers = append(workers, n)
+	}
+	return workers
+}
+
+// BenchmarkWriteSessionsCSV compares the CSV writer with one worker against the parallel pipeline.
+func BenchmarkWriteSessionsCSV(b *testing.B) {
+	sessions := benchmarkSessions(200, 5)
+	for _, workers := range benchmarkWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := exporter.WriteSessionsCSVWithWorkers(context.Background(), io.Discard, sessions, exporter.FormatOptionJSON, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,515 @@
+// Below, the package exporter (@pdf.go) writes sessions as a printable PDF transcript, for
+// archiving conversations.
+//
+// Every session starts on a new page with its topic, mask, model, last update and message count,
+// followed by its messages with their role and date. Fenced code blocks in messages are set in a
+// monospace font on a shaded background. Exports of several sessions open with a table of
+// contents linking to the sessions, which are also listed as bookmarks, and every page is
+// numbered.
+//
+// The DejaVu fonts of the pdf package are embedded in the document, so it renders the same
+// everywhere, offline. They cover the Latin, Greek, Cyrillic, Hebrew and Arabic scripts among
+// others, although text is set left to right without shaping, so right-to-left scripts read
+// reversed. For Chinese, Japanese and Korean, the first installed font of PDFFallbackFonts is
+// used. The fonts option adds TrueType fonts for the characters they lack, in preference to it;
+// characters no font covers are shown as empty boxes and listed in a warning logged with the
+// default slog logger:
+//
+//	e, _ := exporter.Lookup(exporter.FormatNamePDF)
+//	options := exporter.Options{exporter.OptionFonts: "/usr/share/fonts/opentype/ipafont-gothic/ipag.ttf"}
+//	files, err := e.Export(ctx, rfs, sessions, "transcripts.pdf", options)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pdf"
+)
+
+const (
+	// OptionPageSize is the option of the PDF format that sets the page size: "a4" or "letter".
+	OptionPageSize = "pageSize"
+
+	// OptionFonts is the option of the PDF format that lists, separated by commas, the TrueType
+	// font files embedded for the characters the built-in fonts lack.
+	OptionFonts = "fonts"
+)
+
+// PDFFallbackFonts lists the TrueType fonts covering Chinese, Japanese and Korean that are commonly
+// installed. The first one found is added to the fonts of the transcript after those of the fonts
+// option, so that CJK text is not shown as empty boxes by default; only its glyphs used are
+// embedded. Fonts with CFF outlines, such as the .otf and .ttc files of Noto Sans CJK, cannot be
+// used and are not listed.
+var PDFFallbackFonts = []string{
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/droid/DroidSansFallback.ttf",
+	"/usr/share/fonts/truetype/wqy/wqy-microhei.ttc",
+	"/usr/share/fonts/truetype/wqy/wqy-zenhei.ttc",
+	"/usr/share/fonts/truetype/arphic/uming.ttc",
+	`C:\Windows\Fonts\simsun.ttc`,
+}
+
+// Ensure the PDF transcript can be written to any io.Writer.
+var _ WriterExporter = pdfExporter{}
+
+// pdfExporter writes sessions as a PDF transcript, see WritePDF.
+type pdfExporter struct{}
+
+// Name returns the registry name of the format.
+func (pdfExporter) Name() string { return FormatNamePDF }
+
+// Description returns the menu description of the format.
+func (pdfExporter) Description() string { return "PDF Transcript (Printable, With Table of Contents)" }
+
+// Extension returns ".pdf".
+func (pdfExporter) Extension() string { return ".pdf" }
+
+// Options returns the page size and the fallback fonts.
+func (pdfExporter) Options() []Option {
+	return []Option{
+		{Name: OptionPageSize, Description: "page size, a4 or letter", Default: "a4", Check: func(value string) error {
+			_, err := parseChoice(value, "a4", "letter")
+			return err
+		}},
+		{Name: OptionFonts, Description: "TrueType font files for the characters the built-in fonts lack, separated by commas"},
+	}
+}
+
+// Export writes the transcript to outputPath, reading the fonts through rfs.
+func (e pdfExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	settings, err := e.settings(rfs, options)
+	if err != nil {
+		return nil, err
+	}
+	outputFile, err := filesystem.CreateAtomic(rfs, outputPath, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output PDF file: %w", err)
+	}
+	defer outputFile.Discard() // Leave no partial file behind on error or cancellation.
+
+	if err := WritePDF(ctx, outputFile, sessions, settings); err != nil {
+		return nil, err
+	}
+	if err := outputFile.Commit(); err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// ExportTo writes the transcript to w, reading the fonts from the disk.
+func (e pdfExporter) ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error {
+	settings, err := e.settings(filesystem.RealFileSystem{}, options)
+	if err != nil {
+		return err
+	}
+	return WritePDF(ctx, w, sessions, settings)
+}
+
+// settings returns the settings set by options, reading the fonts and the first installed font of
+// PDFFallbackFonts through rfs.
+func (e pdfExporter) settings(rfs filesystem.FileSystem, options Options) (PDFSettings, error) {
+	settings := PDFSettings{PageSize: pdf.A4}
+	if err := options.Validate(e.Options()); err != nil {
+		return settings, err
+	}
+	if letter, _ := parseChoice(options[OptionPageSize], "a4", "letter"); letter {
+		settings.PageSize = pdf.Letter
+	}
+	for _, path := range strings.Split(options[OptionFonts], ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		data, err := rfs.ReadFile(path)
+		if err == nil {
+			var font *pdf.Font
+			if font, err = pdf.ParseFont(data); err == nil {
+				settings.Fonts = append(settings.Fonts, font)
+				continue
+			}
+		}
+		return settings, &FormatError{Kind: "option", Name: OptionFonts, Reason: fmt.Sprintf("cannot use %s: %v", path, err)}
+	}
+	for _, path := range PDFFallbackFonts {
+		if data, err := rfs.ReadFile(path); err == nil {
+			if font, err := pdf.ParseFont(data); err == nil {
+				settings.Fonts = append(settings.Fonts, font)
+				break
+			}
+		}
+	}
+	return settings, nil
+}
+
+// PDFSettings holds the settings of a PDF transcript.
+type PDFSettings struct {
+	PageSize pdf.Size    // Size of the pages.
+	Fonts    []*pdf.Font // Fonts for the characters the built-in fonts lack, in order of preference.
+}
+
+// Layout of the transcripts, in points.
+const (
+	pdfMargin       = 56   // Margin around the text.
+	pdfLeading      = 1.4  // Height of a line, relative to the size of its face.
+	pdfParagraphGap = 4    // Space between paragraphs.
+	pdfMessageGap   = 12   // Space between messages.
+	pdfCodePadding  = 4    // Space between a code block and its shaded background.
+	pdfTextGray     = 0    // Gray level of the text.
+	pdfMetaGray     = 0.4  // Gray level of the metadata and page numbers.
+	pdfCodeGray     = 0.94 // Gray level of the background of code blocks.
+	pdfRuleGray     = 0.75 // Gray level of the rules.
+
+	// pdfMissingListed is the number of characters missing from the fonts listed in the warning.
+	pdfMissingListed = 100
+)
+
+// WritePDF writes sessions to w as a PDF transcript with the settings: a table of contents when
+// there are several sessions, then every session from a new page. The dates of sessions are
+// written in UTC, and the dates of messages as they were recorded.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WritePDF(ctx context.Context, w io.Writer, sessions []Session, settings PDFSettings) error {
+	progress := trackProgress(ctx, sessions)
+	l := newPDFLayout(settings)
+	if len(sessions) == 1 {
+		l.doc.SetTitle(pdfSessionTitle(sessions[0]))
+	} else {
+		l.doc.SetTitle(fmt.Sprintf("%d chat sessions", len(sessions)))
+	}
+
+	// The pages of the table of contents come first, so they are added before the sessions.
+	var contents []*pdf.Page
+	if len(sessions) > 1 {
+		for i := 0; i < l.contentsPages(len(sessions)); i++ {
+			contents = append(contents, l.doc.AddPage())
+		}
+		l.pages = append(l.pages, contents...)
+	}
+
+	starts := make([]*pdf.Page, len(sessions))
+	for i, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		starts[i] = l.session(session)
+		l.doc.Bookmark(pdfSessionTitle(session), starts[i])
+		progress.done(session)
+	}
+	if len(contents) > 0 {
+		l.contents(contents, sessions, starts)
+	}
+	if len(sessions) == 0 {
+		l.newPage()
+		l.line(l.meta, pdfMetaGray, "No sessions.")
+	}
+	l.pageNumbers()
+	if missing := l.doc.Missing(); len(missing) > 0 {
+		slog.WarnContext(ctx, "characters missing from the PDF fonts are shown as empty boxes; set the fonts option to fonts covering them",
+			"characters", string(missing[:min(len(missing), pdfMissingListed)]), "count", len(missing))
+	}
+
+	_, err := l.doc.WriteTo(progress.writer(w))
+	return err
+}
+
+// pdfLayout lays out the transcript, page after page.
+type pdfLayout struct {
+	doc   *pdf.Document
+	size  pdf.Size
+	pages []*pdf.Page // Pages of the document, in order.
+	page  *pdf.Page   // Current page.
+	y     float64     // Baseline of the last line set on the current page.
+
+	body, bold, title, meta, code pdf.Face
+}
+
+// newPDFLayout returns the layout of a document with settings.
+func newPDFLayout(settings PDFSettings) *pdfLayout {
+	face := func(font *pdf.Font, size float64) pdf.Face {
+		fonts := append([]*pdf.Font{font}, settings.Fonts...)
+		if font != pdf.Sans() {
+			fonts = append(fonts, pdf.Sans()) // Symbols missing from the other fonts.
+		}
+		return pdf.Face{Fonts: fonts, Size: size}
+	}
+	return &pdfLayout{
+		doc:   pdf.New(settings.PageSize),
+		size:  settings.PageSize,
+		body:  face(pdf.Sans(), 10),
+		bold:  face(pdf.SansBold(), 10),
+		title: face(pdf.SansBold(), 16),
+		meta:  face(pdf.Sans(), 8.5),
+		code:  face(pdf.Mono(), 8.5),
+	}
+}
+
+// width returns the width of the text area.
+func (l *pdfLayout) width() float64 {
+	return l.size.Width - 2*pdfMargin
+}
+
+// newPage starts a new page.
+func (l *pdfLayout) newPage() {
+	l.page = l.doc.AddPage()
+	l.pages = append(l.pages, l.page)
+	l.y = l.size.Height - pdfMargin
+}
+
+// ensure starts a new page unless height fits above the bottom margin of the current page.
+func (l *pdfLayout) ensure(height float64) {
+	if l.y-height < pdfMargin {
+		l.newPage()
+	}
+}
+
+// space adds vertical space, unless at the top of a page.
+func (l *pdfLayout) space(height float64) {
+	if l.y < l.size.Height-pdfMargin {
+		l.y -= height
+	}
+}
+
+// line sets a line of text in face, moving down by its leading.
+func (l *pdfLayout) line(face pdf.Face, gray float64, text string) {
+	l.ensure(face.Size * pdfLeading)
+	l.y -= face.Size * pdfLeading
+	l.page.Text(pdfMargin, l.y, face, gray, text)
+}
+
+// paragraph sets text in face, wrapped to the width of the text area.
+func (l *pdfLayout) paragraph(face pdf.Face, gray float64, text string) {
+	for _, line := range wrapPDFText(face, text, l.width()) {
+		l.line(face, gray, line)
+	}
+}
+
+// session sets a session from a new page: its title, its metadata and its messages. It returns
+// the first page of the session.
+func (l *pdfLayout) session(session Session) *pdf.Page {
+	l.newPage()
+	start := l.page
+	l.paragraph(l.title, pdfTextGray, pdfSessionTitle(session))
+	l.space(pdfParagraphGap)
+
+	metadata := []string{"Session ID: " + session.ID}
+	if session.Mask.Name != "" {
+		metadata = append(metadata, "Mask: "+session.Mask.Name)
+	}
+	if session.Mask.ModelConfig.Model != "" {
+		metadata = append(metadata, "Model: "+session.Mask.ModelConfig.Model)
+	}
+	if session.LastUpdate > 0 {
+		metadata = append(metadata, "Last update: "+time.UnixMilli(session.LastUpdate).UTC().Format("2006-01-02 15:04:05 UTC"))
+	}
+	metadata = append(metadata, "Messages: "+strconv.Itoa(len(session.Messages)))
+	for _, line := range metadata {
+		l.paragraph(l.meta, pdfMetaGray, line)
+	}
+	l.space(pdfParagraphGap * 2)
+	l.page.Line(pdfMargin, l.y, l.size.Width-pdfMargin, l.y, 0.5, pdfRuleGray)
+
+	if len(session.Messages) == 0 {
+		l.space(pdfMessageGap)
+		l.line(l.meta, pdfMetaGray, "No messages.")
+	}
+	for _, message := range session.Messages {
+		l.space(pdfMessageGap)
+		l.message(message)
+	}
+	return start
+}
+
+// message sets the role and date of a message, followed by its content.
+func (l *pdfLayout) message(message Message) {
+	// The header is kept with the first line of the content.
+	l.ensure((l.bold.Size + l.body.Size) * pdfLeading)
+	l.line(l.bold, pdfTextGray, message.Role)
+	if message.Date != "" {
+		x := pdfMargin + l.bold.Width(message.Role) + l.body.Size
+		l.page.Text(x, l.y, l.meta, pdfMetaGray, message.Date)
+	}
+
+	inCode := false
+	for _, line := range strings.Split(cleanPDFText(message.Content), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			inCode = !inCode
+			l.space(pdfCodePadding)
+		case inCode:
+			l.codeLine(line)
+		case trimmed == "":
+			l.space(pdfParagraphGap)
+		default:
+			l.paragraph(l.body, pdfTextGray, line)
+		}
+	}
+}
+
+// codeLine sets a line of a code block on a shaded background, broken where it overflows the
+// text area, keeping its indentation.
+func (l *pdfLayout) codeLine(line string) {
+	leading := l.code.Size * pdfLeading
+	width := l.width() - 2*pdfCodePadding
+	for {
+		cut := fitPDFText(l.code, line, width)
+		l.ensure(leading)
+		l.page.Rect(pdfMargin, l.y-leading, l.width(), leading, pdfCodeGray)
+		l.y -= leading
+		l.page.Text(pdfMargin+pdfCodePadding, l.y+l.code.Size*(pdfLeading-1)/2+1, l.code, pdfTextGray, line[:cut])
+		if line = line[cut:]; line == "" {
+			return
+		}
+	}
+}
+
+// contentsTitleLines is the number of lines the title of the table of contents takes.
+const contentsTitleLines = 4
+
+// contentsPages returns the number of pages the table of contents of sessions takes, one line
+// per session.
+func (l *pdfLayout) contentsPages(sessions int) int {
+	perPage := int((l.size.Height - 2*pdfMargin) / (l.body.Size * pdfLeading))
+	lines := sessions + contentsTitleLines
+	return (lines + perPage - 1) / perPage
+}
+
+// contents fills the pages of the table of contents, listing every session with its first page
+// and linking to it.
+func (l *pdfLayout) contents(pages []*pdf.Page, sessions []Session, starts []*pdf.Page) {
+	l.page, l.y = pages[0], l.size.Height-pdfMargin
+	next := 1
+	l.line(l.title, pdfTextGray, "Contents")
+	l.line(l.meta, pdfMetaGray, fmt.Sprintf("%d sessions, %d messages", len(sessions), countMessages(sessions)))
+	l.space(l.body.Size * pdfLeading)
+
+	leading := l.body.Size * pdfLeading
+	for i, session := range sessions {
+		if l.y-leading < pdfMargin && next < len(pages) {
+			l.page, l.y = pages[next], l.size.Height-pdfMargin
+			next++
+		}
+		l.y -= leading
+		number := strconv.Itoa(starts[i].Number())
+		numberWidth := l.body.Width(number)
+		title := truncatePDFText(l.body, pdfSessionTitle(session), l.width()-numberWidth-l.body.Size*2)
+		l.page.Text(pdfMargin, l.y, l.body, pdfTextGray, title)
+		l.page.Text(l.size.Width-pdfMargin-numberWidth, l.y, l.body, pdfTextGray, number)
+		l.page.Link(pdfMargin, l.y-l.body.Size*(pdfLeading-1), l.width(), leading, starts[i])
+	}
+}
+
+// pageNumbers numbers every page in its bottom margin.
+func (l *pdfLayout) pageNumbers() {
+	for _, page := range l.pages {
+		text := fmt.Sprintf("Page %d of %d", page.Number(), len(l.pages))
+		page.Text((l.size.Width-l.meta.Width(text))/2, pdfMargin/2, l.meta, pdfMetaGray, text)
+	}
+}
+
+// pdfSessionTitle returns the topic of a session, or its ID if it has none.
+func pdfSessionTitle(session Session) string {
+	if topic := strings.TrimSpace(cleanPDFText(session.Topic)); topic != "" {
+		return strings.Join(strings.Fields(topic), " ")
+	}
+	return "Session " + session.ID
+}
+
+// cleanPDFText returns text with Unix line endings, tabs expanded to four spaces and without the
+// other control characters, which fonts have no glyphs for.
+func cleanPDFText(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\r':
+			return '\n'
+		case r == '\n':
+			return r
+		case unicode.IsControl(r) || r == utf8.RuneError:
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// wrapPDFText breaks text into lines no wider than width when set in face. Lines are broken at
+// spaces and around the characters of scripts written without spaces, such as Chinese; words
+// wider than a line are broken anywhere.
+func wrapPDFText(face pdf.Face, text string, width float64) []string {
+	var lines []string
+	line, lineWidth := "", 0.0
+	for _, word := range breakPDFWords(text) {
+		trimmed := strings.TrimRight(word, " ")
+		if lineWidth+face.Width(trimmed) <= width {
+			line, lineWidth = line+word, lineWidth+face.Width(word)
+			continue
+		}
+		if line != "" {
+			lines = append(lines, strings.TrimRight(line, " "))
+		}
+		for face.Width(trimmed) > width {
+			cut := fitPDFText(face, trimmed, width)
+			lines = append(lines, trimmed[:cut])
+			trimmed, word = trimmed[cut:], word[cut:]
+		}
+		line, lineWidth = word, face.Width(word)
+	}
+	return append(lines, strings.TrimRight(line, " "))
+}
+
+// breakPDFWords splits text where lines may break: after spaces, and before and after the
+// characters of scripts written without spaces. Spaces stay with the word before them.
+func breakPDFWords(text string) []string {
+	var words []string
+	start := 0
+	var previous rune
+	for i, r := range text {
+		if i > start && r != ' ' && (previous == ' ' || isWidePDFRune(r) || isWidePDFRune(previous)) {
+			words = append(words, text[start:i])
+			start = i
+		}
+		previous = r
+	}
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// isWidePDFRune reports whether r belongs to a script written without spaces between words.
+func isWidePDFRune(r rune) bool {
+	return r >= 0x2E80 && (unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		r >= 0x3000 && r <= 0x303F || r >= 0xFF00 && r <= 0xFFEF) // CJK punctuation and fullwidth forms.
+}
+
+// fitPDFText returns the length in bytes of the longest prefix of text no wider than width when
+// set in face, and at least its first character.
+func fitPDFText(face pdf.Face, text string, width float64) int {
+	total := 0.0
+	for i, r := range text {
+		total += face.Width(string(r))
+		if total > width && i > 0 {
+			return i
+		}
+	}
+	return len(text)
+}
+
+// truncatePDFText shortens text with an ellipsis so that it is no wider than width when set in face.
+func truncatePDFText(face pdf.Face, text string, width float64) string {
+	if face.Width(text) <= width {
+		return text
+	}
+	return strings.TrimRight(text[:fitPDFText(face, text, width-face.Width("…"))], " ") + "…"
+}
@@ -0,0 +1,178 @@
+// Below, the package exporter (@pdf_test.go) tests the fonts, pages, outline and text of the PDF
+// transcript, and its fallback fonts for CJK text.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pdf"
+)
+
+// TestPDFExporter verifies that the PDF transcript embeds its fonts, numbers its pages, lists
+// several sessions in a linked table of contents and bookmarks, and sets code in monospace.
+func TestPDFExporter(t *testing.T) {
+	ctx := context.Background()
+	sessions := []exporter.Session{
+		{
+			ID:         "1",
+			Topic:      "Привет, Gopher",
+			LastUpdate: 1701388800000,
+			Mask:       exporter.Mask{Name: "Gopher", ModelConfig: exporter.ModelConfig{Model: "gpt-4"}},
+			Messages: []exporter.Message{
+				{ID: "m1", Date: "11/28/2023, 10:16:25 AM", Role: "user", Content: "How do I make a channel?"},
+				{ID: "m2", Date: "11/28/2023, 10:16:30 AM", Role: "assistant", Content: "Like this:\n```go\nch := make(chan int)\n```"},
+			},
+		},
+		{ID: "2", Topic: "Empty"},
+	}
+	e, ok := exporter.Lookup(exporter.FormatNamePDF)
+	if !ok {
+		t.Fatal("the pdf format is not registered")
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	if _, err := e.Export(ctx, mockFS, sessions, "transcripts.pdf", nil); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	data := string(mockFS.Files["transcripts.pdf"])
+	if !strings.HasPrefix(data, "%PDF-1.7\n") || !strings.HasSuffix(data, "%%EOF\n") {
+		t.Fatalf("transcripts.pdf is not a PDF document: %.40q", data)
+	}
+
+	// The title of every session is a bookmark, in UTF-16 with a byte order mark.
+	bookmark := func(title string) string {
+		var b strings.Builder
+		for _, r := range title {
+			fmt.Fprintf(&b, "%04X", r)
+		}
+		return "/Title <FEFF" + b.String() + ">"
+	}
+	for _, want := range []string{
+		"/Count 3 /MediaBox [0 0 595.28 841.89]", // Contents and a page per session.
+		"/PageMode /UseOutlines", bookmark("Привет, Gopher"), bookmark("Empty"),
+		"/Subtype /Link",
+		"+DejaVuSans /Encoding /Identity-H", "+DejaVuSans-Bold ", "+DejaVuSansMono ", "/FontFile2",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("transcripts.pdf does not contain %q", want)
+		}
+	}
+
+	// The character maps of the fonts make the text searchable, Cyrillic included.
+	streams := regexp.MustCompile(`/Length (\d+) >>\nstream\n`)
+	var content strings.Builder
+	for _, match := range streams.FindAllStringSubmatchIndex(data, -1) {
+		length, _ := strconv.Atoi(data[match[2]:match[3]])
+		r, err := zlib.NewReader(strings.NewReader(data[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatalf("invalid stream: %v", err)
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("invalid stream: %v", err)
+		}
+		content.Write(decoded)
+	}
+	for _, want := range []string{"> <041F>\n", "Tj ET\n", "re f\n"} {
+		if !strings.Contains(content.String(), want) {
+			t.Errorf("the streams of transcripts.pdf do not contain %q", want)
+		}
+	}
+
+	// A single session needs no table of contents.
+	if _, err := e.Export(ctx, mockFS, sessions[:1], "session.pdf", exporter.Options{exporter.OptionPageSize: "letter"}); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	single := string(mockFS.Files["session.pdf"])
+	if !strings.Contains(single, "/Count 1 /MediaBox [0 0 612 792]") || strings.Contains(single, "/Subtype /Link") {
+		t.Error("session.pdf is not a single letter page without a table of contents")
+	}
+
+	// Fallback fonts must be TrueType fonts.
+	font, err := os.ReadFile(filepath.Join("..", "pdf", "fonts", "DejaVuSansMono.ttf"))
+	if err != nil {
+		t.Fatalf("failed to read the font: %v", err)
+	}
+	mockFS.Files["fallback.ttf"], mockFS.Files["fallback.txt"] = font, []byte("not a font")
+	if _, err := e.Export(ctx, mockFS, sessions, "fallback.pdf", exporter.Options{exporter.OptionFonts: "fallback.ttf"}); err != nil {
+		t.Errorf("Export() with a fallback font returned error: %v", err)
+	}
+	if _, err := e.Export(ctx, mockFS, sessions, "fallback.pdf", exporter.Options{exporter.OptionFonts: "fallback.ttf, fallback.txt"}); !errors.Is(err, exporter.ErrInvalidFormat) {
+		t.Errorf("Export() with an invalid font returned %v, want an invalid option", err)
+	}
+
+	// Characters that no font covers are listed in a warning.
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	chinese := []exporter.Session{{ID: "3", Topic: "你好", Messages: []exporter.Message{{Role: "user", Content: "世界 and Gopher"}}}}
+	if err := exporter.WritePDF(ctx, io.Discard, chinese, exporter.PDFSettings{PageSize: pdf.A4}); err != nil {
+		t.Fatalf("WritePDF() returned error: %v", err)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "characters=世你好界 count=4") {
+		t.Errorf("WritePDF() logged %q, want a warning listing the Chinese characters", logs.String())
+	}
+}
+
+// TestPDFFallbackFonts verifies that the first installed font of PDFFallbackFonts is added to the
+// fonts of the transcript, and that a CJK session is set without missing glyphs when a CJK font is
+// installed.
+func TestPDFFallbackFonts(t *testing.T) {
+	ctx := context.Background()
+	e, _ := exporter.Lookup(exporter.FormatNamePDF)
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	// DejaVu Sans Mono has the "⌒" that DejaVu Sans lacks, so it stands in for a CJK font here.
+	font, err := os.ReadFile(filepath.Join("..", "pdf", "fonts", "DejaVuSansMono.ttf"))
+	if err != nil {
+		t.Fatalf("failed to read the font: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files[exporter.PDFFallbackFonts[0]] = font
+	sessions := []exporter.Session{{ID: "1", Topic: "Arc", Messages: []exporter.Message{{Role: "user", Content: "⌒"}}}}
+	if _, err := e.Export(ctx, mockFS, sessions, "arc.pdf", nil); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("Export() with an installed fallback font logged %q, want no missing characters", logs.String())
+	}
+
+	// With a CJK font installed, the default fonts have a glyph for every character.
+	var installed string
+	for _, path := range exporter.PDFFallbackFonts {
+		if _, err := os.Stat(path); err == nil {
+			installed = path
+			break
+		}
+	}
+	if installed == "" {
+		t.Skip("no font of PDFFallbackFonts is installed")
+	}
+	logs.Reset()
+	chinese := []exporter.Session{{ID: "2", Topic: "你好", Messages: []exporter.Message{{Role: "user", Content: "世界, こんにちは"}}}}
+	memFS := filesystem.NewMockFileSystem()
+	memFS.Base = filesystem.RealFileSystem{}
+	if _, err := e.Export(ctx, memFS, chinese, "cjk.pdf", nil); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	if logs.Len() > 0 {
+		t.Errorf("Export() with %s installed logged %q, want no missing characters", installed, logs.String())
+	}
+}
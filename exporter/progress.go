@@ -0,0 +1,137 @@
+// Below, the package exporter (@progress.go) reports the progress of long exports: the sessions
+// and messages processed so far and the bytes written, so that a CLI can render a progress bar
+// instead of staying silent until the export is complete.
+//
+// The reporter travels with the context passed to every Exporter, so progress works for all
+// formats, compression and incremental exports without changing their signatures:
+//
+//	ctx = exporter.WithProgress(ctx, exporter.ProgressFunc(func(p exporter.Progress) {
+//		fmt.Printf("\r%3.0f%% (%d bytes)", 100*p.Fraction(), p.Bytes)
+//	}))
+//	files, err := e.Export(ctx, rfs, sessions, "messages.csv", nil)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"io"
+)
+
+// Progress is a snapshot of the progress of an export.
+type Progress struct {
+	Sessions      int   // Number of sessions written so far.
+	TotalSessions int   // Number of sessions of the export.
+	Messages      int   // Number of messages written so far.
+	TotalMessages int   // Number of messages of the export.
+	Bytes         int64 // Number of bytes of output produced so far, before compression and encryption.
+}
+
+// Fraction returns the completed part of the export between 0 and 1, measured in messages,
+// or in sessions if there are no messages at all.
+func (p Progress) Fraction() float64 {
+	switch {
+	case p.TotalMessages > 0:
+		return float64(p.Messages) / float64(p.TotalMessages)
+	case p.TotalSessions > 0:
+		return float64(p.Sessions) / float64(p.TotalSessions)
+	default:
+		return 1
+	}
+}
+
+// Done reports whether every session of the export has been written.
+func (p Progress) Done() bool {
+	return p.Sessions >= p.TotalSessions
+}
+
+// ProgressReporter receives the progress of an export.
+type ProgressReporter interface {
+	// Report is called once when an export starts, after every session and whenever output is
+	// written. It is never called concurrently for the same export, and should return quickly,
+	// since the export waits for it.
+	Report(p Progress)
+}
+
+// ProgressFunc adapts a function to a ProgressReporter.
+type ProgressFunc func(p Progress)
+
+// Report calls f(p).
+func (f ProgressFunc) Report(p Progress) { f(p) }
+
+// progressKey is the context key of the ProgressReporter.
+type progressKey struct{}
+
+// WithProgress returns a copy of ctx that makes every export run with it report its progress to r.
+func WithProgress(ctx context.Context, r ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, r)
+}
+
+// ProgressFromContext returns the ProgressReporter of ctx, or nil if there is none.
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	r, _ := ctx.Value(progressKey{}).(ProgressReporter)
+	return r
+}
+
+// progressTracker accumulates the progress of a single export and passes it to the reporter.
+// A nil *progressTracker, as returned when the context has no reporter, does nothing.
+type progressTracker struct {
+	reporter ProgressReporter
+	progress Progress
+}
+
+// trackProgress starts tracking an export of sessions if ctx carries a ProgressReporter.
+func trackProgress(ctx context.Context, sessions []Session) *progressTracker {
+	reporter := ProgressFromContext(ctx)
+	if reporter == nil {
+		return nil
+	}
+	t := &progressTracker{reporter: reporter, progress: Progress{TotalSessions: len(sessions)}}
+	for _, session := range sessions {
+		t.progress.TotalMessages += len(session.Messages)
+	}
+	reporter.Report(t.progress)
+	return t
+}
+
+// done records that sessions have been written.
+func (t *progressTracker) done(sessions ...Session) {
+	if t == nil {
+		return
+	}
+	for _, session := range sessions {
+		t.progress.Sessions++
+		t.progress.Messages += len(session.Messages)
+	}
+	t.reporter.Report(t.progress)
+}
+
+// wrote records that n bytes of output have been produced.
+func (t *progressTracker) wrote(n int) {
+	if t == nil || n == 0 {
+		return
+	}
+	t.progress.Bytes += int64(n)
+	t.reporter.Report(t.progress)
+}
+
+// writer returns a writer that records the bytes written through it to w.
+func (t *progressTracker) writer(w io.Writer) io.Writer {
+	if t == nil {
+		return w
+	}
+	return &progressWriter{w: w, t: t}
+}
+
+// progressWriter counts the bytes written to w.
+type progressWriter struct {
+	w io.Writer
+	t *progressTracker
+}
+
+// Write writes p to the underlying writer and records the bytes written.
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	pw.t.wrote(n)
+	return n, err
+}
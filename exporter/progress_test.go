@@ -0,0 +1,68 @@
+// Below, the package exporter (@progress_test.go) tests the progress reported by the formats.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestProgress verifies that every format reports its progress through the context, up to every
+// session, message and byte written.
+func TestProgress(t *testing.T) {
+	sessions := benchmarkSessions(20, 3)
+	formats := []string{
+		exporter.FormatNameInline, exporter.FormatNamePerLine, exporter.FormatNameJSON,
+		exporter.FormatNameSeparate, exporter.FormatNameDataset, exporter.FormatNameJSONL,
+	}
+	for _, name := range formats {
+		e, _ := exporter.Lookup(name)
+		var reports []exporter.Progress
+		ctx := exporter.WithProgress(context.Background(), exporter.ProgressFunc(func(p exporter.Progress) {
+			reports = append(reports, p)
+		}))
+
+		mockFS := filesystem.NewMockFileSystem()
+		var options exporter.Options
+		if name == exporter.FormatNameSeparate {
+			options = exporter.Options{exporter.OptionMessages: "messages.csv"}
+		}
+		files, err := e.Export(ctx, mockFS, sessions, "output"+e.Extension(), options)
+		if err != nil {
+			t.Fatalf("%s: Export() returned error: %v", name, err)
+		}
+		var size int64
+		for _, file := range files {
+			size += int64(len(mockFS.Files[file]))
+		}
+
+		if len(reports) == 0 || reports[0].Sessions != 0 || reports[0].Bytes != 0 {
+			t.Fatalf("%s: the first report is %+v, want an empty progress", name, reports)
+		}
+		for i := 1; i < len(reports); i++ {
+			if reports[i].Messages < reports[i-1].Messages || reports[i].Bytes < reports[i-1].Bytes {
+				t.Errorf("%s: the progress went backwards from %+v to %+v", name, reports[i-1], reports[i])
+			}
+		}
+		want := exporter.Progress{Sessions: 20, TotalSessions: 20, Messages: 60, TotalMessages: 60, Bytes: size}
+		if last := reports[len(reports)-1]; last != want || !last.Done() || last.Fraction() != 1 {
+			t.Errorf("%s: the last report is %+v, want %+v", name, last, want)
+		}
+	}
+
+	// Appending reports the progress of the increment only.
+	e, _ := exporter.Lookup(exporter.FormatNamePerLine)
+	var last exporter.Progress
+	ctx := exporter.WithProgress(context.Background(), exporter.ProgressFunc(func(p exporter.Progress) { last = p }))
+	inc := exporter.Increment{Sessions: sessions[:2], New: map[string]bool{"s000": true, "s001": true}}
+	if _, err := e.(exporter.AppendExporter).Append(ctx, filesystem.NewMockFileSystem(), inc, "output.csv", nil); err != nil {
+		t.Fatalf("Append() returned error: %v", err)
+	}
+	if last.Sessions != 2 || last.Messages != 6 || last.Bytes == 0 {
+		t.Errorf("Append() last reported %+v, want 2 sessions and 6 messages", last)
+	}
+}
@@ -0,0 +1,176 @@
+// Below, the package exporter (@registry.go) defines the Exporter interface and a registry
+// of export formats keyed by name.
+//
+// Every format the tool can write, such as the CSV variants and the Hugging Face dataset, is an
+// Exporter registered here. The CLI menus, the -formats flag of the watch command and the pipeline
+// configuration are all built from the registry, so a new format only has to call Register to
+// show up everywhere.
+//
+// # Example Usage
+//
+//	e, ok := exporter.Lookup("perline")
+//	if !ok {
+//		log.Fatal("unknown format")
+//	}
+//	files, err := e.Export(ctx, rfs, sessions, "messages.csv", nil)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// Exporter is implemented by every export format.
+type Exporter interface {
+	// Name returns the unique name of the format, as used in flags and configuration files.
+	Name() string
+
+	// Description returns a short human-readable description, as shown in the CLI menus.
+	Description() string
+
+	// Extension returns the file extension of the primary output, including the leading dot.
+	Extension() string
+
+	// Options returns the schema of the options accepted by Export.
+	Options() []Option
+
+	// Export writes sessions to outputPath through rfs using the given options and returns the
+	// paths of all files that were written. Options that are not set use their defaults.
+	Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error)
+}
+
+// WriterExporter is implemented by exporters that produce a single output stream and can
+// therefore write to any io.Writer, such as os.Stdout, a pipe or an in-memory buffer.
+type WriterExporter interface {
+	Exporter
+
+	// ExportTo writes sessions to w using the given options.
+	ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error
+}
+
+// PrimaryOutputNamer is implemented by exporters whose output path holds only one part of a
+// multi-file export, such as the sessions file of the separate CSV format.
+type PrimaryOutputNamer interface {
+	// PrimaryOutputName returns what the file at the output path contains, e.g. "sessions".
+	PrimaryOutputName() string
+}
+
+// Option describes an option accepted by an Exporter.
+type Option struct {
+	Name        string // Key in Options, e.g. "messages".
+	Description string // Human-readable description used for help text and prompts.
+	Default     string // Value used when the option is not set.
+	Required    bool   // Whether the option must be set.
+	Path        bool   // Whether the value is the path of an additional output file.
+
+	Check func(value string) error // Reports why a value that is set is invalid; nil accepts any value.
+}
+
+// Options holds the option values passed to Exporter.Export, keyed by Option.Name.
+type Options map[string]string
+
+// Get returns the value of the named option, or its default from schema if it is not set.
+func (o Options) Get(schema []Option, name string) string {
+	if value, ok := o[name]; ok && value != "" {
+		return value
+	}
+	for _, option := range schema {
+		if option.Name == name {
+			return option.Default
+		}
+	}
+	return ""
+}
+
+// Validate checks the option values against schema. It reports unknown options, required
+// options that have neither a value nor a default, and values rejected by their Check.
+func (o Options) Validate(schema []Option) error {
+	known := make(map[string]bool, len(schema))
+	for _, option := range schema {
+		known[option.Name] = true
+		if option.Required && o.Get(schema, option.Name) == "" {
+			return &FormatError{Kind: "option", Name: option.Name, Reason: "is required"}
+		}
+		if value := o[option.Name]; value != "" && option.Check != nil {
+			if err := option.Check(value); err != nil {
+				return &FormatError{Kind: "option", Name: option.Name, Reason: err.Error()}
+			}
+		}
+	}
+	for name := range o {
+		if !known[name] {
+			return &FormatError{Kind: "option", Name: name}
+		}
+	}
+	return nil
+}
+
+// registry holds the registered exporters in registration order.
+var registry = struct {
+	sync.RWMutex
+	byName map[string]Exporter
+	order  []Exporter
+}{byName: make(map[string]Exporter)}
+
+// Register makes an exporter available by its name.
+// It panics if the exporter is nil or if an exporter with the same name is already registered.
+func Register(e Exporter) {
+	if e == nil {
+		panic("exporter: Register exporter is nil")
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	name := e.Name()
+	if _, dup := registry.byName[name]; dup {
+		panic("exporter: Register called twice for exporter " + name)
+	}
+	registry.byName[name] = e
+	registry.order = append(registry.order, e)
+}
+
+// Lookup returns the exporter registered under name.
+func Lookup(name string) (Exporter, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	e, ok := registry.byName[name]
+	return e, ok
+}
+
+// Exporters returns all registered exporters in registration order.
+func Exporters() []Exporter {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return append([]Exporter(nil), registry.order...)
+}
+
+// ExportersByExtension returns the registered exporters whose primary output has the given
+// extension, in registration order.
+func ExportersByExtension(ext string) []Exporter {
+	var matches []Exporter
+	for _, e := range Exporters() {
+		if strings.EqualFold(e.Extension(), ext) {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// Names returns the names of all registered exporters in registration order.
+func Names() []string {
+	exporters := Exporters()
+	names := make([]string, len(exporters))
+	for i, e := range exporters {
+		names[i] = e.Name()
+	}
+	return names
+}
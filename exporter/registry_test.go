@@ -0,0 +1,85 @@
+// Below, the package exporter (@registry_test.go) tests that the registered formats write through
+// any file system and writer.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestExportThroughFileSystemAndWriter verifies that the registered exporters write through the
+// given filesystem.FileSystem and, for single-stream formats, to any io.Writer with the same output.
+func TestExportThroughFileSystemAndWriter(t *testing.T) {
+	sessions := []exporter.Session{{
+		ID:    "1",
+		Topic: "Test Session",
+		Messages: []exporter.Message{
+			{ID: "m1", Date: "2023-01-01", Role: "user", Content: "Hello"},
+		},
+	}}
+	ctx := context.Background()
+
+	for _, name := range []string{exporter.FormatNameInline, exporter.FormatNamePerLine, exporter.FormatNameJSON, exporter.FormatNameDataset, exporter.FormatNameJSONL, exporter.FormatNameXLSX, exporter.FormatNamePDF} {
+		t.Run(name, func(t *testing.T) {
+			e, _ := exporter.Lookup(name)
+			mockFS := filesystem.NewMockFileSystem()
+			files, err := e.Export(ctx, mockFS, sessions, "output"+e.Extension(), nil)
+			if err != nil {
+				t.Fatalf("Export() returned error: %v", err)
+			}
+			if len(files) != 1 || mockFS.Files[files[0]] == nil {
+				t.Fatalf("Export() wrote %v, want the output in the mock filesystem", files)
+			}
+
+			we, ok := e.(exporter.WriterExporter)
+			if !ok {
+				t.Fatalf("%s does not implement exporter.WriterExporter", name)
+			}
+			var buf bytes.Buffer
+			if err := we.ExportTo(ctx, &buf, sessions, nil); err != nil {
+				t.Fatalf("ExportTo() returned error: %v", err)
+			}
+			if got, want := buf.String(), string(mockFS.Files[files[0]]); got != want {
+				t.Errorf("ExportTo() = %q, want the file contents %q", got, want)
+			}
+		})
+	}
+
+	e, _ := exporter.Lookup(exporter.FormatNameSeparate)
+	mockFS := filesystem.NewMockFileSystem()
+	options := exporter.Options{exporter.OptionMessages: "messages.csv"}
+	if _, err := e.Export(ctx, mockFS, sessions, "sessions.csv", options); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	if !strings.Contains(string(mockFS.Files["messages.csv"]), "Hello") {
+		t.Errorf("messages.csv = %q, want the message content", mockFS.Files["messages.csv"])
+	}
+	if !strings.Contains(string(mockFS.Files["sessions.csv"]), "Test Session") {
+		t.Errorf("sessions.csv = %q, want the session topic", mockFS.Files["sessions.csv"])
+	}
+}
+
+// testExporter is a minimal exporter that does not count the records it writes.
+type testExporter struct{}
+
+func (testExporter) Name() string               { return "test-format" }
+func (testExporter) Description() string        { return "Test Format" }
+func (testExporter) Extension() string          { return ".test" }
+func (testExporter) Options() []exporter.Option { return nil }
+func (testExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, outputPath string, options exporter.Options) ([]string, error) {
+	return []string{outputPath}, nil
+}
+
+// TestCountRows verifies that the formats that do not count their records leave the rows out.
+func TestCountRows(t *testing.T) {
+	if rows := exporter.CountRows(testExporter{}, exporter.FullIncrement(nil), "out.test", nil); rows != nil {
+		t.Errorf("CountRows() of a format without counter = %v, want nil", rows)
+	}
+}
@@ -0,0 +1,77 @@
+// Below, the package exporter (@rows.go) tells how many records the built-in formats write, so
+// that runs can report the rows written to every file.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+// RowCounter is implemented by exporters that can tell how many records they write, such as the
+// rows of a CSV file without its header, or the lines of a JSONL file.
+type RowCounter interface {
+	// Rows returns the number of records written to every file, keyed by path, when the records
+	// of inc are written at outputPath with options. An export of sessions writes the records of
+	// FullIncrement(sessions).
+	Rows(inc Increment, outputPath string, options Options) map[string]int
+}
+
+// Ensure the built-in formats count their records.
+var (
+	_ RowCounter = (*csvExporter)(nil)
+	_ RowCounter = (*perLineExporter)(nil)
+	_ RowCounter = separateCSVExporter{}
+	_ RowCounter = datasetExporter{}
+	_ RowCounter = jsonlExporter{}
+)
+
+// CountRows returns the records written by e for inc at outputPath, keyed by path, or nil if e
+// does not count its records.
+func CountRows(e Exporter, inc Increment, outputPath string, options Options) map[string]int {
+	counter, ok := e.(RowCounter)
+	if !ok {
+		return nil
+	}
+	return counter.Rows(inc, outputPath, options)
+}
+
+// Rows returns one row per session.
+func (e *csvExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	return map[string]int{outputPath: len(inc.Sessions)}
+}
+
+// Rows returns one row per message.
+func (e *perLineExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	return map[string]int{outputPath: countMessages(inc.Sessions)}
+}
+
+// Rows returns one row per new session in the sessions file and one row per message in the
+// messages file.
+func (e separateCSVExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	newSessions := 0
+	for _, session := range inc.Sessions {
+		if inc.IsNew(session) {
+			newSessions++
+		}
+	}
+	return map[string]int{
+		outputPath:                               newSessions,
+		options.Get(e.Options(), OptionMessages): countMessages(inc.Sessions),
+	}
+}
+
+// Rows returns one dataset entry per session.
+func (datasetExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	return map[string]int{outputPath: len(inc.Sessions)}
+}
+
+// Rows returns one line per message.
+func (jsonlExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	return map[string]int{outputPath: countMessages(inc.Sessions)}
+}
+
+// countMessages returns the number of messages of sessions.
+func countMessages(sessions []Session) int {
+	n := 0
+	for _, session := range sessions {
+		n += len(session.Messages)
+	}
+	return n
+}
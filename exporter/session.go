@@ -11,6 +11,7 @@
 //   - Convert sessions to CSV with different formatting options
 //   - Create separate CSV files for sessions and messages
 //   - Extract sessions to a JSON format for Hugging Face datasets
+//   - Look up every export format by name through the Exporter registry
 //
 // The package also handles fields in the source JSON that may be represented as either
 // strings or integers by using the custom StringOrInt type.
@@ -61,6 +62,16 @@
 //	    log.Fatal(err)
 //	}
 //
+// ReadJSONFromFileFS, ConvertSessionsToCSVFS and CreateSeparateCSVFilesFS do the same through a
+// filesystem.FileSystem, such as a filesystem.MockFileSystem in tests.
+//
+// To write CSV to any io.Writer, such as standard output:
+//
+//	err = exporter.WriteSessionsCSV(ctx, os.Stdout, store.ChatNextWebStore.Sessions, exporter.FormatOptionPerLine)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//
 // To extract chat sessions to a JSON dataset:
 //
 //	datasetJSON, err := exporter.ExtractToDataset(store.ChatNextWebStore.Sessions)
@@ -73,13 +84,19 @@
 package exporter
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"reflect"
 	"strconv"
 	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
 )
 
 const (
@@ -139,13 +156,96 @@ type Stat struct {
 }
 
 // Mask represents an anonymization mask for a participant in a chat session,
-// including the participant's ID, avatar link, name, language, and creation timestamp.
+// including the participant's ID, avatar link, name, language, and creation timestamp,
+// as well as the context messages and model configuration it applies.
 type Mask struct {
-	ID        StringOrInt `json:"id"` // Use the custom type for ID
-	Avatar    string      `json:"avatar"`
-	Name      string      `json:"name"`
-	Lang      string      `json:"lang"`
-	CreatedAt int64       `json:"createdAt"` // Assuming it's a Unix timestamp
+	ID               StringOrInt `json:"id"` // Use the custom type for ID
+	Avatar           string      `json:"avatar"`
+	Name             string      `json:"name"`
+	Context          []Message   `json:"context"`
+	SyncGlobalConfig bool        `json:"syncGlobalConfig"`
+	ModelConfig      ModelConfig `json:"modelConfig"`
+	Lang             string      `json:"lang"`
+	Builtin          bool        `json:"builtin"`
+	CreatedAt        int64       `json:"createdAt"` // Assuming it's a Unix timestamp
+	HideContext      bool        `json:"hideContext"`
+}
+
+// ModelConfig represents the model settings of a mask, such as the model name and the
+// sampling parameters sent with every request.
+type ModelConfig struct {
+	Model                          string  `json:"model"`                          // The model name.
+	Temperature                    float64 `json:"temperature"`                    // The temperature for generating responses.
+	TopP                           float64 `json:"top_p"`                          // The top-p value for generating responses.
+	MaxTokens                      int     `json:"max_tokens"`                     // The maximum number of tokens in a generated response.
+	PresencePenalty                float64 `json:"presence_penalty"`               // The presence penalty for generating responses.
+	FrequencyPenalty               float64 `json:"frequency_penalty"`              // The frequency penalty for generating responses.
+	N                              int     `json:"n"`                              // The number of responses to generate.
+	Quality                        string  `json:"quality"`                        // The quality of generated images.
+	Size                           string  `json:"size"`                           // The size of generated images.
+	Style                          string  `json:"style"`                          // The style of generated images.
+	SystemFingerprint              string  `json:"system_fingerprint"`             // The fingerprint of the system.
+	SendMemory                     bool    `json:"sendMemory"`                     // Whether to send memory to the model.
+	HistoryMessageCount            int     `json:"historyMessageCount"`            // The number of history messages to include.
+	CompressMessageLengthThreshold int     `json:"compressMessageLengthThreshold"` // The threshold for compressing message length.
+	EnableInjectSystemPrompts      bool    `json:"enableInjectSystemPrompts"`      // Whether to enable injecting system prompts.
+	Template                       string  `json:"template"`                       // The template for the user input.
+}
+
+// UnmarshalJSON decodes a mask. Its context and settings are decoded on a best-effort basis: one
+// of another type than expected, as written by other NextChat versions, is left unset rather than
+// failing the whole backup.
+func (m *Mask) UnmarshalJSON(data []byte) error {
+	var mask struct {
+		ID               StringOrInt     `json:"id"`
+		Avatar           string          `json:"avatar"`
+		Name             string          `json:"name"`
+		Lang             string          `json:"lang"`
+		CreatedAt        int64           `json:"createdAt"`
+		Context          json.RawMessage `json:"context"`
+		SyncGlobalConfig json.RawMessage `json:"syncGlobalConfig"`
+		ModelConfig      json.RawMessage `json:"modelConfig"`
+		Builtin          json.RawMessage `json:"builtin"`
+		HideContext      json.RawMessage `json:"hideContext"`
+	}
+	if err := json.Unmarshal(data, &mask); err != nil {
+		return err
+	}
+	*m = Mask{ID: mask.ID, Avatar: mask.Avatar, Name: mask.Name, Lang: mask.Lang, CreatedAt: mask.CreatedAt}
+	decodeSetting(mask.Context, &m.Context)
+	decodeSetting(mask.SyncGlobalConfig, &m.SyncGlobalConfig)
+	decodeSetting(mask.ModelConfig, &m.ModelConfig)
+	decodeSetting(mask.Builtin, &m.Builtin)
+	decodeSetting(mask.HideContext, &m.HideContext)
+	return nil
+}
+
+// UnmarshalJSON decodes the model settings one by one, leaving a setting of another type than
+// expected unset.
+func (c *ModelConfig) UnmarshalJSON(data []byte) error {
+	var settings map[string]json.RawMessage
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if raw, ok := settings[key]; ok {
+			setting := reflect.New(v.Field(i).Type())
+			if json.Unmarshal(raw, setting.Interface()) == nil {
+				v.Field(i).Set(setting.Elem())
+			}
+		}
+	}
+	return nil
+}
+
+// decodeSetting decodes data into v, leaving v unchanged if data is missing or of another type.
+func decodeSetting[T any](data json.RawMessage, v *T) {
+	var value T
+	if len(data) > 0 && json.Unmarshal(data, &value) == nil {
+		*v = value
+	}
 }
 
 // Session represents a single chat session, including session metadata,
@@ -173,36 +273,71 @@ type ChatNextWebStore struct {
 }
 
 // ReadJSONFromFile reads a JSON file from the given file path and unmarshals it into a ChatNextWebStore struct.
+// Backups compressed with gzip, zstd or zip are decompressed transparently, and encrypted backups are
+// decrypted with the passphrase from the encryption.PassphraseEnv environment variable.
 //
 // It returns an error if the file cannot be opened, the JSON
 // is invalid, or the JSON format does not match the expected ChatNextWebStore format.
 func ReadJSONFromFile(filePath string) (ChatNextWebStore, error) {
-	// Variable `store` is of type ChatNextWebStore. It is used to store the unmarshaled JSON data.
-	var store ChatNextWebStore
+	return ReadJSONFromFileFS(&filesystem.RealFileSystem{}, filePath)
+}
+
+// ReadJSONFromFileFS is like ReadJSONFromFile, but opens the file through the provided FileSystem.
+func ReadJSONFromFileFS(rfs filesystem.FileSystem, filePath string) (ChatNextWebStore, error) {
+	return ReadJSONFromFileWithKeys(rfs, filePath, nil)
+}
 
-	// Variable `file` is of type *os.File. It holds the pointer to the opened JSON file.
+// ReadJSONFromFileWithKeys is like ReadJSONFromFileFS, but takes the passphrase of encrypted
+// backups from keys, which may prompt the user.
+func ReadJSONFromFileWithKeys(rfs filesystem.FileSystem, filePath string, keys *encryption.KeySource) (ChatNextWebStore, error) {
+	// Variable `file` is of type filesystem.File. It holds the handle of the opened JSON file.
 	// Variable `err` is of type error. It is used to capture any errors that occur during the file opening and JSON decoding process.
-	file, err := os.Open(filePath)
+	file, err := rfs.Open(filePath)
 	if err != nil {
-		// If an error occurs while opening the file, the function returns the empty `store` and the error.
-		return store, err
+		// If an error occurs while opening the file, the function returns an empty store and the error.
+		return ChatNextWebStore{}, err
 	}
 	// Defer the closing of the file until the function exits.
 	// This ensures that the file is closed properly to free resources and avoid leaks.
 	defer file.Close()
 
-	// Variable `decoder` is of type *json.Decoder. It is used to decode the JSON file into the `store` struct.
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&store)
+	// Transparently decrypt encrypted backups, then decompress gzip, zstd and zip backups,
+	// detected by extension or magic bytes.
+	plaintext, err := encryption.OpenReader(rfs, file, keys)
 	if err != nil {
-		// If an error occurs during decoding, the function returns the empty `store` and the error.
-		return store, err
+		return ChatNextWebStore{}, err
+	}
+	r, err := compression.NewReader(plaintext, encryption.TrimExtension(filePath))
+	if err != nil {
+		return ChatNextWebStore{}, err
+	}
+	defer r.Close()
+
+	store, err := ReadJSON(r)
+	return store, withPath(err, filePath)
+}
+
+// ReadJSON decodes JSON data from the given reader into a ChatNextWebStore struct.
+//
+// It returns a *ParseError if the JSON is invalid, or a *SchemaError if it does not match the expected
+// ChatNextWebStore format.
+func ReadJSON(r io.Reader) (ChatNextWebStore, error) {
+	// Variable `store` is of type ChatNextWebStore. It is used to store the unmarshaled JSON data.
+	var store ChatNextWebStore
+
+	// Variable `decoder` is of type *json.Decoder. It is used to decode the JSON data into the `store` struct.
+	counter := &countingReader{r: r}
+	decoder := json.NewDecoder(counter)
+	err := decoder.Decode(&store)
+	if err != nil {
+		// If an error occurs during decoding, the function returns the empty `store` and a ParseError or SchemaError.
+		return store, decodeError(counter.n, err)
 	}
 
 	// Check if the `Sessions` field in `store.ChatNextWebStore` is nil, which indicates the JSON was not in the expected format.
 	if store.ChatNextWebStore.Sessions == nil {
 		// If the JSON format is incorrect, the function returns the empty `store` and a format error.
-		return store, fmt.Errorf("JSON does not match the expected format chat-next-web-store")
+		return store, &SchemaError{}
 	}
 
 	// If no error occurs, the function returns the populated `store` and a nil error.
@@ -211,112 +346,89 @@ func ReadJSONFromFile(filePath string) (ChatNextWebStore, error) {
 
 // ConvertSessionsToCSV writes a slice of Session objects into a CSV file with support for context cancellation.
 //
-// It delegates the writing of sessions to format-specific functions based on the formatOption provided.
+// It delegates the writing of sessions to the registered CSV format matching the formatOption provided.
+// The file is written atomically: it only appears at outputFilePath once the export is complete, and a cancelled
+// or failed export leaves any previous file untouched.
 //
 // The outputFilePath parameter specifies the path to the output CSV file.
 //
 // It returns an error if the context is cancelled, the format option is invalid, or writing to the CSV fails.
 func ConvertSessionsToCSV(ctx context.Context, sessions []Session, formatOption int, outputFilePath string) error {
-	outputFile, err := os.Create(outputFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to create output CSV file: %w", err)
-	}
-	defer outputFile.Close()
+	return ConvertSessionsToCSVFS(ctx, &filesystem.RealFileSystem{}, sessions, formatOption, outputFilePath)
+}
 
-	csvWriter := csv.NewWriter(outputFile)
-	defer csvWriter.Flush()
+// ConvertSessionsToCSVFS is like ConvertSessionsToCSV, but creates the file through the provided
+// FileSystem, so that it can be redirected or mocked.
+func ConvertSessionsToCSVFS(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, formatOption int, outputFilePath string) error {
+	return writeCSVFile(rfs, outputFilePath, func(w io.Writer) error {
+		return WriteSessionsCSV(ctx, w, sessions, formatOption)
+	})
+}
 
-	headers, err := getCSVHeaders(formatOption)
+// writeCSVFile writes the CSV written by write to the file at outputFilePath, atomically.
+func writeCSVFile(rfs filesystem.FileSystem, outputFilePath string, write func(w io.Writer) error) error {
+	outputFile, err := filesystem.CreateAtomic(rfs, outputFilePath, 0644)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create output CSV file: %w", err)
 	}
+	defer outputFile.Discard() // Leave no partial file behind on error or cancellation.
 
-	if err := WriteHeaders(csvWriter, headers); err != nil {
+	if err := write(outputFile); err != nil {
 		return err
 	}
+	return outputFile.Commit()
+}
+
+// WriteSessionsCSV writes a slice of Session objects as CSV to w, such as a file, a buffer or os.Stdout,
+// using the registered CSV format matching the formatOption provided.
+// The rows of the sessions are formatted concurrently, see WriteSessionsCSVWithWorkers.
+//
+// It returns an error if the context is cancelled, the format option is invalid, or writing to the CSV fails.
+func WriteSessionsCSV(ctx context.Context, w io.Writer, sessions []Session, formatOption int) error {
+	return WriteSessionsCSVWithWorkers(ctx, w, sessions, formatOption, DefaultWorkers())
+}
 
-	writeFunc, err := getWriteFunction(formatOption)
+// WriteSessionsCSVWithWorkers is like WriteSessionsCSV, but formats the rows of the sessions with up to
+// workers goroutines. The rows are still written in the order of sessions, so the output does not
+// depend on the number of workers.
+func WriteSessionsCSVWithWorkers(ctx context.Context, w io.Writer, sessions []Session, formatOption int, workers int) error {
+	format, err := csvExporterForOption(formatOption)
 	if err != nil {
 		return err
 	}
-
-	for _, session := range sessions {
-		if err := checkContextCancellation(ctx); err != nil {
-			return err
-		}
-
-		if err := writeFunc(csvWriter, session); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return writeSessionsCSV(ctx, w, sessions, format.columns.layout(), workers)
 }
 
-// getCSVHeaders returns the headers for the CSV file based on the formatOption.
-// It returns an error if the formatOption is not recognized.
-func getCSVHeaders(formatOption int) ([]string, error) {
-	switch formatOption {
-	case FormatOptionInline:
-		return []string{"id", "topic", "memoryPrompt", "messages"}, nil
-	case FormatOptionPerLine:
-		return []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"}, nil
-	case FormatOptionJSON:
-		return []string{"id", "topic", "memoryPrompt", "messages"}, nil
-	default:
-		return nil, fmt.Errorf("invalid format option")
+// writeSessionsCSV is WriteSessionsCSVWithWorkers writing the columns of layout in its dialect.
+func writeSessionsCSV(ctx context.Context, w io.Writer, sessions []Session, layout *csvLayout, workers int) error {
+	progress := trackProgress(ctx, sessions)
+	w = progress.writer(w)
+	csvWriter, err := initializeCSVWriter(w, layout)
+	if err != nil {
+		return err
 	}
-}
-
-// getWriteFunction returns a function that corresponds to the CSV writing strategy for the given formatOption.
-// The returned function takes a csv.Writer and a Session object to write the session data according to the format.
-// It returns an error if the formatOption is not recognized.
-func getWriteFunction(formatOption int) (func(*csv.Writer, Session) error, error) {
-	switch formatOption {
-	case FormatOptionInline:
-		return writeInlineFormat, nil
-	case FormatOptionPerLine:
-		return writePerLineFormat, nil
-	case FormatOptionJSON:
-		return writeJSONFormat, nil
-	default:
-		return nil, fmt.Errorf("invalid format option")
+	if err := flushCSVWriter(csvWriter); err != nil {
+		return err
 	}
-}
 
-// writeInlineFormat writes session data in an inline format to the provided csv.Writer.
-// Messages are concatenated into a single string with a delimiter.
-// It returns an error if writing to the CSV fails.
-func writeInlineFormat(csvWriter *csv.Writer, session Session) error {
-	var messageContents []string
-	for _, message := range session.Messages {
-		messageContents = append(messageContents, fmt.Sprintf("[%s, %s] \"%s\"", message.Role, message.Date, message.Content))
+	render := func(ctx context.Context, session Session) ([]byte, error) {
+		var buf bytes.Buffer
+		sessionWriter := layout.dialect.newWriter(&buf)
+		if err := layout.writeRows(sessionWriter, session); err != nil {
+			return nil, err
+		}
+		if err := flushCSVWriter(sessionWriter); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
 	}
-	sessionData := []string{session.ID, session.Topic, session.MemoryPrompt, strings.Join(messageContents, "; ")}
-	return csvWriter.Write(sessionData)
-}
-
-// writePerLineFormat writes each message of a session on a new line in the provided csv.Writer.
-// It returns an error if writing to the CSV fails.
-func writePerLineFormat(csvWriter *csv.Writer, session Session) error {
-	for _, message := range session.Messages {
-		sessionData := []string{session.ID, message.ID, message.Date, message.Role, message.Content, session.MemoryPrompt}
-		if err := csvWriter.Write(sessionData); err != nil {
+	return ProcessOrdered(ctx, sessions, workers, render, func(i int, rows []byte) error {
+		if _, err := w.Write(rows); err != nil {
 			return err
 		}
-	}
-	return nil
-}
-
-// writeJSONFormat writes session data with messages as a JSON string to the provided csv.Writer.
-// It returns an error if marshaling messages to JSON or writing to the CSV fails.
-func writeJSONFormat(csvWriter *csv.Writer, session Session) error {
-	messagesJSON, err := json.Marshal(session.Messages)
-	if err != nil {
-		return err
-	}
-	sessionData := []string{session.ID, session.Topic, session.MemoryPrompt, string(messagesJSON)}
-	return csvWriter.Write(sessionData)
+		progress.done(sessions[i])
+		return nil
+	})
 }
 
 // checkContextCancellation checks if the context has been cancelled.
@@ -366,87 +478,126 @@ func WriteMessageData(csvWriter *csv.Writer, sessions []Session) error {
 	return nil
 }
 
-// initializeCSVFile creates and initializes a CSV file with the given name and headers.
-func initializeCSVFile(fileName string, headers []string) (*os.File, *csv.Writer, error) {
-	file, err := os.Create(fileName)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create file %s: %w", fileName, err)
+// initializeCSVWriter starts a new CSV file on top of w with the byte order mark of the dialect of
+// layout, if any, and returns a writer of its rows after writing the headers of layout.
+func initializeCSVWriter(w io.Writer, layout *csvLayout) (rowWriter, error) {
+	if err := layout.dialect.writeBOM(w); err != nil {
+		return nil, err
 	}
+	csvWriter := layout.dialect.newWriter(w)
 
-	csvWriter := csv.NewWriter(file)
-
-	if err := WriteHeaders(csvWriter, headers); err != nil {
-		file.Close() // ignore error; we're already handling an error
-		return nil, nil, err
+	if err := layout.writeHeaders(csvWriter); err != nil {
+		return nil, err
 	}
 
-	return file, csvWriter, nil
+	return csvWriter, nil
 }
 
-// closeCSVWriter closes the csv.Writer and the underlying file, and checks for errors.
-func closeCSVWriter(csvWriter *csv.Writer, file *os.File) error {
+// flushCSVWriter flushes the CSV writer and checks for errors.
+func flushCSVWriter(csvWriter rowWriter) error {
 	csvWriter.Flush()
 	if err := csvWriter.Error(); err != nil {
-		file.Close() // ignore error; we're already handling an error
 		return fmt.Errorf("failed to flush data: %w", err)
 	}
-
-	if err := file.Close(); err != nil {
-		return fmt.Errorf("failed to close file: %w", err)
-	}
-
 	return nil
 }
 
 // CreateSeparateCSVFiles creates two separate CSV files for sessions and messages from a slice of Session objects.
 //
-// It takes the file names as parameters and returns an error if the files cannot be created or if writing the data fails.
-//
-// Errors from closing files or flushing data to the CSV writers are captured and will be returned after all operations are attempted.
-//
-// Error messages are logged to the console.
-func CreateSeparateCSVFiles(sessions []Session, sessionsFileName string, messagesFileName string) (err error) {
-	// Create and initialize the sessions CSV file.
-	var sessionsFile *os.File
-	var sessionsWriter *csv.Writer
-	sessionsFile, sessionsWriter, err = initializeCSVFile(sessionsFileName, []string{"id", "topic", "memoryPrompt"})
+// It takes the file names as parameters and streams the data into both files.
+// Both files are written atomically, so a failed export never leaves a partial sessions or messages file behind.
+// It returns an error if writing the data or creating either file fails.
+func CreateSeparateCSVFiles(sessions []Session, sessionsFileName string, messagesFileName string) error {
+	return CreateSeparateCSVFilesFS(context.Background(), &filesystem.RealFileSystem{}, sessions, sessionsFileName, messagesFileName)
+}
+
+// CreateSeparateCSVFilesFS is like CreateSeparateCSVFiles, but creates the files through the
+// provided FileSystem and stops when ctx is cancelled.
+func CreateSeparateCSVFilesFS(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, sessionsFileName string, messagesFileName string) error {
+	return createSeparateCSVFiles(ctx, rfs, sessions, sessionsFileName, messagesFileName, sessionsColumns.layout(), messagesColumns.layout())
+}
+
+// createSeparateCSVFiles is CreateSeparateCSVFiles reporting its progress to the reporter of ctx,
+// and writing the files with sessionsLayout and messagesLayout.
+func createSeparateCSVFiles(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, sessionsFileName string, messagesFileName string, sessionsLayout, messagesLayout *csvLayout) error {
+	sessionsFile, err := filesystem.CreateAtomic(rfs, sessionsFileName, 0644)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create file %s: %w", sessionsFileName, err)
 	}
-	defer func() {
-		if cerr := closeCSVWriter(sessionsWriter, sessionsFile); cerr != nil {
-			err = cerr
-		}
-	}()
+	defer sessionsFile.Discard() // Leave no partial file behind on error.
 
-	// Write session data.
-	if err = WriteSessionData(sessionsWriter, sessions); err != nil {
+	messagesFile, err := filesystem.CreateAtomic(rfs, messagesFileName, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", messagesFileName, err)
+	}
+	defer messagesFile.Discard() // Leave no partial file behind on error.
+
+	// Both files are only put in place once both have been written completely.
+	if err := writeSeparateCSV(ctx, sessionsFile, messagesFile, sessions, sessionsLayout, messagesLayout); err != nil {
+		return err
+	}
+	if err := sessionsFile.Commit(); err != nil {
 		return err
 	}
+	return messagesFile.Commit()
+}
+
+// sessionsHeaders and messagesHeaders are the headers of the sessions and messages CSV files.
+var (
+	sessionsHeaders = []string{"id", "topic", "memoryPrompt"}
+	messagesHeaders = []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"}
+)
 
-	// Create and initialize the messages CSV file.
-	var messagesFile *os.File
-	var messagesWriter *csv.Writer
-	messagesFile, messagesWriter, err = initializeCSVFile(messagesFileName, []string{"session_id", "message_id", "date", "role", "content", "memoryPrompt"})
+// WriteSeparateCSV writes the session data as CSV to sessionsWriter and the message data as CSV
+// to messagesWriter, each with its own headers.
+func WriteSeparateCSV(sessionsWriter io.Writer, messagesWriter io.Writer, sessions []Session) error {
+	return writeSeparateCSV(context.Background(), sessionsWriter, messagesWriter, sessions, sessionsColumns.layout(), messagesColumns.layout())
+}
+
+// writeSeparateCSV is WriteSeparateCSV reporting its progress to the reporter of ctx, and writing
+// the files with sessionsLayout and messagesLayout. A session counts as written once its messages
+// are in the messages file.
+func writeSeparateCSV(ctx context.Context, sessionsWriter io.Writer, messagesWriter io.Writer, sessions []Session, sessionsLayout, messagesLayout *csvLayout) error {
+	progress := trackProgress(ctx, sessions)
+
+	// Initialize the sessions CSV and write session data.
+	sessionsCSV, err := initializeCSVWriter(progress.writer(sessionsWriter), sessionsLayout)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if cerr := closeCSVWriter(messagesWriter, messagesFile); cerr != nil {
-			err = cerr
-		}
-	}()
+	if err := writeRows(sessionsCSV, sessionsLayout, sessions, nil); err != nil {
+		return err
+	}
 
-	// Write message data.
-	if err = WriteMessageData(messagesWriter, sessions); err != nil {
+	// Initialize the messages CSV and write message data.
+	messagesCSV, err := initializeCSVWriter(progress.writer(messagesWriter), messagesLayout)
+	if err != nil {
 		return err
 	}
+	return writeRows(messagesCSV, messagesLayout, sessions, progress)
+}
 
-	return nil
+// writeRows writes the rows of sessions with layout and flushes them. With a progress, the rows
+// of every session are flushed on their own, so that the progress includes their bytes.
+func writeRows(csvWriter rowWriter, layout *csvLayout, sessions []Session, progress *progressTracker) error {
+	for _, session := range sessions {
+		if err := layout.writeRows(csvWriter, session); err != nil {
+			return fmt.Errorf("failed to write %s data: %w", layout.kind(), err)
+		}
+		if progress != nil {
+			if err := flushCSVWriter(csvWriter); err != nil {
+				return err
+			}
+			progress.done(session)
+		}
+	}
+	return flushCSVWriter(csvWriter)
 }
 
 // ExtractToDataset converts a slice of Session objects into a JSON formatted string suitable for use as a dataset in machine learning applications.
 //
+// Every field of the sessions is kept, including the context messages and model settings of their masks.
+//
 // It returns an error if marshaling the sessions into JSON format fails.
 func ExtractToDataset(sessions []Session) (string, error) {
 	dataset := make(map[string][]Session)
@@ -0,0 +1,100 @@
+// Below, the package exporter (@session_test.go) tests the decoding of backups and the CSV and
+// dataset conversions of their sessions.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestConvertSessionsToCSV verifies that sessions are written as CSV through any file system, and
+// that a cancelled export neither touches the existing file nor leaves temporary files behind.
+func TestConvertSessionsToCSV(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	sessions := []exporter.Session{{
+		ID:           "1",
+		Topic:        "Test Session",
+		MemoryPrompt: "Remember",
+		Messages: []exporter.Message{
+			{ID: "m1", Date: "2023-01-01", Role: "user", Content: "Hello, \"Gopher\""},
+			{ID: "m2", Date: "2023-01-02", Role: "assistant", Content: "Hi!"},
+		},
+	}}
+	if err := exporter.ConvertSessionsToCSVFS(context.Background(), mockFS, sessions, exporter.FormatOptionPerLine, "messages.csv"); err != nil {
+		t.Fatalf("ConvertSessionsToCSVFS() returned error: %v", err)
+	}
+	want := "session_id,message_id,date,role,content,memoryPrompt\n" +
+		"1,m1,2023-01-01,user,\"Hello, \"\"Gopher\"\"\",Remember\n" +
+		"1,m2,2023-01-02,assistant,Hi!,Remember\n"
+	if got := string(mockFS.Files["messages.csv"]); got != want {
+		t.Errorf("messages.csv = %q, want %q", got, want)
+	}
+
+	dir := t.TempDir()
+	rfs := filesystem.RealFileSystem{}
+	outputPath := filepath.Join(dir, "messages.csv")
+	if err := rfs.WriteFile(outputPath, []byte("previous export"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := exporter.ConvertSessionsToCSVFS(ctx, rfs, sessions, exporter.FormatOptionPerLine, outputPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ConvertSessionsToCSVFS() returned %v, want context.Canceled", err)
+	}
+	if content, _ := os.ReadFile(outputPath); string(content) != "previous export" {
+		t.Errorf("cancelled export changed the existing file to %q", content)
+	}
+
+	if err := exporter.ConvertSessionsToCSV(context.Background(), sessions, exporter.FormatOptionPerLine, outputPath); err != nil {
+		t.Fatalf("ConvertSessionsToCSV() returned error: %v", err)
+	}
+	if content, _ := os.ReadFile(outputPath); !strings.Contains(string(content), "Hello") {
+		t.Errorf("export did not replace the existing file: %q", content)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("directory contains %v, want only messages.csv", names)
+	}
+}
+
+// TestMaskSettings verifies that mask settings of other types than expected are left unset
+// instead of failing the backup, and that the dataset keeps the context and model settings of the
+// masks.
+func TestMaskSettings(t *testing.T) {
+	backup := `{"chat-next-web-store":{"sessions":[{"id":"s1","mask":{"id":7,"name":"Gopher","context":"none",` +
+		`"hideContext":1,"modelConfig":{"model":"gpt-4","temperature":"0.5","max_tokens":4000}}}]}}`
+	store, err := exporter.ReadJSON(strings.NewReader(backup))
+	if err != nil {
+		t.Fatalf("ReadJSON() with mask settings of other types returned error: %v", err)
+	}
+	mask := store.ChatNextWebStore.Sessions[0].Mask
+	if mask.ID != "7" || mask.Name != "Gopher" || mask.Context != nil || mask.HideContext ||
+		mask.ModelConfig != (exporter.ModelConfig{Model: "gpt-4", MaxTokens: 4000}) {
+		t.Errorf("ReadJSON() mask = %+v", mask)
+	}
+
+	sessions := []exporter.Session{{ID: "s1", Topic: "Go",
+		Mask: exporter.Mask{Name: "Default", ModelConfig: exporter.ModelConfig{Model: "gpt-4", Temperature: 0.5}}}}
+	dataset, err := exporter.ExtractToDataset(sessions)
+	if err != nil || !strings.Contains(dataset, `"context": null`) || !strings.Contains(dataset, `"temperature": 0.5`) {
+		t.Errorf("ExtractToDataset() = %s, %v, want the mask settings", dataset, err)
+	}
+}
@@ -0,0 +1,541 @@
+// Below, the package exporter (@xlsx.go) writes sessions as an Excel workbook, which keeps
+// multi-line messages and Unicode intact where CSV files opened in Excel mangle them.
+//
+// The workbook mirrors the separate CSV format in a single file: a Sessions sheet with a row per
+// session, a Messages sheet with a row per message, and a Summary sheet with the statistics of
+// the export. Numbers and dates are typed cells, long text is wrapped, and the header row of every
+// sheet is frozen and filterable.
+//
+// The workbook is written as plain SpreadsheetML, without dependencies, so it opens in Excel,
+// LibreOffice, Numbers and Google Sheets alike.
+//
+// # Example Usage
+//
+//	e, _ := exporter.Lookup(exporter.FormatNameXLSX)
+//	files, err := e.Export(ctx, rfs, sessions, "sessions.xlsx", nil)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// Sheet names of the Excel workbook.
+const (
+	SheetSessions = "Sessions"
+	SheetMessages = "Messages"
+	SheetSummary  = "Summary"
+)
+
+// xlsxMaxCellLength is the maximum number of UTF-16 code units of a cell in Excel.
+const xlsxMaxCellLength = 32767
+
+// Ensure the Excel workbook can be written to any io.Writer and counts its rows.
+var (
+	_ WriterExporter = xlsxExporter{}
+	_ RowCounter     = xlsxExporter{}
+)
+
+// xlsxExporter writes sessions as an Excel workbook, see WriteXLSX.
+type xlsxExporter struct{}
+
+// Name returns the registry name of the format.
+func (xlsxExporter) Name() string { return FormatNameXLSX }
+
+// Description returns the menu description of the format.
+func (xlsxExporter) Description() string {
+	return "Excel Workbook (Sessions, Messages and Summary Sheets)"
+}
+
+// Extension returns ".xlsx".
+func (xlsxExporter) Extension() string { return ".xlsx" }
+
+// Options returns nil; the Excel workbook has no options.
+func (xlsxExporter) Options() []Option { return nil }
+
+// Export writes the workbook to outputPath.
+func (e xlsxExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []Session, outputPath string, options Options) ([]string, error) {
+	if err := options.Validate(e.Options()); err != nil {
+		return nil, err
+	}
+	outputFile, err := filesystem.CreateAtomic(rfs, outputPath, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output XLSX file: %w", err)
+	}
+	defer outputFile.Discard() // Leave no partial file behind on error or cancellation.
+
+	if err := WriteXLSX(ctx, outputFile, sessions); err != nil {
+		return nil, err
+	}
+	if err := outputFile.Commit(); err != nil {
+		return nil, err
+	}
+	return []string{outputPath}, nil
+}
+
+// ExportTo writes the workbook to w.
+func (e xlsxExporter) ExportTo(ctx context.Context, w io.Writer, sessions []Session, options Options) error {
+	if err := options.Validate(e.Options()); err != nil {
+		return err
+	}
+	return WriteXLSX(ctx, w, sessions)
+}
+
+// Rows returns one row per session in the Sessions sheet plus one row per message in the
+// Messages sheet.
+func (xlsxExporter) Rows(inc Increment, outputPath string, options Options) map[string]int {
+	return map[string]int{outputPath: len(inc.Sessions) + countMessages(inc.Sessions)}
+}
+
+// WriteXLSX writes sessions to w as an Excel workbook with a Sessions, a Messages and a Summary
+// sheet. The dates of sessions are written in UTC, and the dates of messages as they were
+// recorded; dates that cannot be parsed are kept as text.
+//
+// It returns an error if the context is cancelled or writing fails.
+func WriteXLSX(ctx context.Context, w io.Writer, sessions []Session) error {
+	progress := trackProgress(ctx, sessions)
+	archive := zip.NewWriter(progress.writer(w))
+
+	sheets := []struct {
+		name  string
+		write func(*xlsxSheet) error
+	}{
+		{SheetSessions, func(sheet *xlsxSheet) error { return writeSessionsSheet(ctx, sheet, sessions) }},
+		{SheetMessages, func(sheet *xlsxSheet) error { return writeMessagesSheet(ctx, sheet, sessions, progress) }},
+		{SheetSummary, func(sheet *xlsxSheet) error { return writeSummarySheet(sheet, sessions) }},
+	}
+	names := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		names[i] = sheet.name
+	}
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRelationships},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRelationships(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err := writeZipPart(archive, part.name, func(w io.Writer) error {
+			_, err := io.WriteString(w, part.content)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	// The workbook lists the filter range of every sheet, known once the sheets are written.
+	filters := make([]string, len(sheets))
+	for i, sheet := range sheets {
+		err := writeZipPart(archive, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), func(w io.Writer) error {
+			s := &xlsxSheet{w: bufio.NewWriter(w), selected: i == 0}
+			if err := sheet.write(s); err != nil {
+				return err
+			}
+			filters[i] = s.filter
+			return s.close()
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := writeZipPart(archive, "xl/workbook.xml", func(w io.Writer) error {
+		_, err := io.WriteString(w, xlsxWorkbook(names, filters))
+		return err
+	}); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// writeZipPart adds the part name to archive with the content written by write.
+func writeZipPart(archive *zip.Writer, name string, write func(io.Writer) error) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	return write(w)
+}
+
+// writeSessionsSheet writes a row per session.
+func writeSessionsSheet(ctx context.Context, sheet *xlsxSheet, sessions []Session) error {
+	sheet.start([]string{"id", "topic", "memoryPrompt", "messages", "tokenCount", "wordCount", "charCount", "lastUpdate (UTC)", "mask", "model"},
+		[]float64{24, 40, 50, 10, 12, 12, 12, 20, 24, 24})
+	for _, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		sheet.row(
+			textCell(session.ID),
+			textCell(session.Topic),
+			wrappedCell(session.MemoryPrompt),
+			numberCell(float64(len(session.Messages))),
+			numberCell(float64(session.Stat.TokenCount)),
+			numberCell(float64(session.Stat.WordCount)),
+			numberCell(float64(session.Stat.CharCount)),
+			timestampCell(session.LastUpdate),
+			textCell(session.Mask.Name),
+			textCell(session.Mask.ModelConfig.Model),
+		)
+	}
+	return sheet.end(true)
+}
+
+// writeMessagesSheet writes a row per message, with the columns of the messages CSV file.
+// A session counts as written once its messages are in the sheet.
+func writeMessagesSheet(ctx context.Context, sheet *xlsxSheet, sessions []Session, progress *progressTracker) error {
+	sheet.start(messagesHeaders, []float64{24, 24, 20, 12, 80, 40})
+	for _, session := range sessions {
+		if err := checkContextCancellation(ctx); err != nil {
+			return err
+		}
+		for _, message := range session.Messages {
+			sheet.row(
+				textCell(session.ID),
+				textCell(message.ID),
+				dateCell(message.Date),
+				textCell(message.Role),
+				wrappedCell(message.Content),
+				wrappedCell(session.MemoryPrompt),
+			)
+		}
+		if err := sheet.flush(); err != nil {
+			return err
+		}
+		progress.done(session)
+	}
+	return sheet.end(true)
+}
+
+// writeSummarySheet writes the statistics of the export: the number of sessions and of messages
+// by role, the totals of the session statistics, and the range of dates.
+func writeSummarySheet(sheet *xlsxSheet, sessions []Session) error {
+	var messages, tokens, words, chars int
+	var first, last time.Time
+	roles := make(map[string]int)
+	for _, session := range sessions {
+		messages += len(session.Messages)
+		tokens += session.Stat.TokenCount
+		words += session.Stat.WordCount
+		chars += session.Stat.CharCount
+		for _, message := range session.Messages {
+			roles[message.Role]++
+			if date, ok := parseMessageDate(message.Date); ok && (first.IsZero() || date.Before(first)) {
+				first = date
+			}
+		}
+		if session.LastUpdate > 0 {
+			if updated := time.UnixMilli(session.LastUpdate).UTC(); updated.After(last) {
+				last = updated
+			}
+		}
+	}
+	names := make([]string, 0, len(roles))
+	for role := range roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+
+	sheet.start([]string{"metric", "value"}, []float64{32, 20})
+	sheet.row(textCell("Sessions"), numberCell(float64(len(sessions))))
+	sheet.row(textCell("Messages"), numberCell(float64(messages)))
+	for _, role := range names {
+		sheet.row(textCell(fmt.Sprintf("Messages (%s)", role)), numberCell(float64(roles[role])))
+	}
+	sheet.row(textCell("Tokens"), numberCell(float64(tokens)))
+	sheet.row(textCell("Words"), numberCell(float64(words)))
+	sheet.row(textCell("Characters"), numberCell(float64(chars)))
+	if !first.IsZero() {
+		sheet.row(textCell("First message"), timeCell(first))
+	}
+	if !last.IsZero() {
+		sheet.row(textCell("Last update (UTC)"), timeCell(last))
+	}
+	return sheet.end(false)
+}
+
+// messageDateLayouts are the layouts of the dates of messages, as formatted by the browsers
+// running NextChat in common locales.
+var messageDateLayouts = []string{
+	"1/2/2006, 3:04:05 PM",
+	"2006/1/2 15:04:05",
+	"2006-01-02 15:04:05",
+	"02.01.2006, 15:04:05",
+	time.RFC3339,
+}
+
+// parseMessageDate parses the date of a message in one of the messageDateLayouts.
+func parseMessageDate(value string) (time.Time, bool) {
+	for _, layout := range messageDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Styles of the cells, as indexes of the cellXfs of xlsxStyles.
+const (
+	styleDefault = iota
+	styleHeader
+	styleWrapped
+	styleDate
+)
+
+// xlsxCell is a cell of a sheet: text, or a number with a style.
+type xlsxCell struct {
+	text   string
+	number float64
+	isText bool
+	style  int
+}
+
+// textCell returns a text cell.
+func textCell(text string) xlsxCell {
+	return xlsxCell{text: text, isText: true}
+}
+
+// wrappedCell returns a text cell whose lines are wrapped.
+func wrappedCell(text string) xlsxCell {
+	return xlsxCell{text: text, isText: true, style: styleWrapped}
+}
+
+// numberCell returns a number cell.
+func numberCell(number float64) xlsxCell {
+	return xlsxCell{number: number}
+}
+
+// timeCell returns a date cell holding t.
+func timeCell(t time.Time) xlsxCell {
+	// Excel counts days since 1899-12-30, so that 1900-03-01 is day 61.
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return xlsxCell{number: wall.Sub(epoch).Hours() / 24, style: styleDate}
+}
+
+// timestampCell returns a date cell holding the Unix timestamp in milliseconds, in UTC, or an
+// empty cell if it is not set.
+func timestampCell(milliseconds int64) xlsxCell {
+	if milliseconds <= 0 {
+		return textCell("")
+	}
+	return timeCell(time.UnixMilli(milliseconds).UTC())
+}
+
+// dateCell returns a date cell holding the date of a message, or a text cell if it cannot be parsed.
+func dateCell(value string) xlsxCell {
+	if t, ok := parseMessageDate(value); ok {
+		return timeCell(t)
+	}
+	return textCell(value)
+}
+
+// xlsxSheet writes the XML of a worksheet.
+type xlsxSheet struct {
+	w        *bufio.Writer
+	selected bool   // Whether the sheet is the one shown when the workbook is opened.
+	columns  int    // Number of columns.
+	rows     int    // Number of rows written, including the header.
+	filter   string // Range of the autofilter, such as "A1:F10"; empty if the sheet has none.
+	err      error  // First error of the underlying writer.
+}
+
+// start writes the beginning of the worksheet, with frozen headers and the widths of the columns.
+func (s *xlsxSheet) start(headers []string, widths []float64) {
+	s.columns = len(headers)
+	s.printf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n"+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`+
+		`<sheetViews><sheetView workbookViewId="0"%s><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>`+
+		`<selection pane="bottomLeft" activeCell="A2" sqref="A2"/></sheetView></sheetViews>`+
+		`<sheetFormatPr defaultRowHeight="15"/><cols>`, map[bool]string{true: ` tabSelected="1"`}[s.selected])
+	for i, width := range widths {
+		s.printf(`<col min="%d" max="%d" width="%g" customWidth="1"/>`, i+1, i+1, width)
+	}
+	s.printf(`</cols><sheetData>`)
+
+	cells := make([]xlsxCell, len(headers))
+	for i, header := range headers {
+		cells[i] = xlsxCell{text: header, isText: true, style: styleHeader}
+	}
+	s.row(cells...)
+}
+
+// row writes a row of cells.
+func (s *xlsxSheet) row(cells ...xlsxCell) {
+	s.rows++
+	s.printf(`<row r="%d">`, s.rows)
+	for i, cell := range cells {
+		ref := cellReference(i, s.rows)
+		style := ""
+		if cell.style != styleDefault {
+			style = fmt.Sprintf(` s="%d"`, cell.style)
+		}
+		if !cell.isText {
+			s.printf(`<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(cell.number, 'f', -1, 64))
+			continue
+		}
+		if cell.text == "" {
+			continue // Excel reads a missing cell as empty.
+		}
+		s.printf(`<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">`, ref, style)
+		if s.err == nil {
+			s.err = xml.EscapeText(s.w, []byte(truncateCell(cell.text)))
+		}
+		s.printf(`</t></is></c>`)
+	}
+	s.printf(`</row>`)
+}
+
+// end writes the end of the worksheet, with an autofilter on the header row if filter is true.
+func (s *xlsxSheet) end(filter bool) error {
+	s.printf(`</sheetData>`)
+	if filter {
+		s.filter = fmt.Sprintf("A1:%s", cellReference(s.columns-1, s.rows))
+		s.printf(`<autoFilter ref="%s"/>`, s.filter)
+	}
+	s.printf(`</worksheet>`)
+	return s.err
+}
+
+// flush writes the buffered XML.
+func (s *xlsxSheet) flush() error {
+	if s.err == nil {
+		s.err = s.w.Flush()
+	}
+	return s.err
+}
+
+// close flushes the worksheet once it has ended.
+func (s *xlsxSheet) close() error {
+	return s.flush()
+}
+
+// printf writes formatted XML, unless a previous write failed.
+func (s *xlsxSheet) printf(format string, args ...any) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, format, args...)
+	}
+}
+
+// cellReference returns the reference of the cell at the zero-based column and the one-based
+// row, such as "A1" or "AB12".
+func cellReference(column, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
+
+// truncateCell cuts text to the length of the longest cell Excel opens.
+func truncateCell(text string) string {
+	length := 0
+	for i, r := range text {
+		if length += len(utf16.Encode([]rune{r})); length > xlsxMaxCellLength {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+// xlsxContentTypes returns the content types of the parts of a workbook with sheets worksheets.
+func xlsxContentTypes(sheets int) string {
+	content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`
+	for i := 1; i <= sheets; i++ {
+		content += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	return content + `</Types>`
+}
+
+// xlsxRootRelationships points to the workbook of the package.
+const xlsxRootRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxWorkbookRelationships returns the relationships of a workbook with sheets worksheets: the
+// worksheets as rId1 to rIdN, followed by the styles.
+func xlsxWorkbookRelationships(sheets int) string {
+	content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	for i := 1; i <= sheets; i++ {
+		content += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	return content + fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheets+1) +
+		`</Relationships>`
+}
+
+// xlsxWorkbook returns the workbook listing the sheets named names, with the ranges of their
+// autofilters, if any.
+func xlsxWorkbook(names, filters []string) string {
+	content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets>`
+	for i, name := range names {
+		content += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, name, i+1, i+1)
+	}
+	content += `</sheets><definedNames>`
+	for i, filter := range filters {
+		if filter == "" {
+			continue
+		}
+		content += fmt.Sprintf(`<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s!%s</definedName>`,
+			i, names[i], absoluteRange(filter))
+	}
+	return content + `</definedNames></workbook>`
+}
+
+// absoluteRange returns a range such as "A1:F10" as "$A$1:$F$10".
+func absoluteRange(ref string) string {
+	absolute := make([]byte, 0, len(ref)+4)
+	letters := false
+	for i := 0; i < len(ref); i++ {
+		c := ref[i]
+		isLetter := c >= 'A' && c <= 'Z'
+		isDigit := c >= '0' && c <= '9'
+		if isLetter && !letters || isDigit && (i == 0 || ref[i-1] < '0' || ref[i-1] > '9') {
+			absolute = append(absolute, '$')
+		}
+		letters = isLetter
+		absolute = append(absolute, c)
+	}
+	return string(absolute)
+}
+
+// xlsxStyles holds the cell styles: the default, the bold and filled header, wrapped text aligned
+// to the top, and dates, in the order of the style constants.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/><family val="2"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/><family val="2"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFD9E1F2"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,87 @@
+// Below, the package exporter (@xlsx_test.go) tests the sheets and cells of the Excel workbook.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package exporter_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestXLSXExporter verifies that the Excel workbook holds the Sessions, Messages and Summary
+// sheets with typed cells, wrapped text, frozen headers and autofilters.
+func TestXLSXExporter(t *testing.T) {
+	sessions := []exporter.Session{{
+		ID:         "1",
+		Topic:      "Gopher 地鼠",
+		LastUpdate: 1701388800000, // 2023-12-01 00:00:00 UTC.
+		Stat:       exporter.Stat{TokenCount: 42},
+		Messages: []exporter.Message{
+			{ID: "m1", Date: "11/28/2023, 12:00:00 PM", Role: "user", Content: "Hello,\n<Gopher> & 地鼠"},
+			{ID: "m2", Date: "sometime", Role: "assistant", Content: "Hi!"},
+		},
+	}}
+	e, ok := exporter.Lookup(exporter.FormatNameXLSX)
+	if !ok {
+		t.Fatal("the xlsx format is not registered")
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	if _, err := e.Export(context.Background(), mockFS, sessions, "sessions.xlsx", nil); err != nil {
+		t.Fatalf("Export() returned error: %v", err)
+	}
+	data := mockFS.Files["sessions.xlsx"]
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("sessions.xlsx is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = string(content)
+	}
+
+	want := map[string][]string{
+		"[Content_Types].xml": {`PartName="/xl/workbook.xml"`, `PartName="/xl/worksheets/sheet3.xml"`},
+		"xl/workbook.xml": {`<sheet name="Sessions" sheetId="1" r:id="rId1"/>`, `<sheet name="Messages" sheetId="2" r:id="rId2"/>`,
+			`<sheet name="Summary" sheetId="3" r:id="rId3"/>`, `localSheetId="1" hidden="1">Messages!$A$1:$F$3</definedName>`},
+		"xl/styles.xml": {`formatCode="yyyy-mm-dd hh:mm:ss"`, `wrapText="1"`},
+		"xl/worksheets/sheet1.xml": {`state="frozen"`, `<autoFilter ref="A1:J2"/>`, `>Gopher 地鼠</t>`,
+			`<c r="E2"><v>42</v></c>`, `<c r="H2" s="3"><v>45261</v></c>`},
+		"xl/worksheets/sheet2.xml": {`state="frozen"`, `<autoFilter ref="A1:F3"/>`, `<c r="C2" s="3"><v>45258.5</v></c>`,
+			`<c r="E2" s="2" t="inlineStr"><is><t xml:space="preserve">Hello,&#xA;&lt;Gopher&gt; &amp; 地鼠</t>`, `>sometime</t>`},
+		"xl/worksheets/sheet3.xml": {`>Messages (assistant)</t></is></c><c r="B4"><v>1</v>`, `>Tokens</t></is></c><c r="B6"><v>42</v>`},
+	}
+	for name, fragments := range want {
+		for _, fragment := range fragments {
+			if !strings.Contains(parts[name], fragment) {
+				t.Errorf("%s does not contain %q:\n%s", name, fragment, parts[name])
+			}
+		}
+	}
+	if strings.Contains(parts["xl/worksheets/sheet3.xml"], "<autoFilter") {
+		t.Error("the Summary sheet has an autofilter")
+	}
+
+	var buf bytes.Buffer
+	if err := e.(exporter.WriterExporter).ExportTo(context.Background(), &buf, sessions, nil); err != nil {
+		t.Fatalf("ExportTo() returned error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("ExportTo() and Export() wrote different workbooks")
+	}
+}
@@ -0,0 +1,131 @@
+// Below, the package filesystem (@atomic.go) provides crash-safe writes on top of any FileSystem.
+//
+// Outputs are first written to a temporary file in the same directory as the destination,
+// flushed to disk, and only then renamed over the destination. Since a rename within a directory
+// is atomic, readers either see the previous file or the complete new one, never a half-written
+// file left behind by an interrupt or a full disk. The directory is flushed after the rename too,
+// so that the new name survives a crash, and a replaced file keeps its permissions.
+//
+// # Example Usage
+//
+//	f, err := filesystem.CreateAtomic(rfs, "sessions.csv", 0644)
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Discard() // No-op once committed; removes the temporary file otherwise.
+//	if err := writeCSV(ctx, f); err != nil {
+//		return err
+//	}
+//	return f.Commit()
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package filesystem
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// DirSyncer is implemented by the file systems that can flush a directory to disk, so that the
+// files renamed into it survive a crash. Commit flushes the directory of its destination when the
+// file system implements it.
+type DirSyncer interface {
+	SyncDir(dir string) error
+}
+
+// AtomicFile is an output file that only replaces its destination when Commit is called.
+// Until then, all writes go to a temporary file next to the destination.
+type AtomicFile struct {
+	rfs  FileSystem // rfs is the file system the file is written through.
+	temp File       // temp is the temporary file receiving the writes.
+	name string     // name is the destination path.
+	done bool       // done reports whether Commit or Discard has finished the file.
+}
+
+// CreateAtomic starts writing the file name through rfs. The data becomes visible at name only
+// after Commit; Discard, or a failed Commit, removes the temporary file and leaves any existing
+// file at name untouched. As with os.WriteFile, perm only applies if name does not exist yet:
+// an existing file keeps its permissions.
+func CreateAtomic(rfs FileSystem, name string, perm fs.FileMode) (*AtomicFile, error) {
+	if info, err := rfs.Stat(name); err == nil {
+		perm = info.Mode().Perm()
+	}
+	temp, err := rfs.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := temp.Chmod(perm); err != nil {
+		temp.Close()            // ignore error; we're already handling an error
+		rfs.Remove(temp.Name()) // ignore error; we're already handling an error
+		return nil, err
+	}
+	return &AtomicFile{rfs: rfs, temp: temp, name: name}, nil
+}
+
+// Name returns the destination path of the file.
+func (f *AtomicFile) Name() string {
+	return f.name
+}
+
+// Write writes p to the temporary file.
+func (f *AtomicFile) Write(p []byte) (int, error) {
+	if f.done {
+		return 0, fs.ErrClosed
+	}
+	n, err := f.temp.Write(p)
+	return n, wrapIO("write", f.name, err)
+}
+
+// Commit flushes the temporary file to disk, closes it and renames it over the destination, then
+// flushes the directory if the file system is a DirSyncer. If any step up to the rename fails,
+// the temporary file is removed and the destination is left untouched.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return fs.ErrClosed
+	}
+	f.done = true
+
+	if err := f.temp.Sync(); err != nil {
+		f.temp.Close()
+		f.rfs.Remove(f.temp.Name())
+		return wrapIO("sync", f.name, err)
+	}
+	if err := f.temp.Close(); err != nil {
+		f.rfs.Remove(f.temp.Name())
+		return wrapIO("close", f.name, err)
+	}
+	if err := f.rfs.Rename(f.temp.Name(), f.name); err != nil {
+		f.rfs.Remove(f.temp.Name())
+		return err
+	}
+	if ds, ok := f.rfs.(DirSyncer); ok {
+		return ds.SyncDir(filepath.Dir(f.name))
+	}
+	return nil
+}
+
+// Discard closes and removes the temporary file without touching the destination.
+// It does nothing if the file has already been committed or discarded, so it is safe to defer.
+func (f *AtomicFile) Discard() error {
+	if f.done {
+		return nil
+	}
+	f.done = true
+
+	f.temp.Close() // ignore error; the file is removed anyway
+	return f.rfs.Remove(f.temp.Name())
+}
+
+// WriteFileAtomic writes data to the file name through rfs using CreateAtomic, so that the file
+// is either replaced completely or not at all.
+func WriteFileAtomic(rfs FileSystem, name string, data []byte, perm fs.FileMode) error {
+	f, err := CreateAtomic(rfs, name, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Discard()
+		return err
+	}
+	return f.Commit()
+}
@@ -0,0 +1,83 @@
+// Below, the package filesystem (@atomic_test.go) tests that atomic writes only replace their
+// destination once committed, keep its permissions and flush its directory.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package filesystem_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestAtomicWrites verifies that files are written with their permissions, and that an atomic
+// write neither touches its destination before it is committed nor leaves files behind when it
+// is discarded.
+func TestAtomicWrites(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "messages.csv")
+	if err := (filesystem.RealFileSystem{}).WriteFile(outputPath, []byte("previous export"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	if info, err := os.Stat(outputPath); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("Stat() = %v, %v, want a file with permissions 0644", info, err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	f, err := filesystem.CreateAtomic(mockFS, "dataset.json", 0644)
+	if err != nil {
+		t.Fatalf("CreateAtomic() returned error: %v", err)
+	}
+	io.WriteString(f, "partial")
+	if exists, _ := mockFS.FileExists("dataset.json"); exists {
+		t.Error("CreateAtomic() wrote to the destination before Commit()")
+	}
+	if err := f.Discard(); err != nil {
+		t.Fatalf("Discard() returned error: %v", err)
+	}
+	if len(mockFS.Files) != 0 {
+		t.Errorf("Discard() left files behind: %v", mockFS.Files)
+	}
+}
+
+// TestAtomicCommit verifies that replacing a file keeps its permissions, and that Commit flushes
+// the directory of the destination on the file systems that can.
+func TestAtomicCommit(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "sessions.csv")
+	rfs := filesystem.RealFileSystem{}
+	if err := rfs.WriteFile(outputPath, []byte("previous export"), 0600); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	if err := os.Chmod(outputPath, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := rfs.WriteFile(outputPath, []byte("new export"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	if info, err := os.Stat(outputPath); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0640) {
+		t.Errorf("Stat() after replacing the file = %v, %v, want the permissions 0640 kept", info, err)
+	}
+
+	syncFS := &dirSyncFS{MockFileSystem: filesystem.NewMockFileSystem()}
+	if err := filesystem.WriteFileAtomic(syncFS, filepath.Join("exports", "dataset.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("WriteFileAtomic() returned error: %v", err)
+	}
+	if len(syncFS.synced) != 1 || syncFS.synced[0] != "exports" {
+		t.Errorf("Commit() synced the directories %v, want [exports]", syncFS.synced)
+	}
+}
+
+// dirSyncFS is a MockFileSystem recording the directories synced through it.
+type dirSyncFS struct {
+	*filesystem.MockFileSystem
+	synced []string
+}
+
+// SyncDir records dir.
+func (s *dirSyncFS) SyncDir(dir string) error {
+	s.synced = append(s.synced, dir)
+	return nil
+}
@@ -0,0 +1,68 @@
+// Below, the package filesystem (@errors.go) provides IOError, the error returned by the file
+// system operations of RealFileSystem and MockFileSystem, so that callers can tell a failure to
+// read or write a file from invalid data with errors.Is(err, ErrIO).
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// ErrIO is matched by every IOError with errors.Is.
+var ErrIO = errors.New("file system error")
+
+// IOError records a failed file system operation and the path it failed on. It unwraps to the
+// cause, so that errors.Is(err, fs.ErrNotExist) and the like keep working.
+type IOError struct {
+	Op   string // Operation that failed, such as "open", "write" or "rename".
+	Path string // Path of the file the operation failed on.
+	Err  error  // Cause of the failure.
+}
+
+// Error returns the operation, the path and the cause, as *fs.PathError does.
+func (e *IOError) Error() string {
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the cause of the failure.
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrIO.
+func (e *IOError) Is(target error) bool {
+	return target == ErrIO
+}
+
+// IsIO reports whether err is a failure to read or write a file: an IOError, or an error of the
+// os package such as the *fs.PathError returned by the Write method of an *os.File.
+func IsIO(err error) bool {
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	return errors.Is(err, ErrIO) || errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &syscallErr)
+}
+
+// wrapIO returns err as an IOError of op on path, or nil if err is nil. The operation and path of
+// an *fs.PathError or *os.LinkError are kept.
+func wrapIO(op, path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var ioErr *IOError
+	if errors.As(err, &ioErr) {
+		return err
+	}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &IOError{Op: pathErr.Op, Path: pathErr.Path, Err: pathErr.Err}
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return &IOError{Op: linkErr.Op, Path: linkErr.Old + " " + linkErr.New, Err: linkErr.Err}
+	}
+	return &IOError{Op: op, Path: path, Err: err}
+}
@@ -0,0 +1,31 @@
+// Below, the package filesystem (@errors_test.go) tests that failures to read a file are
+// reported as IOError values.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package filesystem_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestIOError verifies that a missing file is reported as an IOError matching os.ErrNotExist, and
+// that FileExists reports it without an error.
+func TestIOError(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	_, err := (filesystem.RealFileSystem{}).Open(missing)
+	var ioErr *filesystem.IOError
+	if !errors.Is(err, filesystem.ErrIO) || !errors.Is(err, os.ErrNotExist) || !errors.As(err, &ioErr) || ioErr.Path != missing {
+		t.Errorf("RealFileSystem.Open() of a missing file = %v, want an IOError matching os.ErrNotExist", err)
+	}
+	if exists, err := (filesystem.RealFileSystem{}).FileExists(missing); exists || err != nil {
+		t.Errorf("RealFileSystem.FileExists() of a missing file = %v, %v, want false, nil", exists, err)
+	}
+	if _, err := filesystem.NewMockFileSystem().ReadFile("missing.json"); !errors.Is(err, filesystem.ErrIO) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("MockFileSystem.ReadFile() of a missing file = %v, want an IOError matching os.ErrNotExist", err)
+	}
+}
@@ -4,50 +4,97 @@
 package filesystem
 
 import (
+	"errors"
+	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"runtime"
 )
 
+// Ensure RealFileSystem adheres to the FileSystem interface and *os.File to the File interface.
+var (
+	_ FileSystem = RealFileSystem{}
+	_ DirSyncer  = RealFileSystem{}
+	_ File       = (*os.File)(nil)
+)
+
+// File is the handle returned by FileSystem.Create. It is the subset of *os.File methods
+// the exporter needs, so that a FileSystem can hand out real or in-memory files alike.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Seeker
+	io.Closer
+	Name() string               // Name returns the name of the file as passed to Create.
+	Stat() (fs.FileInfo, error) // Stat returns the FileInfo describing the file.
+	Sync() error                // Sync commits the contents of the file to stable storage.
+	Chmod(mode fs.FileMode) error
+}
+
 // FileSystem is an interface that abstracts file system operations such as creating
 // files, writing to files, and retrieving file information. This allows for implementations
 // that can interact with the file system or provide mock functionality for testing purposes.
 // FileSystem interface now includes ReadFile method.
 type FileSystem interface {
-	Create(name string) (*os.File, error)
+	Create(name string) (File, error)
+	CreateTemp(dir, pattern string) (File, error)
 	WriteFile(name string, data []byte, perm fs.FileMode) error
 	ReadFile(name string) ([]byte, error) // Added ReadFile method
 	Stat(name string) (os.FileInfo, error)
 	FileExists(name string) (bool, error) // Added FileExists method to the interface
+	MkdirAll(path string, perm fs.FileMode) error
+	Open(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Glob(pattern string) ([]string, error)
 }
 
 // RealFileSystem implements the FileSystem interface by wrapping the os package functions,
-// thus providing an actual file system interaction mechanism.
+// thus providing an actual file system interaction mechanism. Its operations fail with an *IOError.
 type RealFileSystem struct{}
 
-// Create creates a new file with the given name.
-// It wraps the os.Create function and returns a pointer to the created file along with any error encountered.
-func (rfs RealFileSystem) Create(name string) (*os.File, error) {
-	return os.Create(name)
+// Create creates or truncates the file with the given name.
+// It wraps the os.Create function and returns the created file along with any error encountered.
+func (rfs RealFileSystem) Create(name string) (File, error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, wrapIO("create", name, err) // Avoid returning a non-nil File holding a nil *os.File.
+	}
+	return file, nil
+}
+
+// CreateTemp creates a new temporary file in dir with a name built from pattern, as os.CreateTemp does.
+func (rfs RealFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, wrapIO("createtemp", dir, err) // Avoid returning a non-nil File holding a nil *os.File.
+	}
+	return file, nil
 }
 
-// WriteFile writes data to a file named by filename.
-// If the file does not exist, WriteFile creates it with permissions perm;
-// otherwise WriteFile truncates it before writing.
+// WriteFile writes data to a file named by filename, creating it with permissions perm.
+// The data is written to a temporary file in the same directory, synced and renamed into place
+// (see WriteFileAtomic), so an interrupted write never leaves a truncated file behind.
 func (rfs RealFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
-	return os.WriteFile(name, data, perm)
+	return WriteFileAtomic(rfs, name, data, perm)
 }
 
 // ReadFile reads the named file and returns the contents.
 // It wraps the os.ReadFile function.
 func (rfs RealFileSystem) ReadFile(name string) ([]byte, error) {
-	return os.ReadFile(name)
+	data, err := os.ReadFile(name)
+	return data, wrapIO("open", name, err)
 }
 
 // Stat returns the FileInfo structure describing the file named by the given name.
 // It wraps the os.Stat function and returns the FileInfo and any error encountered, for instance,
 // if the file does not exist.
 func (rfs RealFileSystem) Stat(name string) (os.FileInfo, error) {
-	return os.Stat(name)
+	info, err := os.Stat(name)
+	return info, wrapIO("stat", name, err)
 }
 
 // FileExists checks if a file exists in the file system at the given path.
@@ -58,8 +105,73 @@ func (rfs RealFileSystem) FileExists(name string) (bool, error) {
 	if err == nil {
 		return true, nil // File exists
 	}
-	if os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return false, nil // File does not exist
 	}
 	return false, err // Some other error occurred
 }
+
+// MkdirAll creates the directory path along with any necessary parents.
+// It wraps the os.MkdirAll function and does nothing if path is already a directory.
+func (rfs RealFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	return wrapIO("mkdir", path, os.MkdirAll(path, perm))
+}
+
+// Open opens the named file for reading.
+// It wraps the os.Open function and returns the opened file along with any error encountered.
+func (rfs RealFileSystem) Open(name string) (File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, wrapIO("open", name, err) // Avoid returning a non-nil File holding a nil *os.File.
+	}
+	return file, nil
+}
+
+// OpenFile opens the named file with the given flags (os.O_APPEND, os.O_CREATE, ...) and permissions.
+// It wraps the os.OpenFile function, for instance to append to an existing file.
+func (rfs RealFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	file, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, wrapIO("open", name, err) // Avoid returning a non-nil File holding a nil *os.File.
+	}
+	return file, nil
+}
+
+// Rename renames (moves) oldpath to newpath, replacing newpath if it already exists.
+// It wraps the os.Rename function.
+func (rfs RealFileSystem) Rename(oldpath, newpath string) error {
+	return wrapIO("rename", oldpath, os.Rename(oldpath, newpath))
+}
+
+// Remove removes the named file or empty directory.
+// It wraps the os.Remove function.
+func (rfs RealFileSystem) Remove(name string) error {
+	return wrapIO("remove", name, os.Remove(name))
+}
+
+// ReadDir reads the named directory and returns its entries sorted by file name.
+// It wraps the os.ReadDir function.
+func (rfs RealFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := os.ReadDir(name)
+	return entries, wrapIO("readdir", name, err)
+}
+
+// Glob returns the names of all files matching pattern, or nil if there is no matching file.
+// It wraps the filepath.Glob function; the only possible error is filepath.ErrBadPattern.
+func (rfs RealFileSystem) Glob(pattern string) ([]string, error) {
+	return filepath.Glob(pattern)
+}
+
+// SyncDir commits the entries of the directory dir, such as a file just renamed into it, to
+// stable storage. Directories cannot be opened for syncing on Windows, where it does nothing.
+func (rfs RealFileSystem) SyncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return wrapIO("sync", dir, err)
+	}
+	defer d.Close()
+	return wrapIO("sync", dir, d.Sync())
+}
@@ -9,14 +9,17 @@
 package filesystem
 
 import (
-	"bytes"
-	"context"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
-	"unsafe" // this package is used to convert MockFile as Expert in the Real World.
-
-	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
 )
 
 // Ensure MockFileSystem adheres to the FileSystem interface.
@@ -24,7 +27,12 @@ var _ FileSystem = (*MockFileSystem)(nil)
 
 // MockFileSystem is a mock implementation of the FileSystem interface that can be used in tests.
 // It uses a map to store file names and associated data, allowing for the simulation of file creation,
-// reading, and writing without actual file system interaction.
+// reading, and writing without actual file system interaction. Its operations fail with an *IOError, as
+// those of RealFileSystem do.
+//
+// MockFileSystem is safe for concurrent use, and doubles as the in-memory file system exports are
+// collected in before being bundled: with Base set, the files it does not hold are read from Base,
+// so that an export written to memory can still read its inputs, such as fonts, from the disk.
 type MockFileSystem struct {
 	Files                 map[string][]byte // Files maps file names to file contents.
 	WriteFileCalled       bool              // Track if WriteFile has been called.
@@ -37,90 +45,126 @@ type MockFileSystem struct {
 	ReadFileCalled        bool              // this field to track if ReadFile has been caled.
 	ReadFileData          []byte            // Optionally track the data provided to ReadFile.
 	ReadFileErr           error             // Optionally track the error provider to ReadFile.
+	Dirs                  map[string]bool   // Dirs records the directories created with MkdirAll.
+	Base                  FileSystem        // Base, if set, is read from by ReadFile, Open, Stat and FileExists for the files not in Files.
+	tempCounter           int               // tempCounter makes the names returned by CreateTemp unique.
+	mu                    sync.Mutex        // mu guards the fields above while the mock is in use.
 }
 
-// MockExporter is a mock implementation of the exporter.Exporter interface for testing purposes.
-// It allows for the simulation of session conversion to CSV format and can be set to return errors for testing error handling.
-//
-// Note: this types is proof of concept after touring golang.
-type MockExporter struct {
-	ErrToReturn error // ErrToReturn is the error that ConvertSessionsToCSV will return when called.
-}
-
-// mockFileInfo is a dummy implementation of fs.FileInfo used for testing.
-// It provides basic implementations of the fs.FileInfo interface methods.
+// mockFileInfo is a minimal implementation of fs.FileInfo used for testing.
 type mockFileInfo struct {
 	name string // name is the file name.
-	*bytes.Buffer
+	size int64  // size is the length of the file contents in bytes.
+	dir  bool   // dir reports whether the entry is a directory.
 }
 
-// MockFile simulates an os.File for testing purposes, may you not using this if you are not Expert.
-type MockFile struct {
-	name     string
-	contents *bytes.Buffer
+// mockDirEntry is a minimal implementation of fs.DirEntry returned by MockFileSystem.ReadDir.
+type mockDirEntry struct {
+	info mockFileInfo // info describes the entry.
 }
 
-// FileLike is an interface that includes the subset of *os.File methods that you need to mock.
-type FileLike interface {
-	Close() error
-	Read(p []byte) (n int, err error)
-	Write(p []byte) (n int, err error)
-	Seek(offset int64, whence int) (int64, error)
+// MockFile is an in-memory File returned by MockFileSystem.Create.
+// Every write is recorded back into the Files map of the file system that created it,
+// so the contents can be inspected at any time, before or after Close.
+type MockFile struct {
+	fs       *MockFileSystem // fs is the file system whose Files map holds the contents.
+	name     string          // name is the file name as passed to Create or Open.
+	offset   int64           // offset is the current read/write position.
+	closed   bool            // closed reports whether Close has been called.
+	readOnly bool            // readOnly reports whether the file was opened with Open.
+	append   bool            // append reports whether every write goes to the end of the file.
 }
 
-// ConvertSessionsToCSV simulates the conversion of sessions to CSV format.
-// It returns an error specified by ErrToReturn, allowing for error handling tests.
+// Ensure MockFile adheres to the File interface.
+var _ File = (*MockFile)(nil)
+
+// FileLike is the former name of the File interface.
 //
-// Note: this function is proof of concept after touring golang.
-func (m *MockExporter) ConvertSessionsToCSV(ctx context.Context, sessions []exporter.Session, formatOption int, csvFileName string) error {
-	return m.ErrToReturn
-}
+// Deprecated: Use File instead.
+type FileLike = File
 
 // NewMockFileSystem creates a new instance of MockFileSystem with initialized internal structures.
 func NewMockFileSystem() *MockFileSystem {
 	return &MockFileSystem{
 		Files: make(map[string][]byte),
+		Dirs:  make(map[string]bool),
 	}
 }
 
-// Stat returns the FileInfo for the given file name if it exists in the mock file system.
+// Stat returns the FileInfo for the given file or directory name if it exists in the mock file system.
 // If the file does not exist, it returns an error to simulate the os.Stat behavior.
 func (m *MockFileSystem) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
 	if _, ok := m.Files[name]; ok {
+		defer m.mu.Unlock()
 		// Return mock file information.
-		return mockFileInfo{name: name}, nil
+		return mockFileInfo{name: filepath.Base(name), size: int64(len(m.Files[name]))}, nil
+	}
+	if m.isDir(name) {
+		defer m.mu.Unlock()
+		return mockFileInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	m.mu.Unlock()
+	if m.Base != nil {
+		return m.Base.Stat(name)
 	}
-	return nil, os.ErrNotExist
+	return nil, &IOError{Op: "stat", Path: name, Err: fs.ErrNotExist}
 }
 
-// Create simulates the creation of a file by adding a new entry in the Files map.
-func (m *MockFileSystem) Create(name string) (*os.File, error) {
-	if _, exists := m.Files[name]; exists {
-		return nil, os.ErrExist
-	}
+// Create simulates os.Create by adding an empty entry to the Files map, truncating any existing
+// contents, and returns an in-memory file whose writes land in that entry.
+func (m *MockFileSystem) Create(name string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.create(name), nil
+}
+
+// create implements Create with m.mu held.
+func (m *MockFileSystem) create(name string) *MockFile {
 	m.Files[name] = []byte{}
-	mockFile := &MockFile{
-		name:     name,
-		contents: bytes.NewBuffer([]byte{}),
+	return &MockFile{fs: m, name: name}
+}
+
+// CreateTemp simulates os.CreateTemp by creating an empty file in dir whose name is pattern with
+// the last "*" replaced by a unique number (or the number appended if pattern has no "*").
+func (m *MockFileSystem) CreateTemp(dir, pattern string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for {
+		m.tempCounter++
+		suffix := strconv.Itoa(m.tempCounter)
+		name := pattern + suffix
+		if i := strings.LastIndex(pattern, "*"); i >= 0 {
+			name = pattern[:i] + suffix + pattern[i+1:]
+		}
+		name = filepath.Join(dir, name)
+		if _, exists := m.Files[name]; !exists {
+			return m.create(name), nil
+		}
 	}
-	// You would need to convert MockFile to *os.File using an interface or other means.
-	// This is a simplified example and may not work directly without additional setup.
-	var file *os.File = (*os.File)(unsafe.Pointer(mockFile)) // Unsafe conversion for example purposes.
-	return file, nil
 }
 
 // ReadFile simulates reading the content of a file from the Files map.
-// If the file exists, it returns the content as a byte slice; otherwise, it returns an error.
+// If the file exists, it returns the content as a byte slice; otherwise, it reads it from Base if set,
+// or returns an error.
 func (m *MockFileSystem) ReadFile(name string) ([]byte, error) {
-	if content, ok := m.Files[name]; ok {
+	m.mu.Lock()
+	content, ok := m.Files[name]
+	m.mu.Unlock()
+	if ok {
 		return content, nil
 	}
-	return nil, fs.ErrNotExist
+	if m.Base != nil {
+		return m.Base.ReadFile(name)
+	}
+	return nil, &IOError{Op: "open", Path: name, Err: fs.ErrNotExist}
 }
 
 // WriteFile simulates writing data to a file in the Files map.
 // It creates a new buffer with the provided data, simulating a successful write operation.
 func (m *MockFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.Files[name] = data
 	m.WriteFileCalled = true // Set this to true when WriteFile is called.
 	m.WriteFilePath = name   // Record the path.
@@ -129,39 +173,342 @@ func (m *MockFileSystem) WriteFile(name string, data []byte, perm fs.FileMode) e
 	return nil
 }
 
-// FileExists checks if the given file name exists in the mock file system.
+// FileExists checks if the given file name exists in the mock file system, or else in Base if set.
 func (m *MockFileSystem) FileExists(name string) (bool, error) {
+	m.mu.Lock()
 	m.FileExistsCalled = true // Record that FileExists was called
-	if m.FileExistsShouldError {
+	shouldError, exists := m.FileExistsShouldError, false
+	if !shouldError {
+		_, exists = m.Files[name]
+	}
+	m.mu.Unlock()
+	switch {
+	case shouldError:
 		return false, m.FileExistsErr
+	case !exists && m.Base != nil:
+		return m.Base.FileExists(name)
 	}
-	_, exists := m.Files[name]
 	return exists, nil
 }
 
+// MkdirAll records path and all of its parents as directories.
+// Files can be created without calling MkdirAll first; directories only need to exist for ReadDir, Stat and Glob.
+func (m *MockFileSystem) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(path)
+}
+
+// mkdirAll implements MkdirAll with m.mu held.
+func (m *MockFileSystem) mkdirAll(path string) error {
+	if m.Dirs == nil {
+		m.Dirs = make(map[string]bool)
+	}
+	for dir := filepath.Clean(path); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if _, ok := m.Files[dir]; ok {
+			return &IOError{Op: "mkdir", Path: dir, Err: syscall.ENOTDIR}
+		}
+		m.Dirs[dir] = true
+	}
+	return nil
+}
+
+// Open returns a read-only in-memory file for an existing entry of the Files map, or else opens
+// the file in Base if set.
+func (m *MockFileSystem) Open(name string) (File, error) {
+	m.mu.Lock()
+	_, ok := m.Files[name]
+	m.mu.Unlock()
+	switch {
+	case ok:
+		return &MockFile{fs: m, name: name, readOnly: true}, nil
+	case m.Base != nil:
+		return m.Base.Open(name)
+	}
+	return nil, &IOError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// OpenFile simulates os.OpenFile. It honours os.O_CREATE, os.O_EXCL, os.O_TRUNC and os.O_APPEND,
+// and returns a read-only file when opened with os.O_RDONLY.
+func (m *MockFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, exists := m.Files[name]
+	switch {
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &IOError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, &IOError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !exists || flag&os.O_TRUNC != 0:
+		m.Files[name] = []byte{}
+	}
+	readOnly := flag&(os.O_WRONLY|os.O_RDWR) == 0
+	return &MockFile{fs: m, name: name, readOnly: readOnly, append: flag&os.O_APPEND != 0}, nil
+}
+
+// Rename moves a file, or a directory together with everything below it, from oldpath to newpath.
+// An existing file at newpath is replaced, as with os.Rename.
+func (m *MockFileSystem) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data, ok := m.Files[oldpath]; ok {
+		delete(m.Files, oldpath)
+		m.Files[newpath] = data
+		return nil
+	}
+	if !m.isDir(oldpath) {
+		return &IOError{Op: "rename", Path: oldpath, Err: fs.ErrNotExist}
+	}
+
+	oldDir, newDir := filepath.Clean(oldpath), filepath.Clean(newpath)
+	for name, data := range m.Files {
+		if rel, ok := relativeTo(oldDir, name); ok {
+			delete(m.Files, name)
+			m.Files[filepath.Join(newDir, rel)] = data
+		}
+	}
+	for dir := range m.Dirs {
+		if rel, ok := relativeTo(oldDir, dir); ok || dir == oldDir {
+			delete(m.Dirs, dir)
+			m.Dirs[filepath.Join(newDir, rel)] = true
+		}
+	}
+	return m.mkdirAll(newDir)
+}
+
+// Remove deletes a file or an empty directory.
+func (m *MockFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.Files[name]; ok {
+		delete(m.Files, name)
+		return nil
+	}
+	if !m.isDir(name) {
+		return &IOError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	if entries, _ := m.readDir(name); len(entries) > 0 {
+		return &IOError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+	}
+	delete(m.Dirs, filepath.Clean(name))
+	return nil
+}
+
+// ReadDir returns the files and directories directly inside the named directory, sorted by name.
+func (m *MockFileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readDir(name)
+}
+
+// readDir implements ReadDir with m.mu held.
+func (m *MockFileSystem) readDir(name string) ([]fs.DirEntry, error) {
+	if !m.isDir(name) {
+		return nil, &IOError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	dir := filepath.Clean(name)
+	children := make(map[string]mockFileInfo)
+	for _, path := range m.paths() {
+		rel, ok := relativeTo(dir, path)
+		if !ok {
+			continue
+		}
+		child, _, nested := strings.Cut(rel, string(filepath.Separator))
+		if nested || m.isDir(path) {
+			children[child] = mockFileInfo{name: child, dir: true}
+		} else {
+			children[child] = mockFileInfo{name: child, size: int64(len(m.Files[path]))}
+		}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, mockDirEntry{info: info})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Glob returns the names of all files and directories matching pattern, sorted, with the
+// same pattern syntax as filepath.Glob.
+func (m *MockFileSystem) Glob(pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pattern = filepath.Clean(pattern)
+	var matches []string
+	for _, path := range m.paths() {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			matches = append(matches, path)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// paths returns the cleaned names of all files and directories, including the implicit parent
+// directories of every file.
+func (m *MockFileSystem) paths() []string {
+	seen := make(map[string]bool)
+	add := func(path string) {
+		for path = filepath.Clean(path); path != "." && path != string(filepath.Separator) && !seen[path]; path = filepath.Dir(path) {
+			seen[path] = true
+		}
+	}
+	for name := range m.Files {
+		add(name)
+	}
+	for dir := range m.Dirs {
+		add(dir)
+	}
+
+	paths := make([]string, 0, len(seen))
+	for path := range seen {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// isDir reports whether name is the current directory, a directory created with MkdirAll, or the
+// parent of any file or directory in the mock file system.
+func (m *MockFileSystem) isDir(name string) bool {
+	dir := filepath.Clean(name)
+	if dir == "." || m.Dirs[dir] {
+		return true
+	}
+	if _, ok := m.Files[name]; ok {
+		return false
+	}
+	for _, path := range m.paths() {
+		if _, ok := relativeTo(dir, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// relativeTo returns path relative to dir if path lies strictly below dir.
+func relativeTo(dir, path string) (string, bool) {
+	path = filepath.Clean(path)
+	if dir == "." {
+		return path, path != "." && !filepath.IsAbs(path)
+	}
+	rel, ok := strings.CutPrefix(path, dir+string(filepath.Separator))
+	return rel, ok && rel != ""
+}
+
 // Implement the Close method if needed for testing
 func (mf *MockFileSystem) Close() error {
 	return nil
 }
 
-// Close simulates closing the file, it's a no-op for the mock.
+// Name returns the name of the file as passed to Create.
+func (mf *MockFile) Name() string {
+	return mf.name
+}
+
+// Close marks the file as closed; any further operation returns os.ErrClosed.
 func (mf *MockFile) Close() error {
-	return nil // No-op for the mock.
+	if mf.closed {
+		return os.ErrClosed
+	}
+	mf.closed = true
+	return nil
 }
 
-// Write simulates writing bytes to the file.
+// Write writes p at the current offset, or at the end of the file if it was opened with
+// os.O_APPEND, growing the file as needed, and records the resulting contents in the Files map.
 func (mf *MockFile) Write(p []byte) (n int, err error) {
-	return mf.contents.Write(p)
+	if mf.closed {
+		return 0, os.ErrClosed
+	}
+	if mf.readOnly {
+		return 0, &IOError{Op: "write", Path: mf.name, Err: fs.ErrPermission}
+	}
+	mf.fs.mu.Lock()
+	defer mf.fs.mu.Unlock()
+	data := mf.fs.Files[mf.name]
+	if mf.append {
+		mf.offset = int64(len(data))
+	}
+	if end := mf.offset + int64(len(p)); end > int64(len(data)) {
+		grown := make([]byte, end)
+		copy(grown, data)
+		data = grown
+	}
+	n = copy(data[mf.offset:], p)
+	mf.offset += int64(n)
+	mf.fs.Files[mf.name] = data
+	return n, nil
 }
 
-// Read simulates reading bytes from the file.
+// Read reads from the current offset of the file contents held in the Files map.
 func (mf *MockFile) Read(p []byte) (n int, err error) {
-	return mf.contents.Read(p)
+	if mf.closed {
+		return 0, os.ErrClosed
+	}
+	mf.fs.mu.Lock()
+	defer mf.fs.mu.Unlock()
+	data := mf.fs.Files[mf.name]
+	if mf.offset >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n = copy(p, data[mf.offset:])
+	mf.offset += int64(n)
+	return n, nil
 }
 
-// Seek simulates seeking in the file, it's a no-op for the mock.
+// Seek sets the offset for the next Read or Write, following the semantics of os.File.Seek.
 func (mf *MockFile) Seek(offset int64, whence int) (int64, error) {
-	return 0, nil // No-op for the mock.
+	if mf.closed {
+		return 0, os.ErrClosed
+	}
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += mf.offset
+	case io.SeekEnd:
+		mf.fs.mu.Lock()
+		offset += int64(len(mf.fs.Files[mf.name]))
+		mf.fs.mu.Unlock()
+	default:
+		return 0, fmt.Errorf("seek %s: invalid whence %d", mf.name, whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("seek %s: negative position", mf.name)
+	}
+	mf.offset = offset
+	return offset, nil
+}
+
+// Sync simulates committing the file to stable storage, it's a no-op for the mock.
+func (mf *MockFile) Sync() error {
+	if mf.closed {
+		return os.ErrClosed
+	}
+	return nil
+}
+
+// Chmod simulates changing the file mode, it's a no-op for the mock.
+func (mf *MockFile) Chmod(mode fs.FileMode) error {
+	if mf.closed {
+		return os.ErrClosed
+	}
+	return nil
+}
+
+// Stat returns the FileInfo describing the file.
+func (mf *MockFile) Stat() (fs.FileInfo, error) {
+	if mf.closed {
+		return nil, os.ErrClosed
+	}
+	mf.fs.mu.Lock()
+	defer mf.fs.mu.Unlock()
+	return mockFileInfo{name: filepath.Base(mf.name), size: int64(len(mf.fs.Files[mf.name]))}, nil
 }
 
 // Name returns the file name.
@@ -171,12 +518,15 @@ func (m mockFileInfo) Name() string {
 
 // Size returns the size of the file.
 func (m mockFileInfo) Size() int64 {
-	return 0 // Dummy value for size.
+	return m.size
 }
 
-// Mode returns the file mode.
+// Mode returns the file mode, which only carries the directory bit.
 func (m mockFileInfo) Mode() fs.FileMode {
-	return 0 // Dummy value for file mode.
+	if m.dir {
+		return fs.ModeDir
+	}
+	return 0
 }
 
 // ModTime returns the modification time of the file.
@@ -186,10 +536,30 @@ func (m mockFileInfo) ModTime() time.Time {
 
 // IsDir reports whether the file is a directory.
 func (m mockFileInfo) IsDir() bool {
-	return false // Dummy value, always false.
+	return m.dir
 }
 
 // Sys returns the underlying data source (can return nil).
 func (m mockFileInfo) Sys() interface{} {
 	return nil // No system-specific information.
 }
+
+// Name returns the name of the entry.
+func (e mockDirEntry) Name() string {
+	return e.info.Name()
+}
+
+// IsDir reports whether the entry describes a directory.
+func (e mockDirEntry) IsDir() bool {
+	return e.info.IsDir()
+}
+
+// Type returns the type bits of the entry.
+func (e mockDirEntry) Type() fs.FileMode {
+	return e.info.Mode().Type()
+}
+
+// Info returns the FileInfo of the entry.
+func (e mockDirEntry) Info() (fs.FileInfo, error) {
+	return e.info, nil
+}
@@ -0,0 +1,158 @@
+// Below, the package filesystem (@file_system_mock_test.go) tests that MockFileSystem behaves like
+// the real file system, reads through its base and can be used concurrently.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package filesystem_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// TestMockFileSystemCreate verifies that files created through the mock file system behave like
+// real files and that everything written to them lands in Files.
+func TestMockFileSystemCreate(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["existing.txt"] = []byte("old contents")
+
+	file, err := mockFS.Create("existing.txt")
+	if err != nil {
+		t.Fatalf("Create() returned error: %v", err)
+	}
+	if len(mockFS.Files["existing.txt"]) != 0 {
+		t.Errorf("Create() did not truncate the existing file: %q", mockFS.Files["existing.txt"])
+	}
+	if _, err := io.WriteString(file, "Hello Gopher"); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if got := string(mockFS.Files["existing.txt"]); got != "Hello Gopher" {
+		t.Errorf("Files[existing.txt] = %q, want %q", got, "Hello Gopher")
+	}
+
+	if _, err := file.Seek(6, io.SeekStart); err != nil {
+		t.Fatalf("Seek() returned error: %v", err)
+	}
+	if _, err := io.WriteString(file, "World!"); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek() returned error: %v", err)
+	}
+	content, err := io.ReadAll(file)
+	if err != nil || string(content) != "Hello World!" {
+		t.Errorf("ReadAll() = %q, %v, want %q", content, err, "Hello World!")
+	}
+	if info, err := file.Stat(); err != nil || info.Size() != int64(len("Hello World!")) || info.Name() != "existing.txt" {
+		t.Errorf("Stat() = %v, %v, want existing.txt with size %d", info, err, len("Hello World!"))
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if _, err := file.Write([]byte("late")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Write() after Close() returned %v, want os.ErrClosed", err)
+	}
+
+}
+
+// TestMockFileSystemDirectories verifies the directory, rename, remove, open and glob operations of
+// the mock file system.
+func TestMockFileSystemDirectories(t *testing.T) {
+	mockFS := filesystem.NewMockFileSystem()
+	if err := mockFS.MkdirAll(filepath.Join("out", "empty"), 0755); err != nil {
+		t.Fatalf("MkdirAll() returned error: %v", err)
+	}
+	mockFS.Files[filepath.Join("out", "a.csv")] = []byte("a")
+	mockFS.Files[filepath.Join("out", "b.json")] = []byte("b")
+
+	entries, err := mockFS.ReadDir("out")
+	if err != nil {
+		t.Fatalf("ReadDir() returned error: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, fmt.Sprintf("%s:%t", entry.Name(), entry.IsDir()))
+	}
+	if got, want := strings.Join(names, ","), "a.csv:false,b.json:false,empty:true"; got != want {
+		t.Errorf("ReadDir() = %s, want %s", got, want)
+	}
+
+	matches, err := mockFS.Glob(filepath.Join("out", "*.csv"))
+	if err != nil || len(matches) != 1 || matches[0] != filepath.Join("out", "a.csv") {
+		t.Errorf("Glob() = %v, %v, want [%s]", matches, err, filepath.Join("out", "a.csv"))
+	}
+
+	if err := mockFS.Rename("out", "archive"); err != nil {
+		t.Fatalf("Rename() returned error: %v", err)
+	}
+	if _, err := mockFS.Stat(filepath.Join("archive", "empty")); err != nil {
+		t.Errorf("Stat() of the renamed directory returned error: %v", err)
+	}
+	if exists, _ := mockFS.FileExists(filepath.Join("out", "a.csv")); exists {
+		t.Error("Rename() left the old file behind")
+	}
+
+	file, err := mockFS.Open(filepath.Join("archive", "a.csv"))
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	if _, err := file.Write([]byte("x")); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("Write() on an opened file returned %v, want os.ErrPermission", err)
+	}
+	file.Close()
+
+	if err := mockFS.Remove("archive"); err == nil {
+		t.Error("Remove() deleted a non-empty directory")
+	}
+	if err := mockFS.Remove(filepath.Join("archive", "empty")); err != nil {
+		t.Errorf("Remove() of an empty directory returned error: %v", err)
+	}
+	if _, err := mockFS.Open("missing.json"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open() of a missing file returned %v, want os.ErrNotExist", err)
+	}
+
+}
+
+// TestMockFileSystemBase verifies that the files missing from the mock file system are read from
+// its Base, that writes never reach Base, and that the mock can be written to concurrently.
+func TestMockFileSystemBase(t *testing.T) {
+	base := filesystem.NewMockFileSystem()
+	base.Files["font.ttf"] = []byte("glyphs")
+	memFS := filesystem.NewMockFileSystem()
+	memFS.Base = base
+
+	if data, err := memFS.ReadFile("font.ttf"); err != nil || string(data) != "glyphs" {
+		t.Errorf("ReadFile() of a file of Base = %q, %v, want glyphs", data, err)
+	}
+	if exists, err := memFS.FileExists("font.ttf"); err != nil || !exists {
+		t.Errorf("FileExists() of a file of Base = %v, %v, want true", exists, err)
+	}
+	if _, err := memFS.Stat("missing.ttf"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() of a missing file returned %v, want os.ErrNotExist", err)
+	}
+
+	done := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func(i int) {
+			done <- filesystem.WriteFileAtomic(memFS, filepath.Join("out", fmt.Sprintf("%d.csv", i)), []byte("id\n"), 0644)
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("WriteFileAtomic() returned error: %v", err)
+		}
+	}
+	if names, _ := memFS.Glob(filepath.Join("out", "*.csv")); len(names) != 4 {
+		t.Errorf("Glob() = %v, want the 4 files written concurrently", names)
+	}
+	if len(base.Files) != 1 {
+		t.Errorf("Writes reached Base: %v", base.Files)
+	}
+}
@@ -1,3 +1,12 @@
 module github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter
 
 go 1.21.5
+
+require (
+	github.com/klauspost/compress v1.17.4
+	golang.org/x/crypto v0.17.0
+	golang.org/x/term v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.15.0 // indirect
@@ -0,0 +1,190 @@
+// Package i18n provides the localised messages of the CLI tool: its prompts, banners and errors.
+//
+// Messages are looked up by Key in a Catalogue. Catalogues are the JSON files of the catalogues
+// directory, embedded in the binary and named after their language tag, such as "en.json" or
+// "zh.json". A catalogue holds the name of its language and its messages, which may contain the
+// verbs of the fmt package:
+//
+//	{
+//		"language": "English",
+//		"messages": {
+//			"prompt.jsonFilePath": "Enter the path to the JSON file: ",
+//			"success.outputSaved": "%s output saved to %s"
+//		}
+//	}
+//
+// Adding a language only takes adding its catalogue file. Messages missing from a catalogue fall
+// back to the English catalogue.
+//
+// # Example Usage
+//
+//	catalogue, err := i18n.Load(i18n.Detect(*lang, os.Getenv))
+//	if err != nil {
+//		return err
+//	}
+//	i18n.SetDefault(catalogue)
+//	fmt.Print(i18n.T("prompt.jsonFilePath"))
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the tag of the English catalogue, used when no language is set and as the
+// fallback of the other catalogues.
+const DefaultLanguage = "en"
+
+// catalogues holds the catalogue files.
+//
+//go:embed catalogues/*.json
+var catalogues embed.FS
+
+// Key identifies a message in the catalogues.
+type Key string
+
+// Catalogue holds the messages of one language.
+type Catalogue struct {
+	Tag      string         `json:"-"`        // Language tag, from the name of the file, such as "en".
+	Language string         `json:"language"` // Name of the language, in the language itself.
+	Messages map[Key]string `json:"messages"` // Messages by key, as fmt formats.
+
+	fallback *Catalogue // Catalogue of the messages missing from Messages; nil for English.
+}
+
+// T returns the message of key formatted with args, as fmt.Sprintf does. A message missing from
+// the catalogue and its fallback is returned as its key.
+func (c *Catalogue) T(key Key, args ...any) string {
+	for ; c != nil; c = c.fallback {
+		if message, ok := c.Messages[key]; ok {
+			if len(args) == 0 {
+				return message
+			}
+			return fmt.Sprintf(message, args...)
+		}
+	}
+	return string(key)
+}
+
+// Languages returns the tags of the available catalogues, sorted.
+func Languages() []string {
+	entries, _ := catalogues.ReadDir("catalogues") // The directory is embedded, so it can be read.
+	tags := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		tags = append(tags, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Match returns the tag of the catalogue for lang, which may be a tag such as "zh-CN" or a POSIX
+// locale such as "zh_CN.UTF-8". A catalogue of the region is preferred over one of the language.
+func Match(lang string) (string, bool) {
+	lang = normalize(lang)
+	base, _, _ := strings.Cut(lang, "-")
+	var match string
+	for _, tag := range Languages() {
+		switch normalize(tag) {
+		case lang:
+			return tag, true
+		case base:
+			match = tag
+		}
+	}
+	return match, match != ""
+}
+
+// normalize returns lang in lower case with "-" between the language and the region, and
+// without the encoding and modifier of a POSIX locale.
+func normalize(lang string) string {
+	lang, _, _ = strings.Cut(lang, ".")
+	lang, _, _ = strings.Cut(lang, "@")
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// Detect returns the language to use: lang if it is set, otherwise the language of the
+// LC_ALL, LC_MESSAGES or LANG environment variable read with getenv, in that order, or
+// DefaultLanguage for the C and POSIX locales and when none is set.
+func Detect(lang string, getenv func(string) string) string {
+	if lang != "" {
+		return lang
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		switch value := getenv(name); value {
+		case "":
+		case "C", "POSIX":
+			return DefaultLanguage
+		default:
+			return value
+		}
+	}
+	return DefaultLanguage
+}
+
+// Load returns the catalogue for lang, see Match, falling back to the English catalogue.
+func Load(lang string) (*Catalogue, error) {
+	tag, ok := Match(lang)
+	if !ok {
+		return nil, fmt.Errorf("unknown language %q (available: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	return load(tag)
+}
+
+// loaded caches the catalogues by tag.
+var loaded sync.Map
+
+// load reads and caches the catalogue tag.
+func load(tag string) (*Catalogue, error) {
+	if c, ok := loaded.Load(tag); ok {
+		return c.(*Catalogue), nil
+	}
+	data, err := catalogues.ReadFile("catalogues/" + tag + ".json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalogue{Tag: tag}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid catalogue %s: %w", tag, err)
+	}
+	if tag != DefaultLanguage {
+		if c.fallback, err = load(DefaultLanguage); err != nil {
+			return nil, err
+		}
+	}
+	loaded.Store(tag, c)
+	return c, nil
+}
+
+// std is the catalogue used by T.
+var std = mustLoad(DefaultLanguage)
+
+// mustLoad returns the catalogue tag, and panics if it is missing or invalid.
+func mustLoad(tag string) *Catalogue {
+	c, err := load(tag)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Default returns the catalogue used by T, English unless SetDefault was called.
+func Default() *Catalogue {
+	return std
+}
+
+// SetDefault makes c the catalogue used by T.
+func SetDefault(c *Catalogue) {
+	std = c
+}
+
+// T returns the message of key in the default catalogue formatted with args, see Catalogue.T.
+func T(key Key, args ...any) string {
+	return std.T(key, args...)
+}
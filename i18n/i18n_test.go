@@ -0,0 +1,88 @@
+// Below, the package i18n (@i18n_test.go) tests the catalogues, the detection of the language and
+// the fallback of missing messages.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package i18n_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+)
+
+// TestCatalogues verifies that every catalogue translates the English messages with the same fmt
+// verbs, in the same order, and has no message that English lacks.
+func TestCatalogues(t *testing.T) {
+	english, err := i18n.Load(i18n.DefaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verbs := regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+	for _, tag := range i18n.Languages() {
+		catalogue, err := i18n.Load(tag)
+		if err != nil {
+			t.Fatalf("Load(%q) failed: %v", tag, err)
+		}
+		if catalogue.Language == "" {
+			t.Errorf("catalogue %s has no language name", tag)
+		}
+		for key, message := range english.Messages {
+			translation, ok := catalogue.Messages[key]
+			if !ok {
+				t.Errorf("catalogue %s is missing %q", tag, key)
+				continue
+			}
+			if got, want := verbs.FindAllString(translation, -1), verbs.FindAllString(message, -1); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("catalogue %s translates %q with verbs %v, want %v", tag, key, got, want)
+			}
+		}
+		for key := range catalogue.Messages {
+			if _, ok := english.Messages[key]; !ok {
+				t.Errorf("catalogue %s has %q, which is not an English message", tag, key)
+			}
+		}
+	}
+	if _, err := i18n.Load("xx"); err == nil {
+		t.Error("Load of an unknown language succeeded")
+	}
+}
+
+// TestDetect verifies that the flag wins over the environment, which is read like a POSIX locale.
+func TestDetect(t *testing.T) {
+	env := map[string]string{"LANG": "zh_CN.UTF-8"}
+	getenv := func(name string) string { return env[name] }
+	for _, tc := range []struct {
+		flag, lcAll, want string
+	}{
+		{"", "", "zh"},
+		{"id", "", "id"},
+		{"", "id_ID.UTF-8", "id"},
+		{"", "C", "en"},
+		{"en-GB", "", "en"},
+	} {
+		env["LC_ALL"] = tc.lcAll
+		catalogue, err := i18n.Load(i18n.Detect(tc.flag, getenv))
+		if err != nil || catalogue.Tag != tc.want {
+			t.Errorf("language for flag %q and LC_ALL %q = %v, %v, want %s", tc.flag, tc.lcAll, catalogue, err, tc.want)
+		}
+	}
+}
+
+// TestFallback verifies that missing messages fall back to English, then to their key.
+func TestFallback(t *testing.T) {
+	chinese, err := i18n.Load("zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	translation := chinese.Messages["progress.exporting"]
+	delete(chinese.Messages, "progress.exporting")
+	defer func() { chinese.Messages["progress.exporting"] = translation }()
+	if got := chinese.T("progress.exporting"); got != "Exporting" {
+		t.Errorf("missing translation = %q, want the English message", got)
+	}
+	if got := chinese.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("missing message = %q, want its key", got)
+	}
+}
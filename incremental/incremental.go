@@ -0,0 +1,377 @@
+// Package incremental exports only what changed in a backup since the previous run.
+//
+// A small state file records, for every exported session, its LastUpdate, the number of messages
+// that were exported and a digest of those messages and of the session fields that were written.
+// On the next run, Compare splits the sessions of the backup into new sessions, sessions with new
+// messages, unchanged sessions, and sessions whose exported messages were edited or deleted.
+// Export then either appends the new records to the files of the previous export (formats
+// implementing exporter.AppendExporter, such as the per-line CSV, the separate CSV and JSONL
+// formats), or rewrites them when an edit makes appending impossible.
+//
+// Sessions that disappear from the backup are kept in the state and in appended files, so that
+// an incremental export also works as an archive of sessions deleted from the browser.
+//
+// # Example Usage
+//
+//	e, _ := exporter.Lookup(exporter.FormatNameJSONL)
+//	result, err := incremental.Export(ctx, rfs, e.(exporter.AppendExporter), sessions,
+//		"history.jsonl", nil, "history.state.json")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Printf("%d sessions appended, rewritten: %t\n", len(result.Changes.Increment.Sessions), result.Rewritten)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package incremental
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"sort"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+const (
+	// StateVersion is the version of the state file format written by this package.
+	StateVersion = 1
+
+	// DeltaFormat is the format recorded in the state files of ExportDelta, whose files are
+	// replaced by every run rather than appended to.
+	DeltaFormat = "delta"
+)
+
+// State is the content of a state file: what a previous run exported.
+type State struct {
+	Version  int                     `json:"version"`           // Format version, see StateVersion.
+	Format   string                  `json:"format"`            // Name of the exporter that wrote the files, or DeltaFormat.
+	Sessions map[string]SessionState `json:"sessions"`          // Exported sessions by ID.
+	Files    map[string]int64        `json:"files"`             // Size of every written file at the end of the run.
+	Options  exporter.Options        `json:"options,omitempty"` // Options the files were written with.
+}
+
+// SessionState records what was exported of a session.
+type SessionState struct {
+	LastUpdate int64  `json:"lastUpdate"` // LastUpdate of the session when it was exported.
+	Messages   int    `json:"messages"`   // Number of messages that were exported.
+	Digest     string `json:"digest"`     // Digest of the written session fields and exported messages.
+}
+
+// LoadState reads the state file at path. A missing file yields an empty state, as for a first run.
+func LoadState(rfs filesystem.FileSystem, path string) (*State, error) {
+	data, err := rfs.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return newState(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %w", path, err)
+	}
+	if state.Version != StateVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d", path, state.Version)
+	}
+	if state.Sessions == nil {
+		state.Sessions = make(map[string]SessionState)
+	}
+	return &state, nil
+}
+
+// newState returns the empty state of a first run.
+func newState() *State {
+	return &State{Version: StateVersion, Sessions: make(map[string]SessionState)}
+}
+
+// Save writes the state atomically to path.
+func (s *State) Save(rfs filesystem.FileSystem, path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return rfs.WriteFile(path, data, 0644)
+}
+
+// Empty reports whether nothing has been exported yet.
+func (s *State) Empty() bool {
+	return len(s.Sessions) == 0 && len(s.Files) == 0
+}
+
+// Record marks every message of sessions as exported, along with their topic and memory prompt.
+func (s *State) Record(sessions []exporter.Session) {
+	s.record(sessions, defaultFields)
+}
+
+// record marks every message of sessions as exported, along with the session fields returned by
+// fields.
+func (s *State) record(sessions []exporter.Session, fields func(session exporter.Session) []string) {
+	for _, session := range sessions {
+		s.Sessions[session.ID] = SessionState{
+			LastUpdate: session.LastUpdate,
+			Messages:   len(session.Messages),
+			Digest:     digest(fields(session), session.Messages),
+		}
+	}
+}
+
+// Changes describes how the sessions of a backup differ from a state.
+type Changes struct {
+	Increment exporter.Increment // New sessions and messages, ready to be appended.
+	Edited    []string           // IDs of exported sessions whose written fields or exported messages changed.
+	Removed   []string           // IDs of exported sessions missing from the backup, sorted.
+	Unchanged int                // Number of sessions without any change.
+}
+
+// NeedsRewrite reports whether previously exported records changed, so that the files of the
+// previous export must be rewritten instead of appended to.
+func (c *Changes) NeedsRewrite() bool {
+	return len(c.Edited) > 0
+}
+
+// Compare returns the changes of sessions since state was recorded, an edit being a change to
+// the topic, memory prompt or exported messages of a session.
+func Compare(state *State, sessions []exporter.Session) *Changes {
+	return compare(state, sessions, defaultFields)
+}
+
+// compare is Compare with the session fields that were written returned by fields.
+func compare(state *State, sessions []exporter.Session, fields func(session exporter.Session) []string) *Changes {
+	changes := &Changes{Increment: exporter.Increment{New: make(map[string]bool)}}
+	present := make(map[string]bool, len(sessions))
+	for _, session := range sessions {
+		present[session.ID] = true
+		exported, ok := state.Sessions[session.ID]
+		switch {
+		case !ok:
+			changes.Increment.Sessions = append(changes.Increment.Sessions, session)
+			changes.Increment.New[session.ID] = true
+		case session.LastUpdate == exported.LastUpdate && len(session.Messages) == exported.Messages:
+			// NextChat bumps LastUpdate on every change, so the session can be trusted as is.
+			changes.Unchanged++
+		case len(session.Messages) < exported.Messages || digest(fields(session), session.Messages[:exported.Messages]) != exported.Digest:
+			changes.Edited = append(changes.Edited, session.ID)
+		case len(session.Messages) == exported.Messages:
+			changes.Unchanged++
+		default:
+			added := session
+			added.Messages = session.Messages[exported.Messages:]
+			changes.Increment.Sessions = append(changes.Increment.Sessions, added)
+		}
+	}
+	for id := range state.Sessions {
+		if !present[id] {
+			changes.Removed = append(changes.Removed, id)
+		}
+	}
+	sort.Strings(changes.Removed)
+	return changes
+}
+
+// Result describes an incremental export.
+type Result struct {
+	Files     []string // The files that were written.
+	Changes   *Changes // The changes found since the previous run.
+	Rewritten bool     // Whether the files were rewritten instead of appended to.
+	Reason    string   // Why the files were rewritten.
+}
+
+// Export brings the files of e at outputPath up to date with sessions, using the state file at
+// statePath to find out what was exported before, and saves the new state once all files have
+// been written.
+//
+// New sessions and messages are appended. The files are rewritten in full instead on the first
+// run, when exported records were edited, when the state was written by another format or with
+// other options, or when a file was modified since the previous run (for example by a run that
+// was interrupted).
+func Export(ctx context.Context, rfs filesystem.FileSystem, e exporter.AppendExporter, sessions []exporter.Session, outputPath string, options exporter.Options, statePath string) (*Result, error) {
+	if err := checkIDs(sessions); err != nil {
+		return nil, err
+	}
+	fields, err := e.SessionFields(options)
+	if err != nil {
+		return nil, err
+	}
+	state, err := LoadState(rfs, statePath)
+	if err != nil {
+		return nil, err
+	}
+	changes := compare(state, sessions, fields)
+	result := &Result{Changes: changes}
+
+	switch {
+	case state.Empty():
+		result.Reason = "first run"
+	case state.Format != e.Name():
+		result.Reason = fmt.Sprintf("the state was written by format %q", state.Format)
+	case !maps.Equal(state.Options, setOptions(options)):
+		// Other columns, headers or a dialect would not match the rows already written.
+		result.Reason = "the format options changed since the last run"
+	case changes.NeedsRewrite():
+		result.Reason = fmt.Sprintf("%d exported sessions were edited", len(changes.Edited))
+	default:
+		result.Reason = changedFile(rfs, state)
+	}
+
+	if result.Reason != "" {
+		result.Rewritten = true
+		// A rewrite starts over from the backup, so sessions that were removed from it are dropped.
+		state = newState()
+		result.Files, err = e.Export(ctx, rfs, sessions, outputPath, options)
+	} else {
+		result.Files, err = e.Append(ctx, rfs, changes.Increment, outputPath, options)
+	}
+	if err != nil {
+		return result, err
+	}
+	state.record(sessions, fields)
+
+	state.Format = e.Name()
+	state.Options = setOptions(options)
+	state.Files = make(map[string]int64, len(result.Files))
+	for _, file := range result.Files {
+		info, err := rfs.Stat(file)
+		if err != nil {
+			return result, err
+		}
+		state.Files[file] = info.Size()
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, state.Save(rfs, statePath)
+}
+
+// ExportDelta writes the sessions added or edited since the previous run to a delta file using
+// write, which receives the sessions returned by Delta and returns the files it wrote, and then
+// saves the new state to statePath. On the first run, the delta holds every session.
+func ExportDelta(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, statePath string, write func(delta []exporter.Session) ([]string, error)) (*Result, error) {
+	if err := checkIDs(sessions); err != nil {
+		return nil, err
+	}
+	state, err := LoadState(rfs, statePath)
+	if err != nil {
+		return nil, err
+	}
+	changes := Compare(state, sessions)
+	result := &Result{Changes: changes}
+	if result.Files, err = write(Delta(changes, sessions)); err != nil {
+		return result, err
+	}
+
+	state.Record(sessions)
+	state.Format = DeltaFormat
+	state.Files = nil
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, state.Save(rfs, statePath)
+}
+
+// Delta returns the sessions to write to a delta file: every new session, the new messages of
+// the other sessions, and edited sessions in full, so that a consumer can replace them.
+func Delta(changes *Changes, sessions []exporter.Session) []exporter.Session {
+	edited := make(map[string]bool, len(changes.Edited))
+	for _, id := range changes.Edited {
+		edited[id] = true
+	}
+	appended := make(map[string]exporter.Session, len(changes.Increment.Sessions))
+	for _, session := range changes.Increment.Sessions {
+		appended[session.ID] = session
+	}
+
+	var delta []exporter.Session
+	for _, session := range sessions {
+		if edited[session.ID] {
+			delta = append(delta, session)
+		} else if added, ok := appended[session.ID]; ok {
+			delta = append(delta, added)
+		}
+	}
+	return delta
+}
+
+// setOptions returns the options of options that are set, or nil if there are none, as recorded
+// in the state.
+func setOptions(options exporter.Options) exporter.Options {
+	var set exporter.Options
+	for name, value := range options {
+		if value == "" {
+			continue
+		}
+		if set == nil {
+			set = make(exporter.Options)
+		}
+		set[name] = value
+	}
+	return set
+}
+
+// changedFile returns a reason to rewrite if a file recorded in state was modified or deleted
+// since the previous run, or an empty string if all files are as they were left.
+func changedFile(rfs filesystem.FileSystem, state *State) string {
+	files := make([]string, 0, len(state.Files))
+	for file := range state.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		info, err := rfs.Stat(file)
+		if err != nil {
+			return fmt.Sprintf("%s is missing", file)
+		}
+		if info.Size() != state.Files[file] {
+			return fmt.Sprintf("%s was modified since the last run", file)
+		}
+	}
+	return ""
+}
+
+// checkIDs returns an error if a session has no ID or the ID of another session, since the state
+// tells sessions apart by their IDs.
+func checkIDs(sessions []exporter.Session) error {
+	seen := make(map[string]bool, len(sessions))
+	for i, session := range sessions {
+		switch {
+		case session.ID == "":
+			return fmt.Errorf("session %d has no id, which incremental exports require", i+1)
+		case seen[session.ID]:
+			return fmt.Errorf("session id %q is used by several sessions, which incremental exports do not support", session.ID)
+		}
+		seen[session.ID] = true
+	}
+	return nil
+}
+
+// defaultFields returns the topic and memory prompt of session, the session fields of delta files
+// and of states recorded without an exporter.
+func defaultFields(session exporter.Session) []string {
+	return []string{session.Topic, session.MemoryPrompt}
+}
+
+// digest returns a digest of the session fields and messages, which together determine the
+// records previously written for a session.
+func digest(fields []string, messages []exporter.Message) string {
+	h := sha256.New()
+	write := func(s string) {
+		fmt.Fprintf(h, "%d:%s", len(s), s)
+	}
+	for _, field := range fields {
+		write(field)
+	}
+	for _, message := range messages {
+		write(message.ID)
+		write(message.Date)
+		write(message.Role)
+		write(message.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
@@ -0,0 +1,131 @@
+// Below, the package incremental (@incremental_test.go) tests that exports are appended to or
+// rewritten as the sessions and options change.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package incremental_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/incremental"
+)
+
+// TestIncrementalExport verifies that incremental exports append new sessions and messages, and
+// rewrite the files when exported records or the options were changed.
+func TestIncrementalExport(t *testing.T) {
+	ctx := context.Background()
+	sessions := []exporter.Session{
+		{ID: "s1", Topic: "Go", LastUpdate: 1, Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Hello"}}},
+	}
+
+	for _, name := range []string{exporter.FormatNamePerLine, exporter.FormatNameSeparate, exporter.FormatNameJSONL} {
+		t.Run(name, func(t *testing.T) {
+			e, _ := exporter.Lookup(name)
+			ae := e.(exporter.AppendExporter)
+			var options exporter.Options
+			if name == exporter.FormatNameSeparate {
+				options = exporter.Options{exporter.OptionMessages: "messages.csv"}
+			}
+			mockFS := filesystem.NewMockFileSystem()
+			output := "output" + e.Extension()
+
+			result, err := incremental.Export(ctx, mockFS, ae, sessions, output, options, "state.json")
+			if err != nil || !result.Rewritten || result.Reason != "first run" {
+				t.Fatalf("First Export() = %+v, %v, want a full export", result, err)
+			}
+
+			// A new message in s1 and a new session s2 are appended.
+			grown := []exporter.Session{sessions[0], {ID: "s2", Topic: "Rust", LastUpdate: 2, Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Hi"}}}}
+			grown[0].LastUpdate = 3
+			grown[0].Messages = append([]exporter.Message{}, sessions[0].Messages...)
+			grown[0].Messages = append(grown[0].Messages, exporter.Message{ID: "m2", Role: "assistant", Content: "Hello, Gopher"})
+			result, err = incremental.Export(ctx, mockFS, ae, grown, output, options, "state.json")
+			if err != nil || result.Rewritten || len(result.Changes.Increment.Sessions) != 2 {
+				t.Fatalf("Second Export() = %+v, %v, want two appended sessions", result, err)
+			}
+
+			var want exporter.Options
+			if options != nil {
+				want = exporter.Options{exporter.OptionMessages: "expected-messages.csv"}
+			}
+			if _, err := e.Export(ctx, mockFS, grown, "expected"+e.Extension(), want); err != nil {
+				t.Fatalf("Export() returned error: %v", err)
+			}
+			if got, want := string(mockFS.Files[output]), string(mockFS.Files["expected"+e.Extension()]); got != want {
+				t.Errorf("Appended output = %q, want %q", got, want)
+			}
+			if options != nil && string(mockFS.Files["messages.csv"]) != string(mockFS.Files["expected-messages.csv"]) {
+				t.Errorf("Appended messages = %q, want %q", mockFS.Files["messages.csv"], mockFS.Files["expected-messages.csv"])
+			}
+
+			// Nothing changed: nothing is appended.
+			before := string(mockFS.Files[output])
+			result, err = incremental.Export(ctx, mockFS, ae, grown, output, options, "state.json")
+			if err != nil || result.Rewritten || result.Changes.Unchanged != 2 || string(mockFS.Files[output]) != before {
+				t.Errorf("Unchanged Export() = %+v, %v", result, err)
+			}
+
+			// An edited message forces a rewrite.
+			edited := append([]exporter.Session{}, grown...)
+			edited[0].Messages = []exporter.Message{{ID: "m1", Role: "user", Content: "Hello, edited"}, grown[0].Messages[1]}
+			edited[0].LastUpdate = 4
+			result, err = incremental.Export(ctx, mockFS, ae, edited, output, options, "state.json")
+			if err != nil || !result.Rewritten || len(result.Changes.Edited) != 1 {
+				t.Errorf("Edited Export() = %+v, %v, want a rewrite", result, err)
+			}
+			if !strings.Contains(string(mockFS.Files[output])+string(mockFS.Files["messages.csv"]), "Hello, edited") {
+				t.Error("The rewrite does not contain the edited message")
+			}
+
+			// A file modified behind the state's back forces a rewrite as well.
+			mockFS.Files[output] = append(mockFS.Files[output], "garbage"...)
+			result, err = incremental.Export(ctx, mockFS, ae, edited, output, options, "state.json")
+			if err != nil || !result.Rewritten || !strings.Contains(result.Reason, "modified") {
+				t.Errorf("Export() after a modification = %+v, %v, want a rewrite", result, err)
+			}
+		})
+	}
+
+	t.Run("session fields", func(t *testing.T) {
+		e, _ := exporter.Lookup(exporter.FormatNamePerLine)
+		ae := e.(exporter.AppendExporter)
+		options := exporter.Options{exporter.OptionColumns: "id,mask.name,message.content"}
+		mockFS := filesystem.NewMockFileSystem()
+		if _, err := incremental.Export(ctx, mockFS, ae, sessions, "output.csv", options, "state.json"); err != nil {
+			t.Fatalf("First Export() returned error: %v", err)
+		}
+
+		// A written session field that changed forces a rewrite, so that no row keeps the old value.
+		renamed := append([]exporter.Session{}, sessions...)
+		renamed[0].Mask.Name = "Gopher"
+		renamed[0].LastUpdate = 2
+		result, err := incremental.Export(ctx, mockFS, ae, renamed, "output.csv", options, "state.json")
+		if err != nil || !result.Rewritten || len(result.Changes.Edited) != 1 {
+			t.Errorf("Export() after a mask rename = %+v, %v, want a rewrite", result, err)
+		}
+
+		// Sessions are told apart by their IDs, so sessions without one are rejected.
+		anonymous := []exporter.Session{{Topic: "No ID", Messages: sessions[0].Messages}}
+		if _, err := incremental.Export(ctx, mockFS, ae, anonymous, "output.csv", options, "state.json"); err == nil || !strings.Contains(err.Error(), "no id") {
+			t.Errorf("Export() of a session without an ID = %v, want an error", err)
+		}
+	})
+	t.Run("options", func(t *testing.T) {
+		e, _ := exporter.Lookup(exporter.FormatNamePerLine)
+		ae := e.(exporter.AppendExporter)
+		mockFS := filesystem.NewMockFileSystem()
+		if _, err := incremental.Export(ctx, mockFS, ae, sessions, "history.csv", nil, "history.state.json"); err != nil {
+			t.Fatalf("First Export() returned error: %v", err)
+		}
+
+		// The file is rewritten when the options change instead of appended to.
+		result, err := incremental.Export(ctx, mockFS, ae, sessions, "history.csv", exporter.Options{exporter.OptionDelimiter: ";"}, "history.state.json")
+		if err != nil || !result.Rewritten || !strings.HasPrefix(string(mockFS.Files["history.csv"]), "session_id;") {
+			t.Errorf("Export() with a new delimiter = %+v, %v, want a rewrite", result, err)
+		}
+	})
+}
@@ -0,0 +1,193 @@
+// Below, the package interactivity (@editor.go) reads an answer key by key, for the prompts that
+// need more than a line read by the terminal: passwords echoed as asterisks, and paths completed
+// with the Tab key.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+// ErrInterrupted is returned when the user presses Ctrl+C while an answer is read key by key,
+// where the terminal does not turn Ctrl+C into an interrupt signal. It matches context.Canceled
+// with errors.Is, so it is handled like a cancelled prompt.
+var ErrInterrupted = fmt.Errorf("input interrupted: %w", context.Canceled)
+
+// edit writes prompt and reads an answer key by key, in raw mode if the Terminal is a terminal.
+// Typed characters are echoed, or replaced by asterisks if mask is set, and complete, if not nil,
+// is used to complete the answer when Tab is pressed.
+func (p *Prompter) edit(ctx context.Context, prompt string, mask bool, complete func(partial string) []string) (string, error) {
+	if p.Terminal != nil && term.IsTerminal(int(p.Terminal.Fd())) {
+		oldState, err := term.MakeRaw(int(p.Terminal.Fd()))
+		if err != nil {
+			return "", err
+		}
+		defer term.Restore(int(p.Terminal.Fd()), oldState)
+	}
+
+	fmt.Fprint(p.Out, prompt)
+	input, err := p.editLine(ctx, prompt, mask, complete)
+	if err != nil && ctx.Err() != nil {
+		fmt.Fprint(p.Out, "\r\n")
+		return "", ctx.Err()
+	}
+	return input, err
+}
+
+// editLine reads keys from the Input of In until Enter is pressed and returns the line typed.
+func (p *Prompter) editLine(ctx context.Context, prompt string, mask bool, complete func(partial string) []string) (string, error) {
+	in := InputOf(p.In)
+	var line []rune
+	echo := func(runes []rune) {
+		if mask {
+			fmt.Fprint(p.Out, strings.Repeat("*", len(runes)))
+		} else {
+			fmt.Fprint(p.Out, string(runes))
+		}
+	}
+
+	for {
+		r, err := in.Next(ctx)
+		if err != nil {
+			return string(line), err
+		}
+		switch r {
+		case '\r', '\n':
+			if r == '\r' && in.Buffered() > 0 {
+				// Consume the newline of a CRLF line ending, so that it does not answer the next prompt.
+				if next, err := in.Next(ctx); err == nil && next != '\n' {
+					in.Unread(next)
+				}
+			}
+			fmt.Fprint(p.Out, "\r\n")
+			return string(line), nil
+		case 0x03: // Ctrl+C
+			fmt.Fprint(p.Out, "^C\r\n")
+			return "", ErrInterrupted
+		case 0x04: // Ctrl+D
+			if len(line) == 0 {
+				fmt.Fprint(p.Out, "\r\n")
+				return "", io.EOF
+			}
+		case 0x7f, 0x08: // Backspace
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+				fmt.Fprint(p.Out, "\b \b")
+			}
+		case 0x15: // Ctrl+U
+			fmt.Fprint(p.Out, strings.Repeat("\b \b", len(line)))
+			line = nil
+		case '\t':
+			if complete == nil {
+				continue
+			}
+			completed, candidates := completeLine(string(line), complete(string(line)))
+			if added := []rune(completed)[len(line):]; len(added) > 0 {
+				line = append(line, added...)
+				echo(added)
+			} else if len(candidates) > 1 {
+				names := make([]string, len(candidates))
+				for i, candidate := range candidates {
+					names[i] = filepath.Base(candidate)
+					if strings.HasSuffix(candidate, string(filepath.Separator)) {
+						names[i] += string(filepath.Separator)
+					}
+				}
+				fmt.Fprint(p.Out, "\r\n"+strings.Join(names, "  ")+"\r\n"+prompt+string(line))
+			}
+		case 0x1b:
+			skipEscapeSequence(ctx, in)
+		default:
+			if unicode.IsPrint(r) {
+				line = append(line, r)
+				echo([]rune{r})
+			}
+		}
+	}
+}
+
+// completeLine returns line extended to the longest common prefix of candidates, along with the
+// candidates that start with line.
+func completeLine(line string, candidates []string) (string, []string) {
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, line) {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) == 0 {
+		return line, nil
+	}
+	prefix := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, prefix) {
+			_, size := lastRune(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix, matches
+}
+
+// lastRune returns the last rune of s and its size in bytes.
+func lastRune(s string) (rune, int) {
+	runes := []rune(s)
+	last := runes[len(runes)-1]
+	return last, len(string(last))
+}
+
+// skipEscapeSequence consumes the rest of an escape sequence, such as the one sent by an arrow key,
+// after its escape character. Arrow keys have no use when typing a single line.
+func skipEscapeSequence(ctx context.Context, in *Input) {
+	if in.Buffered() == 0 {
+		return
+	}
+	introducer, err := in.Next(ctx)
+	if err != nil || (introducer != '[' && introducer != 'O') {
+		return
+	}
+	for {
+		r, err := in.Next(ctx)
+		if err != nil || (r >= 0x40 && r <= 0x7e) {
+			return
+		}
+	}
+}
+
+// CompletePath returns the paths of the entries of rfs that complete partial, sorted, with a
+// trailing separator for directories. Hidden entries are only completed once partial names them
+// with a leading dot.
+func CompletePath(rfs filesystem.FileSystem, partial string) []string {
+	dir, prefix := filepath.Split(partial)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := rfs.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		candidate := dir + name
+		if entry.IsDir() {
+			candidate += string(filepath.Separator)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
@@ -0,0 +1,183 @@
+// Below, the package interactivity (@input.go) reads the input shared by every prompt, so that a
+// prompt can stop waiting when its context is cancelled without leaving a read behind that would
+// race with the next prompt or swallow its answer.
+//
+// Reads of a *bufio.Reader go through an Input, which runs at most one read at a time. A read
+// that outlives the prompt that started it is not lost: its character goes to the next prompt.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"sync"
+)
+
+// Input reads characters from a *bufio.Reader for callers that may give up waiting. Once a reader
+// is read through an Input, it must only be read through an Input.
+//
+// An Input is a plain value owned by its caller: the Inputs of a reader share what is left of an
+// abandoned read through a registry that only keeps a reader while such a read or characters put
+// back are pending, and forgets it as soon as they have been consumed.
+type Input struct {
+	src *bufio.Reader
+}
+
+// inputState is what the Inputs of a reader share while a read or characters put back are pending.
+type inputState struct {
+	mu       sync.Mutex
+	results  chan runeResult // Receives the character of the pending read.
+	pending  bool            // Whether a read is running in the background.
+	unread   []rune          // Characters put back by Unread, read first.
+	released bool            // Whether the state was removed from inputs; it must not be used.
+}
+
+// runeResult is the outcome of a background read.
+type runeResult struct {
+	r   rune
+	err error
+}
+
+var (
+	inputsMu sync.Mutex
+	inputs   = make(map[*bufio.Reader]*inputState) // States of the readers with something pending.
+)
+
+// InputOf returns an Input reading from reader.
+func InputOf(reader *bufio.Reader) *Input {
+	return &Input{src: reader}
+}
+
+// acquire returns the state of the reader of in, locked, registering a new one if needed.
+func (in *Input) acquire() *inputState {
+	for {
+		inputsMu.Lock()
+		st, ok := inputs[in.src]
+		if !ok {
+			st = &inputState{results: make(chan runeResult, 1)}
+			inputs[in.src] = st
+		}
+		inputsMu.Unlock()
+
+		st.mu.Lock()
+		if !st.released {
+			return st
+		}
+		// Released between the lookup and the lock: look again.
+		st.mu.Unlock()
+	}
+}
+
+// release unlocks st, removing it from the registry when nothing is left pending.
+func (in *Input) release(st *inputState) {
+	if !st.pending && len(st.unread) == 0 {
+		inputsMu.Lock()
+		delete(inputs, in.src)
+		inputsMu.Unlock()
+		st.released = true
+	}
+	st.mu.Unlock()
+}
+
+// Next returns the next character, or the error of ctx if it is cancelled first. A read that
+// has to wait for input runs in the background, so that it can be given up; its character is then
+// returned by the next call.
+func (in *Input) Next(ctx context.Context) (rune, error) {
+	st := in.acquire()
+	defer in.release(st)
+	if n := len(st.unread); n > 0 {
+		r := st.unread[n-1]
+		st.unread = st.unread[:n-1]
+		return r, nil
+	}
+	if !st.pending && (ctx.Done() == nil || in.src.Buffered() > 0) {
+		// Nothing else reads src, and the read cannot be cancelled or will not wait.
+		r, _, err := in.src.ReadRune()
+		return r, err
+	}
+	if !st.pending {
+		st.pending = true
+		go func() {
+			r, _, err := in.src.ReadRune()
+			st.results <- runeResult{r: r, err: err}
+		}()
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case res := <-st.results:
+		st.pending = false
+		return res.r, res.err
+	}
+}
+
+// Unread puts r back, so that it is returned by the next call to Next.
+func (in *Input) Unread(r rune) {
+	st := in.acquire()
+	defer in.release(st)
+	st.unread = append(st.unread, r)
+}
+
+// Buffered returns the number of bytes that can be read without waiting, plus the characters
+// put back.
+func (in *Input) Buffered() int {
+	st := in.acquire()
+	defer in.release(st)
+	if st.pending {
+		// A read only runs in the background when nothing was buffered.
+		return len(st.unread)
+	}
+	return in.src.Buffered() + len(st.unread)
+}
+
+// ReadLine returns the next line without its line ending, or the error of ctx if it is cancelled
+// first. If the input ends without a newline, the partial line is returned along with the error.
+func (in *Input) ReadLine(ctx context.Context) (string, error) {
+	var line strings.Builder
+	for {
+		r, err := in.Next(ctx)
+		if err != nil {
+			return strings.TrimRight(line.String(), "\r"), err
+		}
+		if r == '\n' {
+			return strings.TrimRight(line.String(), "\r"), nil
+		}
+		line.WriteRune(r)
+	}
+}
+
+// Reader returns a reader of the characters of in that gives up when ctx is cancelled, for the
+// functions reading from an io.RuneScanner.
+func (in *Input) Reader(ctx context.Context) *InputReader {
+	return &InputReader{in: in, ctx: ctx}
+}
+
+// InputReader reads the characters of an Input with a context, see Input.Reader.
+type InputReader struct {
+	in   *Input
+	ctx  context.Context
+	last rune // Last character read, put back by UnreadRune.
+}
+
+// ReadRune returns the next character and its size in bytes.
+func (r *InputReader) ReadRune() (rune, int, error) {
+	c, err := r.in.Next(r.ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	r.last = c
+	return c, len(string(c)), nil
+}
+
+// UnreadRune puts back the last character read.
+func (r *InputReader) UnreadRune() error {
+	r.in.Unread(r.last)
+	return nil
+}
+
+// Buffered returns the number of bytes that can be read without waiting.
+func (r *InputReader) Buffered() int {
+	return r.in.Buffered()
+}
@@ -0,0 +1,59 @@
+// Below, the package interactivity (@input_test.go) tests that the reads given up by a prompt go
+// to the next one, and that readers are forgotten once nothing is pending.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestInputRelease verifies that the character of an abandoned read is returned by the next
+// read of another Input of the same reader, and that the reader is then no longer registered.
+func TestInputRelease(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	reader := bufio.NewReader(pipeReader)
+
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := InputOf(reader).Next(timeout); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Next() without input = %v, want context.DeadlineExceeded", err)
+	}
+	if registered(reader) == nil {
+		t.Fatal("The reader with a pending read is not registered")
+	}
+
+	go pipeWriter.Write([]byte("yes\n"))
+	line, err := InputOf(reader).ReadLine(context.Background())
+	if err != nil || line != "yes" {
+		t.Errorf("ReadLine() after an abandoned read = %q, %v, want yes", line, err)
+	}
+	if st := registered(reader); st != nil {
+		t.Errorf("The reader is still registered after its input was consumed: %+v", st)
+	}
+
+	in := InputOf(reader)
+	in.Unread('x')
+	if in.Buffered() != 1 || registered(reader) == nil {
+		t.Errorf("Buffered() after Unread() = %d, want 1 character kept for the next read", in.Buffered())
+	}
+	if r, err := InputOf(reader).Next(context.Background()); err != nil || r != 'x' {
+		t.Errorf("Next() after Unread() = %q, %v, want x", r, err)
+	}
+	if registered(reader) != nil {
+		t.Error("The reader is still registered after the character put back was read")
+	}
+}
+
+// registered returns the state registered for reader, or nil.
+func registered(reader *bufio.Reader) *inputState {
+	inputsMu.Lock()
+	defer inputsMu.Unlock()
+	return inputs[reader]
+}
@@ -3,30 +3,36 @@
 // It includes utilities for prompting users for confirmation and reading their input in a context-aware manner, which allows for graceful
 // cancellation of input requests. The package is designed to integrate with a filesystem interface to check for file existence and handle potential file overwrites.
 //
+// Prompter gathers the prompts in one toolkit: validated input with defaults, yes/no confirmations,
+// numbered single and multiple choices, file paths completed with the Tab key and masked passwords.
+//
+// # Example Usage
+//
+//	p := interactivity.NewTerminalPrompter(os.Stdin, bufio.NewReader(os.Stdin), os.Stdout, &filesystem.RealFileSystem{})
+//	path, err := p.Path(ctx, "Enter the path to the JSON file: ", "", interactivity.ExistingFile(p.FS))
+//	if err != nil {
+//		return err
+//	}
+//	formats, err := p.MultiSelect(ctx, "Select the output formats:", exporter.Names(), []int{0})
+//
 // Copyright (c) 2023 H0llyW00dzZ
 package interactivity
 
 import (
 	"bufio"
 	"context"
-	"fmt"
+	"errors"
+	"os"
 	"strings"
 
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
 )
 
-// result is a helper struct used internally within the interactivity package
-// to encapsulate the user input along with any error that might have occurred
-// during the input reading process. It is used to communicate between
-// goroutines in the promptForInput function.
-type result struct {
-	input string
-	err   error
-}
-
 // ConfirmOverwrite checks if a file with the given fileName exists in the provided filesystem.
 // If the file does exist, it prompts the user for confirmation to overwrite the file.
-// The function reads the user's input via the provided bufio.Reader and expects a 'yes' or 'no' response.
+// The function reads the user's input via the provided bufio.Reader and expects a yes or no answer, such as y or N;
+// pressing Enter keeps the file.
 // A context.Context is used to handle cancellation of the input request.
 // It returns a boolean indicating whether the file should be overwritten and any error encountered.
 func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, fileName string) (bool, error) {
@@ -41,14 +47,42 @@ func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bu
 	}
 
 	// If the file exists, ask the user for confirmation.
-	fmt.Printf("File '%s' already exists. Overwrite? (yes/no): ", fileName)
+	question := i18n.T("prompt.overwrite", fileName)
+	return NewPrompter(reader, os.Stdout).Confirm(ctx, question, DefaultNo)
+}
 
-	// Call promptForInput without the extra string argument.
-	overwrite, err := promptForInput(ctx, reader)
+// PromptPassphrase asks the user for a passphrase with the given prompt and reads it via the provided bufio.Reader.
+// On a terminal, the passphrase is shown as asterisks while it is typed.
+// A context.Context is used to handle cancellation of the input request.
+// It returns an error if the user enters an empty or blank passphrase.
+func PromptPassphrase(ctx context.Context, reader *bufio.Reader, prompt string) (string, error) {
+	passphrase, err := NewTerminalPrompter(os.Stdin, reader, os.Stdout, nil).Password(ctx, prompt)
 	if err != nil {
-		return false, err
+		return "", err
+	}
+	// The passphrase is kept as typed, spaces included, as it is when read from the environment
+	// variable or a key file, so that a backup decrypts whichever source gives its passphrase.
+	if strings.TrimSpace(passphrase) == "" {
+		return "", errors.New(i18n.T("validate.noPassphrase"))
 	}
-	return strings.ToLower(overwrite) == "yes", nil
+	return passphrase, nil
+}
+
+// PromptNewPassphrase asks the user for a new passphrase twice, as used before encrypting data,
+// and returns an error if the two entries do not match.
+func PromptNewPassphrase(ctx context.Context, reader *bufio.Reader) (string, error) {
+	passphrase, err := PromptPassphrase(ctx, reader, i18n.T("prompt.passphrase"))
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := PromptPassphrase(ctx, reader, i18n.T("prompt.passphraseConfirm"))
+	if err != nil {
+		return "", err
+	}
+	if confirmation != passphrase {
+		return "", errors.New(i18n.T("validate.passphraseMismatch"))
+	}
+	return passphrase, nil
 }
 
 // promptForInput waits for a line of user input read from the provided bufio.Reader.
@@ -56,19 +90,18 @@ func ConfirmOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bu
 // The function trims the newline character from the input and returns the resulting string.
 // If the context is cancelled before the user inputs a line, the context's error is returned.
 func promptForInput(ctx context.Context, reader *bufio.Reader) (string, error) {
-	resultChan := make(chan result)
-
-	go func() {
-		input, err := reader.ReadString('\n')
-		resultChan <- result{input: input, err: err}
-	}()
+	input, err := readLine(ctx, reader)
+	return strings.TrimSpace(input), err
+}
 
-	select {
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case res := <-resultChan:
-		return strings.TrimSpace(res.input), res.err
+// readLine is promptForInput without the trimming of spaces: only the line ending is removed.
+// The line is read through the Input of reader, so that a cancelled prompt leaves no read behind.
+func readLine(ctx context.Context, reader *bufio.Reader) (string, error) {
+	input, err := InputOf(reader).ReadLine(ctx)
+	if err != nil && ctx.Err() != nil {
+		return "", err
 	}
+	return input, err
 }
 
 // determineFileName should be a function that determines the file name based on the fileType or other logic.
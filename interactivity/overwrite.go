@@ -0,0 +1,215 @@
+// Below, the package interactivity (@overwrite.go) decides what happens to a file that already
+// exists where an output is about to be written: it is overwritten, skipped, kept while the output
+// is written under a free name such as "sessions (1).csv", moved to a timestamped backup, or
+// appended to when the format allows it. The decision follows an OverwritePolicy, given by a flag,
+// or is asked to the user.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+)
+
+// OverwritePolicy tells what to do with an existing file in place of a new output.
+// It implements flag.Value, so that it can be set with a command-line flag.
+type OverwritePolicy int
+
+const (
+	// PolicyAsk asks the user which of the other policies to apply.
+	PolicyAsk OverwritePolicy = iota
+	// PolicyOverwrite replaces the existing file.
+	PolicyOverwrite
+	// PolicySkip keeps the existing file and writes nothing.
+	PolicySkip
+	// PolicyRename keeps the existing file and writes the output under the first free name
+	// made by numbering it, such as "sessions (1).csv".
+	PolicyRename
+	// PolicyBackup renames the existing file with a timestamp, such as
+	// "sessions.csv.20231201-150405.bak", and writes the output in its place.
+	PolicyBackup
+	// PolicyAppend adds the output to the existing file, for formats holding one record per line.
+	PolicyAppend
+)
+
+// BackupTimeFormat is the layout of the timestamp added to the name of backed up files.
+const BackupTimeFormat = "20060102-150405"
+
+// ErrCannotAppend is returned by ResolveOverwrite when PolicyAppend is applied to an output whose
+// format cannot be appended to.
+var ErrCannotAppend = errors.New("the output format cannot be appended to an existing file")
+
+// policyNames holds the names of the policies, as accepted by ParseOverwritePolicy.
+var policyNames = [...]string{
+	PolicyAsk:       "ask",
+	PolicyOverwrite: "overwrite",
+	PolicySkip:      "skip",
+	PolicyRename:    "rename",
+	PolicyBackup:    "backup",
+	PolicyAppend:    "append",
+}
+
+// OverwritePolicyNames returns the names of the policies, as accepted by ParseOverwritePolicy.
+func OverwritePolicyNames() []string {
+	return append([]string(nil), policyNames[:]...)
+}
+
+// ParseOverwritePolicy returns the policy with the given name, in any case.
+func ParseOverwritePolicy(name string) (OverwritePolicy, error) {
+	for policy, policyName := range policyNames {
+		if strings.EqualFold(name, policyName) {
+			return OverwritePolicy(policy), nil
+		}
+	}
+	return PolicyAsk, fmt.Errorf("unknown overwrite policy %q (available: %s)", name, strings.Join(policyNames[:], ", "))
+}
+
+// String returns the name of the policy.
+func (p OverwritePolicy) String() string {
+	if p < 0 || int(p) >= len(policyNames) {
+		return fmt.Sprintf("OverwritePolicy(%d)", int(p))
+	}
+	return policyNames[p]
+}
+
+// Set sets the policy from its name, for use as a flag.Value.
+func (p *OverwritePolicy) Set(name string) error {
+	policy, err := ParseOverwritePolicy(name)
+	if err != nil {
+		return err
+	}
+	*p = policy
+	return nil
+}
+
+// Resolution tells how to write an output once an OverwritePolicy has been applied.
+type Resolution struct {
+	Path   string          // File to write, which differs from the requested one after PolicyRename.
+	Action OverwritePolicy // PolicyOverwrite to create or replace Path, PolicyAppend to append to it, or PolicySkip to write nothing.
+	Backup string          // File the previous contents were moved to by PolicyBackup, if any.
+}
+
+// Skipped reports whether nothing must be written.
+func (r Resolution) Skipped() bool {
+	return r.Action == PolicySkip
+}
+
+// ResolveOverwrite applies policy to fileName, if it already exists in rfs, and returns how to
+// write the output. PolicyAsk asks the user through reader, offering to append only if canAppend
+// is set; applying PolicyAppend to an output that cannot be appended to returns ErrCannotAppend.
+// PolicyBackup moves the existing file before returning.
+func ResolveOverwrite(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, fileName string, policy OverwritePolicy, canAppend bool) (Resolution, error) {
+	exists, err := rfs.FileExists(fileName)
+	if err != nil {
+		return Resolution{}, err
+	}
+	if !exists {
+		return Resolution{Path: fileName, Action: PolicyOverwrite}, nil
+	}
+
+	if policy == PolicyAsk {
+		if policy, err = AskOverwritePolicy(ctx, NewPrompter(reader, os.Stdout), fileName, canAppend); err != nil {
+			return Resolution{}, err
+		}
+	}
+
+	switch policy {
+	case PolicyOverwrite, PolicySkip:
+		return Resolution{Path: fileName, Action: policy}, nil
+	case PolicyAppend:
+		if !canAppend {
+			return Resolution{}, fmt.Errorf("%s: %w", fileName, ErrCannotAppend)
+		}
+		return Resolution{Path: fileName, Action: PolicyAppend}, nil
+	case PolicyRename:
+		path, err := AvailableName(rfs, fileName)
+		if err != nil {
+			return Resolution{}, err
+		}
+		return Resolution{Path: path, Action: PolicyOverwrite}, nil
+	case PolicyBackup:
+		backup, err := BackupFile(rfs, fileName, time.Now())
+		if err != nil {
+			return Resolution{}, err
+		}
+		return Resolution{Path: fileName, Action: PolicyOverwrite, Backup: backup}, nil
+	default:
+		return Resolution{}, fmt.Errorf("unknown overwrite policy %s", policy)
+	}
+}
+
+// AskOverwritePolicy asks the user what to do with the existing file fileName and returns the
+// chosen policy, offering PolicyAppend only if canAppend is set. Pressing Enter skips the file.
+func AskOverwritePolicy(ctx context.Context, p *Prompter, fileName string, canAppend bool) (OverwritePolicy, error) {
+	renamed := numberedName(fileName, 1)
+	policies := []OverwritePolicy{PolicyOverwrite, PolicySkip, PolicyRename, PolicyBackup}
+	choices := []string{
+		i18n.T("prompt.overwritePolicy.overwrite"),
+		i18n.T("prompt.overwritePolicy.skip"),
+		i18n.T("prompt.overwritePolicy.rename", filepath.Base(renamed)),
+		i18n.T("prompt.overwritePolicy.backup"),
+	}
+	if canAppend {
+		policies = append(policies, PolicyAppend)
+		choices = append(choices, i18n.T("prompt.overwritePolicy.append"))
+	}
+
+	choice, err := p.Select(ctx, i18n.T("prompt.overwritePolicy", fileName), choices, 1)
+	if err != nil {
+		return PolicyAsk, err
+	}
+	return policies[choice], nil
+}
+
+// AvailableName returns the first name made by numbering fileName, such as "sessions (1).csv",
+// that does not exist in rfs. The number goes before the extension, and before the compression
+// extension too, as in "backup (1).json.gz".
+func AvailableName(rfs filesystem.FileSystem, fileName string) (string, error) {
+	for n := 1; ; n++ {
+		candidate := numberedName(fileName, n)
+		exists, err := rfs.FileExists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+	}
+}
+
+// numberedName returns fileName with n inserted before its extensions, as in "sessions (1).csv".
+func numberedName(fileName string, n int) string {
+	trimmed := compression.TrimExtension(fileName)
+	ext := filepath.Ext(trimmed) + fileName[len(trimmed):]
+	return fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(fileName, ext), n, ext)
+}
+
+// BackupFile moves fileName to a name made of it and the timestamp now, such as
+// "sessions.csv.20231201-150405.bak", numbered if that name is taken, and returns the new name.
+func BackupFile(rfs filesystem.FileSystem, fileName string, now time.Time) (string, error) {
+	backup := fileName + "." + now.Format(BackupTimeFormat) + ".bak"
+	exists, err := rfs.FileExists(backup)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		if backup, err = AvailableName(rfs, backup); err != nil {
+			return "", err
+		}
+	}
+	if err := rfs.Rename(fileName, backup); err != nil {
+		return "", fmt.Errorf("backing up %s: %w", fileName, err)
+	}
+	return backup, nil
+}
@@ -0,0 +1,97 @@
+// Below, the package interactivity (@overwrite_test.go) tests the overwrite policies applied to
+// existing output files.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// TestOverwritePolicy checks that every overwrite policy is applied to an existing output file,
+// from a flag or from the user's answer, and that backups get a free timestamped name.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestOverwritePolicy(t *testing.T) {
+	ctx := context.Background()
+	var policy interactivity.OverwritePolicy
+	if err := policy.Set("Rename"); err != nil || policy != interactivity.PolicyRename || policy.String() != "rename" {
+		t.Errorf("Set(Rename) = %v, %v, want rename", policy, err)
+	}
+	if err := policy.Set("replace"); err == nil {
+		t.Error("Set(replace) succeeded, want an error")
+	}
+
+	newFS := func() *filesystem.MockFileSystem {
+		mockFS := filesystem.NewMockFileSystem()
+		mockFS.Files["sessions.csv"] = []byte("old")
+		mockFS.Files["sessions (1).csv"] = []byte("older")
+		mockFS.Files["backup.json.gz"] = []byte("old")
+		return mockFS
+	}
+	resolve := func(mockFS *filesystem.MockFileSystem, input, fileName string, policy interactivity.OverwritePolicy, canAppend bool) (interactivity.Resolution, error) {
+		return interactivity.ResolveOverwrite(mockFS, ctx, bufio.NewReader(strings.NewReader(input)), fileName, policy, canAppend)
+	}
+
+	tests := []struct {
+		name      string
+		fileName  string
+		policy    interactivity.OverwritePolicy
+		input     string
+		canAppend bool
+		wantPath  string
+		wantDo    interactivity.OverwritePolicy
+	}{
+		{"NewFile", "new.csv", interactivity.PolicySkip, "", false, "new.csv", interactivity.PolicyOverwrite},
+		{"Overwrite", "sessions.csv", interactivity.PolicyOverwrite, "", false, "sessions.csv", interactivity.PolicyOverwrite},
+		{"Skip", "sessions.csv", interactivity.PolicySkip, "", false, "sessions.csv", interactivity.PolicySkip},
+		{"Rename", "sessions.csv", interactivity.PolicyRename, "", false, "sessions (2).csv", interactivity.PolicyOverwrite},
+		{"RenameCompressed", "backup.json.gz", interactivity.PolicyRename, "", false, "backup (1).json.gz", interactivity.PolicyOverwrite},
+		{"Append", "sessions.csv", interactivity.PolicyAppend, "", true, "sessions.csv", interactivity.PolicyAppend},
+		{"AskDefaultSkips", "sessions.csv", interactivity.PolicyAsk, "\n", true, "sessions.csv", interactivity.PolicySkip},
+		{"AskRename", "sessions.csv", interactivity.PolicyAsk, "3\n", false, "sessions (2).csv", interactivity.PolicyOverwrite},
+		{"AskAppend", "sessions.csv", interactivity.PolicyAsk, "5\n", true, "sessions.csv", interactivity.PolicyAppend},
+		{"AskAppendUnavailable", "sessions.csv", interactivity.PolicyAsk, "5\n1\n", false, "sessions.csv", interactivity.PolicyOverwrite},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFS := newFS()
+			got, err := resolve(mockFS, tc.input, tc.fileName, tc.policy, tc.canAppend)
+			if err != nil || got.Path != tc.wantPath || got.Action != tc.wantDo || got.Backup != "" {
+				t.Errorf("ResolveOverwrite() = %+v, %v, want %s to %s", got, err, tc.wantDo, tc.wantPath)
+			}
+			if string(mockFS.Files["sessions.csv"]) != "old" {
+				t.Error("ResolveOverwrite() changed the existing file")
+			}
+		})
+	}
+
+	mockFS := newFS()
+	if _, err := resolve(mockFS, "", "sessions.csv", interactivity.PolicyAppend, false); !errors.Is(err, interactivity.ErrCannotAppend) {
+		t.Errorf("ResolveOverwrite(append) of a document = %v, want ErrCannotAppend", err)
+	}
+	got, err := resolve(mockFS, "", "sessions.csv", interactivity.PolicyBackup, false)
+	if err != nil || got.Path != "sessions.csv" || got.Action != interactivity.PolicyOverwrite {
+		t.Fatalf("ResolveOverwrite(backup) = %+v, %v", got, err)
+	}
+	if !regexp.MustCompile(`^sessions\.csv\.\d{8}-\d{6}\.bak$`).MatchString(got.Backup) || string(mockFS.Files[got.Backup]) != "old" {
+		t.Errorf("ResolveOverwrite(backup) backed up to %q, want a timestamped copy of the old file", got.Backup)
+	}
+	if _, exists := mockFS.Files["sessions.csv"]; exists {
+		t.Error("ResolveOverwrite(backup) left the old file in place")
+	}
+	now := time.Date(2023, 12, 1, 15, 4, 5, 0, time.UTC)
+	mockFS.Files["notes.txt"] = []byte("a")
+	mockFS.Files["notes.txt.20231201-150405.bak"] = []byte("b")
+	if backup, err := interactivity.BackupFile(mockFS, "notes.txt", now); err != nil || backup != "notes.txt.20231201-150405 (1).bak" {
+		t.Errorf("BackupFile() = %q, %v, want a numbered backup", backup, err)
+	}
+}
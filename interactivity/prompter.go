@@ -0,0 +1,310 @@
+// Below, the package interactivity (@prompter.go) provides Prompter, the prompt toolkit used by
+// every interactive question of the CLI: free input with defaults and validation, yes/no
+// confirmations, numbered single and multiple choices, file paths with tab completion and masked
+// passwords.
+//
+// Every prompt is cancellable through its context, and reads from and writes to the reader and
+// writer of the Prompter, so that prompts can be tested with in-memory input and output:
+//
+//	p := interactivity.NewPrompter(strings.NewReader("y\n"), io.Discard)
+//	ok, err := p.Confirm(ctx, "Overwrite?", interactivity.DefaultNo)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+)
+
+// Default is the answer of Confirm when the user just presses Enter.
+type Default int
+
+const (
+	// NoDefault requires an explicit answer.
+	NoDefault Default = iota
+	// DefaultYes answers yes.
+	DefaultYes
+	// DefaultNo answers no.
+	DefaultNo
+)
+
+// hint returns the answers shown after a yes/no question, with the default capitalized.
+func (d Default) hint() string {
+	switch d {
+	case DefaultYes:
+		return i18n.T("prompt.confirm.defaultYes")
+	case DefaultNo:
+		return i18n.T("prompt.confirm.defaultNo")
+	default:
+		return i18n.T("prompt.confirm.noDefault")
+	}
+}
+
+// Validator checks an answer. A non-nil error is shown to the user, who is asked again.
+type Validator func(answer string) error
+
+// Required is a Validator rejecting empty answers.
+func Required(answer string) error {
+	if answer == "" {
+		return errors.New(i18n.T("validate.required"))
+	}
+	return nil
+}
+
+// ExistingFile returns a Validator accepting only the paths of existing files in rfs.
+func ExistingFile(rfs filesystem.FileSystem) Validator {
+	return func(answer string) error {
+		if answer == "" {
+			return errors.New(i18n.T("validate.pathRequired"))
+		}
+		info, err := rfs.Stat(answer)
+		if err != nil {
+			return errors.New(i18n.T("validate.notExist", answer))
+		}
+		if info.IsDir() {
+			return errors.New(i18n.T("validate.directory", answer))
+		}
+		return nil
+	}
+}
+
+// Prompter asks questions on Out and reads the answers from In.
+type Prompter struct {
+	In       *bufio.Reader         // Answers are read from In.
+	Out      io.Writer             // Questions and error messages are written to Out.
+	FS       filesystem.FileSystem // File system used for the tab completion of paths; nil disables it.
+	Terminal *os.File              // Terminal put in raw mode while reading key by key, if it is one.
+	Editor   bool                  // Whether passwords and paths are read key by key, with masking and tab completion.
+}
+
+// NewPrompter returns a Prompter reading whole lines from in, which is used as is if it is a
+// *bufio.Reader, and writing to out.
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	reader, ok := in.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(in)
+	}
+	return &Prompter{In: reader, Out: out}
+}
+
+// NewTerminalPrompter returns a Prompter reading from reader, which should read from terminal,
+// and writing to out. When terminal is a terminal, passwords are masked and paths are completed
+// with the Tab key using rfs; otherwise the Prompter reads whole lines like NewPrompter.
+func NewTerminalPrompter(terminal *os.File, reader *bufio.Reader, out io.Writer, rfs filesystem.FileSystem) *Prompter {
+	return &Prompter{In: reader, Out: out, FS: rfs, Terminal: terminal, Editor: term.IsTerminal(int(terminal.Fd()))}
+}
+
+// Line writes prompt and returns the next line of input with surrounding spaces trimmed.
+// If the input ends without a newline, the partial line is returned along with io.EOF.
+func (p *Prompter) Line(ctx context.Context, prompt string) (string, error) {
+	fmt.Fprint(p.Out, prompt)
+	return promptForInput(ctx, p.In)
+}
+
+// Input asks for a value until it passes validate, which may be nil. An empty answer stands for
+// def, which is shown in the prompt if it is not empty.
+func (p *Prompter) Input(ctx context.Context, prompt, def string, validate Validator) (string, error) {
+	return p.ask(ctx, withDefault(prompt, def), def, validate, p.Line)
+}
+
+// Confirm asks a yes/no question until it is answered with y, yes, n or no in any case, or with
+// an empty answer if def is not NoDefault.
+func (p *Prompter) Confirm(ctx context.Context, question string, def Default) (bool, error) {
+	prompt := question + " " + def.hint() + ": "
+	for {
+		answer, err := p.Line(ctx, prompt)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case isAnswer(answer, "y, yes", "prompt.confirm.yes"):
+			return true, nil
+		case isAnswer(answer, "n, no", "prompt.confirm.no"):
+			return false, nil
+		case answer == "" && def != NoDefault:
+			return def == DefaultYes, nil
+		}
+		fmt.Fprintln(p.Out, i18n.T("prompt.confirm.retry"))
+	}
+}
+
+// Select lists choices numbered from 1 under prompt and asks for one of them until a valid number
+// is entered. It returns the index of the choice. An empty answer selects the choice at index def,
+// unless def is negative.
+func (p *Prompter) Select(ctx context.Context, prompt string, choices []string, def int) (int, error) {
+	p.printChoices(prompt, choices)
+	defaultAnswer := ""
+	if def >= 0 && def < len(choices) {
+		defaultAnswer = strconv.Itoa(def + 1)
+	}
+	answer, err := p.ask(ctx, withDefault(i18n.T("prompt.select"), defaultAnswer), defaultAnswer, func(answer string) error {
+		_, err := parseChoice(answer, len(choices))
+		return err
+	}, p.Line)
+	if err != nil {
+		return -1, err
+	}
+	return parseChoice(answer, len(choices))
+}
+
+// MultiSelect lists choices numbered from 1 under prompt and asks for any number of them, as
+// numbers separated by commas or spaces, ranges such as 2-4, or "all". It returns the indexes of
+// the chosen choices in ascending order. An empty answer selects defaults, unless it is empty.
+func (p *Prompter) MultiSelect(ctx context.Context, prompt string, choices []string, defaults []int) ([]int, error) {
+	p.printChoices(prompt, choices)
+	numbers := make([]string, len(defaults))
+	for i, index := range defaults {
+		numbers[i] = strconv.Itoa(index + 1)
+	}
+	defaultAnswer := strings.Join(numbers, ",")
+	question := withDefault(i18n.T("prompt.multiSelect"), defaultAnswer)
+	answer, err := p.ask(ctx, question, defaultAnswer, func(answer string) error {
+		_, err := parseChoices(answer, len(choices))
+		return err
+	}, p.Line)
+	if err != nil {
+		return nil, err
+	}
+	return parseChoices(answer, len(choices))
+}
+
+// Path asks for a file path until it passes validate, which may be nil. An empty answer stands
+// for def. With an Editor, the Tab key completes the path from the entries of FS.
+func (p *Prompter) Path(ctx context.Context, prompt, def string, validate Validator) (string, error) {
+	read := p.Line
+	if p.Editor && p.FS != nil {
+		read = func(ctx context.Context, prompt string) (string, error) {
+			answer, err := p.edit(ctx, prompt, false, func(partial string) []string {
+				return CompletePath(p.FS, partial)
+			})
+			return strings.TrimSpace(answer), err
+		}
+	}
+	return p.ask(ctx, withDefault(prompt, def), def, validate, read)
+}
+
+// Password asks for a secret. With an Editor, every typed character is shown as an asterisk;
+// otherwise the answer is read as a line, since it cannot be hidden. Surrounding spaces are kept.
+func (p *Prompter) Password(ctx context.Context, prompt string) (string, error) {
+	if !p.Editor {
+		fmt.Fprint(p.Out, prompt)
+		return readLine(ctx, p.In)
+	}
+	return p.edit(ctx, prompt, true, nil)
+}
+
+// ask reads an answer with read until it passes validate, substituting def for empty answers.
+func (p *Prompter) ask(ctx context.Context, prompt, def string, validate Validator, read func(context.Context, string) (string, error)) (string, error) {
+	for {
+		answer, err := read(ctx, prompt)
+		if err != nil {
+			return answer, err
+		}
+		if answer == "" {
+			answer = def
+		}
+		if validate == nil {
+			return answer, nil
+		}
+		if err := validate(answer); err != nil {
+			fmt.Fprintln(p.Out, i18n.T("prompt.invalid", err))
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// isAnswer reports whether answer is one of the comma-separated answers, in any case, or one of
+// the answers of key in the current language.
+func isAnswer(answer, answers string, key i18n.Key) bool {
+	for _, accepted := range strings.Split(answers+","+i18n.T(key), ",") {
+		if strings.EqualFold(answer, strings.TrimSpace(accepted)) {
+			return true
+		}
+	}
+	return false
+}
+
+// printChoices writes prompt followed by choices numbered from 1.
+func (p *Prompter) printChoices(prompt string, choices []string) {
+	fmt.Fprintln(p.Out, prompt)
+	for i, choice := range choices {
+		fmt.Fprintf(p.Out, "%d) %s\n", i+1, choice)
+	}
+}
+
+// withDefault shows def in prompt, before its trailing colon if it has one.
+func withDefault(prompt, def string) string {
+	if def == "" {
+		return prompt
+	}
+	trimmed := strings.TrimRight(prompt, " ")
+	if strings.HasSuffix(trimmed, ":") {
+		return strings.TrimSuffix(trimmed, ":") + " [" + def + "]: "
+	}
+	return trimmed + " [" + def + "] "
+}
+
+// parseChoice parses the number of one of n choices and returns its index.
+func parseChoice(answer string, n int) (int, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || number < 1 || number > n {
+		return -1, errors.New(i18n.T("validate.choiceRange", n))
+	}
+	return number - 1, nil
+}
+
+// parseChoices parses numbers and ranges of n choices and returns their sorted indexes.
+func parseChoices(answer string, n int) ([]int, error) {
+	if strings.EqualFold(strings.TrimSpace(answer), "all") {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	chosen := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		first, last, isRange := strings.Cut(field, "-")
+		from, err := parseChoice(first, n)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if isRange {
+			if to, err = parseChoice(last, n); err != nil {
+				return nil, err
+			}
+		}
+		if to < from {
+			return nil, errors.New(i18n.T("validate.invalidRange", field))
+		}
+		for i := from; i <= to; i++ {
+			chosen[i] = true
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, errors.New(i18n.T("validate.noChoice"))
+	}
+
+	indexes := make([]int, 0, len(chosen))
+	for i := range chosen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
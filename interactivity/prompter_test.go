@@ -0,0 +1,156 @@
+// Below, the package interactivity (@prompter_test.go) tests the prompts of Prompter against
+// in-memory input and output.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package interactivity_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+// TestPrompter checks the prompts of interactivity.Prompter against in-memory input and output:
+// defaults and re-prompting on invalid answers, numbered choices, tab completion of paths,
+// masked passwords and cancellation.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestPrompter(t *testing.T) {
+	ctx := context.Background()
+	newPrompter := func(input string) (*interactivity.Prompter, *bytes.Buffer) {
+		out := new(bytes.Buffer)
+		return interactivity.NewPrompter(strings.NewReader(input), out), out
+	}
+
+	confirmations := []struct {
+		input string
+		def   interactivity.Default
+		want  bool
+	}{
+		{"y\n", interactivity.DefaultNo, true},
+		{"YES\n", interactivity.NoDefault, true},
+		{"N\n", interactivity.DefaultYes, false},
+		{"\n", interactivity.DefaultYes, true},
+		{"\n", interactivity.DefaultNo, false},
+		{"maybe\n\nyes\n", interactivity.NoDefault, true},
+	}
+	for _, tc := range confirmations {
+		p, out := newPrompter(tc.input)
+		got, err := p.Confirm(ctx, "Overwrite?", tc.def)
+		if err != nil || got != tc.want {
+			t.Errorf("Confirm(%q) = %v, %v, want %v", tc.input, got, err, tc.want)
+		}
+		if strings.HasPrefix(tc.input, "maybe") && strings.Count(out.String(), "Please answer yes or no.") != 2 {
+			t.Errorf("Confirm(%q) wrote %q, want two reminders", tc.input, out.String())
+		}
+	}
+	p, _ := newPrompter("")
+	if _, err := p.Confirm(ctx, "Overwrite?", interactivity.DefaultNo); !errors.Is(err, io.EOF) {
+		t.Errorf("Confirm() at the end of input = %v, want io.EOF", err)
+	}
+
+	p, out := newPrompter("0\nthree\n2\n")
+	if got, err := p.Select(ctx, "Select the output format:", []string{"csv", "json", "jsonl"}, 0); err != nil || got != 1 {
+		t.Errorf("Select() = %d, %v, want 1", got, err)
+	}
+	if !strings.Contains(out.String(), "2) json") || strings.Count(out.String(), "Invalid input") != 2 {
+		t.Errorf("Select() wrote %q, want numbered choices and two errors", out.String())
+	}
+	p, _ = newPrompter("\n")
+	if got, err := p.Select(ctx, "Select:", []string{"csv", "json"}, 1); err != nil || got != 1 {
+		t.Errorf("Select() with the default = %d, %v, want 1", got, err)
+	}
+
+	multiSelections := []struct {
+		input string
+		want  []int
+	}{
+		{"3, 1\n", []int{0, 2}},
+		{"2-4 1\n", []int{0, 1, 2, 3}},
+		{"All\n", []int{0, 1, 2, 3, 4}},
+		{"\n", []int{1}},
+		{"4-2\n6\n5\n", []int{4}},
+	}
+	for _, tc := range multiSelections {
+		p, _ := newPrompter(tc.input)
+		got, err := p.MultiSelect(ctx, "Select:", []string{"a", "b", "c", "d", "e"}, []int{1})
+		if err != nil || fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("MultiSelect(%q) = %v, %v, want %v", tc.input, got, err, tc.want)
+		}
+	}
+
+	p, out = newPrompter("\n  \nbackup\n")
+	if got, err := p.Input(ctx, "Enter a name: ", "", interactivity.Required); err != nil || got != "backup" {
+		t.Errorf("Input() = %q, %v, want backup", got, err)
+	}
+	if strings.Count(out.String(), "Invalid input: a value is required") != 2 {
+		t.Errorf("Input() wrote %q, want two errors", out.String())
+	}
+	p, _ = newPrompter("\n")
+	if got, err := p.Input(ctx, "Enter the name: ", "sessions", nil); err != nil || got != "sessions" {
+		t.Errorf("Input() with the default = %q, %v, want sessions", got, err)
+	}
+
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["backups/chat-2023.json"] = []byte("{}")
+	mockFS.Files["backups/chat-2024.json"] = []byte("{}")
+	mockFS.Files["backups/.hidden.json"] = []byte("{}")
+	mockFS.Files["notes.txt"] = []byte("")
+	if got := interactivity.CompletePath(mockFS, "ba"); len(got) != 1 || got[0] != "backups"+string(filepath.Separator) {
+		t.Errorf("CompletePath(ba) = %v, want the backups directory", got)
+	}
+	if got := interactivity.CompletePath(mockFS, "backups/"); len(got) != 2 {
+		t.Errorf("CompletePath(backups/) = %v, want the two visible files", got)
+	}
+
+	// Tab completes the directory, then the common prefix, then lists the candidates; the
+	// incomplete first answer is rejected.
+	p, out = newPrompter("ba\t\t\t\rb\tc\t3\x7f4\t\r")
+	p.FS, p.Editor = mockFS, true
+	got, err := p.Path(ctx, "Enter the path to the JSON file: ", "", interactivity.ExistingFile(mockFS))
+	if err != nil || got != "backups/chat-2024.json" {
+		t.Errorf("Path() = %q, %v, want backups/chat-2024.json", got, err)
+	}
+	if !strings.Contains(out.String(), "chat-2023.json  chat-2024.json") || !strings.Contains(out.String(), "Invalid input") {
+		t.Errorf("Path() wrote %q, want the candidates listed and one error", out.String())
+	}
+
+	p, out = newPrompter(" s3cr\x7fret \r")
+	p.Editor = true
+	if got, err := p.Password(ctx, "Enter the passphrase: "); err != nil || got != " s3cret " {
+		t.Errorf("Password() = %q, %v, want \" s3cret \"", got, err)
+	}
+	if strings.Contains(out.String(), "s3c") || !strings.Contains(out.String(), "*****\b \b***") {
+		t.Errorf("Password() echoed %q, want asterisks", out.String())
+	}
+	p, _ = newPrompter("abc\x03")
+	p.Editor = true
+	if _, err := p.Password(ctx, "Enter the passphrase: "); !errors.Is(err, context.Canceled) {
+		t.Errorf("Password() after Ctrl+C = %v, want context.Canceled", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	p = interactivity.NewPrompter(pipeReader, io.Discard)
+	if _, err := p.Confirm(cancelled, "Overwrite?", interactivity.DefaultNo); !errors.Is(err, context.Canceled) {
+		t.Errorf("Confirm() with a cancelled context = %v, want context.Canceled", err)
+	}
+	p.Editor = true
+	if _, err := p.Password(cancelled, "Enter the passphrase: "); !errors.Is(err, context.Canceled) {
+		t.Errorf("Password() with a cancelled context = %v, want context.Canceled", err)
+	}
+	// The cancelled prompts leave no read behind that would take the next answer.
+	go pipeWriter.Write([]byte("y\n"))
+	if got, err := p.Confirm(ctx, "Overwrite?", interactivity.DefaultNo); err != nil || !got {
+		t.Errorf("Confirm() after cancelled prompts = %v, %v, want true", got, err)
+	}
+}
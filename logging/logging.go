@@ -0,0 +1,159 @@
+// Package logging provides the structured logging of the CLI tool, built on log/slog, and the
+// machine-readable summary written at the end of every run.
+//
+// Loggers write text or JSON records. A Recorder wraps the handler of a logger to keep the
+// warnings and errors logged during a run, whatever the level of the logger, so that they can be
+// reported in the Summary of the run, which orchestration tools can parse instead of the messages
+// meant for people.
+//
+// # Example Usage
+//
+//	summary := logging.NewSummary("run", time.Now())
+//	logger, err := logging.NewLogger(os.Stderr, logging.FormatJSON, slog.LevelInfo)
+//	if err != nil {
+//		return err
+//	}
+//	slog.SetDefault(slog.New(logging.NewRecorder(logger.Handler(), summary)))
+//
+//	// ... run the export, adding its inputs and outputs to the summary ...
+//
+//	summary.Finish(time.Now(), 0)
+//	summary.WriteJSON(os.Stdout)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	// FormatText writes records as key=value pairs.
+	FormatText = "text"
+	// FormatJSON writes records as JSON objects, one per line.
+	FormatJSON = "json"
+)
+
+// Formats returns the names of the record formats accepted by NewLogger.
+func Formats() []string {
+	return []string{FormatText, FormatJSON}
+}
+
+// NewLogger returns a logger writing records of level or above to w in format.
+func NewLogger(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case FormatText:
+		return slog.New(slog.NewTextHandler(w, options)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, options)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+}
+
+// Discard returns a logger that writes nothing, for runs without a log.
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// ParseLevel returns the level with the given name, such as debug, info, warn or error.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("unknown log level %q (available: debug, info, warn, error)", name)
+	}
+	return level, nil
+}
+
+// Collector receives the warnings and errors kept by a Recorder. Summary is a Collector.
+type Collector interface {
+	// Warn receives the message of a warning, followed by its attributes.
+	Warn(message string)
+	// Error receives the message of an error, followed by its attributes.
+	Error(message string)
+}
+
+// Recorder is a slog.Handler passing records on to another handler and sending the warnings and
+// errors to a Collector, even when the other handler discards them.
+type Recorder struct {
+	handler   slog.Handler
+	collector Collector
+	attrs     string // Attributes added by WithAttrs, formatted as key=value pairs.
+	group     string // Prefix of the keys of the attributes, from WithGroup.
+}
+
+// Ensure Recorder is a handler.
+var _ slog.Handler = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder passing records on to handler and sending warnings and errors
+// to collector.
+func NewRecorder(handler slog.Handler, collector Collector) *Recorder {
+	return &Recorder{handler: handler, collector: collector}
+}
+
+// Enabled reports whether records of level are handled: warnings and errors always are.
+func (r *Recorder) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn || r.handler.Enabled(ctx, level)
+}
+
+// Handle sends warnings and errors to the collector and passes record on if the other handler
+// is enabled for its level.
+func (r *Recorder) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level >= slog.LevelWarn {
+		message := record.Message + r.attrs
+		record.Attrs(func(attr slog.Attr) bool {
+			message += formatAttr(r.group, attr)
+			return true
+		})
+		if record.Level >= slog.LevelError {
+			r.collector.Error(message)
+		} else {
+			r.collector.Warn(message)
+		}
+	}
+	if !r.handler.Enabled(ctx, record.Level) {
+		return nil
+	}
+	return r.handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a Recorder whose records include attrs.
+func (r *Recorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	formatted := r.attrs
+	for _, attr := range attrs {
+		formatted += formatAttr(r.group, attr)
+	}
+	return &Recorder{handler: r.handler.WithAttrs(attrs), collector: r.collector, attrs: formatted, group: r.group}
+}
+
+// WithGroup returns a Recorder whose following attributes belong to the group name.
+func (r *Recorder) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return r
+	}
+	return &Recorder{handler: r.handler.WithGroup(name), collector: r.collector, attrs: r.attrs, group: r.group + name + "."}
+}
+
+// formatAttr formats attr as " key=value", with its key prefixed by group.
+func formatAttr(group string, attr slog.Attr) string {
+	if attr.Equal(slog.Attr{}) {
+		return ""
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		prefix := group
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		var formatted string
+		for _, member := range attr.Value.Group() {
+			formatted += formatAttr(prefix, member)
+		}
+		return formatted
+	}
+	return fmt.Sprintf(" %s%s=%v", group, attr.Key, attr.Value.Resolve())
+}
@@ -0,0 +1,89 @@
+// Below, the package logging (@logging_test.go) tests the recording of warnings and errors into
+// the summary of a run, and the summary itself.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/logging"
+)
+
+// TestLoggingSummary verifies that warnings and errors reach the summary of a run whatever the
+// level of the log, that records are written as JSON, and that the summary is written with every
+// field.
+func TestLoggingSummary(t *testing.T) {
+	summary := logging.NewSummary("run", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	var records bytes.Buffer
+	inner, err := logging.NewLogger(&records, logging.FormatJSON, slog.LevelError)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(logging.NewRecorder(inner.Handler(), summary))
+	logger.Info("not logged")
+	logger.With("path", "a.json").Warn("input recovered", "sessions", 2)
+	logger.WithGroup("export").Error("output failed", "path", "b.csv")
+	if len(summary.Warnings) != 1 || summary.Warnings[0] != "input recovered path=a.json sessions=2" {
+		t.Errorf("Warnings = %q", summary.Warnings)
+	}
+	if len(summary.Errors) != 1 || summary.Errors[0] != "output failed export.path=b.csv" {
+		t.Errorf("Errors = %q", summary.Errors)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(records.Bytes(), &record); err != nil || record["msg"] != "output failed" {
+		t.Errorf("Only the error should be logged as JSON, got %q (%v)", records.String(), err)
+	}
+	if _, err := logging.NewLogger(io.Discard, "xml", slog.LevelInfo); err == nil {
+		t.Error("NewLogger(xml) succeeded, want an error")
+	}
+	if level, err := logging.ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("ParseLevel(debug) = %v, %v", level, err)
+	}
+
+	// The output layer logs its warnings too.
+	o := bannercli.NewOutput(io.Discard, io.Discard)
+	o.Logger = logger
+	o.Warn("\n[GopherHelper] Skipped out.csv, which already exists.\n")
+	if len(summary.Warnings) != 2 || summary.Warnings[1] != "Skipped out.csv, which already exists." {
+		t.Errorf("Warnings after Output.Warn = %q", summary.Warnings)
+	}
+
+	summary.AddInputs(1, "testing.json")
+	summary.AddOutput(logging.OutputSummary{Path: "messages.jsonl", Format: "jsonl",
+		Rows: map[string]int{"messages.jsonl": 2}, Files: []string{"messages.jsonl"}}, 1)
+	summary.AddOutput(logging.OutputSummary{Path: "sessions.csv", Format: "separate",
+		Rows: map[string]int{"sessions.csv": 1, "messages.csv": 2}, Files: []string{"sessions.csv", "messages.csv"}}, 1)
+	summary.Finish(summary.StartedAt.Add(1500*time.Millisecond), 0)
+	var written bytes.Buffer
+	if err := summary.WriteJSON(&written); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Command          string
+		Status           string
+		Inputs           []string
+		SessionsRead     int
+		SessionsExported int
+		Outputs          []logging.OutputSummary
+		Files            []string
+		Warnings         []string
+		Errors           []string
+		DurationSeconds  float64
+	}
+	if err := json.Unmarshal(written.Bytes(), &decoded); err != nil {
+		t.Fatalf("WriteJSON() wrote invalid JSON: %v", err)
+	}
+	if decoded.Command != "run" || decoded.Status != logging.StatusOK || len(decoded.Inputs) != 1 || decoded.SessionsRead != 1 ||
+		decoded.SessionsExported != 1 || len(decoded.Outputs) != 2 || len(decoded.Files) != 3 ||
+		len(decoded.Warnings) != 2 || len(decoded.Errors) != 1 || decoded.DurationSeconds != 1.5 {
+		t.Errorf("WriteJSON() = %s", written.String())
+	}
+}
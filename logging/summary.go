@@ -0,0 +1,137 @@
+// Below, the package logging (@summary.go) provides Summary, the machine-readable report of a run:
+// the inputs read, the sessions read and exported, the records and files written for every output,
+// the warnings and errors, the duration and the exit code.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	// StatusOK is the status of a run that exited with code 0.
+	StatusOK = "ok"
+	// StatusFailed is the status of a run that exited with another code.
+	StatusFailed = "failed"
+)
+
+// OutputSummary describes what was written for one output of a run.
+type OutputSummary struct {
+	Path   string         `json:"path"`           // Output path, as given by the user or the configuration.
+	Format string         `json:"format"`         // Name of the exporter, or of the operation for other commands.
+	Rows   map[string]int `json:"rows,omitempty"` // Records written to every file, such as CSV rows or JSONL lines, if the format counts them.
+	Files  []string       `json:"files"`          // Files written for the output.
+}
+
+// Summary reports a run. It is safe for concurrent use, and is a Collector of the warnings and
+// errors logged during the run.
+type Summary struct {
+	mu               sync.Mutex
+	Command          string          `json:"command"`          // Command that was run; "interactive" without one.
+	Status           string          `json:"status"`           // StatusOK or StatusFailed, set by Finish.
+	ExitCode         int             `json:"exitCode"`         // Exit code of the process, set by Finish.
+	Inputs           []string        `json:"inputs"`           // Backup files read.
+	SessionsRead     int             `json:"sessionsRead"`     // Sessions read from the inputs.
+	SessionsExported int             `json:"sessionsExported"` // Sessions written to the outputs, after filtering.
+	Outputs          []OutputSummary `json:"outputs"`          // Outputs written, in order.
+	Files            []string        `json:"files"`            // Every file created or written, in order.
+	Warnings         []string        `json:"warnings"`         // Warnings logged during the run.
+	Errors           []string        `json:"errors"`           // Errors logged during the run.
+	StartedAt        time.Time       `json:"startedAt"`        // When the run started.
+	Duration         float64         `json:"durationSeconds"`  // Duration of the run in seconds, set by Finish.
+}
+
+// Ensure Summary collects the warnings and errors of a Recorder.
+var _ Collector = (*Summary)(nil)
+
+// NewSummary returns an empty Summary of a run of command started at start.
+func NewSummary(command string, start time.Time) *Summary {
+	return &Summary{
+		Command:   command,
+		Inputs:    []string{},
+		Outputs:   []OutputSummary{},
+		Files:     []string{},
+		Warnings:  []string{},
+		Errors:    []string{},
+		StartedAt: start,
+	}
+}
+
+// AddInputs records that paths were read and held sessions sessions together.
+func (s *Summary) AddInputs(sessions int, paths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Inputs = append(s.Inputs, paths...)
+	s.SessionsRead += sessions
+}
+
+// AddOutput records an output and the sessions written to it. The sessions of the largest output
+// are reported as exported, since every output of a run holds the same sessions.
+func (s *Summary) AddOutput(output OutputSummary, sessions int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if output.Files == nil {
+		output.Files = []string{}
+	}
+	s.Outputs = append(s.Outputs, output)
+	s.Files = append(s.Files, output.Files...)
+	s.SessionsExported = max(s.SessionsExported, sessions)
+}
+
+// Warn records a warning.
+func (s *Summary) Warn(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Warnings = append(s.Warnings, message)
+}
+
+// Error records an error.
+func (s *Summary) Error(message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Errors = append(s.Errors, message)
+}
+
+// Finish records the end of the run at end with exitCode.
+func (s *Summary) Finish(end time.Time, exitCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ExitCode = exitCode
+	s.Status = StatusOK
+	if exitCode != 0 {
+		s.Status = StatusFailed
+	}
+	s.Duration = end.Sub(s.StartedAt).Seconds()
+}
+
+// WriteJSON writes the summary to w as an indented JSON object.
+func (s *Summary) WriteJSON(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// LogValue returns the summary as a group of attributes, so that it can be logged in one record.
+func (s *Summary) LogValue() slog.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slog.GroupValue(
+		slog.String("command", s.Command),
+		slog.String("status", s.Status),
+		slog.Int("exitCode", s.ExitCode),
+		slog.Any("inputs", s.Inputs),
+		slog.Int("sessionsRead", s.SessionsRead),
+		slog.Int("sessionsExported", s.SessionsExported),
+		slog.Any("files", s.Files),
+		slog.Int("warnings", len(s.Warnings)),
+		slog.Int("errors", len(s.Errors)),
+		slog.Float64("durationSeconds", s.Duration),
+	)
+}
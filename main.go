@@ -6,50 +6,71 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/logging"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tui"
 )
 
 const (
-	// Output format options
-	OutputFormatCSV         = exporter.FormatOptionInline // Assuming this is the inline format
-	OutputFormatDataset     = 2                           // Keep the dataset format option as is
-	OutputFormatInline      = exporter.FormatOptionInline
-	OutputFormatPerLine     = exporter.FormatOptionPerLine
-	OutputFormatSeparateCSV = exporter.OutputFormatSeparateCSVFiles // Assuming this is the separate CSV files format
-	OutputFormatJSONInCSV   = exporter.FormatOptionJSON             // Assuming this is the JSON format
+	// Output format menu options; further options are generated from the exporter registry.
+	OutputFormatCSV = "1"
+
+	// CSVExtension is the extension of the exporters listed in the CSV menu.
+	CSVExtension = ".csv"
 
 	// File type
 	FileTypeDataset = "dataset"
 
-	// Prompt messages
-	PromptEnterJSONFilePath        = "Enter the path to the JSON file: "
-	PromptRepairData               = "Do you want to repair data? (yes/no): "
-	PromptSelectOutputFormat       = "Select the output format:\n1) CSV\n2) Hugging Face Dataset\n"
-	PromptSelectCSVOutputFormat    = "Select the message output format:\n1) Inline Formatting\n2) One Message Per Line\n3) JSON String in CSV\n4) Separate Files for Sessions and Messages\n"
-	PromptEnterCSVFileName         = "Enter the name of the CSV file to save: "
-	PromptEnterSessionsCSVFileName = "Enter the name of the sessions CSV file to save: "
-	PromptEnterMessagesCSVFileName = "Enter the name of the messages CSV file to save: "
-	PromptSaveOutputToFile         = "Do you want to save the output to a file? (yes/no)\n"
-	PromptEnterFileName            = "Enter the name of the %s file to save: "
+	// Prompt messages, as keys of the message catalogues of the i18n package
+	PromptEnterJSONFilePath     i18n.Key = "prompt.jsonFilePath"
+	PromptRepairData            i18n.Key = "prompt.repairData"
+	PromptSelectOutputFormat    i18n.Key = "prompt.selectOutputFormat"
+	PromptSelectCSVOutputFormat i18n.Key = "prompt.selectCSVOutputFormat"
+	PromptEnterCSVFileName      i18n.Key = "prompt.csvFileName"
+	PromptEnterOption           i18n.Key = "prompt.option"
+	PromptSaveOutputToFile      i18n.Key = "prompt.saveOutputToFile"
+	PromptEnterFileNameKey      i18n.Key = "prompt.fileName"
+	PromptRecoverData           i18n.Key = "prompt.recoverData"
+	PromptBrowseSessions        i18n.Key = "prompt.browseSessions"
 )
 
+// PromptEnterFileName is the prompt for the name of an output file, as a format taking the type of
+// the file. setupLanguage sets it to the message of PromptEnterFileNameKey in the selected language.
+var PromptEnterFileName = i18n.T(PromptEnterFileNameKey)
+
+// backupKeys supplies the passphrase of encrypted backups. It reads the encryption.PassphraseEnv
+// environment variable, and main adds an interactive prompt as a fallback.
+var backupKeys = &encryption.KeySource{}
+
+// overwritePolicy tells what to do with existing output files. It is set by the -overwrite flag,
+// and asks the user by default.
+var overwritePolicy interactivity.OverwritePolicy
+
 // main initializes the application, setting up context for cancellation and
 // starting the user interaction flow for data processing and exporting.
 func main() {
-	bannercli.PrintTypingBanner("ChatGPT Session Exporter", 100*time.Millisecond)
+	// Parse the global flags, which precede the command, if any.
+	parseGlobalFlags()
+
+	bannercli.PrintTypingBanner(i18n.T("banner.title"), 100*time.Millisecond)
 	// Prepare a cancellable context for handling graceful shutdown.
 	// This context will be passed down to functions that support cancellation.
 	ctx, cancel := context.WithCancel(context.Background())
@@ -59,71 +80,156 @@ func main() {
 	// This listens for system signals like SIGINT (Ctrl+C) and terminates the application.
 	setupSignalHandling(cancel)
 
+	// Finish the log and the summary of the run when the interactive flow returns.
+	defer finishRun(ExitOK)
+
+	// Run a non-interactive command such as "watch" when one is given on the command line.
+	if flag.NArg() > 0 {
+		code := runCommand(ctx, flag.Arg(0), flag.Args()[1:])
+		cancel()
+		exit(code)
+	}
+
 	// Initialize a buffered reader for user input.
 	reader := bufio.NewReader(os.Stdin)
 
-	// Collect the JSON file path from the user.
-	jsonFilePath, err := promptForInput(ctx, reader, PromptEnterJSONFilePath)
+	// Ask for the passphrase of an encrypted backup only when one is read.
+	backupKeys.Prompt = func() (string, error) {
+		return interactivity.PromptPassphrase(ctx, reader, i18n.T(PromptEnterPassphrase))
+	}
+
+	// Create an instance of your real file system implementation.
+	realFS := &filesystem.RealFileSystem{}
+
+	// Collect the JSON file path from the user, completing it with the Tab key in a terminal.
+	prompter := interactivity.NewTerminalPrompter(os.Stdin, reader, os.Stdout, realFS)
+	jsonFilePath, err := prompter.Path(ctx, i18n.T(PromptEnterJSONFilePath), "", interactivity.ExistingFile(realFS))
 	if err != nil {
-		handleInputError(err)
+		handleError(i18n.T("error.readInput"), err)
 		return
 	}
 
 	// Offer the user an option to repair the data before processing.
-	repairData, err := promptForInput(ctx, reader, PromptRepairData)
+	repairData, err := confirm(ctx, reader, i18n.T(PromptRepairData))
 	if err != nil {
-		handleInputError(err)
+		handleError(i18n.T("error.readInput"), err)
 		return
 	}
 
-	if strings.ToLower(repairData) == "yes" {
-		// Create an instance of your real file system implementation.
-		realFS := &filesystem.RealFileSystem{}
+	if repairData {
 		// Pass the real file system instance when calling repairJSONData.
 		newFilePath, err := repairJSONData(realFS, ctx, jsonFilePath)
+		if err != nil && isCorruptedJSONError(err) {
+			// Offer the tolerant recovery mode when the data cannot be parsed as-is.
+			newFilePath, err = promptRecoverJSONData(realFS, ctx, reader, jsonFilePath)
+		}
 		if err != nil {
-			errorMessage := fmt.Sprintf("Error: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
+			handleError(i18n.T("error.repairJSON"), err)
 		}
-		successMessage := fmt.Sprintf("Repaired JSON data has been saved to: %s\n", newFilePath)
-		bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
-		os.Exit(0)
+		recordInput(jsonFilePath, 0)
+		recordFile("repair", newFilePath, 0)
+		successMessage := i18n.T("success.repaired", newFilePath) + "\n"
+		bannercli.Success(successMessage)
+		exit(ExitOK)
 	}
 
 	// Load and parse the JSON file into session data.
-	store, err := exporter.ReadJSONFromFile(jsonFilePath)
+	store, err := exporter.ReadJSONFromFileWithKeys(realFS, jsonFilePath, backupKeys)
 	if err != nil {
-		errorMessage := fmt.Sprintf("Error reading or parsing the JSON file: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
+		handleError(i18n.T("error.parseJSON"), err)
+	}
+
+	// Offer the session browser to pick the sessions to export, when running in a terminal.
+	sessions := store.ChatNextWebStore.Sessions
+	recordInput(jsonFilePath, len(sessions))
+	bannercli.Debug(fmt.Sprintf("Loaded %d sessions from %s", len(sessions), jsonFilePath))
+	if tui.IsTerminal(os.Stdin, os.Stdout) {
+		browse, err := confirm(ctx, reader, i18n.T(PromptBrowseSessions))
+		if err != nil {
+			handleError(i18n.T("error.readInput"), err)
+			return
+		}
+		if browse {
+			selection, ok := browseSessions(ctx, reader, sessions)
+			if !ok {
+				return
+			}
+			sessions = selection
+		}
 	}
 
 	// Query the user for the preferred output format and process accordingly.
-	outputOption, err := promptForInput(ctx, reader, PromptSelectOutputFormat)
+	outputOption, err := promptForInput(ctx, reader, outputFormatMenu())
 	if err != nil {
-		handleInputError(err)
+		handleError(i18n.T("error.readInput"), err)
 		return
 	}
 
-	// Create an instance of your real file system implementation.
-	realFS := &filesystem.RealFileSystem{}
 	// Pass the real file system instance when calling processOutputOption.
-	processOutputOption(realFS, ctx, reader, outputOption, store.ChatNextWebStore.Sessions)
+	processOutputOption(realFS, ctx, reader, outputOption, sessions)
 }
 
-// handleInputError checks the type of error and handles it accordingly.
-func handleInputError(err error) {
-	if err == context.Canceled || err == io.EOF {
-		// Handle a context cancellation or EOF, if applicable
-		bannercli.PrintTypingBanner("\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-		os.Exit(0)
-	} else {
-		// Format the error message before passing it to PrintTypingBanner
-		errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
-	}
+// parseGlobalFlags parses the flags preceding the command, if any, and applies them to the
+// language, the overwrite policy, the output layer and the structured log. Invalid flags exit with status code 2.
+func parseGlobalFlags() {
+	output := bannercli.Default()
+	quiet := flag.Bool("quiet", false, "only print errors")
+	verbose := flag.Bool("verbose", false, "also print debugging details")
+	flag.Var(&output.Speed, "speed", "the `speed` of the typing animations: instant, fast, normal, slow or a factor of their delays")
+	lang := flag.String("lang", "", "the `language` of the messages: "+strings.Join(i18n.Languages(), ", ")+" (default from LC_ALL, LC_MESSAGES or LANG)")
+	theme := flag.String("theme", output.Theme.Name, "the colour `theme` of the messages: "+strings.Join(bannercli.ThemeNames(), ", "))
+	flag.Var(&overwritePolicy, "overwrite", "the `policy` for existing output files: "+strings.Join(interactivity.OverwritePolicyNames(), ", ")+" (default ask)")
+	logPath := flag.String("log", "", "write a structured log to `file` (\""+StandardStream+"\" for the standard error)")
+	logFormat := flag.String("log-format", logging.FormatText, "the `format` of the log: "+strings.Join(logging.Formats(), ", "))
+	logLevel := flag.String("log-level", "info", "the lowest `level` logged: debug, info, warn or error")
+	flag.StringVar(&summaryPath, "summary", "", "write a JSON summary of the run to `file` when it ends (\""+StandardStream+"\" for the standard output)")
+	flag.Usage = func() {
+		setupLanguage(*lang) // ignore error; an unknown language leaves the usage in English
+		printCommandUsage()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		runSummary.Command = flag.Arg(0)
+	}
+	if err := setupLanguage(*lang); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitUsage)
+	}
+	if err := setupLogging(*logPath, *logFormat, *logLevel); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(ExitUsage)
+	}
+
+	switch {
+	case *quiet && *verbose:
+		fmt.Fprintln(os.Stderr, i18n.T("error.quietVerbose"))
+		exit(ExitUsage)
+	case *quiet:
+		output.Level = bannercli.LevelQuiet
+	case *verbose:
+		output.Level = bannercli.LevelVerbose
+	}
+	selected, ok := bannercli.LookupTheme(*theme)
+	if !ok {
+		fmt.Fprintln(os.Stderr, i18n.T("error.unknownTheme", *theme, strings.Join(bannercli.ThemeNames(), ", ")))
+		exit(ExitUsage)
+	}
+	output.Theme = selected
+}
+
+// setupLanguage selects the message catalogue of lang, or of the locale of the environment if lang
+// is empty. An unknown lang is an error, while an unknown locale falls back to English.
+func setupLanguage(lang string) error {
+	catalogue, err := i18n.Load(i18n.Detect(lang, os.Getenv))
+	switch {
+	case err == nil:
+		i18n.SetDefault(catalogue)
+		PromptEnterFileName = i18n.T(PromptEnterFileNameKey)
+	case lang != "":
+		return errors.New(i18n.T("error.unknownLanguage", lang, strings.Join(i18n.Languages(), ", ")))
+	}
+	return nil
 }
 
 // setupSignalHandling configures the application to respond to interrupt signals for
@@ -139,7 +245,7 @@ func setupSignalHandling(cancel context.CancelFunc) {
 	// Start a new goroutine that will block waiting for a signal.
 	go func() {
 		<-signals // Wait for a signal
-		fmt.Println("\n[GopherHelper] Exiting gracefully...")
+		fmt.Println("\n[GopherHelper] " + i18n.T("banner.exiting"))
 		cancel() // Cancel the context
 	}()
 }
@@ -147,36 +253,171 @@ func setupSignalHandling(cancel context.CancelFunc) {
 // promptForInput displays a prompt to the user and returns the trimmed input response.
 // It supports context cancellation, which can interrupt the blocking read operation.
 func promptForInput(ctx context.Context, reader *bufio.Reader, prompt string) (string, error) {
-	fmt.Print(prompt)
-	type result struct {
-		input string
-		err   error
-	}
-	resultChan := make(chan result)
-
-	go func() {
-		input, err := reader.ReadString('\n')
-		resultChan <- result{input: input, err: err}
-	}()
+	return interactivity.NewPrompter(reader, os.Stdout).Line(ctx, prompt)
+}
 
-	select {
-	case <-ctx.Done():
-		return "", ctx.Err()
-	case res := <-resultChan:
-		return strings.TrimSpace(res.input), res.err
-	}
+// confirm asks a yes/no question, accepting y, yes, n or no in any case; pressing Enter answers no.
+func confirm(ctx context.Context, reader *bufio.Reader, question string) (bool, error) {
+	return interactivity.NewPrompter(reader, os.Stdout).Confirm(ctx, question, interactivity.DefaultNo)
 }
 
 // processOutputOption directs the processing flow based on the user's choice of output format.
 // It now respects the context for cancellation, ensuring long-running operations can be interrupted.
 func processOutputOption(fs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, outputOption string, sessions []exporter.Session) {
-	switch outputOption {
-	case `1`:
+	if outputOption == OutputFormatCSV {
 		processCSVOption(fs, ctx, reader, sessions)
-	case `2`:
+		return
+	}
+
+	// Every other option selects one of the non-CSV exporters, numbered after the CSV option.
+	e, ok := selectMenuExporter(otherExporters(), outputOption, 2)
+	if !ok {
+		bannercli.Error("\n" + i18n.T("error.invalidOutputOption"))
+		return
+	}
+	if e.Name() == exporter.FormatNameDataset {
 		processDatasetOption(fs, ctx, reader, sessions)
-	default:
-		bannercli.PrintTypingBanner("\nInvalid output option.", 100*time.Millisecond)
+		return
+	}
+	processExporterOption(fs, ctx, reader, e, sessions)
+}
+
+// otherExporters returns the registered exporters that are not listed in the CSV menu.
+func otherExporters() []exporter.Exporter {
+	var others []exporter.Exporter
+	for _, e := range exporter.Exporters() {
+		if !strings.EqualFold(e.Extension(), CSVExtension) {
+			others = append(others, e)
+		}
+	}
+	return others
+}
+
+// outputFormatMenu builds the output format prompt from the exporter registry.
+func outputFormatMenu() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T(PromptSelectOutputFormat))
+	fmt.Fprintf(&sb, "%s) CSV\n", OutputFormatCSV)
+	for i, e := range otherExporters() {
+		fmt.Fprintf(&sb, "%d) %s\n", i+2, e.Description())
+	}
+	return sb.String()
+}
+
+// csvFormatMenu builds the CSV format prompt from the exporter registry.
+func csvFormatMenu() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T(PromptSelectCSVOutputFormat))
+	for i, e := range exporter.ExportersByExtension(CSVExtension) {
+		fmt.Fprintf(&sb, "%d) %s\n", i+1, e.Description())
+	}
+	return sb.String()
+}
+
+// selectMenuExporter returns the exporter for a menu choice, where the first exporter is numbered first.
+func selectMenuExporter(exporters []exporter.Exporter, choice string, first int) (exporter.Exporter, bool) {
+	index, err := strconv.Atoi(choice)
+	if err != nil || index < first || index-first >= len(exporters) {
+		return nil, false
+	}
+	return exporters[index-first], true
+}
+
+// outputLabel returns the label used in prompts for the primary output of an exporter,
+// for example "CSV" or "sessions CSV".
+func outputLabel(e exporter.Exporter) string {
+	label := strings.ToUpper(strings.TrimPrefix(e.Extension(), "."))
+	if namer, ok := e.(exporter.PrimaryOutputNamer); ok {
+		label = namer.PrimaryOutputName() + " " + label
+	}
+	return label
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// promptExporterOptions prompts the user for the required options in the exporter's schema; the
+// others, such as the CSV dialect, keep their defaults and are set in pipeline configurations.
+// Options that name additional output files get the overwrite policy like the primary output,
+// unless the export is appended, in which case they are appended to as well.
+// It returns false if the user cancelled the operation.
+func promptExporterOptions(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, e exporter.Exporter, appending bool) (exporter.Options, bool) {
+	options := make(exporter.Options)
+	prompter := interactivity.NewTerminalPrompter(os.Stdin, reader, os.Stdout, rfs)
+	for _, option := range e.Options() {
+		if !option.Required {
+			continue
+		}
+		var value string
+		var err error
+		if option.Path {
+			prompt := fmt.Sprintf(PromptEnterFileName, option.Name+" "+strings.ToUpper(strings.TrimPrefix(e.Extension(), ".")))
+			value, err = prompter.Path(ctx, prompt, option.Default, nil)
+		} else {
+			value, err = prompter.Input(ctx, i18n.T(PromptEnterOption, option.Description), option.Default, nil)
+		}
+		if err != nil {
+			handleError(i18n.T("error.readInput"), err)
+			return nil, false
+		}
+
+		if option.Path && !appending {
+			// Apply the overwrite policy to the additional output file
+			resolution, ok := resolveOutput(rfs, ctx, reader, value, false)
+			if !ok {
+				bannercli.Info(i18n.T("info.optionCancelled", option.Name))
+				return nil, false
+			}
+			value = resolution.Path
+		}
+		options[option.Name] = value
+	}
+	return options, true
+}
+
+// processExporterOption exports the sessions with a registered exporter that has no dedicated flow.
+// It prompts for the output file name and the exporter's options, and confirms overwrites.
+func processExporterOption(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, e exporter.Exporter, sessions []exporter.Session) {
+	fileName, err := promptForInput(ctx, reader, fmt.Sprintf(PromptEnterFileName, outputLabel(e)))
+	if err != nil {
+		handleError(i18n.T("error.readInput"), err)
+		return
+	}
+	if fileName == "" {
+		bannercli.Info(i18n.T("info.noFileName"))
+		return
+	}
+	if filepath.Ext(fileName) == "" {
+		fileName += e.Extension()
+	}
+
+	resolution, ok := resolveOutput(rfs, ctx, reader, fileName, canAppend(e))
+	if !ok {
+		return
+	}
+
+	options, ok := promptExporterOptions(rfs, ctx, reader, e, resolution.Action == interactivity.PolicyAppend)
+	if !ok {
+		return
+	}
+
+	var written []string
+	err = withProgressBar(ctx, func(ctx context.Context) (err error) {
+		written, err = exportResolved(ctx, rfs, e, sessions, resolution, options)
+		return err
+	})
+	if err != nil {
+		handleError(i18n.T("error.exportSessions"), err)
+	}
+
+	for _, name := range written {
+		successMessage := i18n.T("success.outputSaved", e.Description(), name) + "\n"
+		bannercli.Success(successMessage)
 	}
 }
 
@@ -187,24 +428,15 @@ func processOutputOption(fs filesystem.FileSystem, ctx context.Context, reader *
 // It prints the output file names or error messages accordingly.
 func processCSVOption(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
 	// Prompt the user for the CSV format option
-	formatOptionStr, err := promptForInput(ctx, reader, PromptSelectCSVOutputFormat)
+	formatOptionStr, err := promptForInput(ctx, reader, csvFormatMenu())
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
-		}
+		handleError(i18n.T("error.readInput"), err)
 	}
 
 	formatOption, err := strconv.Atoi(formatOptionStr)
 	if err != nil {
 		// If the format option is not a valid number, print an error message and return.
-		bannercli.PrintTypingBanner("\nInvalid format option.", 100*time.Millisecond)
+		bannercli.Error("\n" + i18n.T("error.invalidFormatOption"))
 		return
 	}
 
@@ -217,16 +449,7 @@ func processCSVOption(rfs filesystem.FileSystem, ctx context.Context, reader *bu
 func processDatasetOption(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
 	datasetOutput, err := exporter.ExtractToDataset(sessions)
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\n[GopherHelper] Error reading input: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
-		}
+		handleError(i18n.T("error.extractDataset"), err)
 	}
 	saveToFile(rfs, ctx, reader, datasetOutput, "dataset")
 }
@@ -235,23 +458,23 @@ func processDatasetOption(rfs filesystem.FileSystem, ctx context.Context, reader
 // This function now also accepts a context, allowing file operations to be cancelable.
 func saveToFile(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, content string, fileType string) {
 	// Ask user if they want to save the output to a file
-	saveOutput, err := promptForInput(ctx, reader, PromptSaveOutputToFile)
+	saveOutput, err := confirm(ctx, reader, i18n.T(PromptSaveOutputToFile))
 	if err != nil {
-		handleInputError(err)
+		handleError(i18n.T("error.readInput"), err)
 		return
 	}
 
-	if strings.ToLower(saveOutput) == "yes" {
+	if saveOutput {
 		// Determine the file name here (or pass it as a parameter)
 		fileName, err := promptForInput(ctx, reader, fmt.Sprintf(PromptEnterFileName, fileType))
 		if err != nil {
-			handleInputError(err)
+			handleError(i18n.T("error.readInput"), err)
 			return
 		}
 
 		// Ensure the fileName is not empty
 		if fileName == "" {
-			bannercli.PrintTypingBanner("No file name entered. Operation cancelled.", 100*time.Millisecond)
+			bannercli.Info(i18n.T("info.noFileName"))
 			return
 		}
 
@@ -262,50 +485,34 @@ func saveToFile(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Re
 			fileName += ".csv" // Assuming default fileType is CSV
 		}
 
-		// Check if the file exists and confirm overwrite if necessary
-		overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, fileName)
-		if err != nil {
-			handleInputError(err)
-			return
-		}
-		if !overwrite {
-			bannercli.PrintTypingBanner("Operation cancelled by the user.", 100*time.Millisecond)
+		// Apply the overwrite policy if the file exists; a whole document cannot be appended to
+		resolution, ok := resolveOutput(rfs, ctx, reader, fileName, false)
+		if !ok {
 			return
 		}
+		fileName = resolution.Path
 
 		// Now that we've confirmed, attempt to write the file
 		err = rfs.WriteFile(fileName, []byte(content), 0644)
 		if err != nil {
-			errorMessage := fmt.Sprintf("Error writing file: %s", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			return
+			handleError(i18n.T("error.writeFile"), err)
 		}
 
-		successMessage := fmt.Sprintf("%s output saved to %s", strings.ToTitle(fileType), fileName)
-		bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
+		recordFile(fileType, fileName, 0)
+		successMessage := i18n.T("success.outputSaved", strings.ToTitle(fileType), fileName)
+		bannercli.Success(successMessage)
 	} else {
-		bannercli.PrintTypingBanner("Save to file operation cancelled by the user.", 100*time.Millisecond)
-	}
-}
-
-// handleInputCancellation checks the error type and handles context cancellation and EOF.
-func handleInputCancellation(err error) {
-	if err == context.Canceled || err == io.EOF {
-		bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-		os.Exit(0)
-	} else {
-		errorMessage := fmt.Sprintf("\nError reading input: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		os.Exit(1)
+		bannercli.Info(i18n.T("info.saveCancelled"))
 	}
 }
 
 // repairJSONData attempts to repair malformed JSON data at the provided file path.
-// Despite accepting a context parameter, it currently does not support cancellation.
 // The function reads the broken JSON, repairs it, and writes the repaired JSON back to a new file.
+// Nothing is written once the context is cancelled, and the write itself is atomic with the real
+// file system, so an interrupt never leaves a truncated repaired backup behind.
 func repairJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath string) (string, error) {
-	// Read the broken JSON data using the file system interface
-	data, err := rfs.ReadFile(jsonFilePath)
+	// Read the broken JSON data using the file system interface, decrypting and decompressing it if needed
+	data, err := encryption.ReadFile(rfs, jsonFilePath, backupKeys)
 	if err != nil {
 		return "", err // Handle the error properly
 	}
@@ -317,7 +524,12 @@ func repairJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath
 	}
 
 	// Define the path for the repaired file
-	repairedPath := "repaired_" + jsonFilePath
+	repairedPath := "repaired_" + compression.TrimExtension(encryption.TrimExtension(jsonFilePath))
+
+	// Do not start writing if the user has already asked to exit.
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
 
 	// Write the repaired JSON data using the file system interface
 	err = rfs.WriteFile(repairedPath, repairedData, 0644)
@@ -329,126 +541,238 @@ func repairJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath
 	return repairedPath, nil
 }
 
+// isCorruptedJSONError reports whether err indicates malformed or truncated JSON,
+// which the tolerant recovery mode of the repairdata package may be able to salvage.
+func isCorruptedJSONError(err error) bool {
+	var parseErr *repairdata.ParseError
+	return errors.As(err, &parseErr)
+}
+
+// promptRecoverJSONData asks the user whether to attempt a tolerant recovery of corrupted JSON data
+// and, if confirmed, runs recoverJSONData and prints the recovery report.
+func promptRecoverJSONData(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, jsonFilePath string) (string, error) {
+	recoverData, err := confirm(ctx, reader, i18n.T(PromptRecoverData))
+	if err != nil {
+		handleError(i18n.T("error.readInput"), err)
+		return "", err
+	}
+	if !recoverData {
+		return "", errors.New(i18n.T("error.recoveryDeclined", jsonFilePath))
+	}
+
+	recoveredPath, report, err := recoverJSONData(rfs, ctx, jsonFilePath)
+	if err != nil {
+		return "", err
+	}
+	bannercli.Info(recoveryMessage(report))
+	return recoveredPath, nil
+}
+
+// recoveryMessage describes what a tolerant recovery did, as RecoveryReport.String does, in the
+// language of the messages.
+func recoveryMessage(report *repairdata.RecoveryReport) string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("recovery.recovered", report.SessionsRecovered, report.MessagesRecovered))
+	if report.BOMRemoved {
+		sb.WriteString("\n- " + i18n.T("recovery.bomRemoved"))
+	}
+	if report.InvalidUTF8Replaced {
+		sb.WriteString("\n- " + i18n.T("recovery.invalidUTF8"))
+	}
+	if report.ControlCharsEscaped > 0 {
+		sb.WriteString("\n- " + i18n.T("recovery.controlChars", report.ControlCharsEscaped))
+	}
+	if report.TrailingBytesRemoved > 0 {
+		sb.WriteString("\n- " + i18n.T("recovery.trailingBytes", report.TrailingBytesRemoved))
+	}
+	if report.Truncated {
+		sb.WriteString("\n- " + i18n.T("recovery.truncated", report.CorruptionOffset, report.DiscardedBytes))
+		if report.PartialPath != "" {
+			sb.WriteString("\n- " + i18n.T("recovery.partialPath", report.PartialPath))
+		}
+	}
+	return sb.String()
+}
+
+// recoverJSONData salvages every complete session and message from corrupted or truncated JSON data
+// at the provided file path, closes the remaining structure, and writes the result to a new file.
+// It returns the path to the recovered file and a report describing what was lost.
+func recoverJSONData(rfs filesystem.FileSystem, ctx context.Context, jsonFilePath string) (string, *repairdata.RecoveryReport, error) {
+	data, err := encryption.ReadFile(rfs, jsonFilePath, backupKeys)
+	if err != nil {
+		return "", nil, err
+	}
+
+	recoveredData, report, err := repairdata.RecoverSessionData(data)
+	if err != nil {
+		return "", report, err
+	}
+
+	// Use the same naming scheme as repairJSONData so the result can be loaded the same way.
+	recoveredPath := "repaired_" + compression.TrimExtension(encryption.TrimExtension(jsonFilePath))
+	if err := ctx.Err(); err != nil {
+		return "", report, err
+	}
+	if err := rfs.WriteFile(recoveredPath, recoveredData, 0644); err != nil {
+		return "", report, err
+	}
+
+	return recoveredPath, report, nil
+}
+
 // executeCSVConversion handles the CSV conversion process based on the user-selected format option.
 // It is now context-aware, allowing for cancellation during the CSV conversion process.
 func executeCSVConversion(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, formatOption int, sessions []exporter.Session) {
-	var csvFileName string
-	var err error
-
 	// Check if the format option is valid before proceeding
-	if formatOption != OutputFormatInline && formatOption != OutputFormatPerLine &&
-		formatOption != OutputFormatSeparateCSV && formatOption != OutputFormatJSONInCSV {
-		bannercli.PrintTypingBanner("Invalid CSV format option.", 100*time.Millisecond)
+	e, ok := selectMenuExporter(exporter.ExportersByExtension(CSVExtension), strconv.Itoa(formatOption), 1)
+	if !ok {
+		bannercli.Error(i18n.T("error.invalidCSVFormatOption"))
 		return
 	}
 
-	// If the format option is not for separate CSV files, prompt for a single CSV file name.
-	if formatOption != OutputFormatSeparateCSV {
-		csvFileName, err = promptForInput(ctx, reader, PromptEnterCSVFileName)
-		if err != nil {
-			handleInputError(err)
+	// Exporters that write additional files, such as separate sessions and messages files,
+	// prompt for every file name themselves.
+	for _, option := range e.Options() {
+		if option.Path {
+			createSeparateCSVFiles(rfs, ctx, reader, sessions, e)
 			return
 		}
 	}
 
-	switch formatOption {
-	case OutputFormatInline, OutputFormatPerLine, OutputFormatJSONInCSV:
-		// Call the function to convert sessions to a single CSV file
-		convertToSingleCSV(rfs, ctx, reader, sessions, formatOption, csvFileName)
-	case OutputFormatSeparateCSV:
-		// Call the function to create separate CSV files for sessions and messages
-		createSeparateCSVFiles(rfs, ctx, reader, sessions)
-	default:
-		bannercli.PrintTypingBanner("Invalid format option.", 100*time.Millisecond)
+	// Otherwise, prompt for a single CSV file name.
+	csvFileName, err := promptForInput(ctx, reader, i18n.T(PromptEnterCSVFileName))
+	if err != nil {
+		handleError(i18n.T("error.readInput"), err)
+		return
 	}
+
+	// Call the function to convert sessions to a single CSV file
+	convertToSingleCSV(rfs, ctx, reader, sessions, e, csvFileName)
 }
 
-// createSeparateCSVFiles prompts the user for file names and creates separate CSV files for sessions and messages.
+// createSeparateCSVFiles prompts the user for file names and creates separate CSV files, such as
+// the sessions and messages files, using the given multi-file exporter.
 // This function is context-aware and supports cancellation during the prompt for input.
-func createSeparateCSVFiles(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session) {
-	sessionsFileName, err := promptForInput(ctx, reader, PromptEnterSessionsCSVFileName)
-	if err != nil {
-		handleInputError(err)
-		return
+func createSeparateCSVFiles(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session, e exporter.Exporter) {
+	primaryName := outputLabel(e)
+	if namer, ok := e.(exporter.PrimaryOutputNamer); ok {
+		primaryName = namer.PrimaryOutputName()
 	}
 
-	// Confirm overwrite for sessions CSV file
-	overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, sessionsFileName)
+	primaryFileName, err := promptForInput(ctx, reader, fmt.Sprintf(PromptEnterFileName, outputLabel(e)))
 	if err != nil {
-		handleInputError(err)
+		handleError(i18n.T("error.readInput"), err)
 		return
 	}
-	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user for sessions file.", 100*time.Millisecond)
+
+	// Apply the overwrite policy to the primary CSV file
+	resolution, ok := resolveOutput(rfs, ctx, reader, primaryFileName, canAppend(e))
+	if !ok {
+		bannercli.Info(i18n.T("info.optionCancelled", primaryName))
 		return
 	}
+	primaryFileName = resolution.Path
 
-	messagesFileName, err := promptForInput(ctx, reader, PromptEnterMessagesCSVFileName)
-	if err != nil {
-		handleInputError(err)
+	// Prompt for the remaining file names and apply the overwrite policy to them
+	options, ok := promptExporterOptions(rfs, ctx, reader, e, resolution.Action == interactivity.PolicyAppend)
+	if !ok {
 		return
 	}
 
-	// Confirm overwrite for messages CSV file
-	overwrite, err = interactivity.ConfirmOverwrite(rfs, ctx, reader, messagesFileName)
+	err = withProgressBar(ctx, func(ctx context.Context) error {
+		_, err := exportResolved(ctx, rfs, e, sessions, resolution, options)
+		return err
+	})
 	if err != nil {
-		handleInputError(err)
-		return
+		handleError(i18n.T("error.createCSVFiles"), err)
 	}
-	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user for messages file.", 100*time.Millisecond)
+
+	successMessage := i18n.T("success.dataSaved", capitalize(primaryName), primaryFileName) + "\n"
+	bannercli.Success(successMessage)
+
+	for _, option := range e.Options() {
+		if option.Path {
+			successMessage := i18n.T("success.dataSaved", capitalize(option.Name), options[option.Name]) + "\n"
+			bannercli.Success(successMessage)
+		}
+	}
+}
+
+// convertToSingleCSV converts the session data to a single CSV file using the specified exporter.
+// It now checks for context cancellation and halts the operation if a cancellation is requested.
+func convertToSingleCSV(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session, e exporter.Exporter, csvFileName string) {
+	// Apply the overwrite policy if the file already exists
+	resolution, ok := resolveOutput(rfs, ctx, reader, csvFileName, canAppend(e))
+	if !ok {
 		return
 	}
+	csvFileName = resolution.Path
 
-	err = exporter.CreateSeparateCSVFiles(sessions, sessionsFileName, messagesFileName)
+	err := withProgressBar(ctx, func(ctx context.Context) error {
+		_, err := exportResolved(ctx, rfs, e, sessions, resolution, nil)
+		return err
+	})
 	if err != nil {
-		if err == context.Canceled || err == io.EOF {
-			// If the error is context.Canceled or io.EOF, exit gracefully.
-			bannercli.PrintTypingBanner("\n[GopherHelper] Exiting gracefully...\nReason: Operation canceled or end of input. Exiting program.", 100*time.Millisecond)
-			os.Exit(0)
-		} else {
-			// For other types of errors, print the error message and exit with status code 1.
-			errorMessage := fmt.Sprintf("\nError creating CSV files: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-			os.Exit(1)
-		}
+		handleError(i18n.T("error.convertCSV"), err)
 	}
 
-	successMessageSessions := fmt.Sprintf("Sessions data saved to %s\n", sessionsFileName)
-	bannercli.PrintTypingBanner(successMessageSessions, 100*time.Millisecond)
-
-	successMessageMessages := fmt.Sprintf("Messages data saved to %s\n", messagesFileName)
-	bannercli.PrintTypingBanner(successMessageMessages, 100*time.Millisecond)
+	successMessage := i18n.T("success.csvSaved", csvFileName) + "\n"
+	bannercli.Success(successMessage)
 }
 
-// convertToSingleCSV converts the session data to a single CSV file using the specified format option.
-// It now checks for context cancellation and halts the operation if a cancellation is requested.
-func convertToSingleCSV(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, sessions []exporter.Session, formatOption int, csvFileName string) {
-	// Confirm overwrite if the file already exists
-	overwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, csvFileName)
+// resolveOutput applies the overwrite policy to fileName, which can be appended to if canAppend is
+// set, and reports a backup of the existing file. It returns false if the file is skipped, and ends
+// the run through handleError if the policy could not be applied.
+func resolveOutput(rfs filesystem.FileSystem, ctx context.Context, reader *bufio.Reader, fileName string, canAppend bool) (interactivity.Resolution, bool) {
+	resolution, err := interactivity.ResolveOverwrite(rfs, ctx, reader, fileName, overwritePolicy, canAppend)
 	if err != nil {
-		errorMessage := fmt.Sprintf("Failed to check file existence: %s\n", err)
-		bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		return // Handle the error as appropriate for your application
+		handleError(i18n.T("error.prepareFile", fileName), err)
+		return resolution, false
 	}
-	if !overwrite {
-		bannercli.PrintTypingBanner("Operation cancelled by the user.", 100*time.Millisecond)
-		return
+	bannercli.Debug(fmt.Sprintf("Overwrite policy %s applied to %s: %s %s", overwritePolicy, fileName, resolution.Action, resolution.Path))
+	if resolution.Skipped() {
+		bannercli.Warn(i18n.T("info.skipped", fileName))
+		return resolution, false
+	}
+	if resolution.Backup != "" {
+		bannercli.Info(i18n.T("info.backedUp", fileName, resolution.Backup))
 	}
+	return resolution, true
+}
 
-	err = exporter.ConvertSessionsToCSV(ctx, sessions, formatOption, csvFileName)
-	if err != nil {
-		if err == context.Canceled {
-			bannercli.PrintTypingBanner("Operation was canceled by the user.", 100*time.Millisecond)
-		} else {
-			errorMessage := fmt.Sprintf("Failed to convert sessions to CSV: %s\n", err)
-			bannercli.PrintTypingBanner(errorMessage, 100*time.Millisecond)
-		}
-		return // Handle the error as appropriate for your application
+// canAppend reports whether the outputs of e can be appended to existing files.
+func canAppend(e exporter.Exporter) bool {
+	_, ok := e.(exporter.AppendExporter)
+	return ok
+}
+
+// exportResolved exports sessions with e to the file given by resolution, appending them to it
+// if the overwrite policy says so. The export is recorded into the summary of the run.
+func exportResolved(ctx context.Context, rfs filesystem.FileSystem, e exporter.Exporter, sessions []exporter.Session, resolution interactivity.Resolution, options exporter.Options) ([]string, error) {
+	var written []string
+	var err error
+	inc := exporter.FullIncrement(sessions)
+	if appender, ok := e.(exporter.AppendExporter); ok && resolution.Action == interactivity.PolicyAppend {
+		written, err = appender.Append(ctx, rfs, inc, resolution.Path, options)
+	} else {
+		written, err = e.Export(ctx, rfs, sessions, resolution.Path, options)
+	}
+	if err == nil {
+		recordExport(e, resolution.Path, inc, options, written)
 	}
+	return written, err
+}
 
-	successMessage := fmt.Sprintf("CSV output saved to %s\n", csvFileName)
-	bannercli.PrintTypingBanner(successMessage, 100*time.Millisecond)
+// withProgressBar runs export with a context reporting its progress to a progress bar on standard
+// error, so that large backups are not exported in silence. The bar is completed once export returns.
+// No bar is shown in quiet mode.
+func withProgressBar(ctx context.Context, export func(ctx context.Context) error) error {
+	if !bannercli.Default().Enabled(bannercli.LevelNormal) {
+		return export(ctx)
+	}
+	bar := bannercli.NewProgressBar(os.Stderr, i18n.T("progress.exporting"))
+	defer bar.Finish()
+	return export(exporter.WithProgress(ctx, bar))
 }
 
 // writeContentToFile collects a file name from the user and writes the provided content to the specified file.
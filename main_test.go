@@ -10,15 +10,24 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
 	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/sanitize"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/watcher"
 )
 
 // loadTestSessions is a helper function that loads test session data from a JSON file.
@@ -324,3 +333,387 @@ func TestWriteContentToFile_ContextCancellation(t *testing.T) {
 		t.Error("WriteFile should not have been called after context cancellation")
 	}
 }
+
+// TestWriteContentToFileLanguages verifies that writeContentToFile asks for the file name in the
+// language selected with setupLanguage.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestWriteContentToFileLanguages(t *testing.T) {
+	defer setupLanguage(i18n.DefaultLanguage)
+
+	for _, lang := range i18n.Languages() {
+		t.Run(lang, func(t *testing.T) {
+			if err := setupLanguage(lang); err != nil {
+				t.Fatalf("setupLanguage(%q) returned error: %v", lang, err)
+			}
+			reader := bufio.NewReader(strings.NewReader("testing\n"))
+			mockFS := filesystem.NewMockFileSystem()
+
+			// Capture the prompt by redirecting stdout.
+			oldStdout := os.Stdout
+			r, w, _ := os.Pipe()
+			os.Stdout = w
+			err := writeContentToFile(mockFS, context.Background(), reader, "{}", FileTypeDataset)
+			w.Close()
+			os.Stdout = oldStdout // Restore the original stdout.
+			var buf bytes.Buffer
+			io.Copy(&buf, r)
+
+			if err != nil || mockFS.WriteFilePath != "testing.json" {
+				t.Errorf("writeContentToFile() wrote %q, %v, want testing.json", mockFS.WriteFilePath, err)
+			}
+			if want := i18n.T(PromptEnterFileNameKey, FileTypeDataset); !strings.Contains(buf.String(), want) {
+				t.Errorf("writeContentToFile() printed %q, want the prompt %q", buf.String(), want)
+			}
+		})
+	}
+}
+
+// TestRecoverJSONData verifies that recoverJSONData salvages complete sessions from a truncated backup
+// that also carries a BOM and trailing garbage, and that it reports what was lost.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestRecoverJSONData(t *testing.T) {
+	data, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		input         []byte
+		wantTruncated bool
+		wantSessions  int
+	}{
+		{"BOMAndTrailingGarbage", append(append([]byte{0xEF, 0xBB, 0xBF}, data...), "\x00garbage"...), false, 1},
+		{"TruncatedMidMessage", data[:len(data)/2], true, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFS := filesystem.NewMockFileSystem()
+			mockFS.Files["broken.json"] = tc.input
+
+			// The strict repair must fail before the tolerant recovery is needed.
+			if _, err := repairJSONData(mockFS, context.Background(), "broken.json"); err == nil || !isCorruptedJSONError(err) {
+				t.Fatalf("repairJSONData() error = %v, want a corrupted JSON error", err)
+			}
+
+			recoveredPath, report, err := recoverJSONData(mockFS, context.Background(), "broken.json")
+			if err != nil {
+				t.Fatalf("recoverJSONData() returned an error: %v", err)
+			}
+			if report.Truncated != tc.wantTruncated {
+				t.Errorf("report.Truncated = %v, want %v", report.Truncated, tc.wantTruncated)
+			}
+			if report.SessionsRecovered < tc.wantSessions {
+				t.Errorf("report.SessionsRecovered = %d, want at least %d", report.SessionsRecovered, tc.wantSessions)
+			}
+
+			var store exporter.ChatNextWebStore
+			if err := json.Unmarshal(mockFS.Files[recoveredPath], &store); err != nil {
+				t.Fatalf("recovered data is not valid JSON: %v", err)
+			}
+			if len(store.ChatNextWebStore.Sessions) != report.SessionsRecovered {
+				t.Errorf("recovered file has %d sessions, report says %d", len(store.ChatNextWebStore.Sessions), report.SessionsRecovered)
+			}
+		})
+	}
+}
+
+// TestWatchExportsNewBackup verifies that a backup dropped into a watched directory is picked up
+// once it stops changing, exported into its own sub-directory, and that the watcher stops cleanly
+// when the context is cancelled.
+func TestWatchExportsNewBackup(t *testing.T) {
+	watchDir := t.TempDir()
+	outDir := t.TempDir()
+
+	data, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := watchOptions{outDir: outDir, formats: []string{exporter.FormatNameSeparate, exporter.FormatNameDataset}}
+	exported := make(chan []string, 1)
+	w := &watcher.Watcher{Dir: watchDir, Interval: 10 * time.Millisecond, Debounce: 30 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(ctx context.Context, event watcher.Event) error {
+			written, err := exportBackup(&filesystem.RealFileSystem{}, ctx, event.Path, opts)
+			if err != nil {
+				return err
+			}
+			exported <- written
+			return nil
+		})
+	}()
+
+	// Drop the backup after the watcher has taken its baseline scan.
+	time.Sleep(20 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(watchDir, "backup.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case written := <-exported:
+		if len(written) != 3 {
+			t.Errorf("exportBackup() wrote %d files, want 3: %v", len(written), written)
+		}
+		for _, name := range []string{"sessions.csv", "messages.csv", "dataset.json"} {
+			if _, err := os.Stat(filepath.Join(outDir, "backup", name)); err != nil {
+				t.Errorf("expected %s in the output tree: %v", name, err)
+			}
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the backup to be exported")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() returned an error after cancellation: %v", err)
+	}
+}
+
+// testExporter is a minimal exporter used to verify that registered formats show up in the CLI menus.
+type testExporter struct{}
+
+func (testExporter) Name() string               { return "test-format" }
+func (testExporter) Description() string        { return "Test Format" }
+func (testExporter) Extension() string          { return ".test" }
+func (testExporter) Options() []exporter.Option { return nil }
+func (testExporter) Export(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, outputPath string, options exporter.Options) ([]string, error) {
+	return []string{outputPath}, nil
+}
+
+// TestExporterRegistryMenus verifies that the CLI menus are generated from the exporter registry,
+// keeping the numbering of the built-in formats and listing newly registered formats automatically.
+func TestExporterRegistryMenus(t *testing.T) {
+	wantCSVMenu := "Select the message output format:\n1) Inline Formatting\n2) One Message Per Line\n3) JSON String in CSV\n4) Separate Files for Sessions and Messages\n"
+	if got := csvFormatMenu(); got != wantCSVMenu {
+		t.Errorf("csvFormatMenu() = %q, want %q", got, wantCSVMenu)
+	}
+
+	if _, ok := exporter.Lookup("test-format"); !ok {
+		exporter.Register(testExporter{})
+	}
+	wantMenu := "Select the output format:\n1) CSV\n2) Hugging Face Dataset\n3) JSON Lines (One Message Per Line)\n4) Excel Workbook (Sessions, Messages and Summary Sheets)\n5) PDF Transcript (Printable, With Table of Contents)\n6) Test Format\n"
+	if got := outputFormatMenu(); got != wantMenu {
+		t.Errorf("outputFormatMenu() = %q, want %q", got, wantMenu)
+	}
+
+	e, ok := selectMenuExporter(otherExporters(), "6", 2)
+	if !ok || e.Name() != "test-format" {
+		t.Errorf("selectMenuExporter() = %v, %v, want the registered test exporter", e, ok)
+	}
+	if _, ok := selectMenuExporter(otherExporters(), "9", 2); ok {
+		t.Error("selectMenuExporter() accepted an out-of-range option")
+	}
+}
+
+// TestEncryptCommands verifies that the encrypt and decrypt commands restore the original backup
+// with the passphrase of a key file.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestEncryptCommands(t *testing.T) {
+	data, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["passphrase.txt"] = []byte("secret\n")
+	mockFS.Files["backup.json"] = data
+	keys := &encryption.KeySource{Env: "CHAT_EXPORTER_TEST_UNSET", KeyFile: "passphrase.txt"}
+	if err := encryptFile(mockFS, context.Background(), "backup.json", "backup.json.enc", keys); err != nil {
+		t.Fatalf("encryptFile() returned error: %v", err)
+	}
+	if !encryption.IsEncrypted(mockFS.Files["backup.json.enc"]) {
+		t.Fatal("encryptFile() did not write an encrypted file")
+	}
+
+	if err := decryptFile(mockFS, context.Background(), "backup.json.enc", "decrypted.json", keys); err != nil {
+		t.Fatalf("decryptFile() returned error: %v", err)
+	}
+	if !bytes.Equal(mockFS.Files["decrypted.json"], data) {
+		t.Error("decryptFile() did not restore the original backup")
+	}
+}
+
+// TestSanitizeBackup verifies that the sanitize command names its output after the backup and
+// leaves an importable backup behind.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestSanitizeBackup(t *testing.T) {
+	data, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["backup.json"] = data
+	if name := sanitizedFileName("backup.json.gz.enc"); name != "backup.sanitized.json" {
+		t.Errorf("sanitizedFileName() = %q, want backup.sanitized.json", name)
+	}
+	report, err := sanitizeBackup(mockFS, context.Background(), "backup.json", "backup.sanitized.json", sanitize.Options{AppConfig: true}, nil)
+	if err != nil {
+		t.Fatalf("sanitizeBackup() returned error: %v", err)
+	}
+	if report.String() != "1 fields sanitized: app-config removed" {
+		t.Errorf("sanitizeBackup() report = %s", report)
+	}
+	store, err := exporter.ReadJSONFromFileFS(mockFS, "backup.sanitized.json")
+	if err != nil || len(store.ChatNextWebStore.Sessions) != 1 {
+		t.Errorf("The sanitized backup is not importable: %d sessions, %v", len(store.ChatNextWebStore.Sessions), err)
+	}
+}
+
+// TestDiffBackups verifies that the diff command finds no differences between identical backups.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestDiffBackups(t *testing.T) {
+	data, err := os.ReadFile("testing.json")
+	if err != nil {
+		t.Fatalf("Failed to read testing.json: %v", err)
+	}
+	mockFS := filesystem.NewMockFileSystem()
+	mockFS.Files["backup.json"] = data
+	if result, err := diffBackups(mockFS, "backup.json", "backup.json", nil); err != nil || !result.Empty() {
+		t.Errorf("diffBackups() of identical backups = %+v, %v, want no differences", result, err)
+	}
+}
+
+// TestExportSelection verifies that the sessions picked in the browser are exported into their
+// own directory.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestExportSelection(t *testing.T) {
+	sessions := []exporter.Session{{ID: "1", Topic: "Go generics",
+		Messages: []exporter.Message{{Role: "user", Date: "2023-11-28", Content: "How do type parameters work?"}}}}
+	e, _ := exporter.Lookup(exporter.FormatNameJSONL)
+	mockFS := filesystem.NewMockFileSystem()
+	files, err := exportSelection(mockFS, context.Background(), e, sessions, "picked")
+	if err != nil || len(files) != 1 || !strings.Contains(string(mockFS.Files[files[0]]), "type parameters") {
+		t.Errorf("exportSelection() = %v, %v, want the selected session in picked/jsonl.jsonl", files, err)
+	}
+}
+
+// TestExportResolved checks that appending an export to a previous one adds its rows under the
+// existing header.
+// Note: This test does not perform operations on the actual disk I/O.
+func TestExportResolved(t *testing.T) {
+	ctx := context.Background()
+	e, _ := exporter.Lookup(exporter.FormatNamePerLine)
+	sessions := []exporter.Session{{ID: "1", Topic: "Test Session", Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Hello"}}}}
+	mockFS := filesystem.NewMockFileSystem()
+	if _, err := e.Export(ctx, mockFS, sessions, "messages.csv", nil); err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	resolution, err := interactivity.ResolveOverwrite(mockFS, ctx, bufio.NewReader(strings.NewReader("")), "messages.csv", interactivity.PolicyAppend, canAppend(e))
+	if err != nil {
+		t.Fatalf("ResolveOverwrite(append) error = %v", err)
+	}
+	if _, err := exportResolved(ctx, mockFS, e, sessions, resolution, nil); err != nil {
+		t.Fatalf("exportResolved() error = %v", err)
+	}
+	content := string(mockFS.Files["messages.csv"])
+	if strings.Count(content, "session_id") != 1 || strings.Count(content, "Hello") != 2 {
+		t.Errorf("Appended export = %q, want one header and two rows", content)
+	}
+}
+
+// TestErrorTaxonomy verifies that the errors of the exporter, repairdata and filesystem packages
+// map to the documented exit codes, and which of them count as corrupted JSON.
+func TestErrorTaxonomy(t *testing.T) {
+	_, parseErr := exporter.ReadJSON(strings.NewReader(`{"chat-next-web-store": {"sessions": [}`))
+	_, schemaErr := exporter.ReadJSON(strings.NewReader(`{"chat-next-web-store": {"sessions": "none"}}`))
+	_, ioErr := exporter.ReadJSONFromFileFS(filesystem.NewMockFileSystem(), "missing.json")
+	separate, _ := exporter.Lookup(exporter.FormatNameSeparate)
+	formatErr := exporter.Options{"colour": "red"}.Validate(separate.Options())
+	_, repairParseErr := repairdata.RepairSessionData([]byte(`{"chat-next-web-store": {"sessions": [{"id": 1,}]}}`))
+	_, repairSchemaErr := repairdata.RepairSessionData([]byte(`{"chat-next-web-store": {"sessions": 5}}`))
+
+	if !isCorruptedJSONError(repairParseErr) || isCorruptedJSONError(repairSchemaErr) {
+		t.Errorf("isCorruptedJSONError() = %v for %v and %v for %v, want only malformed JSON to be corrupted",
+			isCorruptedJSONError(repairParseErr), repairParseErr, isCorruptedJSONError(repairSchemaErr), repairSchemaErr)
+	}
+
+	codes := []struct {
+		err  error
+		want int
+	}{
+		{nil, ExitOK},
+		{errors.New("wrong passphrase"), ExitFailure},
+		{fmt.Errorf("outputs[0]: %w", formatErr), ExitUsage},
+		{parseErr, ExitParse},
+		{repairParseErr, ExitParse},
+		{fmt.Errorf("recovery: %w", repairdata.ErrUnrecoverable), ExitParse},
+		{schemaErr, ExitSchema},
+		{ioErr, ExitIO},
+		{&os.PathError{Op: "write", Path: "out.csv", Err: errors.New("no space left on device")}, ExitIO},
+		{fmt.Errorf("export: %w", context.Canceled), ExitCancelled},
+		{interactivity.ErrInterrupted, ExitCancelled},
+		{io.EOF, ExitCancelled},
+	}
+	for _, tc := range codes {
+		if got := exitCode(tc.err); got != tc.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+// TestLocalisation verifies that the messages used by the tool are in the English catalogue, that
+// unknown languages are rejected, and that prompts are translated.
+func TestLocalisation(t *testing.T) {
+	english, err := i18n.Load(i18n.DefaultLanguage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every key used in the sources must be in the English catalogue.
+	sources, _ := filepath.Glob("*.go")
+	more, _ := filepath.Glob("interactivity/*.go")
+	used := regexp.MustCompile(`(?:i18n\.T|isAnswer)\([^"]*"([a-z]+\.[A-Za-z.]+)"|(?:i18n\.Key = |summary: )"([^"]+)"`)
+	for _, source := range append(sources, more...) {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range used.FindAllStringSubmatch(string(data), -1) {
+			key := i18n.Key(match[1] + match[2])
+			if _, ok := english.Messages[key]; !ok {
+				t.Errorf("%s uses %q, which is missing from the English catalogue", source, key)
+			}
+		}
+	}
+
+	// The recovery report reads the same in English as RecoveryReport.String.
+	report := &repairdata.RecoveryReport{SessionsRecovered: 1, MessagesRecovered: 2, BOMRemoved: true,
+		TrailingBytesRemoved: 3, Truncated: true, CorruptionOffset: 4, DiscardedBytes: 5, PartialPath: "sessions[0]"}
+	if got := recoveryMessage(report); got != report.String() {
+		t.Errorf("recoveryMessage() = %q, want %q", got, report.String())
+	}
+
+	if err := setupLanguage("xx"); err == nil || !strings.Contains(err.Error(), "en, id, zh") {
+		t.Errorf("setupLanguage(\"xx\") = %v, want an error listing the languages", err)
+	}
+
+	// Prompts are translated, and accept translated answers as well as English ones.
+	chinese, err := i18n.Load("zh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i18n.SetDefault(chinese)
+	defer i18n.SetDefault(english)
+	var out bytes.Buffer
+	prompter := interactivity.NewPrompter(strings.NewReader("maybe\n是\nyes\n"), &out)
+	for _, want := range []bool{true, true} {
+		if got, err := prompter.Confirm(context.Background(), i18n.T(PromptRepairData), interactivity.NoDefault); err != nil || got != want {
+			t.Errorf("Confirm() = %v, %v, want %v", got, err, want)
+		}
+	}
+	if !strings.Contains(out.String(), "是否要修复数据？ (y/n): ") || !strings.Contains(out.String(), "请回答是或否。") {
+		t.Errorf("prompt is not translated: %q", out.String())
+	}
+	if got := recoveryMessage(report); !strings.HasPrefix(got, "已恢复 1 个会话和 2 条消息。") {
+		t.Errorf("recovery report is not translated: %q", got)
+	}
+	if _, err := colorEnabled("sometimes", os.Stdout); err == nil || !strings.Contains(err.Error(), "未知的颜色模式") {
+		t.Errorf("colorEnabled() error is not translated: %v", err)
+	}
+}
@@ -0,0 +1,479 @@
+// Below, the package pdf (@font.go) reads TrueType fonts and writes the subsets embedded in
+// documents, which keep only the outlines of the glyphs a document uses.
+//
+// Fonts with TrueType outlines are supported, as .ttf files or as the first font of a .ttc
+// collection. Fonts with CFF outlines (most .otf files) are rejected by ParseFont.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pdf
+
+import (
+	"bytes"
+	"embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// fontFiles holds the DejaVu fonts, see fonts/LICENSE.
+//
+//go:embed fonts/*.ttf
+var fontFiles embed.FS
+
+// Font is a parsed TrueType font.
+type Font struct {
+	name        string           // PostScript name, such as "DejaVuSans".
+	data        []byte           // Content of the font file.
+	tables      map[string]table // Tables of the font by tag.
+	unitsPerEm  int              // Units of the glyph coordinates per em.
+	numGlyphs   int              // Number of glyphs.
+	advances    []uint16         // Advance widths of the glyphs with a metric of their own.
+	glyphs      map[rune]uint16  // Glyph of every character the font maps.
+	loca        []uint32         // Offsets of the glyph outlines in the glyf table, numGlyphs+1 entries.
+	ascent      int              // Ascent above the baseline, in font units.
+	descent     int              // Descent below the baseline, negative, in font units.
+	capHeight   int              // Height of capital letters, in font units.
+	bbox        [4]int           // Bounding box of all glyphs, in font units.
+	italicAngle float64          // Slant of the font in degrees, counter-clockwise from vertical.
+	fixedPitch  bool             // Whether all glyphs have the same advance width.
+}
+
+// table locates a table in the font file.
+type table struct {
+	offset, length uint32
+}
+
+var (
+	sans     = sync.OnceValue(func() *Font { return mustParseEmbedded("DejaVuSans.ttf") })
+	sansBold = sync.OnceValue(func() *Font { return mustParseEmbedded("DejaVuSans-Bold.ttf") })
+	mono     = sync.OnceValue(func() *Font { return mustParseEmbedded("DejaVuSansMono.ttf") })
+)
+
+// Sans returns the embedded DejaVu Sans font, which covers the Latin, Greek, Cyrillic, Armenian,
+// Georgian, Hebrew and Arabic scripts and many symbols.
+func Sans() *Font { return sans() }
+
+// SansBold returns the embedded DejaVu Sans Bold font.
+func SansBold() *Font { return sansBold() }
+
+// Mono returns the embedded DejaVu Sans Mono font, for code.
+func Mono() *Font { return mono() }
+
+// mustParseEmbedded parses the embedded font file name, and panics if it is missing or invalid.
+func mustParseEmbedded(name string) *Font {
+	data, err := fontFiles.ReadFile("fonts/" + name)
+	if err == nil {
+		var f *Font
+		if f, err = ParseFont(data); err == nil {
+			return f
+		}
+	}
+	panic(fmt.Sprintf("pdf: embedded font %s: %v", name, err))
+}
+
+// ParseFont parses the TrueType font data.
+func ParseFont(data []byte) (*Font, error) {
+	r := reader(data)
+	start := uint32(0)
+	switch r.u32(0) {
+	case 0x00010000, 0x74727565: // TrueType, as "\x00\x01\x00\x00" or "true".
+	case 0x74746366: // "ttcf": a collection, of which the first font is used.
+		start = r.u32(12)
+		if v := r.u32(start); v != 0x00010000 && v != 0x74727565 {
+			return nil, errors.New("unsupported font: the first font of the collection has no TrueType outlines")
+		}
+	case 0x4F54544F: // "OTTO"
+		return nil, errors.New("unsupported font: CFF outlines, use a font with TrueType outlines")
+	default:
+		return nil, errors.New("not a TrueType font")
+	}
+
+	f := &Font{data: data, tables: make(map[string]table)}
+	numTables := int(r.u16(start + 4))
+	for i := 0; i < numTables; i++ {
+		record := start + 12 + uint32(i)*16
+		t := table{offset: r.u32(record + 8), length: r.u32(record + 12)}
+		if uint64(t.offset)+uint64(t.length) > uint64(len(data)) {
+			return nil, errors.New("invalid font: table out of bounds")
+		}
+		f.tables[string(data[record:record+4])] = t
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("invalid font: missing %s table", tag)
+		}
+	}
+
+	head := f.table("head")
+	f.unitsPerEm = int(head.u16(18))
+	f.bbox = [4]int{int(head.i16(36)), int(head.i16(38)), int(head.i16(40)), int(head.i16(42))}
+	longLoca := head.i16(50) == 1
+	f.numGlyphs = int(f.table("maxp").u16(4))
+	if f.unitsPerEm == 0 || f.numGlyphs == 0 {
+		return nil, errors.New("invalid font: no glyphs")
+	}
+
+	hhea := f.table("hhea")
+	f.ascent, f.descent = int(hhea.i16(4)), int(hhea.i16(6))
+	hmtx := f.table("hmtx")
+	numMetrics := int(hhea.u16(34))
+	if numMetrics == 0 || numMetrics > f.numGlyphs || len(hmtx) < numMetrics*4 {
+		return nil, errors.New("invalid font: bad horizontal metrics")
+	}
+	f.advances = make([]uint16, numMetrics)
+	for i := range f.advances {
+		f.advances[i] = hmtx.u16(uint32(i * 4))
+	}
+
+	loca := f.table("loca")
+	f.loca = make([]uint32, f.numGlyphs+1)
+	for i := range f.loca {
+		if longLoca {
+			f.loca[i] = loca.u32(uint32(i * 4))
+		} else {
+			f.loca[i] = uint32(loca.u16(uint32(i*2))) * 2
+		}
+	}
+	if f.loca[f.numGlyphs] > uint32(len(f.table("glyf"))) {
+		return nil, errors.New("invalid font: glyph outlines out of bounds")
+	}
+
+	f.capHeight = f.ascent * 7 / 10
+	if os2 := f.table("OS/2"); len(os2) >= 90 && os2.u16(0) >= 2 {
+		f.capHeight = int(os2.i16(88))
+	}
+	if post := f.table("post"); len(post) >= 16 {
+		f.italicAngle = float64(int32(post.u32(4))) / 65536
+		f.fixedPitch = post.u32(12) != 0
+	}
+	f.name = f.postScriptName()
+
+	var err error
+	if f.glyphs, err = parseCmap(f.table("cmap")); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Name returns the PostScript name of the font.
+func (f *Font) Name() string {
+	return f.name
+}
+
+// Has reports whether the font has a glyph for r.
+func (f *Font) Has(r rune) bool {
+	_, ok := f.glyphs[r]
+	return ok
+}
+
+// Width returns the width of s set in the font at size, in points. Characters the font has no
+// glyph for count as its missing glyph.
+func (f *Font) Width(s string, size float64) float64 {
+	units := 0
+	for _, r := range s {
+		units += f.advance(f.glyphs[r])
+	}
+	return float64(units) * size / float64(f.unitsPerEm)
+}
+
+// advance returns the advance width of the glyph, in font units.
+func (f *Font) advance(glyph uint16) int {
+	if int(glyph) < len(f.advances) {
+		return int(f.advances[glyph])
+	}
+	return int(f.advances[len(f.advances)-1])
+}
+
+// scale converts font units to the thousandths of an em used by PDF font metrics.
+func (f *Font) scale(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+// table returns the content of the table tag, or nil if the font has none.
+func (f *Font) table(tag string) reader {
+	t, ok := f.tables[tag]
+	if !ok {
+		return nil
+	}
+	return reader(f.data[t.offset : t.offset+t.length])
+}
+
+// postScriptName returns the PostScript name of the font from its name table, reduced to the
+// characters allowed in PDF names, or "Font" if it has none.
+func (f *Font) postScriptName() string {
+	name := f.table("name")
+	if len(name) < 6 {
+		return "Font"
+	}
+	count, storage := uint32(name.u16(2)), uint32(name.u16(4))
+	for i := uint32(0); i < count; i++ {
+		record := 6 + i*12
+		platform, nameID := name.u16(record), name.u16(record+6)
+		length, offset := uint32(name.u16(record+8)), uint32(name.u16(record+10))
+		if nameID != 6 || storage+offset+length > uint32(len(name)) {
+			continue
+		}
+		raw := name[storage+offset : storage+offset+length]
+		if platform == 0 || platform == 3 {
+			units := make([]uint16, len(raw)/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(raw[j*2:])
+			}
+			raw = []byte(string(utf16.Decode(units)))
+		}
+		clean := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+				return r
+			}
+			return -1
+		}, string(raw))
+		if clean != "" {
+			return clean
+		}
+	}
+	return "Font"
+}
+
+// parseCmap returns the glyphs of the characters mapped by the Unicode subtable of the cmap
+// table, preferring a subtable of format 12, which covers characters beyond the BMP.
+func parseCmap(cmap reader) (map[rune]uint16, error) {
+	var format4, format12 reader
+	count := uint32(cmap.u16(2))
+	for i := uint32(0); i < count; i++ {
+		record := 4 + i*8
+		platform, encoding, offset := cmap.u16(record), cmap.u16(record+2), cmap.u32(record+4)
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) || offset >= uint32(len(cmap)) {
+			continue
+		}
+		switch sub := cmap[offset:]; sub.u16(0) {
+		case 4:
+			format4 = sub
+		case 12:
+			format12 = sub
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	switch {
+	case format12 != nil:
+		groups := format12.u32(12)
+		for i := uint32(0); i < groups && 16+i*12+12 <= uint32(len(format12)); i++ {
+			group := 16 + i*12
+			first, last, glyph := format12.u32(group), format12.u32(group+4), format12.u32(group+8)
+			for c := first; c <= last && c <= 0x10FFFF; c++ {
+				if glyph+c-first != 0 {
+					glyphs[rune(c)] = uint16(glyph + c - first)
+				}
+			}
+		}
+	case format4 != nil:
+		segments := uint32(format4.u16(6)) / 2
+		ends, starts := uint32(14), 16+segments*2
+		deltas, ranges := starts+segments*2, starts+segments*4
+		for i := uint32(0); i < segments; i++ {
+			start, end := uint32(format4.u16(starts+i*2)), uint32(format4.u16(ends+i*2))
+			delta, rangeOffset := format4.u16(deltas+i*2), uint32(format4.u16(ranges+i*2))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				glyph := uint16(c) + delta
+				if rangeOffset != 0 {
+					if glyph = format4.u16(ranges + i*2 + rangeOffset + (c-start)*2); glyph != 0 {
+						glyph += delta
+					}
+				}
+				if glyph != 0 {
+					glyphs[rune(c)] = glyph
+				}
+			}
+		}
+	default:
+		return nil, errors.New("unsupported font: no Unicode character map")
+	}
+	return glyphs, nil
+}
+
+// subsetTables are the tables kept in subsets: the tables PDF requires of embedded TrueType
+// fonts, along with OS/2 and post for the viewers that read them.
+var subsetTables = []string{"OS/2", "cvt ", "fpgm", "glyf", "head", "hhea", "hmtx", "loca", "maxp", "post", "prep"}
+
+// subset returns a font file with the outlines of the glyphs used and of their components. The
+// other glyphs keep their index but have no outline, so glyph indexes need no remapping.
+func (f *Font) subset(used map[uint16]rune) []byte {
+	glyf := f.table("glyf")
+	keep := make(map[uint16]bool, len(used)+1)
+	queue := []uint16{0} // The missing glyph is always kept.
+	for glyph := range used {
+		queue = append(queue, glyph)
+	}
+	for len(queue) > 0 {
+		glyph := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if keep[glyph] || int(glyph) >= f.numGlyphs {
+			continue
+		}
+		keep[glyph] = true
+		queue = append(queue, compositeComponents(f.outline(glyf, glyph))...)
+	}
+
+	var outlines bytes.Buffer
+	loca := make([]byte, (f.numGlyphs+1)*4)
+	for glyph := 0; glyph < f.numGlyphs; glyph++ {
+		binary.BigEndian.PutUint32(loca[glyph*4:], uint32(outlines.Len()))
+		if keep[uint16(glyph)] {
+			outlines.Write(f.outline(glyf, uint16(glyph)))
+			for outlines.Len()%4 != 0 {
+				outlines.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[f.numGlyphs*4:], uint32(outlines.Len()))
+
+	tables := make(map[string][]byte, len(subsetTables))
+	for _, tag := range subsetTables {
+		if content := f.table(tag); content != nil {
+			tables[tag] = append([]byte(nil), content...)
+		}
+	}
+	tables["glyf"], tables["loca"] = outlines.Bytes(), loca
+	binary.BigEndian.PutUint16(tables["head"][50:], 1) // Long loca offsets.
+	binary.BigEndian.PutUint32(tables["head"][8:], 0)  // Checksum adjustment, set below.
+	if post := tables["post"]; len(post) >= 32 {
+		tables["post"] = post[:32]
+		binary.BigEndian.PutUint32(tables["post"], 0x00030000) // No glyph names.
+	}
+
+	data := writeFontFile(tables)
+	binary.BigEndian.PutUint32(data[headOffset(data)+8:], 0xB1B0AFBA-checksum(data))
+	return data
+}
+
+// outline returns the outline of the glyph in the glyf table, or nil if it has none or its
+// offsets are invalid.
+func (f *Font) outline(glyf reader, glyph uint16) reader {
+	start, end := f.loca[glyph], f.loca[glyph+1]
+	if start >= end || end > uint32(len(glyf)) {
+		return nil
+	}
+	return glyf[start:end]
+}
+
+// compositeComponents returns the glyphs a composite glyph is made of, or nil for a simple glyph.
+func compositeComponents(outline reader) []uint16 {
+	if len(outline) < 10 || int16(outline.u16(0)) >= 0 {
+		return nil
+	}
+	const (
+		argsAreWords   = 0x0001
+		haveScale      = 0x0008
+		moreComponents = 0x0020
+		haveXYScale    = 0x0040
+		haveTwoByTwo   = 0x0080
+	)
+	var components []uint16
+	for offset := uint32(10); offset+4 <= uint32(len(outline)); {
+		flags := outline.u16(offset)
+		components = append(components, outline.u16(offset+2))
+		offset += 4
+		if flags&argsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+		switch {
+		case flags&haveScale != 0:
+			offset += 2
+		case flags&haveXYScale != 0:
+			offset += 4
+		case flags&haveTwoByTwo != 0:
+			offset += 8
+		}
+		if flags&moreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// writeFontFile returns a font file holding tables, in the order of their tags.
+func writeFontFile(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	searchRange, selector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		selector++
+	}
+	header := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange*16))
+	binary.BigEndian.PutUint16(header[8:], uint16(selector))
+	binary.BigEndian.PutUint16(header[10:], uint16((len(tags)-searchRange)*16))
+
+	data := bytes.NewBuffer(header)
+	for i, tag := range tags {
+		content := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], checksum(content))
+		binary.BigEndian.PutUint32(record[8:], uint32(data.Len()))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(content)))
+		data.Write(content)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	result := data.Bytes()
+	copy(result, header)
+	return result
+}
+
+// headOffset returns the offset of the head table in a font file written by writeFontFile.
+func headOffset(data []byte) uint32 {
+	r := reader(data)
+	for i := uint32(0); i < uint32(r.u16(4)); i++ {
+		if string(data[12+i*16:16+i*16]) == "head" {
+			return r.u32(12 + i*16 + 8)
+		}
+	}
+	return 0
+}
+
+// checksum returns the sum of data as big-endian 32-bit words, padded with zeros.
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+// reader reads big-endian integers from font data, returning zero past its end.
+type reader []byte
+
+func (r reader) u16(offset uint32) uint16 {
+	if uint64(offset)+2 > uint64(len(r)) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(r[offset:])
+}
+
+func (r reader) i16(offset uint32) int16 {
+	return int16(r.u16(offset))
+}
+
+func (r reader) u32(offset uint32) uint32 {
+	if uint64(offset)+4 > uint64(len(r)) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(r[offset:])
+}
@@ -0,0 +1,464 @@
+// Package pdf writes PDF documents made of text, rules, shaded boxes, internal links and
+// bookmarks, for the printable transcripts of the exporter package.
+//
+// Text is set in TrueType fonts embedded in the document, so it renders the same everywhere,
+// without network access or the fonts being installed. A Face lists fallback fonts for the
+// characters its first font lacks, and only the glyphs a document uses are embedded. The text can
+// be searched and copied, as every glyph is mapped back to its character. Text is set left to
+// right, one glyph per character, without the shaping of complex scripts.
+//
+// Coordinates are in points (1/72 inch) from the bottom left corner of the page.
+//
+// # Example Usage
+//
+//	cjk, err := pdf.ParseFont(droidSansFallback) // The DejaVu fonts have no CJK glyphs.
+//	if err != nil {
+//		return err
+//	}
+//	doc := pdf.New(pdf.A4)
+//	page := doc.AddPage()
+//	face := pdf.Face{Fonts: []*pdf.Font{pdf.Sans(), cjk}, Size: 12}
+//	page.Text(72, pdf.A4.Height-72, face, 0, "Hello, 世界")
+//	doc.Bookmark("Hello", page)
+//	_, err := doc.WriteTo(w)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Size is the size of a page, in points.
+type Size struct {
+	Width, Height float64
+}
+
+// Page sizes.
+var (
+	A4     = Size{595.28, 841.89}
+	Letter = Size{612, 792}
+)
+
+// Face is a font at a size. Each character is set in the first of Fonts that has a glyph for it,
+// or in the missing glyph of the first font if none has.
+type Face struct {
+	Fonts []*Font // Fonts in order of preference; the first is the main font.
+	Size  float64 // Size in points.
+}
+
+// run is a part of a text set in one font.
+type run struct {
+	font *Font
+	text string
+}
+
+// runs splits s into the runs of characters set in the same font.
+func (f Face) runs(s string) []run {
+	var runs []run
+	start := 0
+	var current *Font
+	for i, r := range s {
+		font := f.Fonts[0]
+		for _, fallback := range f.Fonts {
+			if fallback.Has(r) {
+				font = fallback
+				break
+			}
+		}
+		if font != current && i > start {
+			runs = append(runs, run{current, s[start:i]})
+			start = i
+		}
+		current = font
+	}
+	if start < len(s) {
+		runs = append(runs, run{current, s[start:]})
+	}
+	return runs
+}
+
+// Width returns the width of s set in the face, in points.
+func (f Face) Width(s string) float64 {
+	width := 0.0
+	for _, run := range f.runs(s) {
+		width += run.font.Width(run.text, f.Size)
+	}
+	return width
+}
+
+// Ascent returns the height of the face above the baseline, in points.
+func (f Face) Ascent() float64 {
+	return float64(f.Fonts[0].ascent) * f.Size / float64(f.Fonts[0].unitsPerEm)
+}
+
+// Document is a PDF document being built.
+type Document struct {
+	size     Size
+	title    string
+	pages    []*Page
+	fonts    []*documentFont         // Fonts used, in order of first use.
+	byFont   map[*Font]*documentFont // Fonts used, by font.
+	bookmark []bookmark
+	missing  map[rune]bool // Characters set that no font of their face has.
+}
+
+// documentFont is a font used in a document.
+type documentFont struct {
+	font *Font
+	name string          // Resource name, such as "F1".
+	used map[uint16]rune // Glyphs used, with the character each stands for.
+}
+
+// bookmark is an entry of the outline of a document.
+type bookmark struct {
+	title string
+	page  *Page
+}
+
+// Page is a page of a Document.
+type Page struct {
+	doc     *Document
+	number  int
+	content bytes.Buffer
+	links   []link
+}
+
+// link is an area of a page that goes to another page when clicked.
+type link struct {
+	x, y, width, height float64
+	target              *Page
+}
+
+// New returns an empty document with pages of size.
+func New(size Size) *Document {
+	return &Document{size: size, byFont: make(map[*Font]*documentFont), missing: make(map[rune]bool)}
+}
+
+// SetTitle sets the title of the document, shown by viewers instead of the file name.
+func (d *Document) SetTitle(title string) {
+	d.title = title
+}
+
+// AddPage adds a page at the end of the document.
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d, number: len(d.pages) + 1}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Pages returns the number of pages of the document.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Bookmark adds an entry going to page to the outline of the document.
+func (d *Document) Bookmark(title string, page *Page) {
+	d.bookmark = append(d.bookmark, bookmark{title, page})
+}
+
+// Missing returns the characters set so far that none of the fonts of their face has, sorted.
+// They are shown as the missing glyph of the first font, usually an empty box.
+func (d *Document) Missing() []rune {
+	missing := make([]rune, 0, len(d.missing))
+	for r := range d.missing {
+		missing = append(missing, r)
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
+}
+
+// Number returns the number of the page in its document, from 1.
+func (p *Page) Number() int {
+	return p.number
+}
+
+// Text sets s in face with its baseline starting at x, y, in the gray level from 0 (black) to
+// 1 (white). It returns the width of the text.
+func (p *Page) Text(x, y float64, face Face, gray float64, s string) float64 {
+	start := x
+	for _, run := range face.runs(s) {
+		font := p.doc.use(run.font)
+		var glyphs strings.Builder
+		for _, r := range run.text {
+			glyph := run.font.glyphs[r]
+			if !run.font.Has(r) {
+				p.doc.missing[r] = true
+			}
+			if _, ok := font.used[glyph]; !ok && glyph != 0 {
+				font.used[glyph] = r
+			}
+			fmt.Fprintf(&glyphs, "%04X", glyph)
+		}
+		fmt.Fprintf(&p.content, "BT /%s %s Tf %s g %s %s Td <%s> Tj ET\n",
+			font.name, num(face.Size), num(gray), num(x), num(y), glyphs.String())
+		x += run.font.Width(run.text, face.Size)
+	}
+	return x - start
+}
+
+// Rect fills the rectangle of width and height from x, y in the gray level.
+func (p *Page) Rect(x, y, width, height, gray float64) {
+	fmt.Fprintf(&p.content, "%s g %s %s %s %s re f\n", num(gray), num(x), num(y), num(width), num(height))
+}
+
+// Line strokes a line of width from x1, y1 to x2, y2 in the gray level.
+func (p *Page) Line(x1, y1, x2, y2, width, gray float64) {
+	fmt.Fprintf(&p.content, "%s G %s w %s %s m %s %s l S\n", num(gray), num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Link makes the rectangle of width and height from x, y go to target when clicked.
+func (p *Page) Link(x, y, width, height float64, target *Page) {
+	p.links = append(p.links, link{x, y, width, height, target})
+}
+
+// use returns the document font of f, adding it on first use.
+func (d *Document) use(f *Font) *documentFont {
+	font, ok := d.byFont[f]
+	if !ok {
+		font = &documentFont{font: f, name: fmt.Sprintf("F%d", len(d.fonts)+1), used: make(map[uint16]rune)}
+		d.fonts = append(d.fonts, font)
+		d.byFont[f] = font
+	}
+	return font
+}
+
+// Objects of a document before those of its pages, bookmarks and fonts.
+const (
+	catalogObject = iota + 1
+	pagesObject
+	infoObject
+	outlinesObject
+	firstObject
+)
+
+// WriteTo writes the document to w. The output only depends on the content of the document.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &objectWriter{w: bufio.NewWriter(w)}
+	out.printf("%%PDF-1.7\n%%\xE2\xE3\xCF\xD3\n")
+
+	// Objects are numbered up front, so that they can refer to each other.
+	pageObjects := make(map[*Page]int, len(d.pages))
+	next := firstObject
+	for _, p := range d.pages {
+		pageObjects[p] = next
+		next += 2 // The page and its content.
+	}
+	bookmarkObjects := next
+	next += len(d.bookmark)
+	fontObjects := next
+	const objectsPerFont = 5 // Type 0 font, CID font, descriptor, font file and character map.
+
+	var kids, resources strings.Builder
+	for _, p := range d.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", pageObjects[p])
+	}
+	for i, font := range d.fonts {
+		fmt.Fprintf(&resources, "/%s %d 0 R ", font.name, fontObjects+i*objectsPerFont)
+	}
+
+	catalog := fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R", pagesObject)
+	if len(d.bookmark) > 0 {
+		catalog += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", outlinesObject)
+	}
+	out.object(catalogObject, catalog+" >>")
+	out.object(pagesObject, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> >>",
+		kids.String(), len(d.pages), num(d.size.Width), num(d.size.Height), resources.String()))
+	info := "<< /Producer " + textString("ChatGPT Session Exporter")
+	if d.title != "" {
+		info += " /Title " + textString(d.title)
+	}
+	out.object(infoObject, info+" >>")
+	if len(d.bookmark) > 0 {
+		out.object(outlinesObject, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>",
+			bookmarkObjects, bookmarkObjects+len(d.bookmark)-1, len(d.bookmark)))
+	} else {
+		out.object(outlinesObject, "<< /Type /Outlines /Count 0 >>")
+	}
+
+	for _, p := range d.pages {
+		var annots strings.Builder
+		for _, l := range p.links {
+			fmt.Fprintf(&annots, "<< /Type /Annot /Subtype /Link /Rect [%s %s %s %s] /Border [0 0 0] /Dest [%d 0 R /XYZ null null null] >> ",
+				num(l.x), num(l.y), num(l.x+l.width), num(l.y+l.height), pageObjects[l.target])
+		}
+		page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /Contents %d 0 R", pagesObject, pageObjects[p]+1)
+		if annots.Len() > 0 {
+			page += " /Annots [" + annots.String() + "]"
+		}
+		out.object(pageObjects[p], page+" >>")
+		out.stream(pageObjects[p]+1, "", p.content.Bytes())
+	}
+
+	for i, b := range d.bookmark {
+		item := fmt.Sprintf("<< /Title %s /Parent %d 0 R /Dest [%d 0 R /XYZ null null null]",
+			textString(b.title), outlinesObject, pageObjects[b.page])
+		if i > 0 {
+			item += fmt.Sprintf(" /Prev %d 0 R", bookmarkObjects+i-1)
+		}
+		if i < len(d.bookmark)-1 {
+			item += fmt.Sprintf(" /Next %d 0 R", bookmarkObjects+i+1)
+		}
+		out.object(bookmarkObjects+i, item+" >>")
+	}
+
+	for i, font := range d.fonts {
+		font.write(out, fontObjects+i*objectsPerFont)
+	}
+
+	// The cross-reference table lists the offset of every object.
+	xref := out.n
+	out.printf("xref\n0 %d\n0000000000 65535 f \n", len(out.offsets)+1)
+	for object := 1; object <= len(out.offsets); object++ {
+		out.printf("%010d 00000 n \n", out.offsets[object])
+	}
+	out.printf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(out.offsets)+1, catalogObject, infoObject, xref)
+	if out.err == nil {
+		out.err = out.w.Flush()
+	}
+	return out.n, out.err
+}
+
+// write writes the objects of the font, numbered from first: the Type 0 font, its CID font, the
+// font descriptor, the subset of the font file and the character map of the glyphs.
+func (font *documentFont) write(out *objectWriter, first int) {
+	f := font.font
+	glyphs := make([]int, 0, len(font.used))
+	for glyph := range font.used {
+		glyphs = append(glyphs, int(glyph))
+	}
+	sort.Ints(glyphs)
+
+	// Subsets are named with a tag derived from the glyphs they hold.
+	hash := fnv.New32a()
+	fmt.Fprint(hash, f.name, glyphs)
+	sum := hash.Sum32()
+	var tag [6]byte
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	name := string(tag[:]) + "+" + f.name
+
+	var widths strings.Builder
+	for _, glyph := range glyphs {
+		fmt.Fprintf(&widths, "%d [%d] ", glyph, f.scale(f.advance(uint16(glyph))))
+	}
+	flags := 32 // Non-symbolic.
+	if f.fixedPitch {
+		flags |= 1
+	}
+
+	out.object(first, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		name, first+1, first+4))
+	out.object(first+1, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor %d 0 R /DW %d /W [%s] /CIDToGIDMap /Identity >>", name, first+2, f.scale(f.advance(0)), widths.String()))
+	out.object(first+2, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%d %d %d %d] /ItalicAngle %s "+
+		"/Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>", name, flags,
+		f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]), num(f.italicAngle),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight), first+3))
+	subset := f.subset(font.used)
+	out.stream(first+3, fmt.Sprintf("/Length1 %d", len(subset)), subset)
+
+	var cmap strings.Builder
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(glyphs); start += 100 { // At most 100 mappings per block.
+		block := glyphs[start:min(start+100, len(glyphs))]
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", len(block))
+		for _, glyph := range block {
+			fmt.Fprintf(&cmap, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{font.used[uint16(glyph)]}) {
+				fmt.Fprintf(&cmap, "%04X", unit)
+			}
+			cmap.WriteString(">\n")
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	out.stream(first+4, "", []byte(cmap.String()))
+}
+
+// objectWriter writes the objects of a document, recording their offsets.
+type objectWriter struct {
+	w       *bufio.Writer
+	n       int64         // Bytes written.
+	offsets map[int]int64 // Offsets of the objects by number.
+	err     error         // First error of w.
+}
+
+// printf writes formatted output, unless a previous write failed.
+func (o *objectWriter) printf(format string, args ...any) {
+	if o.err == nil {
+		var n int
+		n, o.err = fmt.Fprintf(o.w, format, args...)
+		o.n += int64(n)
+	}
+}
+
+// object writes the object number with the content.
+func (o *objectWriter) object(number int, content string) {
+	o.begin(number)
+	o.printf("%s\nendobj\n", content)
+}
+
+// stream writes the object number as a stream of data compressed with Flate, with the
+// additional entries of its dictionary.
+func (o *objectWriter) stream(number int, entries string, data []byte) {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data) // Writes to a bytes.Buffer do not fail.
+	zw.Close()
+	if entries != "" {
+		entries += " "
+	}
+	o.begin(number)
+	o.printf("<< %s/Filter /FlateDecode /Length %d >>\nstream\n", entries, compressed.Len())
+	if o.err == nil {
+		var n int
+		n, o.err = o.w.Write(compressed.Bytes())
+		o.n += int64(n)
+	}
+	o.printf("\nendstream\nendobj\n")
+}
+
+// begin records the offset of the object number and writes its header.
+func (o *objectWriter) begin(number int) {
+	if o.offsets == nil {
+		o.offsets = make(map[int]int64)
+	}
+	o.offsets[number] = o.n
+	o.printf("%d 0 obj\n", number)
+}
+
+// num formats v with at most two decimals, as PDF numbers.
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// textString returns s as a PDF text string, in UTF-16 with a byte order mark.
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, unit := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", unit)
+	}
+	b.WriteString(">")
+	return b.String()
+}
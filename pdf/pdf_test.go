@@ -0,0 +1,49 @@
+// Below, the package pdf (@pdf_test.go) tests the parsing of fonts and the characters that no
+// font of a face covers.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pdf_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pdf"
+)
+
+// TestDocument verifies that fallback fonts must be TrueType fonts, that text is set in the first
+// font of its face that covers it, and that the characters no font covers are listed.
+func TestDocument(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("fonts", "DejaVuSansMono.ttf"))
+	if err != nil {
+		t.Fatalf("failed to read the font: %v", err)
+	}
+	mono, err := pdf.ParseFont(data)
+	if err != nil {
+		t.Fatalf("ParseFont() returned error: %v", err)
+	}
+	if _, err := pdf.ParseFont([]byte("not a font")); err == nil {
+		t.Error("ParseFont() accepted data that is not a font")
+	}
+
+	doc := pdf.New(pdf.A4)
+	page := doc.AddPage()
+	face := pdf.Face{Fonts: []*pdf.Font{pdf.Sans(), mono}, Size: 12}
+	if width := page.Text(72, 720, face, 0, "Gopher 世界 你好"); width <= 0 || width != face.Width("Gopher 世界 你好") {
+		t.Errorf("Text() = %v, want the width of the face %v", width, face.Width("Gopher 世界 你好"))
+	}
+	if got, want := string(doc.Missing()), "世你好界"; got != want {
+		t.Errorf("Missing() = %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() returned error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "%PDF-1.7\n") || !strings.Contains(buf.String(), "+DejaVuSans ") {
+		t.Errorf("WriteTo() wrote %.40q, want a PDF document embedding its font", buf.String())
+	}
+}
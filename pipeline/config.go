@@ -0,0 +1,365 @@
+// Package pipeline runs a declarative export pipeline described by a configuration file.
+//
+// A pipeline reads one or more NextChat backups, optionally repairs them, filters and redacts
+// the sessions, and writes every configured output. Each step reuses the exporter and repairdata
+// packages, so a pipeline produces exactly the same files as the interactive mode.
+//
+// Configuration files can be written in JSON or YAML; the format is chosen by the file extension
+// (.json is JSON, .yaml and .yml are YAML). Files with another extension, such as .toml, are
+// rejected. Relative paths are resolved against the current working directory.
+//
+// # Example Configuration
+//
+//	inputs:
+//	  - backups/*.json
+//	repair:
+//	  enabled: true
+//	  recover: true
+//	filters:
+//	  topics: ["travel"]
+//	  since: 2023-11-01
+//	  minMessages: 2
+//	redact:
+//	  emails: true
+//	  apiKeys: true
+//	  patterns: ["(?i)password: \\S+"]
+//	outputs:
+//	  - format: perline
+//	    path: out/messages.csv
+//	  - format: perline
+//	    path: out/messages.tsv
+//	    options:
+//	      delimiter: tab
+//	      bom: true
+//	      columns: id,topic,mask.modelConfig.model,message.role,message.content
+//	      headers: id=Session,message.content=Text
+//	  - format: separate
+//	    path: out/sessions.csv
+//	    options:
+//	      messages: out/session-messages.csv
+//	  - format: dataset
+//	    path: out/dataset.json.gz
+//	  - format: jsonl
+//	    path: out/history.jsonl
+//	    incremental:
+//	      state: out/history.state.json
+//	  - format: separate
+//	    path: out/bundle.zip
+//	    encrypt: true
+//	    options:
+//	      messages: messages.csv
+//	encryption:
+//	  keyFile: secrets/passphrase.txt
+//
+// The available formats and their options are those registered in the exporter package.
+// An output with an incremental section only writes what changed since the previous run, as
+// recorded in its state file: in append mode (per-line CSV, separate CSV and JSONL formats), new
+// sessions and messages are appended to the files and edits trigger a rewrite; in delta mode,
+// the files only hold the new and edited sessions of each run.
+//
+// Inputs may be compressed with gzip, zstd or zip. Outputs are compressed according to their
+// compression field, or to the extension of their path (.gz, .zst, .zip); a zip output bundles
+// every file of a multi-file format into one archive. Encrypted inputs are decrypted, and outputs
+// that set encrypt are encrypted, with the passphrase configured under encryption.
+//
+// # Example Usage
+//
+//	cfg, err := pipeline.LoadConfig(rfs, "pipeline.yaml")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	result, err := pipeline.Run(ctx, rfs, cfg)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"gopkg.in/yaml.v3"
+)
+
+// dateLayouts are the layouts accepted for the since and until filters.
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"}
+
+// Config describes a complete export pipeline.
+type Config struct {
+	Inputs  []string       `json:"inputs" yaml:"inputs"`   // Backup files or glob patterns to read.
+	Repair  RepairConfig   `json:"repair" yaml:"repair"`   // How backups are repaired before exporting.
+	Filters FilterConfig   `json:"filters" yaml:"filters"` // Which sessions and messages are exported.
+	Redact  RedactConfig   `json:"redact" yaml:"redact"`   // What is redacted from the exported text.
+	Outputs []OutputConfig `json:"outputs" yaml:"outputs"` // The files to write.
+
+	Encryption EncryptionConfig `json:"encryption" yaml:"encryption"` // Where the passphrase of encrypted inputs and outputs comes from.
+}
+
+// EncryptionConfig configures the passphrase used to decrypt encrypted inputs and to encrypt
+// outputs that set encrypt. Encrypted inputs are detected automatically.
+type EncryptionConfig struct {
+	KeyEnv  string `json:"keyEnv" yaml:"keyEnv"`   // Environment variable holding the passphrase; CHAT_EXPORTER_PASSPHRASE if empty.
+	KeyFile string `json:"keyFile" yaml:"keyFile"` // File whose first line is the passphrase, used if the variable is not set.
+}
+
+// keys returns the key source described by the configuration.
+func (c EncryptionConfig) keys() *encryption.KeySource {
+	return &encryption.KeySource{Env: c.KeyEnv, KeyFile: c.KeyFile}
+}
+
+// RepairConfig configures the repair step.
+type RepairConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"` // Run repairdata.RepairSessionData on every input.
+	Recover bool `json:"recover" yaml:"recover"` // Fall back to repairdata.RecoverSessionData for corrupted input.
+}
+
+// FilterConfig configures the filter step. Empty fields do not filter anything.
+type FilterConfig struct {
+	Topics      []string `json:"topics" yaml:"topics"`           // Keep sessions whose topic contains one of these (case-insensitive).
+	SessionIDs  []string `json:"sessionIds" yaml:"sessionIds"`   // Keep only sessions with these IDs.
+	Since       string   `json:"since" yaml:"since"`             // Keep sessions last updated at or after this date.
+	Until       string   `json:"until" yaml:"until"`             // Keep sessions last updated before this date.
+	MinMessages int      `json:"minMessages" yaml:"minMessages"` // Keep sessions with at least this many messages (after role filtering).
+	Roles       []string `json:"roles" yaml:"roles"`             // Keep only messages sent by these roles.
+}
+
+// RedactConfig configures the redaction step, which is applied to topics, memory prompts and message contents.
+type RedactConfig struct {
+	Emails      bool     `json:"emails" yaml:"emails"`           // Redact e-mail addresses.
+	APIKeys     bool     `json:"apiKeys" yaml:"apiKeys"`         // Redact OpenAI-style API keys.
+	Patterns    []string `json:"patterns" yaml:"patterns"`       // Additional regular expressions to redact.
+	Replacement string   `json:"replacement" yaml:"replacement"` // Replacement text, "[REDACTED]" by default.
+}
+
+// OutputConfig describes a single output of the pipeline.
+type OutputConfig struct {
+	Format      string           `json:"format" yaml:"format"`                               // Name of a registered exporter.
+	Path        string           `json:"path" yaml:"path"`                                   // Destination file, or "-" for standard output.
+	Options     exporter.Options `json:"options,omitempty" yaml:"options,omitempty"`         // Options of the exporter, see Exporter.Options.
+	Compression string           `json:"compression,omitempty" yaml:"compression,omitempty"` // "gzip", "zstd" or "zip"; taken from the extension of Path if empty.
+	Encrypt     bool             `json:"encrypt,omitempty" yaml:"encrypt,omitempty"`         // Encrypt the files with the configured passphrase.
+
+	Incremental *IncrementalConfig `json:"incremental,omitempty" yaml:"incremental,omitempty"` // Export only what changed since the previous run.
+}
+
+// Incremental export modes.
+const (
+	// IncrementalAppend appends new sessions and messages to the files of the previous run.
+	IncrementalAppend = "append"
+	// IncrementalDelta writes only the new sessions and messages, replacing the files of the previous run.
+	IncrementalDelta = "delta"
+)
+
+// IncrementalConfig configures an incremental output, see the incremental package.
+type IncrementalConfig struct {
+	State string `json:"state" yaml:"state"`                   // Path of the state file recording what was exported.
+	Mode  string `json:"mode,omitempty" yaml:"mode,omitempty"` // IncrementalAppend (the default) or IncrementalDelta.
+}
+
+// mode returns the configured mode, IncrementalAppend if none is set.
+func (c IncrementalConfig) mode() string {
+	if c.Mode == "" {
+		return IncrementalAppend
+	}
+	return c.Mode
+}
+
+// compression returns the configured compression format, or the one indicated by the extension of Path.
+// Validate has already rejected unknown names.
+func (o OutputConfig) compression() compression.Format {
+	if o.Compression == "" {
+		return compression.FormatFromExtension(o.Path)
+	}
+	format, _ := compression.ParseFormat(o.Compression)
+	return format
+}
+
+// paths returns the destination file and every additional output path set through options.
+func (o OutputConfig) paths(e exporter.Exporter) []string {
+	paths := []string{o.Path}
+	for _, option := range e.Options() {
+		if option.Path {
+			if path := o.Options.Get(e.Options(), option.Name); path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// LoadConfig reads and validates the pipeline configuration at path.
+func LoadConfig(rfs filesystem.FileSystem, path string) (*Config, error) {
+	data, err := rfs.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := ParseConfig(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ParseConfig decodes and validates a pipeline configuration.
+// The ext parameter selects the syntax: ".json" for JSON, ".yaml" or ".yml" for YAML; other
+// extensions are rejected. Unknown fields are rejected so that typos are reported instead of
+// silently ignored.
+func ParseConfig(data []byte, ext string) (*Config, error) {
+	var cfg Config
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid YAML configuration: %w", err)
+		}
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&cfg); err != nil {
+			return nil, fmt.Errorf("invalid JSON configuration: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported configuration format %q: use a .json, .yaml or .yml file", ext)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks the configuration for mistakes before anything is read or written.
+// All problems are reported at once, each prefixed with the location of the offending field.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if len(c.Inputs) == 0 {
+		errs = append(errs, errors.New("inputs: at least one input is required"))
+	}
+	for i, input := range c.Inputs {
+		if strings.TrimSpace(input) == "" {
+			errs = append(errs, fmt.Errorf("inputs[%d]: path must not be empty", i))
+		} else if _, err := filepath.Match(input, ""); err != nil {
+			errs = append(errs, fmt.Errorf("inputs[%d]: invalid glob pattern %q", i, input))
+		}
+	}
+
+	if c.Repair.Recover && !c.Repair.Enabled {
+		errs = append(errs, errors.New("repair.recover: requires repair.enabled"))
+	}
+
+	since, err := parseDate(c.Filters.Since)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("filters.since: %w", err))
+	}
+	until, err := parseDate(c.Filters.Until)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("filters.until: %w", err))
+	}
+	if !since.IsZero() && !until.IsZero() && !since.Before(until) {
+		errs = append(errs, errors.New("filters: since must be before until"))
+	}
+	if c.Filters.MinMessages < 0 {
+		errs = append(errs, errors.New("filters.minMessages: must not be negative"))
+	}
+
+	for i, pattern := range c.Redact.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("redact.patterns[%d]: %w", i, err))
+		}
+	}
+
+	if len(c.Outputs) == 0 {
+		errs = append(errs, errors.New("outputs: at least one output is required"))
+	}
+	seen := make(map[string]int)
+	for i, output := range c.Outputs {
+		if output.Path == "" {
+			errs = append(errs, fmt.Errorf("outputs[%d].path: must not be empty", i))
+		}
+		e, ok := exporter.Lookup(output.Format)
+		if !ok {
+			errs = append(errs, fmt.Errorf("outputs[%d].format: unknown format %q (valid: %s)",
+				i, output.Format, strings.Join(exporter.Names(), ", ")))
+			continue
+		}
+		if err := output.Options.Validate(e.Options()); err != nil {
+			errs = append(errs, fmt.Errorf("outputs[%d].options: %w", i, err))
+		}
+		if _, ok := e.(exporter.WriterExporter); output.Path == StdoutPath && !ok {
+			errs = append(errs, fmt.Errorf("outputs[%d].path: format %q cannot be written to standard output", i, output.Format))
+		}
+		if _, err := compression.ParseFormat(output.Compression); err != nil {
+			errs = append(errs, fmt.Errorf("outputs[%d].compression: %w", i, err))
+		} else if output.Path == StdoutPath && output.compression() != compression.None {
+			errs = append(errs, fmt.Errorf("outputs[%d].compression: standard output cannot be compressed", i))
+		}
+		if output.Path == StdoutPath && output.Encrypt {
+			errs = append(errs, fmt.Errorf("outputs[%d].encrypt: standard output cannot be encrypted", i))
+		}
+		if output.Incremental != nil {
+			errs = append(errs, validateIncremental(i, output, e)...)
+		}
+		paths := output.paths(e)
+		if output.Incremental != nil && output.Incremental.State != "" {
+			paths = append(paths, output.Incremental.State)
+		}
+		for _, path := range paths {
+			if path == "" || path == StdoutPath {
+				continue
+			}
+			if j, ok := seen[filepath.Clean(path)]; ok {
+				errs = append(errs, fmt.Errorf("outputs[%d]: %s is already written by outputs[%d]", i, path, j))
+			}
+			seen[filepath.Clean(path)] = i
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateIncremental checks the incremental settings of the output at index i.
+func validateIncremental(i int, output OutputConfig, e exporter.Exporter) []error {
+	var errs []error
+	if output.Incremental.State == "" {
+		errs = append(errs, fmt.Errorf("outputs[%d].incremental.state: must not be empty", i))
+	}
+	switch output.Incremental.mode() {
+	case IncrementalAppend:
+		if _, ok := e.(exporter.AppendExporter); !ok {
+			errs = append(errs, fmt.Errorf("outputs[%d].incremental.mode: format %q cannot be appended to (use delta)", i, output.Format))
+		}
+		if output.Path == StdoutPath || output.compression() != compression.None || output.Encrypt {
+			errs = append(errs, fmt.Errorf("outputs[%d].incremental.mode: append requires an uncompressed, unencrypted file (use delta)", i))
+		}
+	case IncrementalDelta:
+	default:
+		errs = append(errs, fmt.Errorf("outputs[%d].incremental.mode: unknown mode %q (want %s or %s)",
+			i, output.Incremental.Mode, IncrementalAppend, IncrementalDelta))
+	}
+	return errs
+}
+
+// parseDate parses a date in one of the accepted layouts. An empty string yields the zero time.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC 3339)", value)
+}
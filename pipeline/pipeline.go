@@ -0,0 +1,421 @@
+// Below, the package pipeline (@pipeline.go) executes the steps of a validated configuration:
+// reading and repairing inputs, filtering, redacting, and writing every output.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/incremental"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+const (
+	// StdoutPath is the output path that writes an export to standard output.
+	StdoutPath = "-"
+
+	// DefaultReplacement is the text that replaces redacted content when none is configured.
+	DefaultReplacement = "[REDACTED]"
+
+	// emailPattern matches e-mail addresses.
+	emailPattern = `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`
+
+	// apiKeyPattern matches OpenAI-style secret keys such as sk-... and sk-proj-....
+	apiKeyPattern = `\bsk-[A-Za-z0-9_\-]{20,}`
+)
+
+// Result summarizes a pipeline run.
+type Result struct {
+	Inputs           []string                              // The input files that were read, after glob expansion.
+	SessionsRead     int                                   // Number of distinct sessions read from all inputs.
+	SessionsExported int                                   // Number of sessions left after filtering.
+	Files            []string                              // The files that were written, in output order.
+	Outputs          []OutputResult                        // What was written for every output, in order.
+	Recoveries       map[string]*repairdata.RecoveryReport // Recovery reports of inputs that had to be recovered.
+	Incremental      map[string]*incremental.Result        // Results of the incremental outputs, keyed by output path.
+}
+
+// OutputResult describes what was written for one output of a pipeline run.
+type OutputResult struct {
+	Path   string         // Path of the output, as configured.
+	Format string         // Name of the exporter of the output.
+	Files  []string       // The files that were written.
+	Rows   map[string]int // Records written to every file, keyed by uncompressed path, if the format counts them.
+}
+
+// Run executes the pipeline described by cfg. The configuration is validated again before any
+// input is read, so a misconfigured pipeline never writes partial output.
+//
+// Every step is logged with the default slog logger, and the recovery of an input as a warning.
+//
+// Run stops at the first error and returns the result gathered so far along with it.
+func Run(ctx context.Context, rfs filesystem.FileSystem, cfg *Config) (*Result, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Recoveries:  make(map[string]*repairdata.RecoveryReport),
+		Incremental: make(map[string]*incremental.Result),
+	}
+
+	inputs, err := expandInputs(rfs, cfg.Inputs)
+	if err != nil {
+		return result, err
+	}
+	result.Inputs = inputs
+
+	keys := cfg.Encryption.keys()
+	var stores [][]exporter.Session
+	for _, input := range inputs {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		slog.InfoContext(ctx, "reading input", "path", input)
+		data, err := encryption.ReadFile(rfs, input, keys)
+		if err != nil {
+			return result, err
+		}
+
+		if cfg.Repair.Enabled {
+			var report *repairdata.RecoveryReport
+			data, report, err = Repair(data, cfg.Repair.Recover)
+			if err != nil {
+				return result, fmt.Errorf("%s: failed to repair: %w", input, err)
+			}
+			if report != nil {
+				slog.WarnContext(ctx, "input recovered", "path", input, "report", report.String())
+				result.Recoveries[input] = report
+			}
+		}
+
+		store, err := exporter.ReadJSON(bytes.NewReader(data))
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", input, err)
+		}
+		slog.DebugContext(ctx, "input read", "path", input, "sessions", len(store.ChatNextWebStore.Sessions))
+		stores = append(stores, store.ChatNextWebStore.Sessions)
+	}
+
+	sessions := MergeSessions(stores...)
+	result.SessionsRead = len(sessions)
+
+	sessions, err = FilterSessions(sessions, cfg.Filters)
+	if err != nil {
+		return result, err
+	}
+	sessions, err = RedactSessionsContext(ctx, sessions, cfg.Redact)
+	if err != nil {
+		return result, err
+	}
+	result.SessionsExported = len(sessions)
+	slog.InfoContext(ctx, "sessions selected", "read", result.SessionsRead, "exported", result.SessionsExported)
+
+	for i, output := range cfg.Outputs {
+		outputResult := OutputResult{Path: output.Path, Format: output.Format}
+		var written exporter.Increment
+		if output.Incremental != nil {
+			var incResult *incremental.Result
+			incResult, err = exportIncremental(ctx, rfs, sessions, output, keys, func(delta []exporter.Session) {
+				written = exporter.FullIncrement(delta)
+			})
+			if incResult != nil {
+				result.Incremental[output.Path] = incResult
+				outputResult.Files = incResult.Files
+				if output.Incremental.mode() != IncrementalDelta {
+					written = incResult.Changes.Increment
+					if incResult.Rewritten {
+						written = exporter.FullIncrement(sessions)
+					}
+				}
+				slog.InfoContext(ctx, "incremental output updated", "path", output.Path,
+					"rewritten", incResult.Rewritten, "reason", incResult.Reason)
+			}
+		} else {
+			outputResult.Files, err = Export(ctx, rfs, sessions, output, keys)
+			written = exporter.FullIncrement(sessions)
+		}
+		result.Files = append(result.Files, outputResult.Files...)
+		if err != nil {
+			return result, fmt.Errorf("outputs[%d]: %w", i, err)
+		}
+		if e, ok := exporter.Lookup(output.Format); ok && output.Path != StdoutPath {
+			outputResult.Rows = exporter.CountRows(e, written, output.Path, output.Options)
+		}
+		result.Outputs = append(result.Outputs, outputResult)
+		slog.InfoContext(ctx, "output written", "path", output.Path, "format", output.Format,
+			"files", outputResult.Files, "rows", outputResult.Rows)
+	}
+
+	return result, nil
+}
+
+// Repair runs repairdata.RepairSessionData on data. When recover is true and the data is corrupted
+// or truncated, it falls back to repairdata.RecoverSessionData and also returns its report.
+func Repair(data []byte, recover bool) ([]byte, *repairdata.RecoveryReport, error) {
+	repaired, err := repairdata.RepairSessionData(data)
+	if err == nil || !recover || !isCorruptedJSONError(err) {
+		return repaired, nil, err
+	}
+	return repairdata.RecoverSessionData(data)
+}
+
+// isCorruptedJSONError reports whether err indicates malformed or truncated JSON.
+func isCorruptedJSONError(err error) bool {
+	var parseErr *repairdata.ParseError
+	return errors.As(err, &parseErr)
+}
+
+// Export writes sessions with the registered exporter named by output.Format and returns the files written.
+// Parent directories of every output path are created as needed.
+//
+// When output.Path is StdoutPath, the export is written to os.Stdout instead of a file; this is
+// only supported by formats that implement exporter.WriterExporter. Otherwise the output is
+// compressed or bundled as configured, see exporter.ExportCompressed, and encrypted with the
+// passphrase from keys if output.Encrypt is set.
+func Export(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, output OutputConfig, keys *encryption.KeySource) ([]string, error) {
+	e, ok := exporter.Lookup(output.Format)
+	if !ok {
+		return nil, &exporter.FormatError{Kind: "format", Name: output.Format}
+	}
+
+	if output.Path == StdoutPath {
+		we, ok := e.(exporter.WriterExporter)
+		if !ok {
+			return nil, fmt.Errorf("format %q cannot be written to standard output", output.Format)
+		}
+		return nil, we.ExportTo(ctx, os.Stdout, sessions, output.Options)
+	}
+
+	for _, path := range output.paths(e) {
+		if err := rfs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	outputOptions := exporter.OutputOptions{Compression: output.compression()}
+	if output.Encrypt {
+		passphrase, err := keys.Passphrase(rfs)
+		if err != nil {
+			return nil, err
+		}
+		outputOptions.Passphrase = passphrase
+	}
+	return exporter.ExportWithOutput(ctx, rfs, e, sessions, output.Path, output.Options, outputOptions)
+}
+
+// ExportIncremental writes only what changed in sessions since the previous run of output, as
+// recorded in the state file of output.Incremental. In append mode, the new records are appended
+// to the files of the output, see incremental.Export; in delta mode, the new and edited sessions
+// are written with Export, replacing the files of the previous run.
+func ExportIncremental(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, output OutputConfig, keys *encryption.KeySource) (*incremental.Result, error) {
+	return exportIncremental(ctx, rfs, sessions, output, keys, nil)
+}
+
+// exportIncremental is ExportIncremental, calling exported, if not nil, with the sessions
+// written in delta mode.
+func exportIncremental(ctx context.Context, rfs filesystem.FileSystem, sessions []exporter.Session, output OutputConfig, keys *encryption.KeySource, exported func(delta []exporter.Session)) (*incremental.Result, error) {
+	e, ok := exporter.Lookup(output.Format)
+	if !ok {
+		return nil, &exporter.FormatError{Kind: "format", Name: output.Format}
+	}
+	statePath := output.Incremental.State
+	if err := rfs.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	if output.Incremental.mode() == IncrementalDelta {
+		return incremental.ExportDelta(ctx, rfs, sessions, statePath, func(delta []exporter.Session) ([]string, error) {
+			if exported != nil {
+				exported(delta)
+			}
+			return Export(ctx, rfs, delta, output, keys)
+		})
+	}
+
+	ae, ok := e.(exporter.AppendExporter)
+	if !ok {
+		return nil, fmt.Errorf("format %q cannot be appended to", output.Format)
+	}
+	for _, path := range output.paths(e) {
+		if err := rfs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	return incremental.Export(ctx, rfs, ae, sessions, output.Path, output.Options, statePath)
+}
+
+// expandInputs resolves glob patterns in inputs into a sorted, de-duplicated list of files.
+// A pattern that matches nothing is an error, so that a typo does not produce an empty export.
+func expandInputs(rfs filesystem.FileSystem, inputs []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	for _, input := range inputs {
+		matches, err := rfs.Glob(input)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %q: %w", input, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("input %q does not match any file", input)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// MergeSessions combines the sessions of several backups. Sessions that appear in more than one
+// backup are kept once, using the copy with the most recent LastUpdate; the order of first
+// appearance is preserved.
+func MergeSessions(stores ...[]exporter.Session) []exporter.Session {
+	index := make(map[string]int)
+	var merged []exporter.Session
+	for _, sessions := range stores {
+		for _, session := range sessions {
+			if i, ok := index[session.ID]; ok {
+				if session.LastUpdate > merged[i].LastUpdate {
+					merged[i] = session
+				}
+				continue
+			}
+			index[session.ID] = len(merged)
+			merged = append(merged, session)
+		}
+	}
+	return merged
+}
+
+// FilterSessions returns the sessions and messages that match filter.
+// The input slice and its sessions are not modified.
+func FilterSessions(sessions []exporter.Session, filter FilterConfig) ([]exporter.Session, error) {
+	since, err := parseDate(filter.Since)
+	if err != nil {
+		return nil, err
+	}
+	until, err := parseDate(filter.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(filter.SessionIDs))
+	for _, id := range filter.SessionIDs {
+		ids[id] = true
+	}
+	roles := make(map[string]bool, len(filter.Roles))
+	for _, role := range filter.Roles {
+		roles[strings.ToLower(role)] = true
+	}
+
+	var filtered []exporter.Session
+	for _, session := range sessions {
+		if len(ids) > 0 && !ids[session.ID] {
+			continue
+		}
+		if len(filter.Topics) > 0 && !containsAny(session.Topic, filter.Topics) {
+			continue
+		}
+		if !since.IsZero() && session.LastUpdate < since.UnixMilli() {
+			continue
+		}
+		if !until.IsZero() && session.LastUpdate >= until.UnixMilli() {
+			continue
+		}
+
+		if len(roles) > 0 {
+			messages := make([]exporter.Message, 0, len(session.Messages))
+			for _, message := range session.Messages {
+				if roles[strings.ToLower(message.Role)] {
+					messages = append(messages, message)
+				}
+			}
+			session.Messages = messages
+		}
+
+		if len(session.Messages) < filter.MinMessages {
+			continue
+		}
+		filtered = append(filtered, session)
+	}
+	return filtered, nil
+}
+
+// containsAny reports whether s contains any of the substrings, ignoring case.
+func containsAny(s string, substrings []string) bool {
+	s = strings.ToLower(s)
+	for _, substring := range substrings {
+		if strings.Contains(s, strings.ToLower(substring)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSessions replaces sensitive text in the topic, memory prompt and message contents of every
+// session according to cfg. The input slice and its sessions are not modified.
+func RedactSessions(sessions []exporter.Session, cfg RedactConfig) ([]exporter.Session, error) {
+	return RedactSessionsContext(context.Background(), sessions, cfg)
+}
+
+// RedactSessionsContext is like RedactSessions, but redacts the sessions concurrently with
+// exporter.TransformSessions and stops when ctx is cancelled.
+func RedactSessionsContext(ctx context.Context, sessions []exporter.Session, cfg RedactConfig) ([]exporter.Session, error) {
+	var patterns []*regexp.Regexp
+	if cfg.Emails {
+		patterns = append(patterns, regexp.MustCompile(emailPattern))
+	}
+	if cfg.APIKeys {
+		patterns = append(patterns, regexp.MustCompile(apiKeyPattern))
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if len(patterns) == 0 {
+		return sessions, nil
+	}
+
+	replacement := cfg.Replacement
+	if replacement == "" {
+		replacement = DefaultReplacement
+	}
+	redact := func(s string) string {
+		for _, re := range patterns {
+			s = re.ReplaceAllLiteralString(s, replacement)
+		}
+		return s
+	}
+
+	return exporter.TransformSessions(ctx, sessions, exporter.DefaultWorkers(), func(ctx context.Context, session exporter.Session) (exporter.Session, error) {
+		session.Topic = redact(session.Topic)
+		session.MemoryPrompt = redact(session.MemoryPrompt)
+		messages := make([]exporter.Message, len(session.Messages))
+		for j, message := range session.Messages {
+			message.Content = redact(message.Content)
+			messages[j] = message
+		}
+		session.Messages = messages
+		return session, nil
+	})
+}
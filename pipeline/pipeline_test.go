@@ -0,0 +1,229 @@
+// Below, the package pipeline (@pipeline_test.go) tests the validation and the runs of pipeline
+// configurations, and benchmarks the parallel redaction of sessions.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pipeline"
+)
+
+// TestRunPipelineConfig verifies that a declarative pipeline configuration is validated up front
+// and that running it filters, redacts and writes every configured output, on any file system,
+// counting the rows of every file.
+func TestRunPipelineConfig(t *testing.T) {
+	t.Run("InvalidConfig", func(t *testing.T) {
+		config := "inputs: []\nfilters:\n  since: yesterday\noutputs:\n  - format: parquet\n    path: out.parquet\n"
+		_, err := pipeline.ParseConfig([]byte(config), ".yaml")
+		if err == nil {
+			t.Fatal("ParseConfig() returned no error for an invalid configuration")
+		}
+		for _, want := range []string{"inputs:", "filters.since:", "outputs[0].format:"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("ParseConfig() error %q does not mention %q", err, want)
+			}
+		}
+
+		// Configurations in an unsupported syntax are rejected instead of decoded as JSON.
+		if _, err := pipeline.ParseConfig([]byte("inputs = [\"backup.json\"]\n"), ".toml"); err == nil || !strings.Contains(err.Error(), `unsupported configuration format ".toml"`) {
+			t.Errorf("ParseConfig() of a TOML file = %v, want an unsupported format error", err)
+		}
+		if _, err := pipeline.ParseConfig([]byte(`{"inputs": ["backup.json"], "outputs": [{"format": "jsonl", "path": "out.jsonl"}]}`), ".JSON"); err != nil {
+			t.Errorf("ParseConfig() of a .JSON file returned an error: %v", err)
+		}
+	})
+
+	t.Run("RunPipeline", func(t *testing.T) {
+		outDir := t.TempDir()
+		config := fmt.Sprintf(`inputs: [../testing.json]
+repair:
+  enabled: true
+filters:
+  since: 2023-01-01
+  roles: [user]
+redact:
+  patterns: ["(?i)istanbul"]
+outputs:
+  - format: perline
+    path: %[1]s/csv/messages.csv
+  - format: dataset
+    path: %[1]s/dataset.json
+`, filepath.ToSlash(outDir))
+
+		cfg, err := pipeline.ParseConfig([]byte(config), ".yml")
+		if err != nil {
+			t.Fatalf("ParseConfig() returned an error: %v", err)
+		}
+
+		result, err := pipeline.Run(context.Background(), &filesystem.RealFileSystem{}, cfg)
+		if err != nil {
+			t.Fatalf("Run() returned an error: %v", err)
+		}
+		if result.SessionsExported != 1 || len(result.Files) != 2 {
+			t.Errorf("Run() exported %d sessions to %v, want 1 session in 2 files", result.SessionsExported, result.Files)
+		}
+
+		content, err := os.ReadFile(filepath.Join(outDir, "csv", "messages.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(string(content)), "istanbul") || !strings.Contains(string(content), pipeline.DefaultReplacement) {
+			t.Errorf("messages.csv was not redacted: %s", content)
+		}
+		if strings.Contains(string(content), ",assistant,") {
+			t.Errorf("messages.csv contains messages filtered out by role: %s", content)
+		}
+	})
+
+	t.Run("MockFileSystem", func(t *testing.T) {
+		mockFS := filesystem.NewMockFileSystem()
+		data, err := os.ReadFile("../testing.json")
+		if err != nil {
+			t.Fatalf("Failed to read testing.json: %v", err)
+		}
+		mockFS.Files[filepath.Join("backups", "testing.json")] = data
+		cfg := &pipeline.Config{
+			Inputs:  []string{filepath.Join("backups", "*.json")},
+			Outputs: []pipeline.OutputConfig{{Format: exporter.FormatNamePerLine, Path: filepath.Join("exports", "csv", "messages.csv")}},
+		}
+		if _, err := pipeline.Run(context.Background(), mockFS, cfg); err != nil {
+			t.Fatalf("Run() returned error: %v", err)
+		}
+		if len(mockFS.Files[filepath.Join("exports", "csv", "messages.csv")]) == 0 {
+			t.Error("Run() did not write the export into the mock file system")
+		}
+		if info, err := mockFS.Stat(filepath.Join("exports", "csv")); err != nil || !info.IsDir() {
+			t.Errorf("Stat() of the output directory = %v, %v, want a directory", info, err)
+		}
+	})
+
+	t.Run("Rows", func(t *testing.T) {
+		outDir := t.TempDir()
+		config := fmt.Sprintf(`inputs: [../testing.json]
+outputs:
+  - format: jsonl
+    path: %[1]s/messages.jsonl
+  - format: separate
+    path: %[1]s/sessions.csv
+    options:
+      messages: %[1]s/messages.csv
+`, filepath.ToSlash(outDir))
+		cfg, err := pipeline.ParseConfig([]byte(config), ".yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := pipeline.Run(context.Background(), &filesystem.RealFileSystem{}, cfg)
+		if err != nil {
+			t.Fatalf("Run() returned an error: %v", err)
+		}
+		if len(result.Outputs) != 2 {
+			t.Fatalf("Run() returned %d outputs, want 2", len(result.Outputs))
+		}
+		jsonl, separate := result.Outputs[0], result.Outputs[1]
+		if jsonl.Rows[jsonl.Path] != 2 || separate.Rows[separate.Path] != 1 || separate.Rows[cfg.Outputs[1].Options["messages"]] != 2 {
+			t.Errorf("Rows = %v and %v, want 2 lines, 1 session and 2 messages", jsonl.Rows, separate.Rows)
+		}
+	})
+}
+
+// TestIncrementalOutputs verifies that incremental outputs write delta files, and that their
+// configuration is validated.
+func TestIncrementalOutputs(t *testing.T) {
+	ctx := context.Background()
+	sessions := []exporter.Session{
+		{ID: "s1", Topic: "Go", LastUpdate: 1, Messages: []exporter.Message{{ID: "m1", Role: "user", Content: "Hello"}}},
+	}
+
+	t.Run("delta", func(t *testing.T) {
+		mockFS := filesystem.NewMockFileSystem()
+		output := pipeline.OutputConfig{
+			Format:      exporter.FormatNamePerLine,
+			Path:        "delta.csv",
+			Incremental: &pipeline.IncrementalConfig{State: "state.json", Mode: pipeline.IncrementalDelta},
+		}
+		if _, err := pipeline.ExportIncremental(ctx, mockFS, sessions, output, nil); err != nil {
+			t.Fatalf("ExportIncremental() returned error: %v", err)
+		}
+		grown := []exporter.Session{sessions[0]}
+		grown[0].LastUpdate = 2
+		grown[0].Messages = append(append([]exporter.Message{}, sessions[0].Messages...), exporter.Message{ID: "m2", Role: "assistant", Content: "New"})
+		result, err := pipeline.ExportIncremental(ctx, mockFS, grown, output, nil)
+		if err != nil || len(result.Files) != 1 {
+			t.Fatalf("ExportIncremental() = %+v, %v", result, err)
+		}
+		if content := string(mockFS.Files["delta.csv"]); strings.Contains(content, "Hello") || !strings.Contains(content, "New") {
+			t.Errorf("delta.csv = %q, want only the new message", content)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		cfg := &pipeline.Config{Inputs: []string{"backup.json"}, Outputs: []pipeline.OutputConfig{
+			{Format: exporter.FormatNameDataset, Path: "dataset.json", Incremental: &pipeline.IncrementalConfig{State: "a.json"}},
+			{Format: exporter.FormatNameJSONL, Path: "history.jsonl.gz", Incremental: &pipeline.IncrementalConfig{State: "b.json"}},
+			{Format: exporter.FormatNameJSONL, Path: "history.jsonl", Incremental: &pipeline.IncrementalConfig{Mode: "sometimes"}},
+		}}
+		err := cfg.Validate()
+		for _, want := range []string{"outputs[0].incremental.mode", "outputs[1].incremental.mode", "outputs[2].incremental.state", "outputs[2].incremental.mode"} {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("Validate() = %v, want an error for %s", err, want)
+			}
+		}
+	})
+}
+
+// BenchmarkTransformSessions compares sequential redaction, a heavy per-session step, against the parallel pipeline.
+func BenchmarkTransformSessions(b *testing.B) {
+	sessions := benchmarkSessions(200, 5)
+	cfg := pipeline.RedactConfig{Emails: true, APIKeys: true}
+	redact := func(ctx context.Context, session exporter.Session) (exporter.Session, error) {
+		redacted, err := pipeline.RedactSessions([]exporter.Session{session}, cfg)
+		if err != nil {
+			return session, err
+		}
+		return redacted[0], nil
+	}
+	for _, workers := range benchmarkWorkers() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := exporter.TransformSessions(context.Background(), sessions, workers, redact); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// benchmarkSessions returns n sessions with the given number of messages each, whose contents
+// are long enough for the formatting and redaction work to dominate.
+func benchmarkSessions(n, messages int) []exporter.Session {
+	content := strings.Repeat("Contact gopher@example.com with key sk-abcdefghijklmnopqrstuvwxyz. ", 40)
+	sessions := make([]exporter.Session, n)
+	for i := range sessions {
+		session := exporter.Session{ID: fmt.Sprintf("s%03d", i), Topic: "Benchmark", MemoryPrompt: "Memory"}
+		for j := 0; j < messages; j++ {
+			session.Messages = append(session.Messages, exporter.Message{
+				ID: fmt.Sprintf("m%d", j), Date: "2023-11-28", Role: "user", Content: content,
+			})
+		}
+		sessions[i] = session
+	}
+	return sessions
+}
+
+// benchmarkWorkers returns the worker counts compared by the benchmarks: one, a few, and one per CPU.
+func benchmarkWorkers() []int {
+	workers := []int{1, 2, 4}
+	if n := exporter.DefaultWorkers(); n > 4 {
+		workers = append(workers, n)
+	}
+	return workers
+}
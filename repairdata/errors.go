@@ -0,0 +1,74 @@
+// Below, the package repairdata (@errors.go) defines the errors of the package, so that callers can
+// tell malformed JSON, which RecoverSessionData may salvage, from JSON of another shape and from
+// data that cannot be recovered at all, with errors.Is and errors.As.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package repairdata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrSchemaMismatch is matched by every SchemaError with errors.Is.
+	ErrSchemaMismatch = errors.New("JSON does not match the expected session data")
+	// ErrUnrecoverable is matched by the errors of RecoverJSON and RecoverSessionData when nothing
+	// could be salvaged.
+	ErrUnrecoverable = errors.New("no recoverable JSON data")
+)
+
+// ParseError reports malformed or truncated JSON, and where. It unwraps to the *json.SyntaxError
+// of the decoder.
+type ParseError struct {
+	Offset int64 // Offset in bytes at which the JSON became invalid.
+	Err    error // Error of the decoder.
+}
+
+// Error returns the offset and the error of the decoder.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid JSON at offset %d: %v", e.Offset, e.Err)
+}
+
+// Unwrap returns the error of the decoder.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// SchemaError reports well-formed JSON holding a value of the wrong type, such as a session that
+// is a string. It unwraps to the *json.UnmarshalTypeError of the decoder.
+type SchemaError struct {
+	Offset int64 // Offset in bytes of the mismatching value.
+	Err    error // Error of the decoder.
+}
+
+// Error returns the error of the decoder.
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrSchemaMismatch, e.Err)
+}
+
+// Unwrap returns the error of the decoder.
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrSchemaMismatch.
+func (e *SchemaError) Is(target error) bool {
+	return target == ErrSchemaMismatch
+}
+
+// unmarshal decodes data into v as json.Unmarshal does, returning a ParseError or a SchemaError.
+func unmarshal(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &ParseError{Offset: syntaxErr.Offset, Err: err}
+	case errors.As(err, &typeErr):
+		return &SchemaError{Offset: typeErr.Offset, Err: err}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,31 @@
+// Below, the package repairdata (@errors_test.go) tests that malformed JSON, JSON of another shape
+// and unrecoverable data are reported with their own errors.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package repairdata_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+// TestErrorTaxonomy verifies that the errors of the repair and the recovery can be told apart
+// with errors.Is and errors.As.
+func TestErrorTaxonomy(t *testing.T) {
+	_, err := repairdata.RepairSessionData([]byte(`{"chat-next-web-store": {"sessions": [{"id": 1,}]}}`))
+	var parseErr *repairdata.ParseError
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &parseErr) || parseErr.Offset == 0 || !errors.As(err, &syntaxErr) {
+		t.Errorf("RepairSessionData() of malformed JSON = %v, want a ParseError wrapping a SyntaxError", err)
+	}
+	_, err = repairdata.RepairSessionData([]byte(`{"chat-next-web-store": {"sessions": 5}}`))
+	if !errors.Is(err, repairdata.ErrSchemaMismatch) || errors.As(err, &parseErr) {
+		t.Errorf("RepairSessionData() of mistyped sessions = %v, want ErrSchemaMismatch", err)
+	}
+	if _, _, err := repairdata.RecoverSessionData([]byte(`}}}`)); !errors.Is(err, repairdata.ErrUnrecoverable) {
+		t.Errorf("RecoverSessionData() of garbage = %v, want ErrUnrecoverable", err)
+	}
+}
@@ -0,0 +1,322 @@
+// Below, the package repairdata (@recover.go) provides a tolerant recovery mode for
+// browser exports that were cut off mid-file or contain a bad byte.
+//
+// Instead of giving up on the first json.Unmarshal error, the recovery mode salvages
+// every complete session and message before the corruption point, closes the open
+// JSON structure, and reports what was lost.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package repairdata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// utf8BOM is the byte order mark some editors and browsers prepend to UTF-8 files.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// RecoveryReport describes what RecoverSessionData had to change or discard in order
+// to produce valid JSON. Offsets refer to the input after the BOM has been removed.
+type RecoveryReport struct {
+	BOMRemoved           bool   // A UTF-8 byte order mark was stripped from the start of the input.
+	InvalidUTF8Replaced  bool   // Invalid UTF-8 sequences were replaced with U+FFFD.
+	ControlCharsEscaped  int    // Raw control characters inside strings that were escaped.
+	TrailingBytesRemoved int    // Bytes after the end of the top-level value that were dropped.
+	Truncated            bool   // The input ended early or was corrupted, and the structure had to be closed.
+	CorruptionOffset     int64  // Offset of the first byte that could not be parsed (or the input length if it ended early).
+	DiscardedBytes       int    // Number of bytes that were dropped between the last complete value and the end of the input.
+	PartialPath          string // Path of the innermost container that had to be closed, e.g. chat-next-web-store.sessions[3].messages.
+	SessionsRecovered    int    // Number of sessions present in the recovered data.
+	MessagesRecovered    int    // Number of messages present in the recovered data.
+}
+
+// Changed reports whether any recovery step modified the input.
+func (r *RecoveryReport) Changed() bool {
+	return r.BOMRemoved || r.InvalidUTF8Replaced || r.ControlCharsEscaped > 0 ||
+		r.TrailingBytesRemoved > 0 || r.Truncated
+}
+
+// String returns a human-readable summary of the recovery.
+func (r *RecoveryReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Recovered %d sessions and %d messages.", r.SessionsRecovered, r.MessagesRecovered)
+	if r.BOMRemoved {
+		sb.WriteString("\n- Removed UTF-8 byte order mark.")
+	}
+	if r.InvalidUTF8Replaced {
+		sb.WriteString("\n- Replaced invalid UTF-8 sequences with U+FFFD.")
+	}
+	if r.ControlCharsEscaped > 0 {
+		fmt.Fprintf(&sb, "\n- Escaped %d raw control characters inside strings.", r.ControlCharsEscaped)
+	}
+	if r.TrailingBytesRemoved > 0 {
+		fmt.Fprintf(&sb, "\n- Removed %d bytes of trailing garbage.", r.TrailingBytesRemoved)
+	}
+	if r.Truncated {
+		fmt.Fprintf(&sb, "\n- Data was corrupted or truncated at offset %d; discarded %d bytes.", r.CorruptionOffset, r.DiscardedBytes)
+		if r.PartialPath != "" {
+			fmt.Fprintf(&sb, "\n- %s was closed early after its last complete entry.", r.PartialPath)
+		}
+	}
+	return sb.String()
+}
+
+// RecoverSessionData is the tolerant counterpart of RepairSessionData.
+//
+// It strips a leading BOM, replaces invalid UTF-8, escapes raw control characters in strings,
+// drops trailing garbage and, when the data is truncated or corrupted, keeps every complete
+// value before the corruption point and closes the remaining structure.
+// The salvaged data is then passed through RepairSessionData.
+//
+// It returns the repaired JSON together with a report of what was lost.
+func RecoverSessionData(data []byte) ([]byte, *RecoveryReport, error) {
+	salvaged, report, err := RecoverJSON(data)
+	if err != nil {
+		return nil, report, err
+	}
+
+	repaired, err := RepairSessionData(salvaged)
+	if err != nil {
+		return nil, report, err
+	}
+
+	// Count what survived so the caller can tell the user.
+	var recovered NewData
+	if err := json.Unmarshal(repaired, &recovered); err != nil {
+		return nil, report, err
+	}
+	report.SessionsRecovered = len(recovered.ChatNextWebStore.Sessions)
+	for _, session := range recovered.ChatNextWebStore.Sessions {
+		report.MessagesRecovered += len(session.Messages)
+	}
+
+	return repaired, report, nil
+}
+
+// RecoverJSON salvages as much of a damaged JSON document as possible without any knowledge
+// of the session schema. It returns syntactically valid JSON and a report of the changes.
+//
+// It returns an error matching ErrUnrecoverable only if nothing at all could be salvaged.
+func RecoverJSON(data []byte) ([]byte, *RecoveryReport, error) {
+	report := &RecoveryReport{}
+
+	if bytes.HasPrefix(data, utf8BOM) {
+		data = data[len(utf8BOM):]
+		report.BOMRemoved = true
+	}
+	if !utf8.Valid(data) {
+		data = bytes.ToValidUTF8(data, []byte("\uFFFD"))
+		report.InvalidUTF8Replaced = true
+	}
+	data, report.ControlCharsEscaped = escapeControlChars(data)
+
+	// Decode the first top-level value to find where it ends or where it breaks.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var value json.RawMessage
+	err := decoder.Decode(&value)
+	if err == nil {
+		end := int(decoder.InputOffset())
+		if rest := bytes.TrimSpace(data[end:]); len(rest) > 0 {
+			report.TrailingBytesRemoved = len(data) - end
+		}
+		return data[:end], report, nil
+	}
+
+	// Determine how many bytes are known to be well-formed.
+	var valid int
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset counts the offending byte, so everything before it is well-formed.
+		valid = int(syntaxErr.Offset) - 1
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		valid = len(data)
+	default:
+		return nil, report, fmt.Errorf("%w: %w", ErrUnrecoverable, err)
+	}
+	if valid < 0 {
+		valid = 0
+	}
+
+	cut, stack := lastCompleteValue(data[:valid])
+	if cut == 0 {
+		return nil, report, fmt.Errorf("%w before offset %d: %w", ErrUnrecoverable, valid, err)
+	}
+
+	report.Truncated = true
+	report.CorruptionOffset = int64(valid)
+	report.DiscardedBytes = len(data) - cut
+	report.PartialPath = containerPath(stack)
+
+	// Close every container that was still open at the cut point, innermost first.
+	salvaged := make([]byte, 0, cut+len(stack))
+	salvaged = append(salvaged, data[:cut]...)
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].open == '{' {
+			salvaged = append(salvaged, '}')
+		} else {
+			salvaged = append(salvaged, ']')
+		}
+	}
+
+	if !json.Valid(salvaged) {
+		return nil, report, fmt.Errorf("%w: recovered data is still not valid JSON: %w", ErrUnrecoverable, err)
+	}
+	return salvaged, report, nil
+}
+
+// container tracks an open JSON object or array while scanning.
+type container struct {
+	open      byte   // '{' or '['
+	key       string // Last key seen in an object.
+	index     int    // Index of the current element in an array.
+	expectKey bool   // Whether the next string in an object is a key.
+	nested    bool   // Whether the container holds another container.
+
+	// The checkpoint that was current before this container was opened.
+	beforeCut  int
+	beforeOpen []container
+}
+
+// lastCompleteValue scans well-formed (but possibly incomplete) JSON and returns the length
+// of the longest prefix that ends on a value boundary, along with the containers that are
+// still open at that point. Appending the matching closers to data[:cut] yields valid JSON.
+//
+// An incomplete flat object inside an array, such as a message that was cut off halfway,
+// is discarded entirely rather than closed early, so only complete records survive.
+func lastCompleteValue(data []byte) (cut int, open []container) {
+	var (
+		stack    []container
+		inString bool
+		escaped  bool
+		keyStart = -1
+	)
+	checkpoint := func(pos int) {
+		cut = pos
+		open = append(open[:0:0], stack...)
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if keyStart >= 0 {
+					stack[len(stack)-1].key = string(data[keyStart:i])
+					keyStart = -1
+				}
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			if n := len(stack); n > 0 && stack[n-1].expectKey {
+				stack[n-1].expectKey = false
+				keyStart = i + 1
+			}
+		case '{', '[':
+			if n := len(stack); n > 0 {
+				stack[n-1].nested = true
+			}
+			stack = append(stack, container{open: c, expectKey: c == '{', beforeCut: cut, beforeOpen: open})
+			checkpoint(i + 1)
+		case '}', ']':
+			if len(stack) == 0 {
+				return cut, open
+			}
+			stack = stack[:len(stack)-1]
+			checkpoint(i + 1)
+			if len(stack) == 0 {
+				return cut, open
+			}
+		case ',':
+			if len(stack) == 0 {
+				return cut, open
+			}
+			// Everything before the comma is a complete member or element.
+			checkpoint(i)
+			top := &stack[len(stack)-1]
+			if top.open == '[' {
+				top.index++
+			} else {
+				top.expectKey = true
+			}
+		}
+	}
+
+	if n := len(open); n >= 2 && open[n-1].open == '{' && open[n-2].open == '[' && !open[n-1].nested {
+		return open[n-1].beforeCut, open[n-1].beforeOpen
+	}
+	return cut, open
+}
+
+// containerPath renders the path of the innermost container in stack,
+// for example chat-next-web-store.sessions[3].messages.
+func containerPath(stack []container) string {
+	var sb strings.Builder
+	for i := 0; i < len(stack)-1; i++ {
+		if stack[i].open == '{' {
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(stack[i].key)
+		} else {
+			sb.WriteString("[" + strconv.Itoa(stack[i].index) + "]")
+		}
+	}
+	return sb.String()
+}
+
+// escapeControlChars replaces raw control characters inside JSON strings with their
+// \u escape sequences, which json.Unmarshal would otherwise reject as a bad byte.
+// It returns the (possibly unchanged) data and the number of characters escaped.
+func escapeControlChars(data []byte) ([]byte, int) {
+	var (
+		out      []byte
+		escapedN int
+		inString bool
+		escaped  bool
+	)
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			case c < 0x20:
+				if out == nil {
+					out = append(make([]byte, 0, len(data)+16), data[:i]...)
+				}
+				out = append(out, fmt.Sprintf(`\u%04x`, c)...)
+				escapedN++
+				continue
+			}
+		} else if c == '"' {
+			inString = true
+		}
+		if out != nil {
+			out = append(out, c)
+		}
+	}
+	if out == nil {
+		return data, 0
+	}
+	return out, escapedN
+}
@@ -0,0 +1,56 @@
+// Below, the package repairdata (@recover_test.go) tests the tolerant recovery of damaged JSON.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package repairdata_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/repairdata"
+)
+
+// TestRecoverJSON verifies that a byte order mark, raw control characters and trailing garbage are
+// removed, and that a truncated document keeps its complete values and is closed.
+func TestRecoverJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		check func(*repairdata.RecoveryReport) bool
+	}{
+		{"BOM", "\xEF\xBB\xBF{\"a\": 1}", `{"a": 1}`, func(r *repairdata.RecoveryReport) bool { return r.BOMRemoved }},
+		{"ControlChars", "{\"a\": \"x\ty\"}", `{"a": "x\ty"}`, func(r *repairdata.RecoveryReport) bool { return r.ControlCharsEscaped == 1 }},
+		{"TrailingGarbage", "{\"a\": 1}\x00garbage", `{"a": 1}`, func(r *repairdata.RecoveryReport) bool { return r.TrailingBytesRemoved == 8 }},
+		{"Truncated", `{"a": [1, 2, {"b": 3}, {"c": `, `{"a": [1, 2, {"b": 3}]}`, func(r *repairdata.RecoveryReport) bool { return r.Truncated && r.PartialPath == "a" }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, report, err := repairdata.RecoverJSON([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("RecoverJSON() returned an error: %v", err)
+			}
+			var gotValue, wantValue any
+			if err := json.Unmarshal(got, &gotValue); err != nil {
+				t.Fatalf("RecoverJSON() = %q, which is not valid JSON: %v", got, err)
+			}
+			json.Unmarshal([]byte(tc.want), &wantValue)
+			if gotJSON, _ := json.Marshal(gotValue); string(gotJSON) != mustMarshal(wantValue) {
+				t.Errorf("RecoverJSON() = %s, want %s", got, tc.want)
+			}
+			if !tc.check(report) || !report.Changed() {
+				t.Errorf("RecoverJSON() report = %+v", report)
+			}
+		})
+	}
+
+	if _, _, err := repairdata.RecoverJSON([]byte(`}}}`)); err == nil {
+		t.Error("RecoverJSON() of garbage returned no error")
+	}
+}
+
+// mustMarshal returns the JSON encoding of v as a string.
+func mustMarshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
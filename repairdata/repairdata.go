@@ -2,6 +2,9 @@
 //
 // It specifically ensures that each session's modelConfig contains a 'systemprompt' field.
 //
+// It also offers a tolerant recovery mode (see RecoverSessionData) that salvages the complete
+// sessions and messages of a corrupted or truncated browser export.
+//
 // Copyright (c) 2023 H0llyW00dzZ
 package repairdata
 
@@ -133,9 +136,11 @@ type Session struct {
 // RepairSessionData transforms JSON data from the old format to the new format.
 //
 // It adds a 'systemprompt' field to the 'modelConfig' within each session if it is missing.
+// It returns a *ParseError if the data is not valid JSON, and a *SchemaError if it has values of
+// the wrong type.
 func RepairSessionData(oldDataBytes []byte) ([]byte, error) {
 	var oldData OldData
-	err := json.Unmarshal(oldDataBytes, &oldData)
+	err := unmarshal(oldDataBytes, &oldData)
 	if err != nil {
 		return nil, err
 	}
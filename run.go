@@ -0,0 +1,99 @@
+// @run.go:
+// Package main (run.go) implements the run command, which executes a declarative export
+// pipeline described by a JSON or YAML configuration file.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/incremental"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pipeline"
+)
+
+// runPipelineCommand loads, validates and executes the pipeline configuration given by the -config flag.
+func runPipelineCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to the pipeline configuration file (.json, .yaml or .yml)")
+	validateOnly := flags.Bool("validate", false, "only validate the configuration without running it")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("error.flagRequired", "run", "-config"))
+		flags.Usage()
+		return ExitUsage
+	}
+
+	rfs := &filesystem.RealFileSystem{}
+	cfg, err := pipeline.LoadConfig(rfs, *configPath)
+	if err != nil {
+		slog.Error("invalid configuration", "path", *configPath, "error", err)
+		fmt.Fprintf(os.Stderr, "[GopherHelper] %s\n%s\n", i18n.T("error.invalidConfig"), err)
+		return ExitUsage
+	}
+	if *validateOnly {
+		bannercli.Print("[GopherHelper] " + i18n.T("info.configValid", *configPath))
+		return ExitOK
+	}
+
+	var result *pipeline.Result
+	err = withProgressBar(ctx, func(ctx context.Context) (err error) {
+		result, err = pipeline.Run(ctx, rfs, cfg)
+		return err
+	})
+	if result != nil {
+		recordPipelineResult(result)
+		printPipelineResult(result)
+	}
+	if err != nil {
+		return printError(i18n.T("error.pipeline"), err)
+	}
+	return ExitOK
+}
+
+// printPipelineResult prints a summary of a pipeline run.
+func printPipelineResult(result *pipeline.Result) {
+	bannercli.Print("[GopherHelper] " + i18n.T("info.pipelineResult",
+		result.SessionsRead, len(result.Inputs), result.SessionsExported))
+
+	inputs := make([]string, 0, len(result.Recoveries))
+	for input := range result.Recoveries {
+		inputs = append(inputs, input)
+	}
+	sort.Strings(inputs)
+	for _, input := range inputs {
+		bannercli.Print("[GopherHelper] " + i18n.T("info.recovered", input, recoveryMessage(result.Recoveries[input])))
+	}
+
+	outputs := make([]string, 0, len(result.Incremental))
+	for output := range result.Incremental {
+		outputs = append(outputs, output)
+	}
+	sort.Strings(outputs)
+	for _, output := range outputs {
+		printIncremental(output, result.Incremental[output])
+	}
+
+	for _, file := range result.Files {
+		bannercli.Print(fmt.Sprintf("  %s", file))
+	}
+}
+
+// printIncremental summarizes what the incremental output at path wrote.
+func printIncremental(path string, result *incremental.Result) {
+	changes := result.Changes
+	bannercli.Print("[GopherHelper] " + i18n.T("info.incremental", path,
+		len(changes.Increment.New), len(changes.Increment.Sessions)-len(changes.Increment.New),
+		len(changes.Edited), len(changes.Removed), changes.Unchanged))
+	if result.Rewritten {
+		bannercli.Print("[GopherHelper] " + i18n.T("info.incrementalRewritten", path, result.Reason))
+	}
+}
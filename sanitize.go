@@ -0,0 +1,110 @@
+// @sanitize.go:
+// Package main (sanitize.go) implements the sanitize command, which removes the API keys,
+// access code and custom URLs from a backup so that it can be shared.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/sanitize"
+)
+
+// runSanitizeCommand writes a copy of a backup without its access-control secrets.
+func runSanitizeCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("sanitize", flag.ContinueOnError)
+	in := flags.String("in", "", "backup to sanitize (required)")
+	out := flags.String("out", "", "sanitized backup to write (default: the input file with .sanitized before .json)")
+	mode := flags.String("mode", sanitize.Strip.String(), "strip secrets, or mask them so that only their first and last characters remain")
+	appConfig := flags.Bool("app-config", false, "also remove the app-config section with the user's customisations")
+	keyFile := flags.String("key-file", "", "file whose first line is the passphrase of an encrypted backup")
+	force := flags.Bool("force", false, "replace the output file if it already exists")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *in == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("error.flagRequired", "sanitize", "-in"))
+		flags.Usage()
+		return ExitUsage
+	}
+
+	options := sanitize.Options{AppConfig: *appConfig}
+	var err error
+	if options.Mode, err = sanitize.ParseMode(*mode); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("error.unknownSanitizeMode", "sanitize", *mode, "strip, mask"))
+		return ExitUsage
+	}
+	if *out == "" {
+		*out = sanitizedFileName(*in)
+	}
+
+	rfs := &filesystem.RealFileSystem{}
+	if exists, err := rfs.FileExists(*out); err == nil && exists && !*force {
+		fmt.Fprintln(os.Stderr, i18n.T("error.outputExists", "sanitize", *out))
+		return ExitFailure
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	keys := &encryption.KeySource{KeyFile: *keyFile, Prompt: func() (string, error) {
+		return interactivity.PromptPassphrase(ctx, reader, i18n.T(PromptEnterPassphrase))
+	}}
+	report, err := sanitizeBackup(rfs, ctx, *in, *out, options, keys)
+	if err != nil {
+		return printError(i18n.T("error.generic"), err)
+	}
+	recordInput(*in, 0)
+	recordFile("sanitize", *out, 0)
+
+	if !report.Changed() {
+		bannercli.Print("[GopherHelper] " + i18n.T("info.noSecrets", *in, *out))
+		return ExitOK
+	}
+	bannercli.Print("[GopherHelper] " + i18n.T("info.sanitized", *out, len(report.Changes), options.Mode))
+	for _, change := range report.Changes {
+		bannercli.Print(fmt.Sprintf("  %s", change))
+	}
+	return ExitOK
+}
+
+// sanitizeBackup reads the backup in, which may be compressed or encrypted, and writes it to out
+// as plain JSON with its secrets removed as described by options.
+func sanitizeBackup(rfs filesystem.FileSystem, ctx context.Context, in, out string, options sanitize.Options, keys *encryption.KeySource) (*sanitize.Report, error) {
+	data, err := encryption.ReadFile(rfs, in, keys)
+	if err != nil {
+		return nil, err
+	}
+	sanitized, report, err := sanitize.Sanitize(data, options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := rfs.WriteFile(out, sanitized, 0644); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// sanitizedFileName returns the default output of the sanitize command: the input without its
+// encryption and compression extensions, with ".sanitized" inserted before the extension,
+// e.g. "backup.json.gz" becomes "backup.sanitized.json".
+func sanitizedFileName(in string) string {
+	name := compression.TrimExtension(encryption.TrimExtension(in))
+	ext := filepath.Ext(name)
+	if ext == "" {
+		ext = ".json"
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".sanitized" + ext
+}
@@ -0,0 +1,276 @@
+// Package sanitize removes access-control secrets from NextChat backups so that they can be
+// shared safely.
+//
+// The access-control section of a backup holds the API keys, the access code and the custom
+// endpoint URLs of the browser it was exported from. Sanitize either strips these fields
+// (resetting them to the values of a fresh NextChat install) or masks them, and can optionally
+// drop the app-config section with the user's customisations as well. Everything else, including
+// the order of the keys, is kept as it is, so the result can still be imported into NextChat.
+//
+// # Example Usage
+//
+//	sanitized, report, err := sanitize.Sanitize(data, sanitize.Options{Mode: sanitize.Mask})
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(report)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package sanitize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// AccessControlKey is the top-level key of the access-control section of a backup.
+	AccessControlKey = "access-control"
+
+	// AppConfigKey is the top-level key of the app-config section of a backup.
+	AppConfigKey = "app-config"
+)
+
+// Mode selects how secret fields are removed.
+type Mode int
+
+const (
+	// Strip resets secret fields to the values of a fresh NextChat install.
+	Strip Mode = iota
+	// Mask replaces secret fields with a masked value that only reveals their first and last characters.
+	Mask
+)
+
+// String returns the name of the mode as accepted by ParseMode.
+func (m Mode) String() string {
+	if m == Mask {
+		return "mask"
+	}
+	return "strip"
+}
+
+// ParseMode parses the name of a mode, "strip" or "mask".
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "strip":
+		return Strip, nil
+	case "mask":
+		return Mask, nil
+	default:
+		return Strip, fmt.Errorf("unknown sanitize mode %q (want strip or mask)", name)
+	}
+}
+
+// Options controls what Sanitize removes.
+type Options struct {
+	Mode      Mode // How secret fields are removed.
+	AppConfig bool // Whether the app-config section is removed as well.
+}
+
+// Change describes a single field that Sanitize removed or masked.
+type Change struct {
+	Section string // Top-level section of the backup, e.g. "access-control".
+	Field   string // Name of the field within the section; empty if the whole section was removed.
+	Action  string // What was done: "stripped", "masked" or "removed".
+}
+
+// String returns the change in the form "access-control.openaiApiKey stripped".
+func (c Change) String() string {
+	if c.Field == "" {
+		return c.Section + " " + c.Action
+	}
+	return c.Section + "." + c.Field + " " + c.Action
+}
+
+// Report lists the changes made by Sanitize.
+type Report struct {
+	Changes []Change // Changes in the order of the fields in the backup.
+}
+
+// Changed reports whether Sanitize modified the backup.
+func (r *Report) Changed() bool {
+	return r != nil && len(r.Changes) > 0
+}
+
+// String returns a human-readable summary of the changes.
+func (r *Report) String() string {
+	if !r.Changed() {
+		return "no secrets found"
+	}
+	parts := make([]string, len(r.Changes))
+	for i, change := range r.Changes {
+		parts[i] = change.String()
+	}
+	return fmt.Sprintf("%d fields sanitized: %s", len(r.Changes), strings.Join(parts, ", "))
+}
+
+// defaults holds the values of secret fields in a fresh NextChat install. Secret fields that are
+// not listed here default to an empty string.
+var defaults = map[string]string{
+	"openaiUrl": "/api/openai",
+}
+
+// IsSecretField reports whether the access-control field name holds a secret: the access code,
+// an API or secret key of any provider (e.g. "openaiApiKey", "baiduSecretKey"), or an endpoint URL
+// (e.g. "azureUrl").
+func IsSecretField(name string) bool {
+	return name == "accessCode" ||
+		strings.HasSuffix(name, "ApiKey") ||
+		strings.HasSuffix(name, "SecretKey") ||
+		strings.HasSuffix(name, "Url")
+}
+
+// Sanitize returns a copy of the backup data with the secrets of its access-control section
+// removed as described by options, along with a report of what was changed. Fields that hold
+// no value, or their default value, are left untouched and are not reported.
+func Sanitize(data []byte, options Options) ([]byte, *Report, error) {
+	backup, err := decodeObject(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse backup: %w", err)
+	}
+
+	report := &Report{}
+	if raw, ok := backup.values[AccessControlKey]; ok {
+		accessControl, err := decodeObject(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse %s: %w", AccessControlKey, err)
+		}
+		if err := sanitizeAccessControl(accessControl, options.Mode, report); err != nil {
+			return nil, nil, err
+		}
+		if backup.values[AccessControlKey], err = accessControl.encode(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if options.AppConfig && backup.remove(AppConfigKey) {
+		report.Changes = append(report.Changes, Change{Section: AppConfigKey, Action: "removed"})
+	}
+
+	sanitized, err := backup.encode()
+	if err != nil {
+		return nil, nil, err
+	}
+	return sanitized, report, nil
+}
+
+// sanitizeAccessControl strips or masks every secret field of the access-control section.
+func sanitizeAccessControl(accessControl *object, mode Mode, report *Report) error {
+	for _, key := range accessControl.keys {
+		if !IsSecretField(key) {
+			continue
+		}
+		var value string
+		if err := json.Unmarshal(accessControl.values[key], &value); err != nil {
+			// Secrets are always strings; anything else is left alone.
+			continue
+		}
+		if value == "" || value == defaults[key] {
+			continue
+		}
+
+		change := Change{Section: AccessControlKey, Field: key, Action: "stripped"}
+		replacement := defaults[key]
+		if mode == Mask {
+			change.Action = "masked"
+			replacement = MaskValue(value)
+		}
+		encoded, err := json.Marshal(replacement)
+		if err != nil {
+			return err
+		}
+		accessControl.values[key] = encoded
+		report.Changes = append(report.Changes, change)
+	}
+	return nil
+}
+
+// MaskValue hides value except for a short prefix and suffix, e.g. "sk-abc...wxyz" becomes
+// "sk-...wxyz". Values too short to keep anything are replaced by asterisks entirely.
+func MaskValue(value string) string {
+	runes := []rune(value)
+	if len(runes) < 12 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:3]) + "..." + string(runes[len(runes)-4:])
+}
+
+// object is a JSON object that remembers the order of its keys, so that a sanitized backup
+// differs from the original only in the fields that were changed.
+type object struct {
+	keys   []string                   // keys in their original order.
+	values map[string]json.RawMessage // values by key.
+}
+
+// decodeObject parses data, which must hold a single JSON object.
+func decodeObject(data []byte) (*object, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("expected a JSON object")
+	}
+
+	obj := &object{values: make(map[string]json.RawMessage)}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string) // Object keys are always strings.
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		if _, seen := obj.values[key]; !seen {
+			obj.keys = append(obj.keys, key)
+		}
+		obj.values[key] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err == nil {
+		return nil, errors.New("unexpected data after the JSON object")
+	}
+	return obj, nil
+}
+
+// remove deletes key from the object and reports whether it was present.
+func (o *object) remove(key string) bool {
+	if _, ok := o.values[key]; !ok {
+		return false
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// encode serializes the object with its keys in their original order.
+func (o *object) encode() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
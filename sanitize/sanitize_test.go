@@ -0,0 +1,52 @@
+// Below, the package sanitize (@sanitize_test.go) tests the removal of the secrets of a backup.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package sanitize_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/sanitize"
+)
+
+// TestSanitize verifies that the access-control secrets are stripped or masked, and that
+// app-config is optionally removed.
+func TestSanitize(t *testing.T) {
+	backup := `{"chat-next-web-store":{"sessions":[]},` +
+		`"access-control":{"accessCode":"letmein","provider":"OpenAI","openaiUrl":"https://proxy.example.com/v1",` +
+		`"openaiApiKey":"sk-abcdefghijklmnopWXYZ","azureUrl":"","azureApiKey":"","needCode":true},` +
+		`"app-config":{"theme":"dark"},"mask-store":{"masks":{}}}`
+
+	sanitized, report, err := sanitize.Sanitize([]byte(backup), sanitize.Options{})
+	if err != nil {
+		t.Fatalf("Sanitize() returned error: %v", err)
+	}
+	want := `{"chat-next-web-store":{"sessions":[]},` +
+		`"access-control":{"accessCode":"","provider":"OpenAI","openaiUrl":"/api/openai",` +
+		`"openaiApiKey":"","azureUrl":"","azureApiKey":"","needCode":true},` +
+		`"app-config":{"theme":"dark"},"mask-store":{"masks":{}}}`
+	if string(sanitized) != want {
+		t.Errorf("Sanitize() = %s, want %s", sanitized, want)
+	}
+	if len(report.Changes) != 3 || report.Changes[2].String() != "access-control.openaiApiKey stripped" {
+		t.Errorf("Sanitize() report = %s, want 3 stripped fields", report)
+	}
+
+	sanitized, report, err = sanitize.Sanitize([]byte(backup), sanitize.Options{Mode: sanitize.Mask, AppConfig: true})
+	if err != nil {
+		t.Fatalf("Sanitize() returned error: %v", err)
+	}
+	for _, want := range []string{`"accessCode":"*******"`, `"openaiApiKey":"sk-...WXYZ"`, `"openaiUrl":"htt...m/v1"`} {
+		if !strings.Contains(string(sanitized), want) {
+			t.Errorf("Sanitize() in mask mode = %s, want it to contain %s", sanitized, want)
+		}
+	}
+	if strings.Contains(string(sanitized), "app-config") || !strings.Contains(report.String(), "app-config removed") {
+		t.Errorf("Sanitize() did not remove app-config: %s (%s)", sanitized, report)
+	}
+
+	if _, _, err := sanitize.Sanitize([]byte(`[1, 2]`), sanitize.Options{}); err == nil {
+		t.Error("Sanitize() accepted a backup that is not a JSON object")
+	}
+}
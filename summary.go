@@ -0,0 +1,127 @@
+// @summary.go:
+// Package main (summary.go) sets up the structured log of a run from the global flags, and records
+// the inputs and outputs of every command into the JSON summary written when the run ends.
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/logging"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pipeline"
+)
+
+// StandardStream is the file name given to -log and -summary for the standard error and output.
+const StandardStream = "-"
+
+// runSummary records the inputs, outputs, warnings and errors of the run.
+var runSummary = logging.NewSummary("interactive", time.Now())
+
+// summaryPath is where the summary is written when the run ends, set by the -summary flag:
+// a file, StandardStream for the standard output, or empty for none.
+var summaryPath string
+
+// logFile is the file opened for the -log flag, closed when the run ends.
+var logFile *os.File
+
+// setupLogging makes the default slog logger write records of level or above in format to
+// logPath, which is a file, StandardStream for the standard error, or empty for no log. The
+// warnings and errors are recorded into runSummary in every case, including those of the output layer.
+func setupLogging(logPath, format, level string) error {
+	minLevel, err := logging.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = io.Discard
+	switch logPath {
+	case "":
+	case StandardStream:
+		w = os.Stderr
+	default:
+		if logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return fmt.Errorf("failed to open the log: %w", err)
+		}
+		w = logFile
+	}
+
+	logger, err := logging.NewLogger(w, format, minLevel)
+	if err != nil {
+		return err
+	}
+	if logPath == "" {
+		logger = logging.Discard()
+	}
+	logger = slog.New(logging.NewRecorder(logger.Handler(), runSummary))
+	slog.SetDefault(logger)
+	bannercli.Default().Logger = logger
+	return nil
+}
+
+// exit ends the run with code, after finishing its log and summary.
+func exit(code int) {
+	finishRun(code)
+	os.Exit(code)
+}
+
+// finishRun logs the end of the run with code and writes the summary, if requested.
+func finishRun(code int) {
+	runSummary.Finish(time.Now(), code)
+	slog.Info("run finished", "summary", runSummary)
+	if logFile != nil {
+		logFile.Close()
+	}
+
+	switch summaryPath {
+	case "":
+	case StandardStream:
+		if err := runSummary.WriteJSON(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "[GopherHelper] %s: %s\n", i18n.T("error.writeSummary"), err)
+		}
+	default:
+		f, err := os.Create(summaryPath)
+		if err == nil {
+			err = runSummary.WriteJSON(f)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[GopherHelper] %s: %s\n", i18n.T("error.writeSummary"), err)
+		}
+	}
+}
+
+// recordInput records that path was read and held sessions sessions.
+func recordInput(path string, sessions int) {
+	runSummary.AddInputs(sessions, path)
+	slog.Info("input read", "path", path, "sessions", sessions)
+}
+
+// recordExport records an export of inc with e at path, which wrote files.
+func recordExport(e exporter.Exporter, path string, inc exporter.Increment, options exporter.Options, files []string) {
+	rows := exporter.CountRows(e, inc, path, options)
+	runSummary.AddOutput(logging.OutputSummary{Path: path, Format: e.Name(), Rows: rows, Files: files}, len(inc.Sessions))
+	slog.Info("output written", "path", path, "format", e.Name(), "files", files, "rows", rows)
+}
+
+// recordFile records a file written by an operation other than an export, such as a repair.
+func recordFile(operation, path string, sessions int) {
+	runSummary.AddOutput(logging.OutputSummary{Path: path, Format: operation, Files: []string{path}}, sessions)
+	slog.Info("file written", "operation", operation, "path", path)
+}
+
+// recordPipelineResult records the inputs and outputs of a pipeline run, which logs them itself.
+func recordPipelineResult(result *pipeline.Result) {
+	runSummary.AddInputs(result.SessionsRead, result.Inputs...)
+	for _, output := range result.Outputs {
+		runSummary.AddOutput(logging.OutputSummary{Path: output.Path, Format: output.Format, Rows: output.Rows, Files: output.Files},
+			result.SessionsExported)
+	}
+}
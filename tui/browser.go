@@ -0,0 +1,518 @@
+// Below, the package tui (@browser.go) implements the session browser itself: the list of
+// sessions, the filter box, the preview of the highlighted session and the selection, along
+// with the rendering of all of them for a screen of a given size.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+)
+
+const (
+	// wideLayoutWidth is the screen width from which the preview is shown beside the list
+	// rather than below it.
+	wideLayoutWidth = 100
+
+	// dateLayout is the layout of the last update column.
+	dateLayout = "2006-01-02 15:04"
+
+	// Styles of the rendered text.
+	styleBold    = "\x1b[1m"
+	styleReverse = "\x1b[7m"
+	styleDim     = "\x1b[2m"
+	styleReset   = "\x1b[0m"
+)
+
+// browserState is whether the browser is still in use and how it was left.
+type browserState int
+
+const (
+	browsing browserState = iota
+	confirmed
+	quit
+)
+
+// Browser holds the state of the session browser. It is driven by HandleKey and drawn by
+// Render, independently of any terminal, so that it can be used and tested without one.
+type Browser struct {
+	sessions  []exporter.Session
+	matches   []int        // Indexes of the sessions matching the filter, in backup order.
+	selected  map[int]bool // Indexes of the selected sessions.
+	cursor    int          // Position in matches of the highlighted session.
+	top       int          // Position in matches of the first session shown in the list.
+	filter    []rune       // Text of the filter box.
+	filtering bool         // Whether the keys typed go to the filter box.
+	scroll    int          // First line of the preview shown.
+	page      int          // Number of sessions shown in the list at the last Render.
+	state     browserState
+}
+
+// NewBrowser returns a browser listing sessions, with none selected.
+func NewBrowser(sessions []exporter.Session) *Browser {
+	b := &Browser{sessions: sessions, selected: make(map[int]bool), page: 10}
+	b.applyFilter()
+	return b
+}
+
+// Confirmed reports whether the user confirmed the selection with Enter.
+func (b *Browser) Confirmed() bool { return b.state == confirmed }
+
+// Quit reports whether the user left the browser without exporting.
+func (b *Browser) Quit() bool { return b.state == quit }
+
+// Selection returns the selected sessions in backup order, including the selected sessions
+// hidden by the filter.
+func (b *Browser) Selection() []exporter.Session {
+	var selection []exporter.Session
+	for i, session := range b.sessions {
+		if b.selected[i] {
+			selection = append(selection, session)
+		}
+	}
+	return selection
+}
+
+// Filter returns the text of the filter box.
+func (b *Browser) Filter() string { return string(b.filter) }
+
+// HandleKey updates the browser for a key typed by the user.
+//
+// In the list: the arrow, page and home/end keys (or j, k, g and G) move the highlight, Space
+// selects the highlighted session, a selects or unselects every session shown, J and K scroll
+// the preview, / moves to the filter box, Enter exports the selection (or the highlighted session
+// if none is selected), and q or Esc quits. In the filter box, the typed text filters the list as
+// it is typed; Enter or Tab returns to the list, Esc clears the filter and Ctrl+U erases it.
+// Ctrl+C always quits.
+func (b *Browser) HandleKey(k Key) {
+	if k.Code == KeyCtrlC {
+		b.state = quit
+		return
+	}
+	if b.filtering {
+		b.handleFilterKey(k)
+		return
+	}
+
+	switch {
+	case k.Code == KeyUp || k == Rune('k'):
+		b.move(-1)
+	case k.Code == KeyDown || k == Rune('j'):
+		b.move(1)
+	case k.Code == KeyPageUp:
+		b.move(-b.page)
+	case k.Code == KeyPageDown:
+		b.move(b.page)
+	case k.Code == KeyHome || k == Rune('g'):
+		b.move(-len(b.matches))
+	case k.Code == KeyEnd || k == Rune('G'):
+		b.move(len(b.matches))
+	case k == Rune(' '):
+		if index, ok := b.current(); ok {
+			b.selected[index] = !b.selected[index]
+			b.move(1)
+		}
+	case k == Rune('a'):
+		b.toggleAll()
+	case k == Rune('J'):
+		b.scroll++
+	case k == Rune('K'):
+		if b.scroll > 0 {
+			b.scroll--
+		}
+	case k == Rune('/') || k.Code == KeyTab:
+		b.filtering = true
+	case k.Code == KeyEnter:
+		if len(b.Selection()) == 0 {
+			index, ok := b.current()
+			if !ok {
+				return
+			}
+			b.selected[index] = true
+		}
+		b.state = confirmed
+	case k.Code == KeyEscape || k == Rune('q'):
+		b.state = quit
+	}
+}
+
+// handleFilterKey edits the filter box.
+func (b *Browser) handleFilterKey(k Key) {
+	switch k.Code {
+	case KeyRune:
+		b.filter = append(b.filter, k.Rune)
+	case KeyBackspace:
+		if len(b.filter) > 0 {
+			b.filter = b.filter[:len(b.filter)-1]
+		}
+	case KeyCtrlU:
+		b.filter = nil
+	case KeyEscape:
+		b.filter = nil
+		b.filtering = false
+	case KeyEnter, KeyTab, KeyDown:
+		b.filtering = false
+		return
+	default:
+		return
+	}
+	b.applyFilter()
+}
+
+// applyFilter lists the sessions matching the filter: every word of the filter must appear,
+// ignoring case, in the topic, the mask name or a message of the session.
+func (b *Browser) applyFilter() {
+	terms := strings.Fields(strings.ToLower(string(b.filter)))
+	b.matches = b.matches[:0]
+	for i, session := range b.sessions {
+		if matches(session, terms) {
+			b.matches = append(b.matches, i)
+		}
+	}
+	b.cursor, b.top, b.scroll = 0, 0, 0
+}
+
+// matches reports whether every term appears in session.
+func matches(session exporter.Session, terms []string) bool {
+	for _, term := range terms {
+		if !contains(session, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains reports whether term appears in the topic, mask name or messages of session.
+func contains(session exporter.Session, term string) bool {
+	if strings.Contains(strings.ToLower(session.Topic), term) || strings.Contains(strings.ToLower(session.Mask.Name), term) {
+		return true
+	}
+	for _, message := range session.Messages {
+		if strings.Contains(strings.ToLower(message.Content), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// current returns the index of the highlighted session.
+func (b *Browser) current() (int, bool) {
+	if len(b.matches) == 0 {
+		return 0, false
+	}
+	return b.matches[b.cursor], true
+}
+
+// move moves the highlight by delta sessions, within the list.
+func (b *Browser) move(delta int) {
+	cursor := b.cursor + delta
+	if cursor >= len(b.matches) {
+		cursor = len(b.matches) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	if cursor != b.cursor {
+		b.cursor = cursor
+		b.scroll = 0
+	}
+}
+
+// toggleAll selects every session shown, or unselects them if they are all selected already.
+func (b *Browser) toggleAll() {
+	all := true
+	for _, index := range b.matches {
+		all = all && b.selected[index]
+	}
+	for _, index := range b.matches {
+		b.selected[index] = !all
+	}
+}
+
+// Render draws the browser for a screen of width columns and height rows and returns its lines,
+// each padded to the full width. The lines contain ANSI escape sequences for styling.
+func (b *Browser) Render(width, height int) []string {
+	if width < 20 || height < 8 {
+		return []string{pad("Terminal too small", width)}
+	}
+
+	lines := []string{
+		styleBold + pad(b.title(), width) + styleReset,
+		b.filterLine(width),
+		strings.Repeat("─", width),
+	}
+	body := height - len(lines) - 2
+	footer := []string{strings.Repeat("─", width), styleDim + pad(b.help(), width) + styleReset}
+
+	if width >= wideLayoutWidth {
+		listWidth := width * 3 / 5
+		previewWidth := width - listWidth - 1
+		list := b.renderList(listWidth, body)
+		preview := b.renderPreview(previewWidth, body)
+		for i := 0; i < body; i++ {
+			lines = append(lines, list[i]+"│"+preview[i])
+		}
+	} else {
+		listHeight := body / 2
+		lines = append(lines, b.renderList(width, listHeight)...)
+		lines = append(lines, strings.Repeat("─", width))
+		lines = append(lines, b.renderPreview(width, body-listHeight-1)...)
+	}
+	return append(lines, footer...)
+}
+
+// title describes the list and the selection.
+func (b *Browser) title() string {
+	return fmt.Sprintf(" Select sessions to export: %d selected, %d of %d shown", len(b.Selection()), len(b.matches), len(b.sessions))
+}
+
+// filterLine draws the filter box, with a cursor while it has the focus.
+func (b *Browser) filterLine(width int) string {
+	if b.filtering {
+		return pad(" Filter: "+string(b.filter)+"█", width)
+	}
+	if len(b.filter) == 0 {
+		return styleDim + pad(" Filter: press / to filter by topic, mask or message", width) + styleReset
+	}
+	return pad(" Filter: "+string(b.filter), width)
+}
+
+// help lists the keys available in the current mode.
+func (b *Browser) help() string {
+	if b.filtering {
+		return " type to filter  enter/tab back to list  esc clear  ctrl+u erase"
+	}
+	return " ↑↓ move  space select  a all  / filter  J/K scroll preview  enter export  q quit"
+}
+
+// columns holds the widths of the columns of the list.
+type columns struct {
+	topic, mask, messages, date int
+}
+
+// listColumns fits the columns of the list into width, dropping the mask and then the date
+// column on narrow screens.
+func listColumns(width int) columns {
+	c := columns{mask: 14, messages: 5, date: len(dateLayout)}
+	const mark = 4 // "> [x]" minus the shared space.
+	c.topic = width - mark - 1 - c.mask - 1 - c.messages - 1 - c.date - 1
+	if c.topic < 12 {
+		c.topic += c.mask + 1
+		c.mask = 0
+	}
+	if c.topic < 12 {
+		c.topic += c.date + 1
+		c.date = 0
+	}
+	return c
+}
+
+// renderList draws the header and the visible rows of the list.
+func (b *Browser) renderList(width, height int) []string {
+	c := listColumns(width)
+	lines := []string{styleBold + pad(c.row("", "Topic", "Mask", "Msgs", "Last update"), width) + styleReset}
+
+	rows := height - 1
+	if rows < 1 {
+		rows = 1
+	}
+	b.page = rows
+	// Keep the highlighted session in view.
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if b.cursor >= b.top+rows {
+		b.top = b.cursor - rows + 1
+	}
+
+	if len(b.matches) == 0 {
+		lines = append(lines, pad("   No session matches the filter.", width))
+	}
+	for pos := b.top; pos < len(b.matches) && pos < b.top+rows; pos++ {
+		index := b.matches[pos]
+		session := b.sessions[index]
+		mark := "[ ]"
+		if b.selected[index] {
+			mark = "[x]"
+		}
+		row := pad(c.row(mark, session.Topic, session.Mask.Name, strconv.Itoa(len(session.Messages)), formatDate(session.LastUpdate)), width)
+		if pos == b.cursor {
+			row = styleReverse + row + styleReset
+		}
+		lines = append(lines, row)
+	}
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines[:height]
+}
+
+// row formats a row of the list.
+func (c columns) row(mark, topic, mask, messages, date string) string {
+	row := " " + pad(mark, 3) + " " + pad(topic, c.topic)
+	if c.mask > 0 {
+		row += " " + pad(mask, c.mask)
+	}
+	row += " " + padLeft(messages, c.messages)
+	if c.date > 0 {
+		row += " " + pad(date, c.date)
+	}
+	return row
+}
+
+// renderPreview draws the messages of the highlighted session, wrapped to width and scrolled.
+func (b *Browser) renderPreview(width, height int) []string {
+	var content []string
+	if index, ok := b.current(); ok {
+		session := b.sessions[index]
+		heading := session.Topic
+		if session.Mask.Name != "" {
+			heading += " (" + session.Mask.Name + ")"
+		}
+		content = append(content, styleBold+pad(" "+heading, width)+styleReset)
+		content = append(content, styleDim+pad(fmt.Sprintf(" %d messages", len(session.Messages)), width)+styleReset)
+		for _, message := range session.Messages {
+			content = append(content, pad("", width))
+			content = append(content, styleBold+pad(" "+message.Role+"  "+message.Date, width)+styleReset)
+			for _, line := range wrap(message.Content, width-3) {
+				content = append(content, pad("   "+line, width))
+			}
+		}
+	}
+
+	if maxScroll := len(content) - height; b.scroll > maxScroll {
+		b.scroll = maxScroll
+	}
+	if b.scroll < 0 {
+		b.scroll = 0
+	}
+	lines := content[b.scroll:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines
+}
+
+// formatDate formats a LastUpdate timestamp in milliseconds, or returns "" if it is not set.
+func formatDate(lastUpdate int64) string {
+	if lastUpdate <= 0 {
+		return ""
+	}
+	return time.UnixMilli(lastUpdate).Format(dateLayout)
+}
+
+// wrap splits text into lines of at most width columns, breaking at spaces where possible.
+func wrap(text string, width int) []string {
+	if width < 2 {
+		width = 2
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(sanitize(text), "\n") {
+		var line strings.Builder
+		lineWidth := 0
+		for _, word := range strings.SplitAfter(paragraph, " ") {
+			if lineWidth > 0 && lineWidth+stringWidth(strings.TrimRight(word, " ")) > width {
+				lines = append(lines, strings.TrimRight(line.String(), " "))
+				line.Reset()
+				lineWidth = 0
+			}
+			// Break words that are longer than a line.
+			for stringWidth(strings.TrimRight(word, " ")) > width {
+				head := truncateWidth(word, width)
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			line.WriteString(word)
+			lineWidth += stringWidth(word)
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return lines
+}
+
+// sanitize replaces tabs with spaces and removes the control characters of text, which would
+// otherwise move the cursor of the terminal.
+func sanitize(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r == '\n':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// pad truncates or pads s with spaces to exactly width columns.
+func pad(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = strings.ReplaceAll(sanitize(s), "\n", " ")
+	if w := stringWidth(s); w > width {
+		s = truncateWidth(s, width-1) + "…"
+	}
+	return s + strings.Repeat(" ", width-stringWidth(s))
+}
+
+// padLeft is pad aligning s to the right.
+func padLeft(s string, width int) string {
+	if w := stringWidth(s); w < width {
+		return strings.Repeat(" ", width-w) + s
+	}
+	return pad(s, width)
+}
+
+// truncateWidth returns the longest prefix of s that fits in width columns.
+func truncateWidth(s string, width int) string {
+	w := 0
+	for i, r := range s {
+		if w+runeWidth(r) > width {
+			return s[:i]
+		}
+		w += runeWidth(r)
+	}
+	return s
+}
+
+// stringWidth returns the number of columns s takes in a terminal.
+func stringWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// runeWidth returns the number of columns r takes in a terminal: none for combining marks,
+// two for East Asian wide characters and emoji, one otherwise.
+func runeWidth(r rune) int {
+	switch {
+	case unicode.Is(unicode.Mn, r), r == 0x200B:
+		return 0
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0xA4CF && r != 0x303F,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,138 @@
+// Below, the package tui (@browser_test.go) tests the keys, filtering, selection and rendering of
+// Browser.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package tui_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/tui"
+)
+
+// TestBrowser verifies the key decoding, filtering, selection and rendering of the session
+// browser.
+func TestBrowser(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("\x1b[A\x1b[B\x1b[6~\x1bOHé \r\x7f\x03\x1b"))
+	want := []tui.Key{
+		{Code: tui.KeyUp}, {Code: tui.KeyDown}, {Code: tui.KeyPageDown}, {Code: tui.KeyHome},
+		tui.Rune('é'), tui.Rune(' '), {Code: tui.KeyEnter}, {Code: tui.KeyBackspace}, {Code: tui.KeyCtrlC}, {Code: tui.KeyEscape},
+	}
+	for i, w := range want {
+		if key, err := tui.ReadKey(reader); err != nil || key != w {
+			t.Errorf("ReadKey() #%d = %+v, %v, want %+v", i, key, err, w)
+		}
+	}
+
+	// A key still awaited when the wait times out is returned by the next read.
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeWriter.Close()
+	input := interactivity.InputOf(bufio.NewReader(pipeReader))
+	timeout, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := tui.ReadKey(input.Reader(timeout)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ReadKey() without input = %v, want context.DeadlineExceeded", err)
+	}
+	go pipeWriter.Write([]byte("\x1b[B"))
+	if key, err := tui.ReadKey(input.Reader(context.Background())); err != nil || key.Code != tui.KeyDown {
+		t.Errorf("ReadKey() after a timeout = %+v, %v, want KeyDown", key, err)
+	}
+
+	sessions := []exporter.Session{
+		{ID: "1", Topic: "Go generics", Mask: exporter.Mask{Name: "Gopher"}, LastUpdate: 1701158400000,
+			Messages: []exporter.Message{{Role: "user", Date: "2023-11-28", Content: "How do type parameters work?"}}},
+		{ID: "2", Topic: "Travel plans", Messages: []exporter.Message{{Role: "user", Content: "Trip to Kyoto"}}},
+		{ID: "3", Topic: "日本語の会話", Mask: exporter.Mask{Name: "Translator"},
+			Messages: []exporter.Message{{Role: "assistant", Content: strings.Repeat("こんにちは ", 40)}}},
+	}
+	typeText := func(b *tui.Browser, text string) {
+		for _, r := range text {
+			b.HandleKey(tui.Rune(r))
+		}
+	}
+
+	b := tui.NewBrowser(sessions)
+	typeText(b, "/kyoto")
+	if b.Filter() != "kyoto" {
+		t.Fatalf("Filter() = %q, want kyoto", b.Filter())
+	}
+	b.HandleKey(tui.Key{Code: tui.KeyEnter})
+	typeText(b, " ")
+	b.HandleKey(tui.Key{Code: tui.KeyTab})
+	b.HandleKey(tui.Key{Code: tui.KeyEscape}) // Clears the filter.
+	b.HandleKey(tui.Key{Code: tui.KeyEnd})
+	typeText(b, " ")
+	b.HandleKey(tui.Key{Code: tui.KeyEnter})
+	if !b.Confirmed() {
+		t.Fatal("The browser is not confirmed after Enter")
+	}
+	if got := b.Selection(); len(got) != 2 || got[0].ID != "2" || got[1].ID != "3" {
+		t.Errorf("Selection() = %v, want sessions 2 and 3 in backup order", got)
+	}
+
+	// Enter without a selection exports the highlighted session; a selects every session shown.
+	b = tui.NewBrowser(sessions)
+	b.HandleKey(tui.Key{Code: tui.KeyDown})
+	b.HandleKey(tui.Key{Code: tui.KeyEnter})
+	if got := b.Selection(); len(got) != 1 || got[0].ID != "2" {
+		t.Errorf("Selection() after Enter = %v, want session 2", got)
+	}
+	b = tui.NewBrowser(sessions)
+	typeText(b, "a")
+	if len(b.Selection()) != 3 {
+		t.Errorf("a selected %d sessions, want 3", len(b.Selection()))
+	}
+	typeText(b, "a")
+	if len(b.Selection()) != 0 {
+		t.Errorf("a again left %d sessions selected, want 0", len(b.Selection()))
+	}
+	typeText(b, "q")
+	if !b.Quit() {
+		t.Error("The browser is not quit after q")
+	}
+
+	// Both layouts fill the screen exactly, including wide characters.
+	ansi := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	b = tui.NewBrowser(sessions)
+	b.HandleKey(tui.Key{Code: tui.KeyEnd})
+	for _, size := range [][2]int{{120, 20}, {60, 30}} {
+		lines := b.Render(size[0], size[1])
+		if len(lines) != size[1] {
+			t.Errorf("Render(%d, %d) returned %d lines", size[0], size[1], len(lines))
+		}
+		screen := ansi.ReplaceAllString(strings.Join(lines, "\n"), "")
+		for i, line := range strings.Split(screen, "\n") {
+			if width := displayWidth(line); width != size[0] {
+				t.Errorf("Render(%d, %d) line %d is %d columns wide: %q", size[0], size[1], i, width, line)
+			}
+		}
+		for _, text := range []string{"3 of 3 shown", "Go generics", "日本語の会話 (Translator)", "assistant", "こんにちは"} {
+			if !strings.Contains(screen, text) {
+				t.Errorf("Render(%d, %d) does not show %q:\n%s", size[0], size[1], text, screen)
+			}
+		}
+	}
+}
+
+// displayWidth returns the number of terminal columns of s, which holds no other wide characters
+// than the Japanese text of TestBrowser.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if r >= 0x3000 && r <= 0x9FFF {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
@@ -0,0 +1,141 @@
+// Below, the package tui (@keys.go) decodes the keys typed in a terminal in raw mode, including
+// the escape sequences sent for the arrow, page and home/end keys.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package tui
+
+import (
+	"io"
+	"unicode"
+)
+
+// KeyCode identifies a key.
+type KeyCode int
+
+const (
+	// KeyUnknown is an escape sequence or control character without a meaning in the browser.
+	KeyUnknown KeyCode = iota
+	// KeyRune is a printable character, see Key.Rune.
+	KeyRune
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	KeyCtrlC
+	KeyCtrlU
+)
+
+// Key is a key typed by the user.
+type Key struct {
+	Code KeyCode // What was typed.
+	Rune rune    // The character, for KeyRune.
+}
+
+// KeyReader is what keys are read from: a *bufio.Reader, or the reader of an interactivity.Input.
+// Buffered tells whether the rest of an escape sequence has already arrived.
+type KeyReader interface {
+	io.RuneScanner
+	Buffered() int
+}
+
+// Rune returns the key of the printable character r.
+func Rune(r rune) Key {
+	return Key{Code: KeyRune, Rune: r}
+}
+
+// ReadKey reads the next key from r, which should read from a terminal in raw mode.
+// A lone escape character is told apart from an escape sequence by the rest of the sequence
+// not being available yet, since terminals send the bytes of a sequence at once.
+func ReadKey(r KeyReader) (Key, error) {
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch c {
+	case 0x03:
+		return Key{Code: KeyCtrlC}, nil
+	case 0x15:
+		return Key{Code: KeyCtrlU}, nil
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, nil
+	case '\t':
+		return Key{Code: KeyTab}, nil
+	case 0x7f, 0x08:
+		return Key{Code: KeyBackspace}, nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return Key{Code: KeyEscape}, nil
+		}
+		return readEscapeSequence(r)
+	}
+	if !unicode.IsPrint(c) {
+		return Key{Code: KeyUnknown}, nil
+	}
+	return Rune(c), nil
+}
+
+// readEscapeSequence decodes the CSI ("ESC [") and SS3 ("ESC O") sequences of the navigation
+// keys, after the escape character has been read. Other sequences are consumed and ignored.
+func readEscapeSequence(r KeyReader) (Key, error) {
+	introducer, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	if introducer != '[' && introducer != 'O' {
+		// Alt+key: ignore the modifier.
+		if err := r.UnreadRune(); err != nil {
+			return Key{}, err
+		}
+		return ReadKey(r)
+	}
+
+	var params []rune
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return Key{}, err
+		}
+		if c >= 0x40 && c <= 0x7e {
+			return sequenceKey(string(params), byte(c)), nil
+		}
+		params = append(params, c)
+	}
+}
+
+// sequenceKey returns the key of an escape sequence with the given parameters and final byte.
+func sequenceKey(params string, final byte) Key {
+	switch final {
+	case 'A':
+		return Key{Code: KeyUp}
+	case 'B':
+		return Key{Code: KeyDown}
+	case 'C':
+		return Key{Code: KeyRight}
+	case 'D':
+		return Key{Code: KeyLeft}
+	case 'H':
+		return Key{Code: KeyHome}
+	case 'F':
+		return Key{Code: KeyEnd}
+	case '~':
+		switch params {
+		case "1", "7":
+			return Key{Code: KeyHome}
+		case "4", "8":
+			return Key{Code: KeyEnd}
+		case "5":
+			return Key{Code: KeyPageUp}
+		case "6":
+			return Key{Code: KeyPageDown}
+		}
+	}
+	return Key{Code: KeyUnknown}
+}
@@ -0,0 +1,138 @@
+// Package tui provides a full-screen terminal user interface for browsing the sessions of a
+// backup before exporting them.
+//
+// The browser lists the sessions with their topic, mask, message count and last update, previews
+// the messages of the highlighted session, and filters the list live as a filter is typed. Sessions
+// are picked with the space bar, and the selection is returned once Enter is pressed, ready to be
+// handed to any exporter.Exporter.
+//
+// The Browser itself does not depend on a terminal: Run puts the terminal in raw mode on the
+// alternate screen, feeds the keys read from it to the Browser and draws what it renders.
+//
+// # Example Usage
+//
+//	selection, err := tui.Run(ctx, os.Stdin, os.Stdout, nil, store.ChatNextWebStore.Sessions)
+//	if errors.Is(err, tui.ErrQuit) {
+//		return // The user left without exporting.
+//	}
+//	if err != nil {
+//		return err
+//	}
+//	files, err := e.Export(ctx, rfs, selection, "selection.csv", nil)
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package tui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/interactivity"
+)
+
+const (
+	// resizePollInterval is how often the size of the terminal is checked, to redraw the browser
+	// when the terminal is resized. Polling works on every platform, unlike SIGWINCH.
+	resizePollInterval = 200 * time.Millisecond
+
+	// Terminal control sequences.
+	enterAltScreen = "\x1b[?1049h"
+	exitAltScreen  = "\x1b[?1049l"
+	hideCursor     = "\x1b[?25l"
+	showCursor     = "\x1b[?25h"
+	cursorHome     = "\x1b[H"
+	clearToEnd     = "\x1b[J"
+)
+
+var (
+	// ErrNotTerminal is returned by Run when the input or the output is not a terminal.
+	ErrNotTerminal = errors.New("the session browser requires an interactive terminal")
+
+	// ErrQuit is returned by Run when the user leaves the browser without exporting.
+	ErrQuit = errors.New("the session browser was closed without a selection")
+)
+
+// IsTerminal reports whether both in and out are terminals, so that Run can be used.
+func IsTerminal(in, out *os.File) bool {
+	return term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd()))
+}
+
+// Run shows a Browser of sessions full-screen on the terminal of in and out until the user
+// confirms a selection, which is returned, or quits, in which case ErrQuit is returned.
+//
+// Keys are read through the interactivity.Input of reader, or of a new reader of in if reader is
+// nil, so that a key read still pending when Run returns goes to the prompts that use reader
+// afterwards instead of racing with them. The terminal is restored before Run returns, including
+// when ctx is cancelled.
+func Run(ctx context.Context, in, out *os.File, reader *bufio.Reader, sessions []exporter.Session) ([]exporter.Session, error) {
+	if !IsTerminal(in, out) {
+		return nil, ErrNotTerminal
+	}
+	if reader == nil {
+		reader = bufio.NewReader(in)
+	}
+
+	fd := int(in.Fd())
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(fd, oldState)
+	fmt.Fprint(out, enterAltScreen+hideCursor)
+	defer fmt.Fprint(out, showCursor+exitAltScreen)
+
+	input := interactivity.InputOf(reader)
+	b := NewBrowser(sessions)
+	width, height := terminalSize(out)
+	draw(out, b, width, height)
+	for {
+		// Waiting for a key gives up after resizePollInterval to check the size of the terminal;
+		// the key is then returned by the next read.
+		keyCtx, cancel := context.WithTimeout(ctx, resizePollInterval)
+		key, err := ReadKey(input.Reader(keyCtx))
+		cancel()
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case errors.Is(err, context.DeadlineExceeded):
+			if w, h := terminalSize(out); w != width || h != height {
+				width, height = w, h
+				draw(out, b, width, height)
+			}
+			continue
+		case err != nil:
+			return nil, err
+		}
+		b.HandleKey(key)
+		switch {
+		case b.Confirmed():
+			return b.Selection(), nil
+		case b.Quit():
+			return nil, ErrQuit
+		}
+		draw(out, b, width, height)
+	}
+}
+
+// terminalSize returns the size of the terminal of out, or 80x24 if it cannot be determined.
+func terminalSize(out *os.File) (int, int) {
+	width, height, err := term.GetSize(int(out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// draw renders b over the whole screen in a single write, to avoid flickering.
+func draw(w io.Writer, b *Browser, width, height int) {
+	io.WriteString(w, cursorHome+strings.Join(b.Render(width, height), "\r\n")+clearToEnd)
+}
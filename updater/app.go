@@ -68,6 +68,7 @@ import (
 const (
 	currentVersion = "1.3.3.7"
 	githubRepo     = "H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter"
+	binaryName     = "ChatGPT-Next-Web-Session-Exporter" // Name of the binary replaced by an update.
 )
 
 // releaseInfo defines the structure for storing information about a GitHub release.
@@ -160,6 +161,13 @@ func getLatestRelease() (*releaseInfo, error) {
 // Returns nil if the application is up to date or the update is successfully applied.
 // If an error occurs during the update process, it returns a non-nil error.
 func UpdateApplication(rfs filesystem.FileSystem) error {
+	return UpdateApplicationWithPolicy(rfs, interactivity.PolicyAsk)
+}
+
+// UpdateApplicationWithPolicy is UpdateApplication with policy applied to the existing binary:
+// it can be overwritten, backed up with a timestamp before being replaced, or kept while the new
+// version is saved next to it, in which case the application is not restarted.
+func UpdateApplicationWithPolicy(rfs filesystem.FileSystem, policy interactivity.OverwritePolicy) error {
 	ctx := context.Background()
 	reader := bufio.NewReader(os.Stdin)
 	release, err := getLatestRelease()
@@ -182,12 +190,15 @@ func UpdateApplication(rfs filesystem.FileSystem) error {
 		return err
 	}
 
-	// Pass the context, reader, and filesystem to applyUpdate
-	if err := applyUpdate(ctx, reader, rfs, tempFileName); err != nil {
+	// Pass the context, reader, filesystem and policy to applyUpdate
+	replaced, err := applyUpdate(ctx, reader, rfs, tempFileName, policy)
+	if err != nil {
 		return err
 	}
 
-	restartApplication()
+	if replaced {
+		restartApplication()
+	}
 	return nil
 }
 
@@ -244,24 +255,32 @@ func downloadAsset(assetURL string) (string, error) {
 	return out.Name(), nil
 }
 
-// applyUpdate applies the update by replacing the current binary with the new one.
-// It takes the name of the temporary file containing the new binary as an argument.
-func applyUpdate(ctx context.Context, reader *bufio.Reader, rfs filesystem.FileSystem, tempFileName string) error {
-	// Confirm whether to overwrite the existing binary
-	shouldOverwrite, err := interactivity.ConfirmOverwrite(rfs, ctx, reader, "ChatGPT-Next-Web-Session-Exporter")
+// applyUpdate applies the update by replacing the current binary with the new one, according to policy.
+// It takes the name of the temporary file containing the new binary as an argument, and reports
+// whether the current binary was replaced.
+func applyUpdate(ctx context.Context, reader *bufio.Reader, rfs filesystem.FileSystem, tempFileName string, policy interactivity.OverwritePolicy) (bool, error) {
+	// Apply the overwrite policy to the existing binary, which cannot be appended to
+	resolution, err := interactivity.ResolveOverwrite(rfs, ctx, reader, binaryName, policy, false)
 	if err != nil {
-		return fmt.Errorf("error during overwrite confirmation: %w", err)
+		return false, fmt.Errorf("error during overwrite confirmation: %w", err)
 	}
-	if !shouldOverwrite {
+	if resolution.Skipped() {
 		fmt.Println("Update cancelled by the user.")
-		return nil
+		return false, nil
+	}
+	if resolution.Backup != "" {
+		fmt.Printf("Previous version backed up to %s\n", resolution.Backup)
 	}
 
-	// Replace the current binary with the new one
-	if err := os.Rename(tempFileName, "ChatGPT-Next-Web-Session-Exporter"); err != nil {
-		return fmt.Errorf("error replacing binary: %w", err)
+	// Replace the current binary with the new one, or save it next to the current one
+	if err := rfs.Rename(tempFileName, resolution.Path); err != nil {
+		return false, fmt.Errorf("error replacing binary: %w", err)
 	}
-	return nil
+	if resolution.Path != binaryName {
+		fmt.Printf("New version saved to %s\n", resolution.Path)
+		return false, nil
+	}
+	return true, nil
 }
 
 // restartApplication restarts the application.
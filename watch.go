@@ -0,0 +1,224 @@
+// @watch.go:
+// Package main (watch.go) implements the watch command, which monitors a directory for
+// NextChat backups and exports each new or changed backup into an output tree.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/bannercli"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/compression"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/encryption"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/exporter"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/i18n"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/pipeline"
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/watcher"
+)
+
+// watchOptions holds the configuration of a single watch run.
+type watchOptions struct {
+	outDir   string   // Root of the output tree; each backup gets its own sub-directory.
+	formats  []string // Names of registered exporters.
+	repair   bool     // Whether backups are repaired (or recovered) before exporting.
+	compress string   // Compression of every export: "gzip", "zstd", "zip" or empty for none.
+	encrypt  bool     // Whether every export is encrypted with the passphrase from keys.
+
+	keys *encryption.KeySource // Passphrase of encrypted backups and exports.
+}
+
+// runWatchCommand parses the flags of the watch command and watches the directory until
+// the context is cancelled through setupSignalHandling.
+func runWatchCommand(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	dir := flags.String("dir", "", "directory to watch for backups (required)")
+	outDir := flags.String("out", "exports", "directory that receives the exported files")
+	pattern := flags.String("pattern", watcher.DefaultPattern, "glob pattern of backup file names")
+	interval := flags.Duration("interval", watcher.DefaultInterval, "how often the directory is scanned")
+	debounce := flags.Duration("debounce", watcher.DefaultDebounce, "how long a file must stay unchanged before it is exported")
+	formats := flags.String("formats", exporter.FormatNameSeparate+","+exporter.FormatNameDataset,
+		"comma-separated exports: "+strings.Join(exporter.Names(), ", "))
+	repair := flags.Bool("repair", false, "repair (or recover) each backup before exporting it")
+	existing := flags.Bool("existing", false, "also export backups that are already present at startup")
+	compress := flags.String("compress", "", "compress every export with gzip or zstd, or bundle each one into a zip")
+	encrypt := flags.Bool("encrypt", false, "encrypt every export with the passphrase")
+	keyFile := flags.String("key-file", "", "file whose first line is the passphrase (default: $"+encryption.PassphraseEnv+")")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if *dir == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("error.flagRequired", "watch", "-dir"))
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if _, err := compression.ParseFormat(*compress); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("error.unknownCompression", "watch", *compress, "gzip, zstd, zip"))
+		return ExitUsage
+	}
+
+	opts := watchOptions{
+		outDir:   *outDir,
+		repair:   *repair,
+		compress: *compress,
+		encrypt:  *encrypt,
+		keys:     &encryption.KeySource{KeyFile: *keyFile},
+	}
+	if opts.encrypt {
+		// Fail at startup rather than on the first backup when no passphrase is available.
+		if _, err := opts.keys.Passphrase(&filesystem.RealFileSystem{}); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("error.passphrase", "watch", err))
+			return ExitUsage
+		}
+	}
+	for _, name := range strings.Split(*formats, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := exporter.Lookup(name); !ok {
+			fmt.Fprintln(os.Stderr, i18n.T("error.unknownFormat", "watch", name, strings.Join(exporter.Names(), ", ")))
+			return ExitUsage
+		}
+		opts.formats = append(opts.formats, name)
+	}
+	if len(opts.formats) == 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("error.formatRequired", "watch"))
+		return ExitUsage
+	}
+
+	rfs := &filesystem.RealFileSystem{}
+	w := &watcher.Watcher{
+		Dir:             *dir,
+		Pattern:         *pattern,
+		Interval:        *interval,
+		Debounce:        *debounce,
+		ProcessExisting: *existing,
+		FS:              rfs,
+		OnError: func(err error) {
+			printError(i18n.T("error.watch"), err)
+		},
+	}
+
+	bannercli.Print("[GopherHelper] " + i18n.T("info.watching", *dir, *pattern))
+	err := w.Run(ctx, func(ctx context.Context, event watcher.Event) error {
+		slog.Info("backup changed", "path", event.Path)
+		written, err := exportBackup(rfs, ctx, event.Path, opts)
+		if err != nil {
+			return err
+		}
+		bannercli.Print("[GopherHelper] " + i18n.T("info.watchExported", event.Path, len(written)))
+		for _, name := range written {
+			bannercli.Print(fmt.Sprintf("  %s", name))
+		}
+		return nil
+	})
+	if err != nil {
+		return printError(i18n.T("error.generic"), err)
+	}
+
+	bannercli.Print("[GopherHelper] " + i18n.T("info.watchStopped"))
+	return ExitOK
+}
+
+// exportBackup validates the backup at backupPath, optionally repairs it, and runs every
+// configured export into a sub-directory of the output tree named after the backup.
+// It returns the paths of the files that were written.
+func exportBackup(rfs filesystem.FileSystem, ctx context.Context, backupPath string, opts watchOptions) ([]string, error) {
+	data, err := encryption.ReadFile(rfs, backupPath, opts.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFile := compression.TrimExtension(encryption.TrimExtension(filepath.Base(backupPath)))
+	baseName := strings.TrimSuffix(baseFile, filepath.Ext(baseFile))
+	targetDir := filepath.Join(opts.outDir, baseName)
+	if err := rfs.MkdirAll(targetDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var written []string
+	if opts.repair {
+		repaired, report, err := pipeline.Repair(data, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to repair backup: %w", err)
+		}
+		if report != nil {
+			slog.Warn("input recovered", "path", backupPath, "report", report.String())
+			bannercli.Print("[GopherHelper] " + i18n.T("info.recovered", backupPath, recoveryMessage(report)))
+		}
+		repairedPath := filepath.Join(targetDir, "repaired.json")
+		content := repaired
+		if opts.encrypt {
+			// The repaired backup is as sensitive as the exports, so it is encrypted alike.
+			passphrase, err := opts.keys.Passphrase(rfs)
+			if err != nil {
+				return nil, err
+			}
+			if content, err = encryption.Encrypt(repaired, passphrase); err != nil {
+				return nil, err
+			}
+			repairedPath += encryption.Extension
+		}
+		if err := rfs.WriteFile(repairedPath, content, 0644); err != nil {
+			return nil, err
+		}
+		written = append(written, repairedPath)
+		recordFile("repair", repairedPath, 0)
+		data = repaired
+	}
+
+	// Validate the backup before exporting anything from it.
+	store, err := exporter.ReadJSON(bytes.NewReader(data))
+	if err != nil {
+		return written, fmt.Errorf("invalid backup: %w", err)
+	}
+	recordInput(backupPath, len(store.ChatNextWebStore.Sessions))
+
+	for _, name := range opts.formats {
+		e, ok := exporter.Lookup(name)
+		if !ok {
+			return written, &exporter.FormatError{Kind: "export format", Name: name}
+		}
+		output := watchOutput(e, targetDir)
+		output.Compression = opts.compress
+		output.Encrypt = opts.encrypt
+
+		files, err := pipeline.Export(ctx, rfs, store.ChatNextWebStore.Sessions, output, opts.keys)
+		written = append(written, files...)
+		if err != nil {
+			return written, err
+		}
+		recordExport(e, output.Path, exporter.FullIncrement(store.ChatNextWebStore.Sessions), output.Options, files)
+	}
+
+	return written, nil
+}
+
+// watchOutput names the files of an export after the format, or after their contents for
+// multi-file exports (for example sessions.csv and messages.csv), inside targetDir.
+func watchOutput(e exporter.Exporter, targetDir string) pipeline.OutputConfig {
+	primary := e.Name()
+	if namer, ok := e.(exporter.PrimaryOutputNamer); ok {
+		primary = namer.PrimaryOutputName()
+	}
+
+	output := pipeline.OutputConfig{Format: e.Name(), Path: filepath.Join(targetDir, primary+e.Extension())}
+	for _, option := range e.Options() {
+		if option.Path {
+			if output.Options == nil {
+				output.Options = make(exporter.Options)
+			}
+			output.Options[option.Name] = filepath.Join(targetDir, option.Name+e.Extension())
+		}
+	}
+	return output
+}
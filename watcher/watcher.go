@@ -0,0 +1,211 @@
+// Package watcher provides a polling directory watcher that reports new or changed files
+// once they have stopped changing.
+//
+// It is used by the watch command to pick up NextChat backups dropped into a shared folder.
+// Polling is used instead of platform-specific notification APIs so that it behaves the same
+// on every operating system and on network shares.
+//
+// A file is only reported after its size and modification time have remained unchanged for
+// the debounce period, which prevents partially written files from being processed.
+//
+// # Example Usage
+//
+//	w := &watcher.Watcher{Dir: "backups", Pattern: "*.json"}
+//	err := w.Run(ctx, func(ctx context.Context, event watcher.Event) error {
+//		fmt.Println("Backup ready:", event.Path)
+//		return nil
+//	})
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/filesystem"
+)
+
+const (
+	// DefaultPattern is the glob pattern used when Watcher.Pattern is empty.
+	DefaultPattern = "*.json"
+
+	// DefaultInterval is the polling interval used when Watcher.Interval is zero.
+	DefaultInterval = 2 * time.Second
+
+	// DefaultDebounce is the quiet period used when Watcher.Debounce is zero.
+	DefaultDebounce = 3 * time.Second
+)
+
+// Event describes a file that is ready to be processed.
+type Event struct {
+	Path    string    // Path of the file, joined with the watched directory.
+	Size    int64     // Size of the file when it was reported.
+	ModTime time.Time // Modification time of the file when it was reported.
+}
+
+// Watcher polls a directory for files matching a pattern.
+type Watcher struct {
+	Dir             string        // Directory to watch.
+	Pattern         string        // Glob pattern matched against file names, e.g. "*.json".
+	Interval        time.Duration // How often the directory is scanned.
+	Debounce        time.Duration // How long a file must remain unchanged before it is reported.
+	ProcessExisting bool          // Whether files present at startup are reported too.
+
+	// FS is the file system the directory is read from; nil means the real file system.
+	FS filesystem.FileSystem
+
+	// OnError, if set, is called with errors from scanning the directory or from the handler.
+	// Errors never stop the watcher; only context cancellation does.
+	OnError func(error)
+}
+
+// fileState tracks what the watcher knows about a single file between scans.
+type fileState struct {
+	size        int64
+	modTime     time.Time
+	stableSince time.Time
+	handled     bool // Whether the current size and modTime have been reported.
+}
+
+// Run scans the directory until ctx is cancelled, calling handle for every new or changed file
+// once it has been stable for the debounce period. Files are handled one at a time.
+//
+// Run returns nil when the context is cancelled, and an error only if the watched directory
+// cannot be read on the first scan.
+func (w *Watcher) Run(ctx context.Context, handle func(context.Context, Event) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	files := make(map[string]*fileState)
+
+	// The first scan establishes a baseline and validates the directory.
+	now := time.Now()
+	entries, err := w.scan()
+	if err != nil {
+		return err
+	}
+	for path, info := range entries {
+		files[path] = &fileState{
+			size:        info.Size(),
+			modTime:     info.ModTime(),
+			stableSince: now,
+			handled:     !w.ProcessExisting,
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.poll(ctx, files, handle)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		entries, err := w.scan()
+		if err != nil {
+			w.reportError(err)
+			continue
+		}
+		w.update(files, entries, time.Now())
+	}
+}
+
+// update merges the result of a scan into the known file states.
+func (w *Watcher) update(files map[string]*fileState, entries map[string]os.FileInfo, now time.Time) {
+	for path := range files {
+		if _, ok := entries[path]; !ok {
+			delete(files, path) // The file was removed or renamed.
+		}
+	}
+	for path, info := range entries {
+		state, ok := files[path]
+		if !ok {
+			files[path] = &fileState{size: info.Size(), modTime: info.ModTime(), stableSince: now}
+			continue
+		}
+		if state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			state.size = info.Size()
+			state.modTime = info.ModTime()
+			state.stableSince = now
+			state.handled = false
+		}
+	}
+}
+
+// poll hands every file that has been stable for the debounce period to the handler.
+func (w *Watcher) poll(ctx context.Context, files map[string]*fileState, handle func(context.Context, Event) error) {
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	now := time.Now()
+	for path, state := range files {
+		if state.handled || now.Sub(state.stableSince) < debounce {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		state.handled = true
+		event := Event{Path: path, Size: state.size, ModTime: state.modTime}
+		if err := handle(ctx, event); err != nil {
+			w.reportError(fmt.Errorf("%s: %w", path, err))
+		}
+	}
+}
+
+// scan lists the regular files in the watched directory that match the pattern.
+func (w *Watcher) scan() (map[string]os.FileInfo, error) {
+	pattern := w.Pattern
+	if pattern == "" {
+		pattern = DefaultPattern
+	}
+
+	rfs := w.FS
+	if rfs == nil {
+		rfs = filesystem.RealFileSystem{}
+	}
+
+	entries, err := rfs.ReadDir(w.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", w.Dir, err)
+	}
+
+	matches := make(map[string]os.FileInfo)
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		ok, err := filepath.Match(pattern, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // The file disappeared between listing and stat.
+		}
+		matches[filepath.Join(w.Dir, entry.Name())] = info
+	}
+	return matches, nil
+}
+
+// reportError passes err to OnError if it is set.
+func (w *Watcher) reportError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+	}
+}
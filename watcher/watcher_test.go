@@ -0,0 +1,74 @@
+// Below, the package watcher (@watcher_test.go) tests that new files are reported once they have
+// stopped changing, and that the watcher stops cleanly.
+//
+// Copyright (c) 2023 H0llyW00dzZ
+package watcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/H0llyW00dzZ/ChatGPT-Next-Web-Session-Exporter/watcher"
+)
+
+// TestWatcher verifies that a file dropped into the directory is reported once, that files present
+// at startup and files not matching the pattern are not, and that Run returns nil when cancelled.
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	events := make(chan watcher.Event, 4)
+	w := &watcher.Watcher{Dir: dir, Interval: 10 * time.Millisecond, Debounce: 30 * time.Millisecond}
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(ctx context.Context, event watcher.Event) error {
+			events <- event
+			return nil
+		})
+	}()
+
+	// Drop the files after the watcher has taken its baseline scan.
+	time.Sleep(20 * time.Millisecond)
+	for _, name := range []string{"notes.txt", "backup.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{"chat-next-web-store": {}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case event := <-events:
+		if event.Path != filepath.Join(dir, "backup.json") || event.Size != 27 {
+			t.Errorf("Run() reported %+v, want backup.json of 27 bytes", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the new file to be reported")
+	}
+
+	// Let a few more scans pass: nothing else may be reported.
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() returned an error after cancellation: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Run() reported %+v, want nothing more", <-events)
+	}
+}
+
+// TestWatcherMissingDirectory verifies that Run fails if the directory cannot be read on the
+// first scan.
+func TestWatcherMissingDirectory(t *testing.T) {
+	w := &watcher.Watcher{Dir: filepath.Join(t.TempDir(), "missing")}
+	err := w.Run(context.Background(), func(context.Context, watcher.Event) error { return nil })
+	if err == nil {
+		t.Error("Run() of a missing directory returned no error")
+	}
+}